@@ -3,6 +3,7 @@ module github.com/SimonHofman/EasySwapBackend
 go 1.25.1
 
 require (
+	github.com/HugoSmits86/nativewebp v0.9.3
 	github.com/SimonHofman/EasySwapBase v0.0.0-20260129092630-df4c3486b5d8 // indirect
 	github.com/anyswap/CrossChain-Bridge v0.3.9 // indirect
 	github.com/bytedance/sonic v1.14.0 // indirect
@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/api/middleware"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+)
+
+const MaxDeadLettersPageSize = 100
+
+// requestAdmin returns the logged in address of the request if it is one of
+// the configured admins.
+func requestAdmin(c *gin.Context, svcCtx *svc.ServerCtx) (string, bool) {
+	if svcCtx.C.Admin == nil {
+		return "", false
+	}
+	return requestAddressIn(c, svcCtx, svcCtx.C.Admin.Addresses)
+}
+
+// requestCurator returns the logged in address of the request if it may
+// curate collections, admins may as well.
+func requestCurator(c *gin.Context, svcCtx *svc.ServerCtx) (string, bool) {
+	if svcCtx.C.Admin == nil {
+		return "", false
+	}
+	allowed := append(append([]string{}, svcCtx.C.Admin.Addresses...), svcCtx.C.Admin.Curators...)
+	return requestAddressIn(c, svcCtx, allowed)
+}
+
+func requestAddressIn(c *gin.Context, svcCtx *svc.ServerCtx, allowed []string) (string, bool) {
+	userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+	if err != nil {
+		return "", false
+	}
+	for _, addr := range userAddrs {
+		for _, admin := range allowed {
+			if strings.EqualFold(addr, admin) {
+				return strings.ToLower(addr), true
+			}
+		}
+	}
+	return "", false
+}
+
+func DeadLettersHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requestAdmin(c, svcCtx); !ok {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		chainID, err := strconv.Atoi(c.Query("chain_id"))
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		chain, ok := chainIDToChain[chainID]
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		if limit <= 0 || limit > MaxDeadLettersPageSize {
+			limit = MaxDeadLettersPageSize
+		}
+
+		res, err := service.GetDeadLetters(c.Request.Context(), svcCtx, chain, c.Query("cursor"), limit)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, types.CommonResp{Result: res})
+	}
+}
+
+func ReplayDeadLettersHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requestAdmin(c, svcCtx); !ok {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		var params types.DeadLetterParams
+		if err := c.BindJSON(&params); err != nil || len(params.IDs) == 0 || len(params.IDs) > MaxDeadLettersPageSize {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		chain, ok := chainIDToChain[params.ChainID]
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.ReplayDeadLetters(c.Request.Context(), svcCtx, chain, params.IDs)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, types.CommonResp{Result: res})
+	}
+}
+
+func PurgeDeadLettersHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requestAdmin(c, svcCtx); !ok {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		var params types.DeadLetterParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		chain, ok := chainIDToChain[params.ChainID]
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.PurgeDeadLetters(c.Request.Context(), svcCtx, chain, params.IDs)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, types.CommonResp{Result: res})
+	}
+}
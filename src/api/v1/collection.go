@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"encoding/json"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+)
+
+const MaxCollectionBidsPageSize = 100
+
+// CollectionBidsHandler returns the bid depth of a collection by price, or of
+// the trait bids on one trait by trait value and price when filtered by trait.
+func CollectionBidsHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collectionAddr := c.Params.ByName("address")
+		if collectionAddr == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		filterParam := c.Query("filters")
+		if filterParam == "" {
+			xhttp.Error(c, errcode.NewCustomErr("Filter param is nil."))
+			return
+		}
+
+		var filter types.CollectionBidFilterParams
+		if err := json.Unmarshal([]byte(filterParam), &filter); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chain, ok := chainIDToChain[filter.ChainID]
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		if filter.Page <= 0 {
+			filter.Page = 1
+		}
+		if filter.PageSize <= 0 || filter.PageSize > MaxCollectionBidsPageSize {
+			filter.PageSize = MaxCollectionBidsPageSize
+		}
+
+		res, err := service.GetBids(c.Request.Context(), svcCtx, chain, collectionAddr, filter)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func CollectionSweepHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collectionAddr := c.Params.ByName("address")
+		if collectionAddr == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		filterParam := c.Query("filters")
+		if filterParam == "" {
+			xhttp.Error(c, errcode.NewCustomErr("Filter param is nil."))
+			return
+		}
+
+		var filter types.SweepParams
+		if err := json.Unmarshal([]byte(filterParam), &filter); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chain, ok := chainIDToChain[filter.ChainID]
+		if !ok || (filter.Count <= 0 && filter.Budget == "") {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.GetSweepQuote(c.Request.Context(), svcCtx, chain, collectionAddr, filter)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
@@ -0,0 +1,155 @@
+package v1
+
+import (
+	"strconv"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MaxCurationNoteLength    = 1000
+	MaxCurationAuditPageSize = 100
+)
+
+// SetCollectionCurationHandler sets the verified, featured and hidden flags
+// and the note of a collection.
+func SetCollectionCurationHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		operator, ok := requestCurator(c, svcCtx)
+		if !ok {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		collectionAddr := c.Params.ByName("address")
+		if !common.IsHexAddress(collectionAddr) {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		var params types.CurationParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		if params.Note != nil && len(*params.Note) > MaxCurationNoteLength {
+			xhttp.Error(c, errcode.NewCustomErr("note is too long"))
+			return
+		}
+		chain, ok := chainIDToChain[params.ChainID]
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.SetCollectionCuration(c.Request.Context(), svcCtx, operator, chain, collectionAddr, params)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, types.CommonResp{Result: res})
+	}
+}
+
+// CollectionCurationsHandler lists the curated collections of a chain.
+func CollectionCurationsHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requestCurator(c, svcCtx); !ok {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		chainID, err := strconv.Atoi(c.Query("chain_id"))
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		if _, ok := chainIDToChain[chainID]; !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.GetCollectionCurations(c.Request.Context(), svcCtx, chainID)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, types.CommonResp{Result: res})
+	}
+}
+
+// CurationAuditsHandler returns the audit log of collection curation, of
+// one collection when an address is given.
+func CurationAuditsHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requestAdmin(c, svcCtx); !ok {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		var chainID int
+		if c.Query("chain_id") != "" {
+			var err error
+			chainID, err = strconv.Atoi(c.Query("chain_id"))
+			if err != nil {
+				xhttp.Error(c, errcode.ErrInvalidParams)
+				return
+			}
+		}
+
+		collectionAddr := c.Query("address")
+		if collectionAddr != "" && !common.IsHexAddress(collectionAddr) {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		page, pageSize := 1, 20
+		if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+			page = p
+		}
+		if ps, err := strconv.Atoi(c.Query("page_size")); err == nil && ps > 0 {
+			pageSize = min(ps, MaxCurationAuditPageSize)
+		}
+
+		res, err := service.GetCurationAudits(c.Request.Context(), svcCtx, chainID, collectionAddr, page, pageSize)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+// FeaturedCollectionsHandler returns the collections featured on the
+// homepage.
+func FeaturedCollectionsHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chainID, err := strconv.Atoi(c.Query("chain_id"))
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		chain, ok := chainIDToChain[chainID]
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.GetFeaturedCollections(c.Request.Context(), svcCtx, chainID, chain)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, types.CommonResp{Result: res})
+	}
+}
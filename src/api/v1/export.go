@@ -0,0 +1,252 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/api/middleware"
+	"github.com/SimonHofman/EasySwapBackend/src/service/export"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const MaxExportAddresses = 20
+
+func TaxExportHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil || len(authAddrs) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		filterParam := c.Query("filters")
+		if filterParam == "" {
+			xhttp.Error(c, errcode.NewCustomErr("Filter param is nil."))
+			return
+		}
+
+		var filter types.TaxExportParams
+		if err := json.Unmarshal([]byte(filterParam), &filter); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		// a wallet only exports its own trades, all of them by default
+		if len(filter.UserAddresses) == 0 {
+			filter.UserAddresses = authAddrs
+		}
+		if len(filter.UserAddresses) > MaxExportAddresses {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		for i, addr := range filter.UserAddresses {
+			if !containsAddress(authAddrs, addr) {
+				xhttp.Error(c, errcode.ErrTokenVerify)
+				return
+			}
+			filter.UserAddresses[i] = strings.ToLower(addr)
+		}
+
+		if filter.Format == "" {
+			filter.Format = export.FormatCSV
+		}
+		if !export.IsFormat(filter.Format) {
+			xhttp.Error(c, errcode.NewCustomErr("Format must be csv or jsonl."))
+			return
+		}
+
+		if filter.To <= 0 {
+			filter.To = time.Now().Unix()
+		}
+		if filter.From < 0 || filter.From > filter.To {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chainIDs, chainNames, ok := exportChainNames(svcCtx, filter.ChainID)
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		streamExport(c, filter.Format, fmt.Sprintf("tax-events-%d-%d", filter.From, filter.To), func(w *export.Writer) error {
+			return service.ExportTaxEvents(c.Request.Context(), svcCtx, chainIDs, chainNames, filter.UserAddresses,
+				filter.From, filter.To, w)
+		})
+	}
+}
+
+func containsAddress(addrs []string, addr string) bool {
+	for _, a := range addrs {
+		if strings.EqualFold(a, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// streamExport sends the records written by fn as an attachment. The status
+// is sent with the first records, so an error ends the stream with an error
+// record telling the client the export is incomplete.
+func streamExport(c *gin.Context, format, filename string, fn func(w *export.Writer) error) {
+	w, err := export.NewWriter(format, c.Writer)
+	if err != nil {
+		xhttp.Error(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	c.Status(http.StatusOK)
+
+	if err := fn(w); err != nil {
+		xzap.WithContext(c.Request.Context()).Error("failed on export", zap.String("file", filename), zap.Error(err))
+		if err := w.WriteError(export.IncompleteMessage); err != nil {
+			xzap.WithContext(c.Request.Context()).Error("failed on write export error", zap.Error(err))
+		}
+		c.Abort()
+	}
+}
+
+// exportChainNames validates the chains of an export, all supported chains
+// when none is given.
+func exportChainNames(svcCtx *svc.ServerCtx, chainIDs []int) ([]int, []string, bool) {
+	if len(chainIDs) == 0 {
+		for _, supported := range svcCtx.C.ChainSupported {
+			chainIDs = append(chainIDs, supported.ChainID)
+		}
+	}
+
+	var chainNames []string
+	for _, chainID := range chainIDs {
+		chain, ok := chainIDToChain[chainID]
+		if !ok {
+			return nil, nil, false
+		}
+		chainNames = append(chainNames, chain)
+	}
+	return chainIDs, chainNames, true
+}
+
+func ActivityExportHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owners, err := requestOwners(c, svcCtx)
+		if err != nil || len(owners) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		filterParam := c.Query("filters")
+		if filterParam == "" {
+			xhttp.Error(c, errcode.NewCustomErr("Filter param is nil."))
+			return
+		}
+
+		var filter types.ActivityExportParams
+		if err := json.Unmarshal([]byte(filterParam), &filter); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if filter.Format == "" {
+			filter.Format = export.FormatJSON
+		}
+		chainIDs, chainNames, ok := exportChainNames(svcCtx, filter.ChainID)
+		if !ok || !export.IsFormat(filter.Format) {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		streamExport(c, filter.Format, "activities", func(w *export.Writer) error {
+			return service.ExportActivities(c.Request.Context(), svcCtx, chainIDs, chainNames, filter, w)
+		})
+	}
+}
+
+func OrderExportHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owners, err := requestOwners(c, svcCtx)
+		if err != nil || len(owners) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		filterParam := c.Query("filters")
+		if filterParam == "" {
+			xhttp.Error(c, errcode.NewCustomErr("Filter param is nil."))
+			return
+		}
+
+		var filter types.OrderExportParams
+		if err := json.Unmarshal([]byte(filterParam), &filter); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if filter.Format == "" {
+			filter.Format = export.FormatJSON
+		}
+		chainIDs, chainNames, ok := exportChainNames(svcCtx, filter.ChainID)
+		if !ok || !export.IsFormat(filter.Format) {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		streamExport(c, filter.Format, "orders", func(w *export.Writer) error {
+			return service.ExportActiveOrders(c.Request.Context(), svcCtx, chainIDs, chainNames, filter, w)
+		})
+	}
+}
+
+func ItemExportHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owners, err := requestOwners(c, svcCtx)
+		if err != nil || len(owners) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		collectionAddr := c.Params.ByName("address")
+		if !common.IsHexAddress(collectionAddr) {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		collectionAddr = strings.ToLower(collectionAddr)
+
+		filterParam := c.Query("filters")
+		if filterParam == "" {
+			xhttp.Error(c, errcode.NewCustomErr("Filter param is nil."))
+			return
+		}
+
+		var filter types.ItemExportParams
+		if err := json.Unmarshal([]byte(filterParam), &filter); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if filter.Format == "" {
+			filter.Format = export.FormatJSON
+		}
+		chain, ok := chainIDToChain[filter.ChainID]
+		if !ok || !export.IsFormat(filter.Format) {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		streamExport(c, filter.Format, "items-"+collectionAddr, func(w *export.Writer) error {
+			return service.ExportCollectionItems(c.Request.Context(), svcCtx, chain, collectionAddr, filter, w)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/media"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// MediaProxyHandler serves the image or animation of an item made safe to
+// show in a browser, see service.MediaProxyPath for the route.
+func MediaProxyHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chainID, err := strconv.Atoi(c.Params.ByName("chain_id"))
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		chain, ok := chainIDToChain[chainID]
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		collectionAddr := c.Params.ByName("address")
+		tokenID := c.Params.ByName("token_id")
+		kind := c.Params.ByName("kind")
+		if collectionAddr == "" || tokenID == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		rasterize, _ := strconv.Atoi(c.Query("rasterize"))
+
+		object, err := service.GetItemMedia(c.Request.Context(), svcCtx, chain, collectionAddr, tokenID, kind, rasterize)
+		if err != nil {
+			if errors.Is(err, media.ErrUnsupportedMedia) || errors.Is(err, media.ErrInvalidSVG) {
+				c.Status(http.StatusUnsupportedMediaType)
+				return
+			}
+			if errors.Is(err, media.ErrMediaUnavailable) {
+				c.Status(http.StatusBadGateway)
+				return
+			}
+			xhttp.Error(c, err)
+			return
+		}
+		defer object.Body.Close()
+
+		c.Header("Content-Security-Policy", media.ContentSecurityPolicy)
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Cross-Origin-Resource-Policy", "cross-origin")
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.Header("ETag", `"`+object.ETag+`"`)
+		if c.GetHeader("If-None-Match") == `"`+object.ETag+`"` {
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		c.Header("Content-Type", object.ContentType)
+		c.Status(http.StatusOK)
+		_, _ = io.Copy(c.Writer, object.Body)
+	}
+}
@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"strconv"
+
+	"github.com/SimonHofman/EasySwapBackend/src/api/middleware"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+)
+
+const MaxNotificationPageSize = 100
+
+func NotificationsHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		filter := types.NotificationFilterParams{Page: 1, PageSize: 20}
+		if page, err := strconv.Atoi(c.Query("page")); err == nil && page > 0 {
+			filter.Page = page
+		}
+		if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil && pageSize > 0 {
+			filter.PageSize = pageSize
+		}
+		if filter.PageSize > MaxNotificationPageSize {
+			filter.PageSize = MaxNotificationPageSize
+		}
+		filter.UnreadOnly = c.Query("unread_only") == "true"
+
+		res, err := service.GetNotifications(c.Request.Context(), svcCtx, userAddrs, filter)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func ReadNotificationsHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		var params types.ReadNotificationsParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if err := service.ReadNotifications(c.Request.Context(), svcCtx, userAddrs, params.IDs); err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, struct{}{})
+	}
+}
@@ -0,0 +1,159 @@
+package v1
+
+import (
+	"strconv"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+)
+
+const MaxValidateOrders = 100
+
+func OrderValidateHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var params types.OrderValidateParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if _, ok := chainIDToChain[params.ChainID]; !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if len(params.OrderIDs) == 0 || len(params.OrderIDs) > MaxValidateOrders {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.ValidateOrders(c.Request.Context(), svcCtx, params)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+const MaxSubmitOrders = 50
+
+func SubmitOrderHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var params types.SubmitOrderParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chain, ok := chainIDToChain[params.ChainID]
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.SubmitOrders(c.Request.Context(), svcCtx, chain, types.SubmitOrdersParams{
+			ChainID: params.ChainID,
+			Orders:  []types.SignedOrder{params.Order},
+		})
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func BatchSubmitOrdersHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var params types.SubmitOrdersParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chain, ok := chainIDToChain[params.ChainID]
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if len(params.Orders) == 0 || len(params.Orders) > MaxSubmitOrders {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.SubmitOrders(c.Request.Context(), svcCtx, chain, params)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func CancelOrdersHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var params types.CancelOrdersParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chain, ok := chainIDToChain[params.ChainID]
+		if !ok || params.Maker == "" || params.Signature == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if len(params.OrderIDs) == 0 || len(params.OrderIDs) > MaxSubmitOrders {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.CancelOrders(c.Request.Context(), svcCtx, chain, params)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func OrderCancellationHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orderID := c.Params.ByName("order_id")
+		if orderID == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chainID, err := strconv.Atoi(c.Query("chain_id"))
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chain, ok := chainIDToChain[chainID]
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.GetOrderCancellation(c.Request.Context(), svcCtx, chainID, chain, orderID)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
@@ -0,0 +1,120 @@
+package v1
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/portfolio"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+)
+
+func UserPnLHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filterParam := c.Query("filters")
+		if filterParam == "" {
+			xhttp.Error(c, errcode.NewCustomErr("Filter param is nil."))
+			return
+		}
+
+		var filter types.PortfolioPnLFilterParams
+		if err := json.Unmarshal([]byte(filterParam), &filter); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if len(filter.UserAddresses) == 0 {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if len(filter.ChainID) == 0 {
+			for _, supported := range svcCtx.C.ChainSupported {
+				filter.ChainID = append(filter.ChainID, supported.ChainID)
+			}
+		}
+
+		var chainNames []string
+		for _, chainID := range filter.ChainID {
+			chain, ok := chainIDToChain[chainID]
+			if !ok {
+				xhttp.Error(c, errcode.ErrInvalidParams)
+				return
+			}
+			chainNames = append(chainNames, chain)
+		}
+
+		res, err := service.GetMultiChainUserPnL(c.Request.Context(), svcCtx, filter.ChainID, chainNames, filter.UserAddresses, filter.CollectionAddresses)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+const (
+	DefaultHistoryDays = 30
+	MaxHistoryDays     = 731
+)
+
+func PortfolioHistoryHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filterParam := c.Query("filters")
+		if filterParam == "" {
+			xhttp.Error(c, errcode.NewCustomErr("Filter param is nil."))
+			return
+		}
+
+		var filter types.PortfolioHistoryFilterParams
+		if err := json.Unmarshal([]byte(filterParam), &filter); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if len(filter.UserAddresses) == 0 {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if filter.Resolution == "" {
+			filter.Resolution = service.ResolutionDay
+		}
+		if !service.IsHistoryResolution(filter.Resolution) {
+			xhttp.Error(c, errcode.NewCustomErr("Resolution must be one of 1d, 1w, 1M."))
+			return
+		}
+
+		if filter.To <= 0 {
+			filter.To = time.Now().Unix()
+		}
+		if filter.From <= 0 {
+			filter.From = filter.To - DefaultHistoryDays*portfolio.Day
+		}
+		if filter.From > filter.To || filter.To-filter.From > MaxHistoryDays*portfolio.Day {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		for _, chainID := range filter.ChainID {
+			if _, ok := chainIDToChain[chainID]; !ok {
+				xhttp.Error(c, errcode.ErrInvalidParams)
+				return
+			}
+		}
+
+		res, err := service.GetPortfolioHistory(c.Request.Context(), svcCtx, filter.ChainID, filter.UserAddresses,
+			filter.CollectionAddresses, portfolio.DayStart(filter.From), filter.To, filter.Resolution)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
@@ -0,0 +1,121 @@
+package v1
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/api/middleware"
+	"github.com/SimonHofman/EasySwapBackend/src/service/storage"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// UserProfileHandler returns the public profile of a user by address or username.
+func UserProfileHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddr := c.Query("address")
+		username := c.Query("username")
+		if username == "" && !common.IsHexAddress(userAddr) {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.GetUserProfile(c.Request.Context(), svcCtx, userAddr, username)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func UpdateProfileHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil || len(userAddrs) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		var params types.UpdateProfileParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.UpdateUserProfile(c.Request.Context(), svcCtx, userAddrs[0], params)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+// UploadAvatarHandler reads the image of the "avatar" multipart field.
+func UploadAvatarHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil || len(userAddrs) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxAvatarSize+(64<<10))
+		fileHeader, err := c.FormFile("avatar")
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		defer file.Close()
+
+		res, err := service.UploadAvatar(c.Request.Context(), svcCtx, userAddrs[0], file)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+// ObjectHandler serves the objects of the local storage, it is only routed
+// when the local storage is used.
+func ObjectHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Params.ByName("key"), "/")
+		object, contentType, err := svcCtx.Storage.Get(c.Request.Context(), key)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				c.Status(http.StatusNotFound)
+				return
+			}
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		defer object.Close()
+
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		c.Status(http.StatusOK)
+		_, _ = io.Copy(c.Writer, object)
+	}
+}
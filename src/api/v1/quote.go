@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"github.com/SimonHofman/EasySwapBackend/src/api/middleware"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+)
+
+func AcceptBidsQuoteHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		var params types.AcceptBidsQuoteParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chain, ok := chainIDToChain[params.ChainID]
+		if !ok || params.CollectionAddress == "" || len(params.Items) == 0 {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.GetAcceptBidsQuote(c.Request.Context(), svcCtx, chain, userAddrs, params)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
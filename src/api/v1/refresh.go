@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+)
+
+func CollectionRefreshHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owners, err := requestOwners(c, svcCtx)
+		if err != nil || len(owners) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		collectionAddr := c.Params.ByName("address")
+		if collectionAddr == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		var params types.RefreshCollectionParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chain, ok := chainIDToChain[params.ChainID]
+		if !ok {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.RefreshCollectionMetadata(c.Request.Context(), svcCtx, int64(params.ChainID), chain, collectionAddr, owners[0])
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, types.CommonResp{Result: res})
+	}
+}
+
+func RefreshJobHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return refreshJobHandler(svcCtx, service.GetRefreshJob)
+}
+
+func CancelRefreshJobHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return refreshJobHandler(svcCtx, service.CancelRefreshJob)
+}
+
+func RetryRefreshJobHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return refreshJobHandler(svcCtx, service.RetryRefreshJob)
+}
+
+// refreshJobHandler runs fn on the refresh job of the `id` path param, which
+// must have been started by the caller.
+func refreshJobHandler(svcCtx *svc.ServerCtx,
+	fn func(ctx context.Context, svcCtx *svc.ServerCtx, jobID string, callers []string) (*types.RefreshJob, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owners, err := requestOwners(c, svcCtx)
+		if err != nil || len(owners) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		jobID := c.Params.ByName("id")
+		if jobID == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := fn(c.Request.Context(), svcCtx, jobID, owners)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, types.CommonResp{Result: res})
+	}
+}
@@ -0,0 +1,76 @@
+package v1
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+)
+
+func SearchHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filterParam := c.Query("filters")
+		if filterParam == "" {
+			xhttp.Error(c, errcode.NewCustomErr("Filter param is nil."))
+			return
+		}
+
+		var filter types.SearchParams
+		if err := json.Unmarshal([]byte(filterParam), &filter); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if filter.Query == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.Search(c.Request.Context(), svcCtx, filter)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func AutocompleteHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Query("q")
+		if query == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		var chainIDs []int
+		if chainIDParam := c.Query("chain_id"); chainIDParam != "" {
+			chainID, err := strconv.Atoi(chainIDParam)
+			if err != nil {
+				xhttp.Error(c, errcode.ErrInvalidParams)
+				return
+			}
+			chainIDs = append(chainIDs, chainID)
+		}
+
+		limit, _ := strconv.Atoi(c.Query("limit"))
+
+		res, err := service.Autocomplete(c.Request.Context(), svcCtx, types.SearchParams{
+			Query:    query,
+			ChainIDs: chainIDs,
+			Limit:    limit,
+		})
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/stream"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const DefaultStreamHeartbeat = 15 * time.Second
+
+var streamCursorPattern = regexp.MustCompile(`^\d+(-\d+)?$`)
+
+// StreamHandler pushes activities, order changes and floor updates as server
+// sent events. Reconnecting clients resume from the Last-Event-ID header or
+// the cursor query param.
+func StreamHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseStreamFilter(c)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		cursor := c.GetHeader("Last-Event-ID")
+		if cursor == "" {
+			cursor = c.Query("cursor")
+		}
+		if cursor != "" && !streamCursorPattern.MatchString(cursor) {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		heartbeat := DefaultStreamHeartbeat
+		if svcCtx.C.Stream != nil && svcCtx.C.Stream.Heartbeat > 0 {
+			heartbeat = time.Duration(svcCtx.C.Stream.Heartbeat) * time.Second
+		}
+
+		// subscribe before replaying so no event is lost in between
+		sub := svcCtx.Stream.Subscribe(*filter)
+		defer svcCtx.Stream.Unsubscribe(sub)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(200)
+		c.Writer.Flush()
+
+		ctx := c.Request.Context()
+		lastID := cursor
+		if cursor != "" {
+			if err := svcCtx.Stream.Replay(ctx, cursor, *filter, func(event *stream.Event) error {
+				lastID = event.ID
+				return writeStreamEvent(c.Writer, event)
+			}); err != nil {
+				xzap.WithContext(ctx).Error("failed on replay stream events", zap.Error(err))
+				return
+			}
+			c.Writer.Flush()
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				c.Writer.Flush()
+			case event, ok := <-sub.C:
+				if !ok {
+					// dropped for being too slow, the client resumes from its last id
+					return
+				}
+				if lastID != "" && !stream.After(event.ID, lastID) {
+					continue
+				}
+				lastID = event.ID
+
+				if err := writeStreamEvent(c.Writer, event); err != nil {
+					return
+				}
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+func parseStreamFilter(c *gin.Context) (*stream.Filter, error) {
+	var filter stream.Filter
+	for _, value := range splitQuery(c.Query("chain_id")) {
+		chainID, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := chainIDToChain[chainID]; !ok {
+			return nil, fmt.Errorf("unsupported chain id %d", chainID)
+		}
+		filter.ChainIDs = append(filter.ChainIDs, chainID)
+	}
+
+	filter.Collections = splitQuery(c.Query("collection"))
+	filter.TokenID = c.Query("token_id")
+	filter.Users = splitQuery(c.Query("user"))
+	filter.EventTypes = splitQuery(c.Query("types"))
+
+	return &filter, nil
+}
+
+func splitQuery(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, strings.ToLower(v))
+		}
+	}
+	return values
+}
+
+func writeStreamEvent(w io.Writer, event *stream.Event) error {
+	rawEvent, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Kind, rawEvent)
+	return err
+}
@@ -0,0 +1,160 @@
+package v1
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/api/middleware"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+)
+
+func WatchlistHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		res, err := service.GetWatchlist(c.Request.Context(), svcCtx, userAddrs)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func AddWatchlistHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil || len(userAddrs) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		var params types.WatchlistParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chain, ok := chainIDToChain[params.ChainID]
+		if !ok || params.CollectionAddress == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if err := service.AddWatchlist(c.Request.Context(), svcCtx, strings.ToLower(userAddrs[0]), chain, params); err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, struct{}{})
+	}
+}
+
+func RemoveWatchlistHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		var params types.WatchlistParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if _, ok := chainIDToChain[params.ChainID]; !ok || params.CollectionAddress == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if err := service.RemoveWatchlist(c.Request.Context(), svcCtx, userAddrs, params); err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, struct{}{})
+	}
+}
+
+func PriceAlertsHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		res, err := service.GetPriceAlerts(c.Request.Context(), svcCtx, userAddrs)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func CreatePriceAlertHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil || len(userAddrs) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		var params types.CreatePriceAlertParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		chain, ok := chainIDToChain[params.ChainID]
+		if !ok || params.CollectionAddress == "" {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		res, err := service.CreatePriceAlert(c.Request.Context(), svcCtx, strings.ToLower(userAddrs[0]), chain, params)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func DeletePriceAlertHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Params.ByName("id"), 10, 64)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if err := service.DeletePriceAlert(c.Request.Context(), svcCtx, userAddrs, id); err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, struct{}{})
+	}
+}
@@ -0,0 +1,181 @@
+package v1
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/api/middleware"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/service/v1"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/xhttp"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const (
+	HeaderAPIKey                 = "X-API-Key"
+	MaxWebhookDeliveriesPageSize = 100
+)
+
+// requestOwners returns the owners a request acts for: the api key of the
+// X-API-Key header, or else the logged in user addresses.
+func requestOwners(c *gin.Context, svcCtx *svc.ServerCtx) ([]string, error) {
+	if key := c.GetHeader(HeaderAPIKey); key != "" {
+		apiKey, err := svcCtx.Dao.QueryAPIKey(c.Request.Context(), key)
+		if err != nil {
+			return nil, err
+		}
+		if apiKey == nil {
+			return nil, errors.New("invalid api key")
+		}
+		return []string{"apikey:" + strconv.FormatInt(apiKey.Id, 10)}, nil
+	}
+
+	userAddrs, err := middleware.GetAuthUserAddress(c, svcCtx.KvStore)
+	if err != nil {
+		return nil, err
+	}
+
+	var owners []string
+	for _, addr := range userAddrs {
+		owners = append(owners, "user:"+strings.ToLower(addr))
+	}
+	return owners, nil
+}
+
+func CreateWebhookHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owners, err := requestOwners(c, svcCtx)
+		if err != nil || len(owners) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		var params types.CreateWebhookParams
+		if err := c.BindJSON(&params); err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if params.ChainID != 0 {
+			if _, ok := chainIDToChain[params.ChainID]; !ok {
+				xhttp.Error(c, errcode.ErrInvalidParams)
+				return
+			}
+		}
+
+		res, err := service.CreateWebhook(c.Request.Context(), svcCtx, owners[0], params)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func WebhooksHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owners, err := requestOwners(c, svcCtx)
+		if err != nil || len(owners) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		res, err := service.GetWebhooks(c.Request.Context(), svcCtx, owners)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrUnexpected)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func DeleteWebhookHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owners, err := requestOwners(c, svcCtx)
+		if err != nil || len(owners) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Params.ByName("id"), 10, 64)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if err := service.DeleteWebhook(c.Request.Context(), svcCtx, owners, id); err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, struct{}{})
+	}
+}
+
+func WebhookDeliveriesHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owners, err := requestOwners(c, svcCtx)
+		if err != nil || len(owners) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Params.ByName("id"), 10, 64)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		filter := types.WebhookDeliveryFilterParams{Status: c.Query("status"), Page: 1, PageSize: 20}
+		if page, err := strconv.Atoi(c.Query("page")); err == nil && page > 0 {
+			filter.Page = page
+		}
+		if pageSize, err := strconv.Atoi(c.Query("page_size")); err == nil && pageSize > 0 {
+			filter.PageSize = pageSize
+		}
+		if filter.PageSize > MaxWebhookDeliveriesPageSize {
+			filter.PageSize = MaxWebhookDeliveriesPageSize
+		}
+
+		res, err := service.GetWebhookDeliveries(c.Request.Context(), svcCtx, owners, id, filter)
+		if err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, res)
+	}
+}
+
+func RetryWebhookDeliveryHandler(svcCtx *svc.ServerCtx) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owners, err := requestOwners(c, svcCtx)
+		if err != nil || len(owners) == 0 {
+			xhttp.Error(c, errcode.ErrTokenVerify)
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Params.ByName("id"), 10, 64)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+		deliveryID, err := strconv.ParseInt(c.Params.ByName("delivery_id"), 10, 64)
+		if err != nil {
+			xhttp.Error(c, errcode.ErrInvalidParams)
+			return
+		}
+
+		if err := service.RetryWebhookDelivery(c.Request.Context(), svcCtx, owners, id, deliveryID); err != nil {
+			xhttp.Error(c, err)
+			return
+		}
+
+		xhttp.OkJson(c, struct{}{})
+	}
+}
@@ -0,0 +1,60 @@
+// Command migrate applies the pending schema migrations of the backend
+// tables. The API refuses to start until they are applied.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb"
+)
+
+func main() {
+	conf := flag.String("conf", "./config/config.toml", "path of the config file")
+	dryRun := flag.Bool("dry-run", false, "list the pending migrations without applying them")
+	flag.Parse()
+
+	if err := run(*conf, *dryRun); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(conf string, dryRun bool) error {
+	c, err := config.UnmarshalConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	db, err := gdb.NewDB(&c.DB)
+	if err != nil {
+		return err
+	}
+
+	var chains []string
+	for _, supported := range c.ChainSupported {
+		chains = append(chains, supported.Name)
+	}
+
+	d := dao.New(context.Background(), db, nil)
+	if dryRun {
+		pending, err := d.PendingMigrations(context.Background(), chains)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			fmt.Println("pending", migration)
+		}
+		return nil
+	}
+
+	applied, err := d.Migrate(context.Background(), chains)
+	for _, migration := range applied {
+		fmt.Println("applied", migration)
+	}
+	return err
+}
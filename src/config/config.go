@@ -18,6 +18,22 @@ type Config struct {
 	Evm            *erc.NftErc       `toml:"evm" json:"evm"`
 	MetadataParse  *MetadataParse    `toml:"metadata_parse" mapstructure:"metadata_parse" json:"metadata_parse"`
 	ChainSupported []*ChainSupported `toml:"chain_supported" mapstructure:"chain_supported" json:"chain_supported"`
+	Search         *SearchConf       `toml:"search" mapstructure:"search" json:"search"`
+	Fee            *FeeConf          `toml:"fee" mapstructure:"fee" json:"fee"`
+	OrderValidator *OrderValidator   `toml:"order_validator" mapstructure:"order_validator" json:"order_validator"`
+	OrderExpiry    *OrderExpiry      `toml:"order_expiry" mapstructure:"order_expiry" json:"order_expiry"`
+	Notification   *Notification     `toml:"notification" mapstructure:"notification" json:"notification"`
+	Stream         *Stream           `toml:"stream" mapstructure:"stream" json:"stream"`
+	Webhook        *Webhook          `toml:"webhook" mapstructure:"webhook" json:"webhook"`
+	PriceAlert     *PriceAlert       `toml:"price_alert" mapstructure:"price_alert" json:"price_alert"`
+	Storage        *Storage          `toml:"storage" mapstructure:"storage" json:"storage"`
+	Snapshot       *Snapshot         `toml:"snapshot" mapstructure:"snapshot" json:"snapshot"`
+	Marketplace    *Marketplace      `toml:"marketplace" mapstructure:"marketplace" json:"marketplace"`
+	RefreshQueue   *RefreshQueue     `toml:"refresh_queue" mapstructure:"refresh_queue" json:"refresh_queue"`
+	Admin          *Admin            `toml:"admin" mapstructure:"admin" json:"admin"`
+	MetadataWorker *MetadataWorker   `toml:"metadata_worker" mapstructure:"metadata_worker" json:"metadata_worker"`
+	Media          *Media            `toml:"media" mapstructure:"media" json:"media"`
+	Balance        *Balance          `toml:"balance" mapstructure:"balance" json:"balance"`
 }
 
 type ProjectCfg struct {
@@ -48,10 +64,119 @@ type MetadataParse struct {
 	TraitValueTags []string `toml:"trait_value_tags" mapstructure:"trait_value_tags" json:"trait_value_tags"`
 }
 
+type SearchConf struct {
+	SyncInterval int `toml:"sync_interval" mapstructure:"sync_interval" json:"sync_interval"`
+}
+
+type FeeConf struct {
+	DefaultRate  string            `toml:"default_rate" mapstructure:"default_rate" json:"default_rate"`
+	Marketplaces []*MarketplaceFee `toml:"marketplaces" mapstructure:"marketplaces" json:"marketplaces"`
+}
+
+type MarketplaceFee struct {
+	MarketplaceID int    `toml:"marketplace_id" mapstructure:"marketplace_id" json:"marketplace_id"`
+	Rate          string `toml:"rate" mapstructure:"rate" json:"rate"`
+}
+
+type OrderValidator struct {
+	Interval  int `toml:"interval" mapstructure:"interval" json:"interval"`
+	BatchSize int `toml:"batch_size" mapstructure:"batch_size" json:"batch_size"`
+}
+
+type OrderExpiry struct {
+	Interval  int `toml:"interval" mapstructure:"interval" json:"interval"`
+	BatchSize int `toml:"batch_size" mapstructure:"batch_size" json:"batch_size"`
+}
+
+type Notification struct {
+	Interval      int `toml:"interval" mapstructure:"interval" json:"interval"`
+	ExpiringHours int `toml:"expiring_hours" mapstructure:"expiring_hours" json:"expiring_hours"`
+}
+
+type Stream struct {
+	FeedInterval int `toml:"feed_interval" mapstructure:"feed_interval" json:"feed_interval"`
+	Heartbeat    int `toml:"heartbeat" mapstructure:"heartbeat" json:"heartbeat"`
+}
+
+type Webhook struct {
+	Interval    int `toml:"interval" mapstructure:"interval" json:"interval"`
+	Timeout     int `toml:"timeout" mapstructure:"timeout" json:"timeout"`
+	MaxAttempts int `toml:"max_attempts" mapstructure:"max_attempts" json:"max_attempts"`
+	MaxPerOwner int `toml:"max_per_owner" mapstructure:"max_per_owner" json:"max_per_owner"`
+	// AllowPrivateURL allows http and private addresses, for local testing only.
+	AllowPrivateURL bool `toml:"allow_private_url" mapstructure:"allow_private_url" json:"allow_private_url"`
+}
+
+type Balance struct {
+	Interval  int `toml:"interval" mapstructure:"interval" json:"interval"`
+	BatchSize int `toml:"batch_size" mapstructure:"batch_size" json:"batch_size"`
+}
+
+type PriceAlert struct {
+	Interval int `toml:"interval" mapstructure:"interval" json:"interval"`
+}
+
+type Storage struct {
+	Type     string `toml:"type" mapstructure:"type" json:"type"`
+	LocalDir string `toml:"local_dir" mapstructure:"local_dir" json:"local_dir"`
+	BaseURL  string `toml:"base_url" mapstructure:"base_url" json:"base_url"`
+}
+
+type Snapshot struct {
+	Interval     int `toml:"interval" mapstructure:"interval" json:"interval"`
+	BackfillDays int `toml:"backfill_days" mapstructure:"backfill_days" json:"backfill_days"`
+}
+
+type Marketplace struct {
+	Interval int                   `toml:"interval" mapstructure:"interval" json:"interval"`
+	Adapters []*MarketplaceAdapter `toml:"adapters" mapstructure:"adapters" json:"adapters"`
+}
+
+type MarketplaceAdapter struct {
+	MarketplaceID int    `toml:"marketplace_id" mapstructure:"marketplace_id" json:"marketplace_id"`
+	Type          string `toml:"type" mapstructure:"type" json:"type"`
+	Path          string `toml:"path" mapstructure:"path" json:"path"`
+}
+
+type RefreshQueue struct {
+	MaxAttempts int `toml:"max_attempts" mapstructure:"max_attempts" json:"max_attempts"`
+	ReclaimIdle int `toml:"reclaim_idle" mapstructure:"reclaim_idle" json:"reclaim_idle"`
+}
+
+type MetadataWorker struct {
+	Enabled         bool     `toml:"enabled" mapstructure:"enabled" json:"enabled"`
+	Concurrency     int      `toml:"concurrency" mapstructure:"concurrency" json:"concurrency"`
+	Timeout         int      `toml:"timeout" mapstructure:"timeout" json:"timeout"`
+	MaxSize         int64    `toml:"max_size" mapstructure:"max_size" json:"max_size"`
+	IPFSGateways    []string `toml:"ipfs_gateways" mapstructure:"ipfs_gateways" json:"ipfs_gateways"`
+	ArweaveGateways []string `toml:"arweave_gateways" mapstructure:"arweave_gateways" json:"arweave_gateways"`
+	// AllowPrivateURL allows fetching from private addresses, for local testing only.
+	AllowPrivateURL bool `toml:"allow_private_url" mapstructure:"allow_private_url" json:"allow_private_url"`
+}
+
+type Media struct {
+	Enabled   bool  `toml:"enabled" mapstructure:"enabled" json:"enabled"`
+	Interval  int   `toml:"interval" mapstructure:"interval" json:"interval"`
+	BatchSize int   `toml:"batch_size" mapstructure:"batch_size" json:"batch_size"`
+	Sizes     []int `toml:"sizes" mapstructure:"sizes" json:"sizes"`
+	// Format is the thumbnail format, webp or png, webp by default.
+	Format string `toml:"format" mapstructure:"format" json:"format"`
+	// Proxy serves unsafe item media, such as svg, through the media proxy.
+	Proxy bool `toml:"proxy" mapstructure:"proxy" json:"proxy"`
+}
+
+type Admin struct {
+	Addresses []string `toml:"addresses" mapstructure:"addresses" json:"addresses"`
+	// Curators may only set the curation flags of collections.
+	Curators []string `toml:"curators" mapstructure:"curators" json:"curators"`
+}
+
 type ChainSupported struct {
-	Name     string `toml:"name" mapstructure:"name" json:"name"`
-	ChainID  int    `toml:"chain_id" mapstructure:"chain_id" json:"chain_id"`
-	Endpoint string `toml:"endpoint" mapstructure:"endpoint" json:"endpoint"`
+	Name             string `toml:"name" mapstructure:"name" json:"name"`
+	ChainID          int    `toml:"chain_id" mapstructure:"chain_id" json:"chain_id"`
+	Endpoint         string `toml:"endpoint" mapstructure:"endpoint" json:"endpoint"`
+	VaultAddress     string `toml:"vault_address" mapstructure:"vault_address" json:"vault_address"`
+	OrderBookAddress string `toml:"order_book_address" mapstructure:"order_book_address" json:"order_book_address"`
 }
 
 func UnmarshalConfig(configFilePath string) (*Config, error) {
@@ -47,6 +47,11 @@ var idToEventTypes = map[int]string{
 	multi.CancelItemBid:       "cancel_item_bid",
 }
 
+func IsActivityEventType(eventType string) bool {
+	_, ok := eventTypesToID[eventType]
+	return ok
+}
+
 type ActivityCountCache struct {
 	Chain             string   `json:"chain"`
 	ContractAddresses []string `json:"contract_addresses"`
@@ -68,7 +73,10 @@ func getActivityCountCacheKey(activity *ActivityCountCache) (string, error) {
 	return CacheActivityNumPrefix + string(uid), nil
 }
 
-func (d *Dao) QueryMultiChainActivities(ctx context.Context, chainName []string, collectionAddrs []string, tokenID string, userAddrs []string, eventTypes []string, page, pageSize int) ([]ActivityMultiChainInfo, int64, error) {
+// QueryMultiChainActivities returns a page of activities across chains.
+// Hidden collections of the chains in curatedChains, chain ids by name, are
+// left out.
+func (d *Dao) QueryMultiChainActivities(ctx context.Context, chainName []string, collectionAddrs []string, curatedChains map[string]int, tokenID string, userAddrs []string, eventTypes []string, page, pageSize int) ([]ActivityMultiChainInfo, int64, error) {
 	var strNums []string
 
 	var total int64
@@ -91,10 +99,11 @@ func (d *Dao) QueryMultiChainActivities(ctx context.Context, chainName []string,
 			sqlMid += " UNION ALL "
 		}
 		sqlMid += fmt.Sprintf("(select '%s' as chain_name,id,collection_address,token_id,currency_address,activity_type,maker,taker,price,tx_hash,event_time,marketplace_id ", chain)
-		sqlMid += fmt.Sprintf("from %s ", multi.ActivityTableName(chain))
+		sqlMid += fmt.Sprintf("from %s a ", multi.ActivityTableName(chain))
 
+		var conditions []string
 		if len(userAddrs) == 1 {
-			sqlMid += fmt.Sprintf("where maker = '%s' or taker = '%s'", strings.ToLower(userAddrs[0]), strings.ToLower(userAddrs[0]))
+			conditions = append(conditions, fmt.Sprintf("(maker = '%s' or taker = '%s')", strings.ToLower(userAddrs[0]), strings.ToLower(userAddrs[0])))
 		} else if len(userAddrs) > 1 {
 			var userAddrsParam string
 			for i, addr := range userAddrs {
@@ -103,12 +112,18 @@ func (d *Dao) QueryMultiChainActivities(ctx context.Context, chainName []string,
 					userAddrsParam += ", "
 				}
 			}
-			sqlMid += fmt.Sprintf("where maker in (%s) or taker in (%s)", userAddrsParam, userAddrsParam)
+			conditions = append(conditions, fmt.Sprintf("(maker in (%s) or taker in (%s))", userAddrsParam, userAddrsParam))
+		}
+		if chainID, ok := curatedChains[chain]; ok {
+			conditions = append(conditions, notHidden(chainID, "a.collection_address"))
+		}
+		if len(conditions) > 0 {
+			sqlMid += "where " + strings.Join(conditions, " and ")
 		}
 		sqlMid += ") "
 	}
 
-	sqlTail := ") as combined"
+	sqlTail := ") as combined "
 	firstFlag := true
 
 	if len(collectionAddrs) == 1 {
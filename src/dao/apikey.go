@@ -0,0 +1,45 @@
+package dao
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+// APIKey grants partners access to the endpoints scoped to an owner without
+// a wallet login. Only the sha256 hash of the key is stored.
+type APIKey struct {
+	Id         int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	Name       string `gorm:"column:name;NOT NULL" json:"name"`
+	KeyHash    string `gorm:"column:key_hash;NOT NULL;uniqueIndex" json:"key_hash"`
+	IsActive   bool   `gorm:"column:is_active" json:"is_active"`
+	CreateTime int64  `gorm:"column:create_time" json:"create_time"`
+	UpdateTime int64  `gorm:"column:update_time" json:"update_time"`
+}
+
+func APIKeyTableName() string {
+	return "ob_api_key"
+}
+
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// QueryAPIKey returns the active api key matching key, or nil.
+func (d *Dao) QueryAPIKey(ctx context.Context, key string) (*APIKey, error) {
+	var apiKeys []APIKey
+	if err := d.DB.WithContext(ctx).Table(APIKeyTableName()).
+		Where("key_hash = ? and is_active = ?", HashAPIKey(key), true).
+		Limit(1).
+		Find(&apiKeys).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query api key")
+	}
+
+	if len(apiKeys) == 0 {
+		return nil, nil
+	}
+	return &apiKeys[0], nil
+}
@@ -0,0 +1,225 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+)
+
+// token_standard values of the collection tables.
+const (
+	TokenStandardERC721  = 721
+	TokenStandardERC1155 = 1155
+)
+
+// ItemBalance is the amount of an ERC-1155 token held by an owner. The owner
+// column of the item table only keeps a single holder of such tokens.
+type ItemBalance struct {
+	Id                int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	CollectionAddress string `gorm:"column:collection_address;NOT NULL;uniqueIndex:idx_item_owner" json:"collection_address"`
+	TokenId           string `gorm:"column:token_id;NOT NULL;uniqueIndex:idx_item_owner" json:"token_id"`
+	Owner             string `gorm:"column:owner;NOT NULL;uniqueIndex:idx_item_owner;index:idx_owner" json:"owner"`
+	Balance           int64  `gorm:"column:balance" json:"balance"`
+	CreateTime        int64  `gorm:"column:create_time" json:"create_time"`
+	UpdateTime        int64  `gorm:"column:update_time" json:"update_time"`
+}
+
+func ItemBalanceTableName(chain string) string {
+	return fmt.Sprintf("ob_item_balance_%s", chain)
+}
+
+// listedByHolder is the condition of a listing order `co` on an item `ci`
+// being backed by its maker: the owner of an ERC-721 token, or a holder of at
+// least the listed quantity of an ERC-1155 token.
+func listedByHolder(chain, co, ci string) string {
+	return fmt.Sprintf("(%[2]s.maker = %[3]s.owner or exists (select 1 from %[1]s ib "+
+		"where ib.collection_address = %[2]s.collection_address and ib.token_id = %[2]s.token_id "+
+		"and ib.owner = %[2]s.maker and ib.balance >= %[2]s.quantity_remaining))",
+		ItemBalanceTableName(chain), co, ci)
+}
+
+// SaveItemBalances records the balances of the holders of a token, stamped
+// with the current time in milliseconds. Holders with a zero balance are
+// removed.
+func (d *Dao) SaveItemBalances(ctx context.Context, chain string, balances []ItemBalance) error {
+	now := time.Now().UnixMilli()
+	var held []ItemBalance
+	for _, balance := range balances {
+		balance.Owner = strings.ToLower(balance.Owner)
+		balance.CreateTime = now
+		balance.UpdateTime = now
+		if balance.Balance > 0 {
+			held = append(held, balance)
+			continue
+		}
+
+		if err := d.DB.WithContext(ctx).Table(ItemBalanceTableName(chain)).
+			Where("collection_address = ? and token_id = ? and owner = ?", balance.CollectionAddress, balance.TokenId, balance.Owner).
+			Delete(&ItemBalance{}).Error; err != nil {
+			return errors.Wrap(err, "failed on delete item balance")
+		}
+	}
+	if len(held) == 0 {
+		return nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(ItemBalanceTableName(chain)).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "collection_address"}, {Name: "token_id"}, {Name: "owner"}},
+			DoUpdates: clause.AssignmentColumns([]string{"balance", "update_time"}),
+		}).
+		Create(&held).Error; err != nil {
+		return errors.Wrap(err, "failed on save item balances")
+	}
+
+	return nil
+}
+
+// QueryItemHolders returns the holders of a token, largest balance first.
+func (d *Dao) QueryItemHolders(ctx context.Context, chain, collectionAddr, tokenID string) ([]ItemBalance, error) {
+	var balances []ItemBalance
+	if err := d.DB.WithContext(ctx).Table(ItemBalanceTableName(chain)).
+		Where("collection_address = ? and token_id = ? and balance > 0", collectionAddr, tokenID).
+		Order("balance desc, id asc").
+		Find(&balances).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query item holders")
+	}
+
+	return balances, nil
+}
+
+// QueryItemHolderCandidates returns the addresses that may hold a token: the
+// known holders and every party of its mints, trades and transfers, as a
+// sender of part of its balance still holds the rest.
+func (d *Dao) QueryItemHolderCandidates(ctx context.Context, chain, collectionAddr, tokenID string) ([]string, error) {
+	var parties []multi.Activity
+	if err := d.DB.WithContext(ctx).Table(multi.ActivityTableName(chain)).
+		Select("distinct maker, taker").
+		Where("collection_address = ? and token_id = ? and activity_type in (?)",
+			collectionAddr, tokenID, []int{multi.Mint, multi.Transfer, multi.Sale, multi.Buy}).
+		Scan(&parties).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query item activity parties")
+	}
+
+	var holders []string
+	if err := d.DB.WithContext(ctx).Table(ItemBalanceTableName(chain)).
+		Where("collection_address = ? and token_id = ?", collectionAddr, tokenID).
+		Pluck("owner", &holders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query item holders")
+	}
+
+	for _, party := range parties {
+		holders = append(holders, party.Maker, party.Taker)
+	}
+
+	var candidates []string
+	for _, addr := range holders {
+		if addr != "" {
+			candidates = append(candidates, strings.ToLower(addr))
+		}
+	}
+	return removeRepeatedElement(candidates), nil
+}
+
+// QueryBalanceActivitiesSince returns the mints, transfers and trades of
+// ERC-1155 tokens after the activity id cursor, oldest first.
+func (d *Dao) QueryBalanceActivitiesSince(ctx context.Context, chain string, cursor int64, limit int) ([]multi.Activity, error) {
+	var activities []multi.Activity
+	if err := d.DB.WithContext(ctx).Table(fmt.Sprintf("%s ga", multi.ActivityTableName(chain))).
+		Select("ga.id, ga.activity_type, ga.maker, ga.taker, ga.collection_address, ga.token_id").
+		Joins(fmt.Sprintf("join %s gc on gc.address = ga.collection_address", multi.CollectionTableName(chain))).
+		Where("ga.id > ? and ga.activity_type in (?) and gc.token_standard = ?",
+			cursor, []int{multi.Mint, multi.Transfer, multi.Sale, multi.Buy}, TokenStandardERC1155).
+		Order("ga.id asc").
+		Limit(limit).
+		Scan(&activities).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query balance activities")
+	}
+
+	return activities, nil
+}
+
+func TokenBalanceKey(tokenID, owner string) string {
+	return strings.ToLower(tokenID + ":" + owner)
+}
+
+// QueryTokenBalances returns the balances of owners of ERC-1155 tokens of a
+// collection, keyed by TokenBalanceKey.
+func (d *Dao) QueryTokenBalances(ctx context.Context, chain, collectionAddr string, tokenIDs []string, owners []string) (map[string]int64, error) {
+	balances := make(map[string]int64)
+	if len(tokenIDs) == 0 || len(owners) == 0 {
+		return balances, nil
+	}
+
+	var itemBalances []ItemBalance
+	if err := d.DB.WithContext(ctx).Table(ItemBalanceTableName(chain)).
+		Select("token_id, owner, balance").
+		Where("collection_address = ? and token_id in (?) and owner in (?)", collectionAddr, tokenIDs, owners).
+		Scan(&itemBalances).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query token balances")
+	}
+
+	for _, balance := range itemBalances {
+		balances[TokenBalanceKey(balance.TokenId, balance.Owner)] = balance.Balance
+	}
+	return balances, nil
+}
+
+// QueryOwnedAmounts returns the amount of each of tokenIDs of a collection
+// held by owners, keyed by token id. Tokens they do not hold are left out.
+func (d *Dao) QueryOwnedAmounts(ctx context.Context, chain, collectionAddr string, tokenIDs []string, owners []string) (map[string]int64, error) {
+	amounts := make(map[string]int64)
+	if len(tokenIDs) == 0 || len(owners) == 0 {
+		return amounts, nil
+	}
+
+	var users []string
+	for _, owner := range owners {
+		users = append(users, strings.ToLower(owner))
+	}
+
+	var owned []struct {
+		TokenId     string
+		OwnedAmount int64
+	}
+	if err := d.DB.WithContext(ctx).Table(fmt.Sprintf("%s gi", multi.ItemTableName(chain))).
+		Select(fmt.Sprintf("gi.token_id as token_id, %s as owned_amount", ownedAmount(chain, "gi", "?")), users).
+		Where("gi.collection_address = ? and gi.token_id in (?)", strings.ToLower(collectionAddr), tokenIDs).
+		Where(heldBy(chain, "gi", "?"), users, users).
+		Scan(&owned).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query owned amounts")
+	}
+
+	for _, item := range owned {
+		if item.OwnedAmount > 0 {
+			amounts[item.TokenId] = item.OwnedAmount
+		}
+	}
+	return amounts, nil
+}
+
+// heldBy is the condition of an item `ci` being held by one of users, the
+// quoted, comma separated addresses or a placeholder bound to them twice.
+func heldBy(chain, ci, users string) string {
+	return fmt.Sprintf("(%[2]s.owner in (%[3]s) or exists (select 1 from %[1]s ib "+
+		"where ib.collection_address = %[2]s.collection_address and ib.token_id = %[2]s.token_id "+
+		"and ib.owner in (%[3]s) and ib.balance > 0))",
+		ItemBalanceTableName(chain), ci, users)
+}
+
+// ownedAmount is the amount of an item `ci` held by users, the quoted, comma
+// separated addresses or a placeholder bound to them: their balance of a
+// token with known balances, else 1.
+func ownedAmount(chain, ci, users string) string {
+	return fmt.Sprintf("(case when exists (select 1 from %[1]s ib "+
+		"where ib.collection_address = %[2]s.collection_address and ib.token_id = %[2]s.token_id) "+
+		"then (select coalesce(sum(ib.balance), 0) from %[1]s ib "+
+		"where ib.collection_address = %[2]s.collection_address and ib.token_id = %[2]s.token_id "+
+		"and ib.owner in (%[3]s)) else 1 end)",
+		ItemBalanceTableName(chain), ci, users)
+}
@@ -0,0 +1,105 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// OrderCancellation keeps the signed cancel message of an order so the
+// matching engine can prove the order must not be filled.
+type OrderCancellation struct {
+	Id          int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	OrderID     string `gorm:"column:order_id;NOT NULL;uniqueIndex" json:"order_id"`
+	Maker       string `gorm:"column:maker;NOT NULL" json:"maker"`
+	OrderIDs    string `gorm:"column:order_ids;type:text;NOT NULL" json:"order_ids"`
+	Expiry      int64  `gorm:"column:expiry" json:"expiry"`
+	Signature   string `gorm:"column:signature;NOT NULL" json:"signature"`
+	MessageHash string `gorm:"column:message_hash;NOT NULL" json:"message_hash"`
+	CreateTime  int64  `gorm:"column:create_time" json:"create_time"`
+	UpdateTime  int64  `gorm:"column:update_time" json:"update_time"`
+}
+
+func OrderCancellationTableName(chain string) string {
+	return fmt.Sprintf("ob_order_cancellation_%s", strings.ToLower(chain))
+}
+
+// SaveOrderCancellations cancels the orders that are still open and stores the
+// cancel proofs and activities in one transaction. It returns the ids of the
+// orders actually cancelled.
+func (d *Dao) SaveOrderCancellations(ctx context.Context, chain string, cancellations []OrderCancellation, activities []multi.Activity) ([]string, error) {
+	if len(cancellations) == 0 {
+		return nil, nil
+	}
+
+	var orderIDs []string
+	for _, cancellation := range cancellations {
+		orderIDs = append(orderIDs, cancellation.OrderID)
+	}
+
+	var cancelled []string
+	err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(multi.OrderTableName(chain)).
+			Where("order_id in (?) and order_status in (?,?)", orderIDs,
+				multi.OrderStatusActive, multi.OrderStatusInactive).
+			Pluck("order_id", &cancelled).Error; err != nil {
+			return errors.Wrap(err, "failed on query open orders")
+		}
+		if len(cancelled) == 0 {
+			return nil
+		}
+
+		if err := tx.Table(multi.OrderTableName(chain)).
+			Where("order_id in (?)", cancelled).
+			Updates(map[string]interface{}{
+				"order_status": multi.OrderStatusCancelled,
+				"update_time":  cancellations[0].CreateTime,
+			}).Error; err != nil {
+			return errors.Wrap(err, "failed on cancel orders")
+		}
+
+		isCancelled := make(map[string]bool)
+		for _, orderID := range cancelled {
+			isCancelled[orderID] = true
+		}
+
+		var rows []OrderCancellation
+		var cancelActivities []multi.Activity
+		for i, cancellation := range cancellations {
+			if isCancelled[cancellation.OrderID] {
+				rows = append(rows, cancellation)
+				cancelActivities = append(cancelActivities, activities[i])
+			}
+		}
+
+		if err := tx.Table(OrderCancellationTableName(chain)).Create(&rows).Error; err != nil {
+			return errors.Wrap(err, "failed on save cancellations")
+		}
+
+		if err := tx.Table(multi.ActivityTableName(chain)).Create(&cancelActivities).Error; err != nil {
+			return errors.Wrap(err, "failed on save cancel activities")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cancelled, nil
+}
+
+func (d *Dao) QueryOrderCancellation(ctx context.Context, chain string, orderID string) (*OrderCancellation, error) {
+	var cancellation OrderCancellation
+	if err := d.DB.WithContext(ctx).Table(OrderCancellationTableName(chain)).
+		Where("order_id = ?", orderID).
+		First(&cancellation).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query order cancellation")
+	}
+
+	return &cancellation, nil
+}
@@ -153,11 +153,11 @@ func (d *Dao) QueryMultiChainUserCollectionInfos(ctx context.Context, chainID []
 			"gc.item_amount as item_amount, " +
 			"gc.symbol as symbol, " +
 			"gc.image_uri as image_uri, " +
-			"count(*) as item_count "
+			fmt.Sprintf("sum(%s) as item_count ", ownedAmount(chainName, "gi", userAddrsParam))
 		sqlMid += fmt.Sprintf("from %s as gc ", multi.CollectionTableName(chainName))
 		sqlMid += fmt.Sprintf("join %s as gi ", multi.ItemTableName(chainName))
-		sqlMid += "on gc.address = gi.collection_address"
-		sqlMid += fmt.Sprintf("Where gi.owner in (%s) ", userAddrsParam)
+		sqlMid += "on gc.address = gi.collection_address "
+		sqlMid += fmt.Sprintf("Where %s ", heldBy(chainName, "gi", userAddrsParam))
 		sqlMid += "group by gc.address"
 		sqlMid += ")"
 
@@ -180,8 +180,11 @@ func (d *Dao) QueryMultiChainUserCollectionInfos(ctx context.Context, chainID []
 	return userCollections, nil
 }
 
+// QueryMultiChainUserItemInfos returns a page of the items users hold.
+// Hidden collections of the chains in curatedChains, chain ids by name, are
+// left out.
 func (d *Dao) QueryMultiChainUserItemInfos(ctx context.Context, chain []string, userAddrs []string,
-	contractAddrs []string, page, pageSize int) ([]types.PortfolioItemInfo, int64, error) {
+	contractAddrs []string, curatedChains map[string]int, page, pageSize int) ([]types.PortfolioItemInfo, int64, error) {
 	var count int64
 	var items []types.PortfolioItemInfo
 
@@ -196,7 +199,7 @@ func (d *Dao) QueryMultiChainUserItemInfos(ctx context.Context, chain []string,
 	sqlCntHead := "SELECT COUNT(*) FROM ("
 	sqlHead := "SELECT * FROM ("
 	sqlTail := fmt.Sprintf(") as combined ORDER BY combined.owned_time DESC LIMIT %d OFFSET %d",
-		pageSize, page-1)
+		pageSize, (page-1)*pageSize)
 	var sqlMids []string
 
 	for _, chainName := range chain {
@@ -206,18 +209,19 @@ func (d *Dao) QueryMultiChainUserItemInfos(ctx context.Context, chain []string,
 			"gi.token_id as token_id, " +
 			"gi.name as name, " +
 			"gi.owner as owner, " +
+			fmt.Sprintf("%s as owned_amount, ", ownedAmount(chainName, "gi", userAddrsParam)) +
 			"sub.last_event_time as owned_time "
 		sqlMid += fmt.Sprintf("from %s gi ", multi.ItemTableName(chainName))
 
 		sqlMid += "left join "
 		sqlMid += "(select sgi.collection_address, sgi.token_id, " +
-			"max(sga.event_time) as last_event_time"
+			"max(sga.event_time) as last_event_time "
 		sqlMid += fmt.Sprintf("from %s sgi join %s sga ",
 			multi.ItemTableName(chainName), multi.ActivityTableName(chainName))
 		sqlMid += "on sgi.collection_address = sga.collection_address " +
 			"and sgi.token_id = sga.token_id "
-		sqlMid += fmt.Sprintf("where sgi.owner in (%s) and sga.activity_type = %d ",
-			userAddrsParam, multi.Sale)
+		sqlMid += fmt.Sprintf("where %s and sga.activity_type = %d ",
+			heldBy(chainName, "sgi", userAddrsParam), multi.Sale)
 
 		if len(contractAddrs) > 0 {
 			sqlMid += fmt.Sprintf("and sgi.collection_address in ('%s'", contractAddrs[0])
@@ -230,7 +234,7 @@ func (d *Dao) QueryMultiChainUserItemInfos(ctx context.Context, chain []string,
 		sqlMid += "on gi.collection_address = sub.collection_address " +
 			"and gi.token_id = sub.token_id "
 
-		sqlMid += fmt.Sprintf("where gi.owner in (%s) ", userAddrsParam)
+		sqlMid += fmt.Sprintf("where %s ", heldBy(chainName, "gi", userAddrsParam))
 		if len(contractAddrs) > 0 {
 			sqlMid += fmt.Sprintf("and gi.collection_address in ('%s'", contractAddrs[0])
 			for i := 1; i < len(contractAddrs); i++ {
@@ -238,6 +242,9 @@ func (d *Dao) QueryMultiChainUserItemInfos(ctx context.Context, chain []string,
 			}
 			sqlMid += ") "
 		}
+		if chainID, ok := curatedChains[chainName]; ok {
+			sqlMid += "and " + notHidden(chainID, "gi.collection_address") + " "
+		}
 		sqlMid += ")"
 
 		sqlMids = append(sqlMids, sqlMid)
@@ -266,8 +273,11 @@ func (d *Dao) QueryMultiChainUserItemInfos(ctx context.Context, chain []string,
 	return items, count, nil
 }
 
+// QueryMultiChainUserListingItemInfos returns a page of the items users hold
+// for their listings. Hidden collections of the chains in curatedChains,
+// chain ids by name, are left out.
 func (d *Dao) QueryMultiChainUserListingItemInfos(ctx context.Context, chain []string, userAddrs []string,
-	contractAddrs []string, page, pageSize int) ([]types.PortfolioItemInfo, int64, error) {
+	contractAddrs []string, curatedChains map[string]int, page, pageSize int) ([]types.PortfolioItemInfo, int64, error) {
 	var count int64
 	var items []types.PortfolioItemInfo
 
@@ -282,13 +292,14 @@ func (d *Dao) QueryMultiChainUserListingItemInfos(ctx context.Context, chain []s
 	sqlCntHead := "SELECT COUNT(*) FROM ("
 	sqlHead := "SELECT * FROM ("
 	sqlTail := fmt.Sprintf(") as combined ORDER BY combined.owned_time DESC LIMIT %d OFFSET %d",
-		pageSize, page-1)
+		pageSize, (page-1)*pageSize)
 	var sqlMids []string
 
 	for _, chainName := range chain {
 		sqlMid := "("
 		sqlMid += "select gi.chain_id as chain_id, gi.collection_address as collection_address, " +
 			"gi.token_id as token_id, gi.name as name, gi.owner as owner, " +
+			fmt.Sprintf("%s as owned_amount, ", ownedAmount(chainName, "gi", userAddrsParam)) +
 			"sub.last_event_time as owned_time "
 		sqlMid += fmt.Sprintf("from %s gi ", multi.ItemTableName(chainName))
 		sqlMid += "left join "
@@ -296,10 +307,10 @@ func (d *Dao) QueryMultiChainUserListingItemInfos(ctx context.Context, chain []s
 			"max(sga.event_time) as last_event_time "
 		sqlMid += fmt.Sprintf("from %s sgi join %s sga ",
 			multi.ItemTableName(chainName), multi.ActivityTableName(chainName))
-		sqlMid += "on sgi.collection_address = sga.collection_address" +
+		sqlMid += "on sgi.collection_address = sga.collection_address " +
 			"and sgi.token_id = sga.token_id "
-		sqlMid += fmt.Sprintf("where sgi.owner in (%s) and sga.activity_type = %d ",
-			userAddrsParam, multi.Sale)
+		sqlMid += fmt.Sprintf("where %s and sga.activity_type = %d ",
+			heldBy(chainName, "sgi", userAddrsParam), multi.Sale)
 
 		if len(contractAddrs) > 0 {
 			sqlMid += fmt.Sprintf("and sgi.collection_address in ('%s'", contractAddrs[0])
@@ -312,13 +323,16 @@ func (d *Dao) QueryMultiChainUserListingItemInfos(ctx context.Context, chain []s
 		sqlMid += "on gi.collection_address = sub.collection_address " +
 			"and gi.token_id = sub.token_id "
 
-		sqlMid += fmt.Sprintf("where gi.owner in (%s) ", userAddrsParam)
+		sqlMid += fmt.Sprintf("where %s ", heldBy(chainName, "gi", userAddrsParam))
 		if len(contractAddrs) > 0 {
 			sqlMid += fmt.Sprintf("and gi.collection_address in ('%s'", contractAddrs[0])
 			for i := 1; i < len(contractAddrs); i++ {
 				sqlMid += fmt.Sprintf(",'%s'", contractAddrs[i])
 			}
-			sqlMid += ")"
+			sqlMid += ") "
+		}
+		if chainID, ok := curatedChains[chainName]; ok {
+			sqlMid += "and " + notHidden(chainID, "gi.collection_address") + " "
 		}
 		sqlMid += ")"
 
@@ -381,8 +395,8 @@ func (d *Dao) QueryFloorPrice(ctx context.Context, chain string, collectionAddr
 		FROM %s as ci
 				left join %s co on co.collection_address = ci.collection_address and co.token_id = ci.token_id
 		Where (co.collection_address = ? and co.order_type = ? and
-		    co.order_status = ? and co.maker = ci.owner and co.marketplace_id != ?)
-		order by co.price asc limit1`, multi.ItemTableName(chain), multi.OrderTableName(chain))
+		    co.order_status = ? and %s and co.marketplace_id != ?)
+		order by co.price asc limit1`, multi.ItemTableName(chain), multi.OrderTableName(chain), listedByHolder(chain, "co", "ci"))
 
 	if err := d.DB.WithContext(ctx).Raw(
 		sql,
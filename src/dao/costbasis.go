@@ -0,0 +1,74 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CostBasisActivityTypes are the activities changing the cost basis of a
+// holder: the seller or sender of a sale or transfer is its maker and the
+// buyer or receiver its taker.
+var CostBasisActivityTypes = []int{multi.Sale, multi.Buy, multi.Mint, multi.Transfer}
+
+// QueryUserTradeActivities returns the sales, buys, mints and transfers of
+// userAddrs on a chain, oldest first, as needed for FIFO cost basis. The
+// activities are limited to the given items when any are given.
+func (d *Dao) QueryUserTradeActivities(ctx context.Context, chain string, userAddrs []string, collectionAddrs []string,
+	items []types.ItemInfo) ([]multi.Activity, error) {
+	var activities []multi.Activity
+	if len(userAddrs) == 0 {
+		return activities, nil
+	}
+
+	db := d.DB.WithContext(ctx).Table(multi.ActivityTableName(chain)).
+		Select("id, activity_type, maker, taker, marketplace_id, collection_address, token_id, currency_address, price, tx_hash, event_time").
+		Where("activity_type in (?) and (maker in (?) or taker in (?))", CostBasisActivityTypes, userAddrs, userAddrs)
+	if len(collectionAddrs) > 0 {
+		db.Where("collection_address in (?)", collectionAddrs)
+	}
+	if len(items) > 0 {
+		var conditions []clause.Expr
+		for _, item := range items {
+			conditions = append(conditions, gorm.Expr("(?, ?)", item.CollectionAddress, item.TokenID))
+		}
+		db.Where("(collection_address, token_id) in (?)", conditions)
+	}
+
+	if err := db.Order("event_time asc, id asc").
+		Scan(&activities).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query user trade activities")
+	}
+
+	return activities, nil
+}
+
+// TaxActivityTypes are the activities of a tax record: trades, mints and
+// transfers in or out of the user addresses.
+var TaxActivityTypes = []int{multi.Sale, multi.Buy, multi.Mint, multi.Transfer}
+
+// QueryUserActivitiesAfter pages through the activities of userAddrs up to
+// `to`, ordered by (event_time, id) and starting after the given position.
+func (d *Dao) QueryUserActivitiesAfter(ctx context.Context, chain string, userAddrs []string, activityTypes []int,
+	to, afterTime, afterID int64, limit int) ([]multi.Activity, error) {
+	var activities []multi.Activity
+	if len(userAddrs) == 0 {
+		return activities, nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(multi.ActivityTableName(chain)).
+		Select("id, activity_type, maker, taker, marketplace_id, collection_address, token_id, currency_address, price, tx_hash, event_time").
+		Where("activity_type in (?) and (maker in (?) or taker in (?))", activityTypes, userAddrs, userAddrs).
+		Where("event_time <= ? and (event_time > ? or (event_time = ? and id > ?))", to, afterTime, afterTime, afterID).
+		Order("event_time asc, id asc").
+		Limit(limit).
+		Scan(&activities).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query user activities")
+	}
+
+	return activities, nil
+}
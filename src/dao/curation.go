@@ -0,0 +1,239 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CollectionCuration holds the flags admins set on a collection. Verified
+// collections are marked in responses, featured ones are listed on the
+// homepage and hidden ones, such as spam airdrops, are left out of ranking,
+// search, portfolio and activity responses.
+type CollectionCuration struct {
+	Id                int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	ChainID           int    `gorm:"column:chain_id;uniqueIndex:idx_chain_collection" json:"chain_id"`
+	CollectionAddress string `gorm:"column:collection_address;NOT NULL;uniqueIndex:idx_chain_collection" json:"collection_address"`
+	IsVerified        bool   `gorm:"column:is_verified" json:"is_verified"`
+	IsFeatured        bool   `gorm:"column:is_featured" json:"is_featured"`
+	IsHidden          bool   `gorm:"column:is_hidden" json:"is_hidden"`
+	Note              string `gorm:"column:note" json:"note"`
+	UpdatedBy         string `gorm:"column:updated_by" json:"updated_by"`
+	CreateTime        int64  `gorm:"column:create_time" json:"create_time"`
+	UpdateTime        int64  `gorm:"column:update_time" json:"update_time"`
+}
+
+// CurationAudit records a change of the curation of a collection with the
+// flags before and after it as json.
+type CurationAudit struct {
+	Id                int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	ChainID           int    `gorm:"column:chain_id;index:idx_chain_collection" json:"chain_id"`
+	CollectionAddress string `gorm:"column:collection_address;NOT NULL;index:idx_chain_collection" json:"collection_address"`
+	Operator          string `gorm:"column:operator;NOT NULL" json:"operator"`
+	Before            string `gorm:"column:flags_before" json:"flags_before"`
+	After             string `gorm:"column:flags_after" json:"flags_after"`
+	CreateTime        int64  `gorm:"column:create_time" json:"create_time"`
+}
+
+func CollectionCurationTableName() string {
+	return "ob_collection_curation"
+}
+
+func CurationAuditTableName() string {
+	return "ob_collection_curation_audit"
+}
+
+const (
+	CacheCollectionCurationsKey = "cache:es:collection:curations"
+	CollectionCurationsCacheTTL = 5 * 60
+)
+
+// ErrCurationRejected is returned by a curation update to leave the
+// curation unchanged.
+var ErrCurationRejected = errors.New("curation update rejected")
+
+// notHidden is the condition of the collection address column col of a chain
+// not being hidden by curation.
+func notHidden(chainID int, col string) string {
+	return fmt.Sprintf("not exists (select 1 from %s cc where cc.chain_id = %d and cc.collection_address = %s and cc.is_hidden = true)",
+		CollectionCurationTableName(), chainID, col)
+}
+
+// QueryCollectionCurations returns the curated collections of the given
+// chains, of every chain when chainIDs is empty. The table is small and read
+// by most listings, it is cached as a whole until a curation is saved.
+func (d *Dao) QueryCollectionCurations(ctx context.Context, chainIDs []int) ([]CollectionCuration, error) {
+	all, err := d.cachedCollectionCurations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(chainIDs) == 0 {
+		return all, nil
+	}
+
+	chains := make(map[int]bool)
+	for _, chainID := range chainIDs {
+		chains[chainID] = true
+	}
+	var curations []CollectionCuration
+	for _, curation := range all {
+		if chains[curation.ChainID] {
+			curations = append(curations, curation)
+		}
+	}
+	return curations, nil
+}
+
+func (d *Dao) cachedCollectionCurations(ctx context.Context) ([]CollectionCuration, error) {
+	if value, err := d.KvStore.Get(CacheCollectionCurationsKey); err == nil && value != "" {
+		var curations []CollectionCuration
+		if err := json.Unmarshal([]byte(value), &curations); err == nil {
+			return curations, nil
+		}
+	}
+
+	var curations []CollectionCuration
+	if err := d.DB.WithContext(ctx).Table(CollectionCurationTableName()).
+		Order("id asc").
+		Find(&curations).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query collection curations")
+	}
+
+	if value, err := json.Marshal(curations); err == nil {
+		if err := d.KvStore.Setex(CacheCollectionCurationsKey, string(value), CollectionCurationsCacheTTL); err != nil {
+			xzap.WithContext(ctx).Error("failed on cache collection curations", zap.Error(err))
+		}
+	}
+	return curations, nil
+}
+
+func (d *Dao) QueryCollectionCuration(ctx context.Context, chainID int, collectionAddr string) (*CollectionCuration, error) {
+	curations, err := d.QueryCollectionCurations(ctx, []int{chainID})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range curations {
+		if strings.EqualFold(curations[i].CollectionAddress, collectionAddr) {
+			return &curations[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveCollectionCuration applies update to the curation of a collection,
+// a new one when it has none, and records the change in the audit log. The
+// row is locked for the transaction, so concurrent partial updates merge
+// rather than overwrite each other. An error of update, such as
+// ErrCurationRejected, is returned as is.
+func (d *Dao) SaveCollectionCuration(ctx context.Context, chainID int, collectionAddr, operator string,
+	update func(curation *CollectionCuration) error) (*CollectionCuration, error) {
+	collectionAddr = strings.ToLower(collectionAddr)
+	now := time.Now().UnixMilli()
+
+	var curation CollectionCuration
+	err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		created := tx.Table(CollectionCurationTableName()).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&CollectionCuration{
+				ChainID:           chainID,
+				CollectionAddress: collectionAddr,
+				UpdatedBy:         operator,
+				CreateTime:        now,
+				UpdateTime:        now,
+			})
+		if created.Error != nil {
+			return errors.Wrap(created.Error, "failed on create collection curation")
+		}
+
+		if err := tx.Table(CollectionCurationTableName()).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("chain_id = ? and collection_address = ?", chainID, collectionAddr).
+			Take(&curation).Error; err != nil {
+			return errors.Wrap(err, "failed on query collection curation")
+		}
+
+		var before []byte
+		if created.RowsAffected == 0 {
+			before, _ = json.Marshal(curation)
+		}
+
+		if err := update(&curation); err != nil {
+			return err
+		}
+		curation.UpdatedBy = operator
+		curation.UpdateTime = now
+		if err := tx.Table(CollectionCurationTableName()).
+			Where("id = ?", curation.Id).
+			Updates(map[string]interface{}{
+				"is_verified": curation.IsVerified,
+				"is_featured": curation.IsFeatured,
+				"is_hidden":   curation.IsHidden,
+				"note":        curation.Note,
+				"updated_by":  curation.UpdatedBy,
+				"update_time": curation.UpdateTime,
+			}).Error; err != nil {
+			return errors.Wrap(err, "failed on update collection curation")
+		}
+
+		after, _ := json.Marshal(curation)
+		if err := tx.Table(CurationAuditTableName()).Create(&CurationAudit{
+			ChainID:           chainID,
+			CollectionAddress: collectionAddr,
+			Operator:          operator,
+			Before:            string(before),
+			After:             string(after),
+			CreateTime:        now,
+		}).Error; err != nil {
+			return errors.Wrap(err, "failed on create curation audit")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := d.KvStore.Del(CacheCollectionCurationsKey); err != nil {
+		xzap.WithContext(ctx).Error("failed on invalidate collection curations", zap.Error(err))
+	}
+	return &curation, nil
+}
+
+// QueryCurationAudits returns the audit log, newest first, of a collection
+// or of every collection when collectionAddr is empty.
+func (d *Dao) QueryCurationAudits(ctx context.Context, chainID int, collectionAddr string, page, pageSize int) ([]CurationAudit, int64, error) {
+	query := func() *gorm.DB {
+		db := d.DB.WithContext(ctx).Table(CurationAuditTableName())
+		if chainID > 0 {
+			db.Where("chain_id = ?", chainID)
+		}
+		if collectionAddr != "" {
+			db.Where("collection_address = ?", strings.ToLower(collectionAddr))
+		}
+		return db
+	}
+
+	var count int64
+	if err := query().Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "failed on count curation audits")
+	}
+
+	var audits []CurationAudit
+	if err := query().Order("id desc").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&audits).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "failed on query curation audits")
+	}
+
+	return audits, count, nil
+}
@@ -0,0 +1,134 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// The Stream functions read a table with a database cursor in id order,
+// starting after afterID. fn is called row by row, so a slow reader holds
+// back the read instead of piling rows up in memory.
+
+func (d *Dao) StreamActivities(ctx context.Context, chain string, filter types.ActivityMultiChainFilterParams, afterID int64,
+	fn func(activity multi.Activity, eventType string) error) error {
+	db := d.DB.WithContext(ctx).Table(multi.ActivityTableName(chain)).
+		Select("id, collection_address, token_id, currency_address, activity_type, maker, taker, price, tx_hash, event_time, marketplace_id").
+		Where("id > ?", afterID)
+
+	if len(filter.CollectionAddresses) > 0 {
+		db.Where("collection_address in (?)", filter.CollectionAddresses)
+	}
+	if filter.TokenID != "" {
+		db.Where("token_id = ?", filter.TokenID)
+	}
+	if len(filter.UserAddresses) > 0 {
+		var userAddrs []string
+		for _, addr := range filter.UserAddresses {
+			userAddrs = append(userAddrs, strings.ToLower(addr))
+		}
+		db.Where("(maker in (?) or taker in (?))", userAddrs, userAddrs)
+	}
+	if len(filter.EventTypes) > 0 {
+		var events []int
+		for _, eventType := range filter.EventTypes {
+			if id, ok := eventTypesToID[eventType]; ok {
+				events = append(events, id)
+			}
+		}
+		if len(events) > 0 {
+			db.Where("activity_type in (?)", events)
+		}
+	}
+
+	rows, err := db.Order("id asc").Rows()
+	if err != nil {
+		return errors.Wrap(err, "failed on query activities")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var activity multi.Activity
+		if err := d.DB.ScanRows(rows, &activity); err != nil {
+			return errors.Wrap(err, "failed on scan activity")
+		}
+		if err := fn(activity, idToEventTypes[activity.ActivityType]); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "failed on read activities")
+}
+
+func (d *Dao) StreamActiveOrders(ctx context.Context, chain string, filter types.OrderExportParams, afterID int64,
+	fn func(order multi.Order) error) error {
+	db := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Select(orderFields).
+		Where("id > ? and order_status = ? and expire_time > ?", afterID, multi.OrderStatusActive, time.Now().Unix())
+
+	if filter.CollectionAddress != "" {
+		db.Where("collection_address = ?", filter.CollectionAddress)
+	}
+	if filter.TokenID != "" {
+		db.Where("token_id = ?", filter.TokenID)
+	}
+	if filter.Maker != "" {
+		db.Where("maker = ?", strings.ToLower(filter.Maker))
+	}
+	if len(filter.OrderTypes) > 0 {
+		db.Where("order_type in (?)", filter.OrderTypes)
+	}
+	if len(filter.Markets) > 0 {
+		db.Where("marketplace_id in (?)", filter.Markets)
+	}
+
+	rows, err := db.Order("id asc").Rows()
+	if err != nil {
+		return errors.Wrap(err, "failed on query orders")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var order multi.Order
+		if err := d.DB.ScanRows(rows, &order); err != nil {
+			return errors.Wrap(err, "failed on scan order")
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "failed on read orders")
+}
+
+// StreamCollectionItems takes the filters of QueryCollectionItemOrder, the
+// items are in id order whatever the sort of the filter.
+func (d *Dao) StreamCollectionItems(ctx context.Context, chain string, filter types.CollectionItemFilterParams, collectionAddr string,
+	afterID int64, fn func(item CollectionItem) error) error {
+	db := d.collectionItemsQuery(ctx, chain, filter, collectionAddr).
+		Where("ci.id > ?", afterID)
+
+	rows, err := db.Session(&gorm.Session{}).Order("ci.id asc").Rows()
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed on query items of %s", collectionAddr))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item CollectionItem
+		if err := d.DB.ScanRows(rows, &item); err != nil {
+			return errors.Wrap(err, "failed on scan item")
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "failed on read items")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
 	"github.com/pkg/errors"
@@ -15,7 +16,7 @@ func (d *Dao) QueryCollectionItemsImage(ctx context.Context, chain string,
 
 	if err := d.DB.WithContext(ctx).
 		Table(multi.ItemExternalTableName(chain)).
-		Select("collection_address, token_id, is_uploaded_oss, "+
+		Select("collection_address, token_id, is_uploaded_oss, upload_status, "+
 			"image_uri, oss_uri, video_type, is_video_uploaded, "+
 			"video_uri, video_oss_uri").
 		Where("collection_address = ? and token_id in (?)",
@@ -77,3 +78,61 @@ func (d *Dao) QueryMultiChainCollectionsItemsImage(ctx context.Context, itemInfo
 
 	return itemsExternal, nil
 }
+
+// upload_status values of the item external table.
+const (
+	MediaPending     = 0
+	MediaUploaded    = 1
+	MediaUnsupported = 2
+	MediaFailed      = 3
+	// MediaAbandoned is an item that failed on every attempt, it is served
+	// from its original uri and not retried.
+	MediaAbandoned = 4
+)
+
+// QueryPendingMediaItems returns a batch of items whose image was not
+// processed yet, or failed before retryBefore, ordered by id.
+func (d *Dao) QueryPendingMediaItems(ctx context.Context, chain string, retryBefore int64, cursor int64, limit int) ([]multi.ItemExternal, error) {
+	var itemsExternal []multi.ItemExternal
+	if err := d.DB.WithContext(ctx).Table(multi.ItemExternalTableName(chain)).
+		Select("id, collection_address, token_id, image_uri, video_uri, is_uploaded_oss, upload_status").
+		Where("id > ? and is_uploaded_oss = ? and image_uri != '' and "+
+			"(upload_status = ? or (upload_status = ? and update_time < ?))",
+			cursor, false, MediaPending, MediaFailed, retryBefore).
+		Order("id asc").
+		Limit(limit).
+		Scan(&itemsExternal).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query pending media items")
+	}
+
+	return itemsExternal, nil
+}
+
+// ItemMedia is the result of processing the media of an item.
+type ItemMedia struct {
+	UploadStatus    int
+	OssUri          string
+	VideoOssUri     string
+	IsVideoUploaded bool
+}
+
+func (d *Dao) SaveItemMedia(ctx context.Context, chain string, id int64, media ItemMedia) error {
+	updates := map[string]interface{}{
+		"upload_status":   media.UploadStatus,
+		"is_uploaded_oss": media.UploadStatus == MediaUploaded,
+		"oss_uri":         media.OssUri,
+		"update_time":     time.Now().UnixMilli(),
+	}
+	if media.IsVideoUploaded {
+		updates["is_video_uploaded"] = true
+		updates["video_oss_uri"] = media.VideoOssUri
+	}
+
+	if err := d.DB.WithContext(ctx).Table(multi.ItemExternalTableName(chain)).
+		Where("id = ?", id).
+		Updates(updates).Error; err != nil {
+		return errors.Wrap(err, "failed on save item media")
+	}
+
+	return nil
+}
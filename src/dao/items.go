@@ -37,8 +37,12 @@ type CollectionItem struct {
 	ListTime       int64  `json:"list_time"`
 	ListExpireTime int64  `json:"list_expire_time"`
 	ListSalt       int64  `json:"list_salt"`
+	ListSize       int64  `json:"list_size"`
+	ListUnfilled   int64  `json:"list_unfilled"`
 }
 
+// QueryCollectionBids returns the collection bid depth of a collection per
+// price.
 func (d *Dao) QueryCollectionBids(ctx context.Context, chain string, collectionAddr string, page, pageSize int) ([]types.CollectionBids, int64, error) {
 	var count int64
 
@@ -71,7 +75,9 @@ func (d *Dao) QueryCollectionBids(ctx context.Context, chain string, collectionA
 	return bids, count, nil
 }
 
-func (d *Dao) QueryCollectionItemOrder(ctx context.Context, chain string, filter types.CollectionItemFilterParams, collectionAddr string) ([]*CollectionItem, int64, error) {
+// collectionItemsQuery selects the items of a collection matching the status,
+// market, token and owner filters.
+func (d *Dao) collectionItemsQuery(ctx context.Context, chain string, filter types.CollectionItemFilterParams, collectionAddr string) *gorm.DB {
 	if len(filter.Markets) == 0 {
 		filter.Markets = []int{int(multi.OrderBookDex)}
 	}
@@ -86,6 +92,7 @@ func (d *Dao) QueryCollectionItemOrder(ctx context.Context, chain string, filter
 				"ci.name as name, ci.owner as owner, " +
 				"min(co.price) as list_price, " +
 				"SUBSTRING_INDEX(GROUP_CONCAT(co.marketplace_id ORDER BY co.price, co.marketplace_id), ',', 1) AS market_id, " +
+				"SUBSTRING_INDEX(GROUP_CONCAT(co.maker ORDER BY co.price, co.marketplace_id), ',', 1) AS list_maker, " +
 				"min(co.price) != 0 as listing")
 
 		if filter.Status[0] == BuyNow {
@@ -94,14 +101,10 @@ func (d *Dao) QueryCollectionItemOrder(ctx context.Context, chain string, filter
 				coTableName)).
 				Where(
 					"co.collection_address = ? and co.order_type = ? and co.order_status = ? "+
-						"and co.maker = ci.owner",
+						"and "+listedByHolder(chain, "co", "ci"),
 					collectionAddr, multi.ListingOrder, multi.OrderStatusActive)
 
-			if len(filter.Markets) == 1 {
-				db.Where("co.marketplace_id = ?", filter.Markets[0])
-			} else if len(filter.Markets) != 5 {
-				db.Where("co.marketplace_id in (?)", filter.Markets)
-			}
+			db.Where("co.marketplace_id in (?)", filter.Markets)
 
 			if filter.TokenID != "" {
 				db.Where("co.token_id = ?", filter.TokenID)
@@ -121,11 +124,7 @@ func (d *Dao) QueryCollectionItemOrder(ctx context.Context, chain string, filter
 					"co.collection_address = ? and co.order_type = ? and co.order_status = ?",
 					collectionAddr, multi.OfferOrder, multi.OrderStatusActive)
 
-			if len(filter.Markets) == 1 {
-				db.Where("co.marketplace_id = ?", filter.Markets[0])
-			} else if len(filter.Markets) != 5 {
-				db.Where("co.marketplace_id in (?)", filter.Markets)
-			}
+			db.Where("co.marketplace_id in (?)", filter.Markets)
 
 			if filter.TokenID != "" {
 				db.Where("co.token_id = ?", filter.TokenID)
@@ -142,20 +141,17 @@ func (d *Dao) QueryCollectionItemOrder(ctx context.Context, chain string, filter
 				"ci.collection_address as collection_address, ci.token_id as token_id, " +
 				"ci.name as name, ci.owner as owner, " +
 				"min(co.price) as list_price, " +
-				"SUBSTRING_INDEX(GROUP_CONCAT(co.marketplace_id ORDER BY co.price, co.marketplace_id), ',', 1) AS market_id")
+				"SUBSTRING_INDEX(GROUP_CONCAT(co.marketplace_id ORDER BY co.price, co.marketplace_id), ',', 1) AS market_id, " +
+				"SUBSTRING_INDEX(GROUP_CONCAT(co.maker ORDER BY co.price, co.marketplace_id), ',', 1) AS list_maker")
 
 		db.Joins(fmt.Sprintf(
 			"join %s co on co.collection_address = ci.collection_address and co.token_id = ci.token_id",
 			coTableName)).
 			Where(
-				"co.collection_address = ? and co.order_status = ? and co.maker = ci.owner",
+				"co.collection_address = ? and co.order_status = ? and "+listedByHolder(chain, "co", "ci"),
 				collectionAddr, multi.OrderStatusActive)
 
-		if len(filter.Markets) == 1 {
-			db.Where("co.marketplace_id = ?", filter.Markets[0])
-		} else if len(filter.Markets) != 5 {
-			db.Where("co.marketplace_id in (?)", filter.Markets)
-		}
+		db.Where("co.marketplace_id in (?)", filter.Markets)
 
 		if filter.TokenID != "" {
 			db.Where("co.token_id = ?", filter.TokenID)
@@ -171,30 +167,27 @@ func (d *Dao) QueryCollectionItemOrder(ctx context.Context, chain string, filter
 					"cis.token_id as token_id, cis.owner as owner, cos.order_id as order_id, "+
 					"min(cos.price) as list_price, "+
 					"SUBSTRING_INDEX(GROUP_CONCAT(cos.marketplace_id ORDER BY cos.price, cos.marketplace_id), ',', 1) AS market_id, "+
+					"SUBSTRING_INDEX(GROUP_CONCAT(cos.maker ORDER BY cos.price, cos.marketplace_id), ',', 1) AS list_maker, "+
 					"min(cos.price) != 0 as listing").
 			Joins(fmt.Sprintf(
 				"join %s cos on cos.collection_address = cis.collection_address and cos.token_id = cis.token_id",
 				coTableName)).
 			Where(
 				"cos.collection_address = ? and cos.order_type = ? and cos.order_status = ? "+
-					"and cos.maker = cis.owner",
+					"and "+listedByHolder(chain, "cos", "cis"),
 				collectionAddr, multi.ListingOrder, multi.OrderStatusActive)
 
-		if len(filter.Markets) == 1 {
-			subQuery.Where("cos.marketplace_id = ?", filter.Markets[0])
-		} else if len(filter.Markets) != 5 {
-			subQuery.Where("cos.marketplace_id in (?)", filter.Markets)
-		}
+		subQuery.Where("cos.marketplace_id in (?)", filter.Markets)
 		subQuery.Group("cos.token_id")
 
 		db.Joins("left join (?) co on co.collection_address = ci.collection_address and co.token_id = ci.token_id",
 			subQuery).
 			Select(
-				"ci.id as id, ci.chain_id as chain_id," +
-					"ci.collection_address as collection_address, ci.token_id as token_id, " +
-					"ci.name as name, ci.owner as owner, " +
-					"co.list_price as list_price, co.market_id as market_id, co.listing as listing").
-			Where(fmt.Sprintf("ci.collection_address = '%s'", collectionAddr))
+				"ci.id as id, ci.chain_id as chain_id,"+
+					"ci.collection_address as collection_address, ci.token_id as token_id, "+
+					"ci.name as name, ci.owner as owner, "+
+					"co.list_price as list_price, co.market_id as market_id, co.list_maker as list_maker, co.listing as listing").
+			Where("ci.collection_address = ?", collectionAddr)
 
 		if filter.TokenID != "" {
 			db.Where(fmt.Sprintf("ci.token_id = '%s'", filter.TokenID))
@@ -204,6 +197,12 @@ func (d *Dao) QueryCollectionItemOrder(ctx context.Context, chain string, filter
 		}
 	}
 
+	return db
+}
+
+func (d *Dao) QueryCollectionItemOrder(ctx context.Context, chain string, filter types.CollectionItemFilterParams, collectionAddr string) ([]*CollectionItem, int64, error) {
+	db := d.collectionItemsQuery(ctx, chain, filter, collectionAddr)
+
 	var count int64
 	countTx := db.Session(&gorm.Session{})
 	if err := countTx.Count(&count).Error; err != nil {
@@ -347,7 +346,7 @@ func (d *Dao) QueryItemsBestBids(ctx context.Context, chain string, userAddr str
 
 	if userAddr == "" {
 		sql = fmt.Sprintf(`
-			SELECT order_id, token_id, event_time, price, salt, expire_time, maker, order_type, quantity_remaining, size
+			SELECT collection_address, order_id, token_id, event_time, price, salt, expire_time, maker, order_type, quantity_remaining, size
 			FROM %s
 			WHERE (collection_address, token_id) IN (?)
 				AND order_type = ?
@@ -357,7 +356,7 @@ func (d *Dao) QueryItemsBestBids(ctx context.Context, chain string, userAddr str
 				`, multi.OrderTableName(chain))
 	} else {
 		sql = fmt.Sprintf(`
-			SELECT order_id, token_id, event_time, price, salt, expire_time, maker, order_type, quantity_remaining, size
+			SELECT collection_address, order_id, token_id, event_time, price, salt, expire_time, maker, order_type, quantity_remaining, size
 			FROM %s
 			WHERE (collection_address, token_id) IN (?)
 				AND order_type = ?
@@ -507,8 +506,8 @@ func (d *Dao) QueryListedAmount(ctx context.Context, chain string, collectionAdd
 			FROM %s as ci
 					join %s co on co.collection_address = ci.collection_address and co.token_id = ci.token_id
 			WHERE (co.collection_address = ? and co.order_type = ? and 
-			    co.order_status = ? and co.maker = ci.owner and co.marketplace_id != ?)
-			    `, multi.ItemTableName(chain), multi.OrderTableName(chain))
+			    co.order_status = ? and %s and co.marketplace_id != ?)
+			    `, multi.ItemTableName(chain), multi.OrderTableName(chain), listedByHolder(chain, "co", "ci"))
 
 	var counts int64
 	if err := d.DB.WithContext(ctx).Raw(
@@ -530,9 +529,9 @@ func (d *Dao) QueryListedAmountEachCollection(ctx context.Context, chain string,
 	sql := fmt.Sprintf(`SELECT ci.collection_address as address, count(distinct (co.token_id)) as list_amount
 			FROM %s as ci
 				join %s co on co.collection_address = ci.collection_address and co.token_id = ci.token_id
-			WHERE (co.collection_address in (?) and ci.owner in (?) and co.order_type = ? and 
-			    co.order_status = ? and co.maker = ci.owner and co.marketplace_id != ?) group by ci.collection_address`,
-		multi.ItemTableName(chain), multi.OrderTableName(chain))
+			WHERE (co.collection_address in (?) and co.maker in (?) and co.order_type = ? and 
+			    co.order_status = ? and %s and co.marketplace_id != ?) group by ci.collection_address`,
+		multi.ItemTableName(chain), multi.OrderTableName(chain), listedByHolder(chain, "co", "ci"))
 	if err := d.DB.WithContext(ctx).Raw(
 		sql,
 		collectionAddrs,
@@ -599,8 +598,8 @@ func (d *Dao) QueryMultiChainUserItemsListInfo(ctx context.Context, userAddrs []
 		sqlMid += "where (co.collection_address, co.token_id) in "
 		sqlMid += tmpStat
 		sqlMid += fmt.Sprintf("and co.order_type = %d and co.order_status = %d "+
-			"and co.maker = ci.owner and co.maker in (%s) ",
-			multi.ListingOrder, multi.OrderStatusActive, userAddrsParam)
+			"and %s and co.maker in (%s) ",
+			multi.ListingOrder, multi.OrderStatusActive, listedByHolder(chainName, "co", "ci"), userAddrsParam)
 		sqlMid += "group by co.collection_address, co.token_id"
 		sqlMid += ")"
 
@@ -661,8 +660,8 @@ func (d *Dao) QueryMultiChainUserItemsExpireListInfo(ctx context.Context, userAd
 		sqlMid += "where (co.collection_address, co.token_id) in "
 		sqlMid += tmpStat
 		sqlMid += fmt.Sprintf("and co.order_type = %d and (co.order_status = %d or co.order_status = %d) "+
-			"and co.maker = ci.owner and co.maker in (%s)",
-			multi.ListingOrder, multi.OrderStatusActive, multi.OrderStatusExpired, userAddrsParam)
+			"and %s and co.maker in (%s)",
+			multi.ListingOrder, multi.OrderStatusActive, multi.OrderStatusExpired, listedByHolder(info.ChainName, "co", "ci"), userAddrsParam)
 		sqlMid += "group by co.collection_address, co.token_id"
 		sqlMid += ")"
 
@@ -700,7 +699,7 @@ func (d *Dao) QueryItemListInfo(ctx context.Context, chain, collectionAddr, toke
 		Joins(fmt.Sprintf("join %s co on co.collection_address = ci.collection_address and co.token_id = ci.token_id",
 			coTableName)).
 		Where("ci.collection_address = ? and ci.token_id = ? and co.order_type = ? and co.order_status = ? "+
-			"and co.maker = ci.owner",
+			"and "+listedByHolder(chain, "co", "ci"),
 			collectionAddr, tokenID, multi.ListingOrder, multi.OrderStatusActive).
 		Group("ci.collection_address, ci.token_id").
 		Scan(&collectionItem).Error
@@ -714,11 +713,15 @@ func (d *Dao) QueryItemListInfo(ctx context.Context, chain, collectionAddr, toke
 	}
 
 	var listOrder multi.Order
-	if err := d.DB.WithContext(ctx).Table(fmt.Sprintf("%s as ci", multi.OrderTableName(chain))).
-		Select("order_id, expire_time, maker, salt, event_time").
-		Where("collection_address = ? and token_id = ? and maker = ? and order_status = ? and price = ?",
+	if err := d.DB.WithContext(ctx).Table(fmt.Sprintf("%s as co", multi.OrderTableName(chain))).
+		Select("co.order_id, co.expire_time, co.maker, co.salt, co.event_time, co.size, co.quantity_remaining").
+		Joins(fmt.Sprintf("join %s ci on ci.collection_address = co.collection_address and ci.token_id = co.token_id",
+			multi.ItemTableName(chain))).
+		Where("co.collection_address = ? and co.token_id = ? and co.order_type = ? and co.order_status = ? and co.price = ? and "+
+			listedByHolder(chain, "co", "ci"),
 			collectionItem.CollectionAddress, collectionItem.TokenId,
-			collectionItem.Owner, multi.OrderStatusActive, collectionItem.ListPrice).
+			multi.ListingOrder, multi.OrderStatusActive, collectionItem.ListPrice).
+		Limit(1).
 		Scan(&listOrder).Error; err != nil {
 		return nil, errors.Wrap(err, "failed on query item order id")
 	}
@@ -728,6 +731,8 @@ func (d *Dao) QueryItemListInfo(ctx context.Context, chain, collectionAddr, toke
 	collectionItem.ListMaker = listOrder.Maker
 	collectionItem.ListSalt = listOrder.Salt
 	collectionItem.ListTime = listOrder.EventTime
+	collectionItem.ListSize = listOrder.Size
+	collectionItem.ListUnfilled = listOrder.QuantityRemaining
 
 	return &collectionItem, nil
 }
@@ -749,7 +754,7 @@ func (d *Dao) QueryListingInfo(ctx context.Context, chain string,
 	if err := d.DB.WithContext(ctx).
 		Table(multi.OrderTableName(chain)).
 		Select("collection_address, token_id, order_id, event_time,"+
-			"expire_time, salt, maker ").
+			"expire_time, salt, maker, size, quantity_remaining ").
 		Where("(collection_address, token_id, maker, order_status, price) in (?)",
 			conditions).
 		Scan(&orders).Error; err != nil {
@@ -890,8 +895,36 @@ func (d *Dao) UpdateItemOwner(ctx context.Context, chain string, collectionAddr,
 	return nil
 }
 
+// QueryCollectionItemCount returns the number of items of a collection.
+func (d *Dao) QueryCollectionItemCount(ctx context.Context, chain string, collectionAddr string) (int64, error) {
+	var count int64
+	if err := d.DB.WithContext(ctx).Table(multi.ItemTableName(chain)).
+		Where("collection_address = ?", collectionAddr).
+		Count(&count).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on count collection items")
+	}
+
+	return count, nil
+}
+
+// QueryCollectionTokenIDs returns a batch of the items of a collection after
+// the cursor id, with their id and token id only.
+func (d *Dao) QueryCollectionTokenIDs(ctx context.Context, chain string, collectionAddr string, cursor int64, limit int) ([]multi.Item, error) {
+	var items []multi.Item
+	if err := d.DB.WithContext(ctx).Table(multi.ItemTableName(chain)).
+		Select("id, token_id").
+		Where("collection_address = ? and id > ?", collectionAddr, cursor).
+		Order("id asc").
+		Limit(limit).
+		Scan(&items).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query collection token ids")
+	}
+
+	return items, nil
+}
+
 func (d *Dao) QueryItemBids(ctx context.Context, chain string, collectionAddr, tokenID string,
-	page, pageSize int) ([]types.ItemBid, int64, error) {
+	traitBidOrderIDs []string, page, pageSize int) ([]types.ItemBid, int64, error) {
 	db := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
 		Select("marketplace_id, collection_address, token_id, order_id, salt, "+
 			"event_time, expire_time, price, maker as bidder, order_type, "+
@@ -902,6 +935,11 @@ func (d *Dao) QueryItemBids(ctx context.Context, chain string, collectionAddr, t
 		Or("collection_address = ? and token_id = ? and order_type = ? and order_status = ? "+
 			"and expire_time > ? and quantity_remaining > 0",
 			collectionAddr, tokenID, multi.ItemBidOrder, multi.OrderStatusActive, time.Now().Unix())
+	if len(traitBidOrderIDs) > 0 {
+		db.Or("order_id in (?) and order_type = ? and order_status = ? "+
+			"and expire_time > ? and quantity_remaining > 0",
+			traitBidOrderIDs, TraitBidOrder, multi.OrderStatusActive, time.Now().Unix())
+	}
 
 	var count int64
 	countTx := db.Session(&gorm.Session{})
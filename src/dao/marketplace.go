@@ -0,0 +1,78 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+)
+
+// MarketplaceOrderIndex is the unique index of the order table on the
+// marketplace and the order id it was given by that marketplace, which is
+// what ingested orders are upserted on.
+const MarketplaceOrderIndex = "idx_marketplace_order"
+
+// QueryOpenMarketplaceOrders returns the active and invalidated orders of a
+// marketplace.
+func (d *Dao) QueryOpenMarketplaceOrders(ctx context.Context, chain string, marketplaceID int) ([]multi.Order, error) {
+	var orders []multi.Order
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Select(orderFields).
+		Where("marketplace_id = ? and order_status in (?,?)", marketplaceID, multi.OrderStatusActive, multi.OrderStatusInactive).
+		Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query marketplace orders")
+	}
+
+	return orders, nil
+}
+
+// SaveMarketplaceOrders upserts the orders of a marketplace on
+// MarketplaceOrderIndex: new orders are inserted and known ones get the price,
+// quantity and expire time of their marketplace. The status of known orders
+// is left to the order validator.
+func (d *Dao) SaveMarketplaceOrders(ctx context.Context, chain string, marketplaceID int, orders []multi.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	now := time.Now().UnixMilli()
+	saved := make([]multi.Order, 0, len(orders))
+	for _, order := range orders {
+		order.MarketplaceId = marketplaceID
+		order.CreateTime = now
+		order.UpdateTime = now
+		saved = append(saved, order)
+	}
+
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "marketplace_id"}, {Name: "order_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"price", "quantity_remaining", "expire_time", "update_time"}),
+		}).
+		CreateInBatches(&saved, 200).Error; err != nil {
+		return errors.Wrap(err, "failed on save marketplace orders")
+	}
+
+	return nil
+}
+
+// UpdateMarketplaceOrdersStatus sets the status of orders of a marketplace,
+// order ids are only unique within their marketplace.
+func (d *Dao) UpdateMarketplaceOrdersStatus(ctx context.Context, chain string, marketplaceID int, orderIDs []string, status int) error {
+	if len(orderIDs) == 0 {
+		return nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Where("marketplace_id = ? and order_id in (?)", marketplaceID, orderIDs).
+		Updates(map[string]interface{}{
+			"order_status": status,
+			"update_time":  time.Now().UnixMilli(),
+		}).Error; err != nil {
+		return errors.Wrap(err, "failed on update marketplace orders status")
+	}
+
+	return nil
+}
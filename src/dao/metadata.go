@@ -0,0 +1,154 @@
+package dao
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ItemMetadata is the parsed metadata of a token to store in the item,
+// item external and item trait tables.
+type ItemMetadata struct {
+	ChainID           int
+	CollectionAddress string
+	TokenId           string
+	Name              string
+	MetaDataUri       string
+	ImageUri          string
+	VideoType         string
+	VideoUri          string
+	Traits            []multi.ItemTrait
+	// Supply is the minted amount of an ERC-1155 token, 0 when unknown.
+	Supply int64
+}
+
+// SaveItemMetadata upserts the metadata of a token and replaces its traits.
+// A changed image or video is marked as not uploaded, so the media is
+// processed again.
+func (d *Dao) SaveItemMetadata(ctx context.Context, chain string, metadata *ItemMetadata) error {
+	collectionAddr := strings.ToLower(metadata.CollectionAddress)
+	now := time.Now().UnixMilli()
+
+	return d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var itemCount int64
+		if err := tx.Table(multi.ItemTableName(chain)).
+			Where("collection_address = ? and token_id = ?", collectionAddr, metadata.TokenId).
+			Count(&itemCount).Error; err != nil {
+			return errors.Wrap(err, "failed on query item")
+		}
+		if itemCount == 0 {
+			supply := metadata.Supply
+			if supply <= 0 {
+				// the held balances stand in for a supply the contract does not expose
+				if err := tx.Table(ItemBalanceTableName(chain)).
+					Select("coalesce(sum(balance), 0)").
+					Where("collection_address = ? and token_id = ?", collectionAddr, metadata.TokenId).
+					Scan(&supply).Error; err != nil {
+					return errors.Wrap(err, "failed on query item balances")
+				}
+			}
+			if supply <= 0 {
+				supply = 1
+			}
+
+			if err := tx.Table(multi.ItemTableName(chain)).Create(&multi.Item{
+				ChainId:           metadata.ChainID,
+				CollectionAddress: collectionAddr,
+				TokenId:           metadata.TokenId,
+				Name:              metadata.Name,
+				Supply:            supply,
+				CreateTime:        now,
+				UpdateTime:        now,
+			}).Error; err != nil {
+				return errors.Wrap(err, "failed on create item")
+			}
+		} else {
+			// a document without a name or a contract without a supply keeps
+			// the stored value
+			updates := map[string]interface{}{
+				"update_time": now,
+			}
+			if metadata.Name != "" {
+				updates["name"] = metadata.Name
+			}
+			if metadata.Supply > 0 {
+				updates["supply"] = metadata.Supply
+			}
+			if err := tx.Table(multi.ItemTableName(chain)).
+				Where("collection_address = ? and token_id = ?", collectionAddr, metadata.TokenId).
+				Updates(updates).Error; err != nil {
+				return errors.Wrap(err, "failed on update item")
+			}
+		}
+
+		var externals []multi.ItemExternal
+		if err := tx.Table(multi.ItemExternalTableName(chain)).
+			Select("id, image_uri, video_uri").
+			Where("collection_address = ? and token_id = ?", collectionAddr, metadata.TokenId).
+			Limit(1).
+			Scan(&externals).Error; err != nil {
+			return errors.Wrap(err, "failed on query item external")
+		}
+		if len(externals) == 0 {
+			if err := tx.Table(multi.ItemExternalTableName(chain)).Create(&multi.ItemExternal{
+				CollectionAddress: collectionAddr,
+				TokenId:           metadata.TokenId,
+				MetaDataUri:       metadata.MetaDataUri,
+				ImageUri:          metadata.ImageUri,
+				VideoType:         metadata.VideoType,
+				VideoUri:          metadata.VideoUri,
+				CreateTime:        now,
+				UpdateTime:        now,
+			}).Error; err != nil {
+				return errors.Wrap(err, "failed on create item external")
+			}
+		} else {
+			updates := map[string]interface{}{
+				"meta_data_uri": metadata.MetaDataUri,
+				"image_uri":     metadata.ImageUri,
+				"video_type":    metadata.VideoType,
+				"video_uri":     metadata.VideoUri,
+				"update_time":   now,
+			}
+			if externals[0].ImageUri != metadata.ImageUri || externals[0].VideoUri != metadata.VideoUri {
+				updates["is_uploaded_oss"] = false
+				updates["upload_status"] = MediaPending
+				updates["oss_uri"] = ""
+				updates["is_video_uploaded"] = false
+				updates["video_oss_uri"] = ""
+			}
+			if err := tx.Table(multi.ItemExternalTableName(chain)).
+				Where("id = ?", externals[0].Id).
+				Updates(updates).Error; err != nil {
+				return errors.Wrap(err, "failed on update item external")
+			}
+		}
+
+		if err := tx.Table(multi.ItemTraitTableName(chain)).
+			Where("collection_address = ? and token_id = ?", collectionAddr, metadata.TokenId).
+			Delete(&multi.ItemTrait{}).Error; err != nil {
+			return errors.Wrap(err, "failed on delete item traits")
+		}
+		if len(metadata.Traits) == 0 {
+			return nil
+		}
+
+		traits := make([]multi.ItemTrait, 0, len(metadata.Traits))
+		for _, trait := range metadata.Traits {
+			trait.CollectionAddress = collectionAddr
+			trait.TokenId = metadata.TokenId
+			trait.CreateTime = now
+			trait.UpdateTime = now
+			traits = append(traits, trait)
+		}
+		if err := tx.Table(multi.ItemTraitTableName(chain)).Create(&traits).Error; err != nil {
+			return errors.Wrap(err, "failed on create item traits")
+		}
+
+		return nil
+	})
+}
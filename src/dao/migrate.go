@@ -0,0 +1,138 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// SchemaMigrationTableName records the migrations applied to the database.
+const SchemaMigrationTableName = "ob_schema_migration"
+
+// Migration is a versioned change of the schema. The statements of a per
+// chain migration are built and applied once for every chain, the others
+// once for the database. Applied, when set, reports whether the change is
+// already in place, for statements MySQL cannot make safe to run again.
+type Migration struct {
+	Version  int
+	Name     string
+	PerChain bool
+	Up       func(chain string) []string
+	Applied  func(db *gorm.DB, chain string) bool
+}
+
+type SchemaMigration struct {
+	Id        int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	Version   int    `gorm:"column:version;uniqueIndex:idx_version_chain" json:"version"`
+	Chain     string `gorm:"column:chain;NOT NULL;uniqueIndex:idx_version_chain" json:"chain"`
+	Name      string `gorm:"column:name;NOT NULL" json:"name"`
+	ApplyTime int64  `gorm:"column:apply_time" json:"apply_time"`
+}
+
+// PendingMigration is a migration to apply, to chain for per chain ones.
+type PendingMigration struct {
+	Migration
+	Chain string
+}
+
+func (p PendingMigration) String() string {
+	if p.Chain == "" {
+		return fmt.Sprintf("%d %s", p.Version, p.Name)
+	}
+	return fmt.Sprintf("%d %s (%s)", p.Version, p.Name, p.Chain)
+}
+
+func migrationKey(version int, chain string) string {
+	return fmt.Sprintf("%d:%s", version, chain)
+}
+
+// pendingMigrations returns the migrations of chains not in applied, keyed by
+// migrationKey, in version order.
+func pendingMigrations(migrations []Migration, chains []string, applied map[string]bool) []PendingMigration {
+	var pending []PendingMigration
+	for _, migration := range migrations {
+		targets := []string{""}
+		if migration.PerChain {
+			targets = chains
+		}
+		for _, chain := range targets {
+			if !applied[migrationKey(migration.Version, chain)] {
+				pending = append(pending, PendingMigration{Migration: migration, Chain: chain})
+			}
+		}
+	}
+	return pending
+}
+
+func (d *Dao) appliedMigrations(ctx context.Context) (map[string]bool, error) {
+	if !d.DB.WithContext(ctx).Migrator().HasTable(SchemaMigrationTableName) {
+		return map[string]bool{}, nil
+	}
+
+	var records []SchemaMigration
+	if err := d.DB.WithContext(ctx).Table(SchemaMigrationTableName).
+		Select("version, chain").
+		Scan(&records).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query schema migrations")
+	}
+
+	applied := make(map[string]bool)
+	for _, record := range records {
+		applied[migrationKey(record.Version, record.Chain)] = true
+	}
+	return applied, nil
+}
+
+// PendingMigrations returns the migrations not applied to the database yet.
+func (d *Dao) PendingMigrations(ctx context.Context, chains []string) ([]PendingMigration, error) {
+	applied, err := d.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pendingMigrations(Migrations, chains, applied), nil
+}
+
+// Migrate applies the pending migrations in version order. DDL is not
+// transactional, so a migration is recorded once all its statements ran and
+// its statements must be safe to run again.
+func (d *Dao) Migrate(ctx context.Context, chains []string) ([]PendingMigration, error) {
+	if err := d.DB.WithContext(ctx).Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+		"id bigint NOT NULL AUTO_INCREMENT, "+
+		"version int NOT NULL, "+
+		"chain varchar(64) NOT NULL DEFAULT '', "+
+		"name varchar(255) NOT NULL, "+
+		"apply_time bigint NOT NULL DEFAULT 0, "+
+		"PRIMARY KEY (id), "+
+		"UNIQUE KEY idx_version_chain (version, chain))", SchemaMigrationTableName)).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on create schema migration table")
+	}
+
+	pending, err := d.PendingMigrations(ctx, chains)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		if migration.Applied == nil || !migration.Applied(d.DB.WithContext(ctx), migration.Chain) {
+			for _, statement := range migration.Up(migration.Chain) {
+				if err := d.DB.WithContext(ctx).Exec(statement).Error; err != nil {
+					return pending[:i], errors.Wrap(err, fmt.Sprintf("failed on migration %s", migration))
+				}
+			}
+		}
+
+		if err := d.DB.WithContext(ctx).Table(SchemaMigrationTableName).Create(&SchemaMigration{
+			Version:   migration.Version,
+			Chain:     migration.Chain,
+			Name:      migration.Name,
+			ApplyTime: time.Now().Unix(),
+		}).Error; err != nil {
+			return pending[:i], errors.Wrap(err, fmt.Sprintf("failed on record migration %s", migration))
+		}
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,42 @@
+package dao
+
+import "testing"
+
+func TestPendingMigrations(t *testing.T) {
+	noop := func(string) []string { return nil }
+	migrations := []Migration{
+		{Version: 1, Name: "per chain", PerChain: true, Up: noop},
+		{Version: 2, Name: "shared", Up: noop},
+	}
+	applied := map[string]bool{
+		migrationKey(1, "eth"): true,
+	}
+
+	pending := pendingMigrations(migrations, []string{"eth", "sepolia"}, applied)
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 pending migrations, got %v", pending)
+	}
+	if pending[0].Version != 1 || pending[0].Chain != "sepolia" {
+		t.Fatalf("expected the per chain migration of sepolia first, got %v", pending[0])
+	}
+	if pending[1].Version != 2 || pending[1].Chain != "" {
+		t.Fatalf("expected the shared migration once, got %v", pending[1])
+	}
+
+	applied[migrationKey(1, "sepolia")] = true
+	applied[migrationKey(2, "")] = true
+	if pending := pendingMigrations(migrations, []string{"eth", "sepolia"}, applied); len(pending) != 0 {
+		t.Fatalf("expected no pending migrations, got %v", pending)
+	}
+}
+
+func TestMigrationVersions(t *testing.T) {
+	for i, migration := range Migrations {
+		if migration.Version != i+1 {
+			t.Fatalf("migration %q has version %d, expected %d", migration.Name, migration.Version, i+1)
+		}
+		if len(migration.Up("eth")) == 0 {
+			t.Fatalf("migration %d has no statements", migration.Version)
+		}
+	}
+}
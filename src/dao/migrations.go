@@ -0,0 +1,290 @@
+package dao
+
+import (
+	"fmt"
+
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"gorm.io/gorm"
+)
+
+// Migrations is the schema of the tables owned by the backend, in version
+// order. Append new versions, never edit or reorder the applied ones.
+var Migrations = []Migration{
+	{
+		Version:  1,
+		Name:     "create trait bid criteria",
+		PerChain: true,
+		Up: func(chain string) []string {
+			return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+				"id bigint NOT NULL AUTO_INCREMENT, "+
+				"order_id varchar(128) NOT NULL, "+
+				"collection_address varchar(42) NOT NULL, "+
+				"trait varchar(255) NOT NULL, "+
+				"trait_value varchar(255) NOT NULL, "+
+				"create_time bigint NOT NULL DEFAULT 0, "+
+				"update_time bigint NOT NULL DEFAULT 0, "+
+				"PRIMARY KEY (id), "+
+				"KEY idx_order_id (order_id), "+
+				"KEY idx_collection_trait (collection_address, trait, trait_value))", TraitBidCriteriaTableName(chain))}
+		},
+	},
+	{
+		Version:  2,
+		Name:     "create order validation",
+		PerChain: true,
+		Up: func(chain string) []string {
+			return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+				"id bigint NOT NULL AUTO_INCREMENT, "+
+				"order_id varchar(128) NOT NULL, "+
+				"collection_address varchar(42) NOT NULL, "+
+				"reason varchar(64) NOT NULL, "+
+				"check_time bigint NOT NULL DEFAULT 0, "+
+				"create_time bigint NOT NULL DEFAULT 0, "+
+				"update_time bigint NOT NULL DEFAULT 0, "+
+				"PRIMARY KEY (id), "+
+				"UNIQUE KEY idx_order_id (order_id))", OrderValidationTableName(chain))}
+		},
+	},
+	{
+		Version:  3,
+		Name:     "create order salt",
+		PerChain: true,
+		Up: func(chain string) []string {
+			return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+				"id bigint NOT NULL AUTO_INCREMENT, "+
+				"maker varchar(42) NOT NULL, "+
+				"salt bigint NOT NULL, "+
+				"order_id varchar(128) NOT NULL, "+
+				"create_time bigint NOT NULL DEFAULT 0, "+
+				"PRIMARY KEY (id), "+
+				"UNIQUE KEY idx_maker_salt (maker, salt))", OrderSaltTableName(chain))}
+		},
+	},
+	{
+		Version:  4,
+		Name:     "create order cancellation",
+		PerChain: true,
+		Up: func(chain string) []string {
+			return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+				"id bigint NOT NULL AUTO_INCREMENT, "+
+				"order_id varchar(128) NOT NULL, "+
+				"maker varchar(42) NOT NULL, "+
+				"order_ids text NOT NULL, "+
+				"expiry bigint NOT NULL DEFAULT 0, "+
+				"signature varchar(132) NOT NULL, "+
+				"message_hash varchar(66) NOT NULL, "+
+				"create_time bigint NOT NULL DEFAULT 0, "+
+				"update_time bigint NOT NULL DEFAULT 0, "+
+				"PRIMARY KEY (id), "+
+				"UNIQUE KEY idx_order_id (order_id), "+
+				"KEY idx_message_hash (message_hash))", OrderCancellationTableName(chain))}
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create notification",
+		Up: func(string) []string {
+			return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+				"id bigint NOT NULL AUTO_INCREMENT, "+
+				"user_address varchar(42) NOT NULL, "+
+				"chain_id int NOT NULL DEFAULT 0, "+
+				"notification_type varchar(32) NOT NULL, "+
+				"collection_address varchar(42) NOT NULL DEFAULT '', "+
+				"token_id varchar(128) NOT NULL DEFAULT '', "+
+				"order_id varchar(128) NOT NULL DEFAULT '', "+
+				"price decimal(65,18) NOT NULL DEFAULT 0, "+
+				"counterparty varchar(42) NOT NULL DEFAULT '', "+
+				"detail varchar(1024) NOT NULL DEFAULT '', "+
+				"dedupe_key varchar(255) NOT NULL, "+
+				"is_read tinyint(1) NOT NULL DEFAULT 0, "+
+				"event_time bigint NOT NULL DEFAULT 0, "+
+				"create_time bigint NOT NULL DEFAULT 0, "+
+				"update_time bigint NOT NULL DEFAULT 0, "+
+				"PRIMARY KEY (id), "+
+				"UNIQUE KEY idx_dedupe_key (dedupe_key), "+
+				"KEY idx_user_address (user_address, is_read))", NotificationTableName())}
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create webhook, webhook delivery and api key",
+		Up: func(string) []string {
+			return []string{
+				fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+					"id bigint NOT NULL AUTO_INCREMENT, "+
+					"owner varchar(42) NOT NULL, "+
+					"url varchar(2048) NOT NULL, "+
+					"secret varchar(128) NOT NULL, "+
+					"chain_id int NOT NULL DEFAULT 0, "+
+					"event_types varchar(255) NOT NULL DEFAULT '', "+
+					"collections text, "+
+					"is_active tinyint(1) NOT NULL DEFAULT 1, "+
+					"create_time bigint NOT NULL DEFAULT 0, "+
+					"update_time bigint NOT NULL DEFAULT 0, "+
+					"PRIMARY KEY (id), "+
+					"KEY idx_owner (owner))", WebhookTableName()),
+				fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+					"id bigint NOT NULL AUTO_INCREMENT, "+
+					"webhook_id bigint NOT NULL, "+
+					"event_id varchar(255) NOT NULL, "+
+					"event_type varchar(64) NOT NULL DEFAULT '', "+
+					"payload text, "+
+					"status varchar(32) NOT NULL, "+
+					"attempts int NOT NULL DEFAULT 0, "+
+					"next_attempt_time bigint NOT NULL DEFAULT 0, "+
+					"response_code int NOT NULL DEFAULT 0, "+
+					"last_error text, "+
+					"create_time bigint NOT NULL DEFAULT 0, "+
+					"update_time bigint NOT NULL DEFAULT 0, "+
+					"PRIMARY KEY (id), "+
+					"UNIQUE KEY idx_webhook_event (webhook_id, event_id), "+
+					"KEY idx_status (status, next_attempt_time))", WebhookDeliveryTableName()),
+				fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+					"id bigint NOT NULL AUTO_INCREMENT, "+
+					"name varchar(255) NOT NULL, "+
+					"key_hash varchar(64) NOT NULL, "+
+					"is_active tinyint(1) NOT NULL DEFAULT 1, "+
+					"create_time bigint NOT NULL DEFAULT 0, "+
+					"update_time bigint NOT NULL DEFAULT 0, "+
+					"PRIMARY KEY (id), "+
+					"UNIQUE KEY idx_key_hash (key_hash))", APIKeyTableName()),
+			}
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create watchlist and price alert",
+		Up: func(string) []string {
+			return []string{
+				fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+					"id bigint NOT NULL AUTO_INCREMENT, "+
+					"user_address varchar(42) NOT NULL, "+
+					"chain_id int NOT NULL DEFAULT 0, "+
+					"collection_address varchar(42) NOT NULL, "+
+					"create_time bigint NOT NULL DEFAULT 0, "+
+					"update_time bigint NOT NULL DEFAULT 0, "+
+					"PRIMARY KEY (id), "+
+					"UNIQUE KEY idx_user_collection (user_address, chain_id, collection_address))", WatchlistTableName()),
+				fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+					"id bigint NOT NULL AUTO_INCREMENT, "+
+					"user_address varchar(42) NOT NULL, "+
+					"chain_id int NOT NULL DEFAULT 0, "+
+					"collection_address varchar(42) NOT NULL, "+
+					"alert_type varchar(32) NOT NULL, "+
+					"threshold decimal(65,18) NOT NULL DEFAULT 0, "+
+					"is_active tinyint(1) NOT NULL DEFAULT 1, "+
+					"is_triggered tinyint(1) NOT NULL DEFAULT 0, "+
+					"trigger_count bigint NOT NULL DEFAULT 0, "+
+					"last_trigger_time bigint NOT NULL DEFAULT 0, "+
+					"create_time bigint NOT NULL DEFAULT 0, "+
+					"update_time bigint NOT NULL DEFAULT 0, "+
+					"PRIMARY KEY (id), "+
+					"KEY idx_user_address (user_address), "+
+					"KEY idx_chain_collection (chain_id, collection_address))", PriceAlertTableName()),
+			}
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create user profile",
+		Up: func(string) []string {
+			return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+				"id bigint NOT NULL AUTO_INCREMENT, "+
+				"address varchar(42) NOT NULL, "+
+				"username varchar(20) NOT NULL DEFAULT '', "+
+				"username_key varchar(20) DEFAULT NULL, "+
+				"avatar_key varchar(255) NOT NULL DEFAULT '', "+
+				"bio varchar(280) NOT NULL DEFAULT '', "+
+				"twitter varchar(15) NOT NULL DEFAULT '', "+
+				"website varchar(200) NOT NULL DEFAULT '', "+
+				"create_time bigint NOT NULL DEFAULT 0, "+
+				"update_time bigint NOT NULL DEFAULT 0, "+
+				"PRIMARY KEY (id), "+
+				"UNIQUE KEY idx_address (address), "+
+				"UNIQUE KEY idx_username_key (username_key))", UserProfileTableName())}
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create portfolio snapshot",
+		Up: func(string) []string {
+			return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+				"id bigint NOT NULL AUTO_INCREMENT, "+
+				"user_address varchar(42) NOT NULL, "+
+				"chain_id int NOT NULL DEFAULT 0, "+
+				"collection_address varchar(42) NOT NULL, "+
+				"day bigint NOT NULL, "+
+				"item_count bigint NOT NULL DEFAULT 0, "+
+				"floor_price decimal(65,18) NOT NULL DEFAULT 0, "+
+				"value decimal(65,18) NOT NULL DEFAULT 0, "+
+				"is_backfill tinyint(1) NOT NULL DEFAULT 0, "+
+				"create_time bigint NOT NULL DEFAULT 0, "+
+				"update_time bigint NOT NULL DEFAULT 0, "+
+				"PRIMARY KEY (id), "+
+				"UNIQUE KEY idx_user_collection_day (user_address, chain_id, collection_address, day))", PortfolioSnapshotTableName())}
+		},
+	},
+	{
+		Version:  10,
+		Name:     "create item balance",
+		PerChain: true,
+		Up: func(chain string) []string {
+			return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+				"id bigint NOT NULL AUTO_INCREMENT, "+
+				"collection_address varchar(42) NOT NULL, "+
+				"token_id varchar(128) NOT NULL, "+
+				"owner varchar(42) NOT NULL, "+
+				"balance bigint NOT NULL DEFAULT 0, "+
+				"create_time bigint NOT NULL DEFAULT 0, "+
+				"update_time bigint NOT NULL DEFAULT 0, "+
+				"PRIMARY KEY (id), "+
+				"UNIQUE KEY idx_item_owner (collection_address, token_id, owner), "+
+				"KEY idx_owner (owner))", ItemBalanceTableName(chain))}
+		},
+	},
+	{
+		// the order table is created by the order book sync, the backend only
+		// adds the index the marketplace ingester upserts on
+		Version:  11,
+		Name:     "add marketplace order index",
+		PerChain: true,
+		Up: func(chain string) []string {
+			return []string{fmt.Sprintf("ALTER TABLE %s ADD UNIQUE INDEX %s (marketplace_id, order_id), ALGORITHM=INPLACE, LOCK=NONE",
+				multi.OrderTableName(chain), MarketplaceOrderIndex)}
+		},
+		Applied: func(db *gorm.DB, chain string) bool {
+			return db.Migrator().HasIndex(multi.OrderTableName(chain), MarketplaceOrderIndex)
+		},
+	},
+	{
+		Version: 12,
+		Name:    "create collection curation and curation audit",
+		Up: func(string) []string {
+			return []string{
+				fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+					"id bigint NOT NULL AUTO_INCREMENT, "+
+					"chain_id int NOT NULL DEFAULT 0, "+
+					"collection_address varchar(42) NOT NULL, "+
+					"is_verified tinyint(1) NOT NULL DEFAULT 0, "+
+					"is_featured tinyint(1) NOT NULL DEFAULT 0, "+
+					"is_hidden tinyint(1) NOT NULL DEFAULT 0, "+
+					"note varchar(1024) NOT NULL DEFAULT '', "+
+					"updated_by varchar(255) NOT NULL DEFAULT '', "+
+					"create_time bigint NOT NULL DEFAULT 0, "+
+					"update_time bigint NOT NULL DEFAULT 0, "+
+					"PRIMARY KEY (id), "+
+					"UNIQUE KEY idx_chain_collection (chain_id, collection_address))", CollectionCurationTableName()),
+				fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ("+
+					"id bigint NOT NULL AUTO_INCREMENT, "+
+					"chain_id int NOT NULL DEFAULT 0, "+
+					"collection_address varchar(42) NOT NULL, "+
+					"operator varchar(255) NOT NULL, "+
+					"flags_before text, "+
+					"flags_after text, "+
+					"create_time bigint NOT NULL DEFAULT 0, "+
+					"PRIMARY KEY (id), "+
+					"KEY idx_chain_collection (chain_id, collection_address))", CurationAuditTableName()),
+			}
+		},
+	},
+}
@@ -0,0 +1,251 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/base"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	NotificationSold            = "sold"
+	NotificationOfferReceived   = "offer_received"
+	NotificationOutbid          = "outbid"
+	NotificationListingExpiring = "listing_expiring"
+	NotificationListingExpired  = "listing_expired"
+	NotificationBidExpired      = "bid_expired"
+	NotificationPriceAlert      = "price_alert"
+)
+
+type Notification struct {
+	Id                int64           `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	UserAddress       string          `gorm:"column:user_address;NOT NULL;index" json:"user_address"`
+	ChainID           int             `gorm:"column:chain_id" json:"chain_id"`
+	NotificationType  string          `gorm:"column:notification_type;NOT NULL" json:"notification_type"`
+	CollectionAddress string          `gorm:"column:collection_address" json:"collection_address"`
+	TokenID           string          `gorm:"column:token_id" json:"token_id"`
+	OrderID           string          `gorm:"column:order_id" json:"order_id"`
+	Price             decimal.Decimal `gorm:"column:price" json:"price"`
+	Counterparty      string          `gorm:"column:counterparty" json:"counterparty"`
+	Detail            string          `gorm:"column:detail" json:"detail"`
+	DedupeKey         string          `gorm:"column:dedupe_key;NOT NULL;uniqueIndex" json:"dedupe_key"`
+	IsRead            bool            `gorm:"column:is_read" json:"is_read"`
+	EventTime         int64           `gorm:"column:event_time" json:"event_time"`
+	CreateTime        int64           `gorm:"column:create_time" json:"create_time"`
+	UpdateTime        int64           `gorm:"column:update_time" json:"update_time"`
+}
+
+func NotificationTableName() string {
+	return "ob_notification"
+}
+
+// CreateNotifications stores notifications, skipping the ones already sent.
+func (d *Dao) CreateNotifications(ctx context.Context, notifications []Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(NotificationTableName()).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&notifications).Error; err != nil {
+		return errors.Wrap(err, "failed on create notifications")
+	}
+
+	return nil
+}
+
+func (d *Dao) QueryUserNotifications(ctx context.Context, userAddrs []string, unreadOnly bool, page, pageSize int) ([]Notification, int64, error) {
+	db := d.DB.WithContext(ctx).Table(NotificationTableName()).
+		Where("user_address in (?)", userAddrs)
+	if unreadOnly {
+		db.Where("is_read = ?", false)
+	}
+
+	var count int64
+	if err := db.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "failed on count notifications")
+	}
+
+	var notifications []Notification
+	if count == 0 {
+		return notifications, 0, nil
+	}
+
+	if err := db.Order("id desc").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Scan(&notifications).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "failed on query notifications")
+	}
+
+	return notifications, count, nil
+}
+
+func (d *Dao) CountUnreadNotifications(ctx context.Context, userAddrs []string) (int64, error) {
+	var count int64
+	if err := d.DB.WithContext(ctx).Table(NotificationTableName()).
+		Where("user_address in (?) and is_read = ?", userAddrs, false).
+		Count(&count).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on count unread notifications")
+	}
+
+	return count, nil
+}
+
+// MarkNotificationsRead marks the given notifications as read, or all of them
+// when no id is given.
+func (d *Dao) MarkNotificationsRead(ctx context.Context, userAddrs []string, ids []int64) error {
+	db := d.DB.WithContext(ctx).Table(NotificationTableName()).
+		Where("user_address in (?) and is_read = ?", userAddrs, false)
+	if len(ids) > 0 {
+		db.Where("id in (?)", ids)
+	}
+
+	if err := db.Updates(map[string]interface{}{
+		"is_read":     true,
+		"update_time": time.Now().UnixMilli(),
+	}).Error; err != nil {
+		return errors.Wrap(err, "failed on mark notifications read")
+	}
+
+	return nil
+}
+
+// QueryRegisteredUsers returns the addresses that logged in at least once.
+func (d *Dao) QueryRegisteredUsers(ctx context.Context, addrs []string) (map[string]bool, error) {
+	users := make(map[string]bool)
+	if len(addrs) == 0 {
+		return users, nil
+	}
+
+	var registered []string
+	if err := d.DB.WithContext(ctx).Table(base.UserTableName()).
+		Where("address in (?)", removeRepeatedElement(addrs)).
+		Pluck("address", &registered).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query registered users")
+	}
+
+	for _, addr := range registered {
+		users[strings.ToLower(addr)] = true
+	}
+	return users, nil
+}
+
+func (d *Dao) QueryActivitiesSince(ctx context.Context, chain string, cursor int64, activityTypes []int, limit int) ([]multi.Activity, error) {
+	var activities []multi.Activity
+	if err := d.DB.WithContext(ctx).Table(multi.ActivityTableName(chain)).
+		Where("id > ? and activity_type in (?)", cursor, activityTypes).
+		Order("id asc").
+		Limit(limit).
+		Scan(&activities).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query activities")
+	}
+
+	return activities, nil
+}
+
+func (d *Dao) QueryOrdersSince(ctx context.Context, chain string, cursor int64, orderTypes []int64, limit int) ([]multi.Order, error) {
+	var orders []multi.Order
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Select(orderFields).
+		Where("id > ? and order_type in (?) and order_status = ?", cursor, orderTypes, multi.OrderStatusActive).
+		Order("id asc").
+		Limit(limit).
+		Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query orders")
+	}
+
+	return orders, nil
+}
+
+func (d *Dao) QueryMaxID(ctx context.Context, table string) (int64, error) {
+	var maxID *int64
+	if err := d.DB.WithContext(ctx).Table(table).
+		Select("max(id)").
+		Scan(&maxID).Error; err != nil {
+		return 0, errors.Wrap(err, fmt.Sprintf("failed on query max id of %s", table))
+	}
+
+	if maxID == nil {
+		return 0, nil
+	}
+	return *maxID, nil
+}
+
+// QueryRegisteredHolders returns the registered users holding an item of a
+// collection with all the traits of criteria, lowercased. The owners of
+// ERC-721 tokens and the holders of ERC-1155 balances are both included.
+func (d *Dao) QueryRegisteredHolders(ctx context.Context, chain string, collectionAddr string, criteria []types.ItemTrait) ([]string, error) {
+	withTraits := func(db *gorm.DB, item string) *gorm.DB {
+		for _, criterion := range criteria {
+			db = db.Where(fmt.Sprintf("exists (select 1 from %s it where it.collection_address = %[2]s.collection_address "+
+				"and it.token_id = %[2]s.token_id and it.trait = ? and it.trait_value = ?)", multi.ItemTraitTableName(chain), item),
+				criterion.Key, criterion.Value)
+		}
+		return db
+	}
+
+	var owners []string
+	if err := withTraits(d.DB.WithContext(ctx).Table(fmt.Sprintf("%s gi", multi.ItemTableName(chain))).
+		Joins(fmt.Sprintf("join %s u on u.address = gi.owner", base.UserTableName())).
+		Where("gi.collection_address = ?", collectionAddr), "gi").
+		Distinct().
+		Pluck("gi.owner", &owners).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query registered owners")
+	}
+
+	var holders []string
+	if err := withTraits(d.DB.WithContext(ctx).Table(fmt.Sprintf("%s ib", ItemBalanceTableName(chain))).
+		Joins(fmt.Sprintf("join %s u on u.address = ib.owner", base.UserTableName())).
+		Where("ib.collection_address = ? and ib.balance > 0", collectionAddr), "ib").
+		Distinct().
+		Pluck("ib.owner", &holders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query registered holders")
+	}
+
+	var result []string
+	for _, addr := range append(owners, holders...) {
+		result = append(result, strings.ToLower(addr))
+	}
+	return removeRepeatedElement(result), nil
+}
+
+// QueryCompetingBids returns the active bids of a type on a collection that
+// were placed before the order with id before, best price first.
+func (d *Dao) QueryCompetingBids(ctx context.Context, chain string, collectionAddr string, orderType int64, before int64) ([]multi.Order, error) {
+	var orders []multi.Order
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Select(orderFields).
+		Where("collection_address = ? and order_type = ? and order_status = ? and expire_time > ? "+
+			"and quantity_remaining > 0 and id < ?",
+			collectionAddr, orderType, multi.OrderStatusActive, time.Now().Unix(), before).
+		Order("price desc").
+		Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query competing bids")
+	}
+
+	return orders, nil
+}
+
+func (d *Dao) QueryExpiringListings(ctx context.Context, chain string, from, to int64, cursor int64, limit int) ([]multi.Order, error) {
+	var orders []multi.Order
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Select(orderFields).
+		Where("id > ? and order_type = ? and order_status = ? and expire_time > ? and expire_time <= ?",
+			cursor, multi.ListingOrder, multi.OrderStatusActive, from, to).
+		Order("id asc").
+		Limit(limit).
+		Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query expiring listings")
+	}
+
+	return orders, nil
+}
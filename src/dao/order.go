@@ -0,0 +1,304 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const orderFields = "id, marketplace_id, collection_address, token_id, order_id, order_status, event_time, expire_time, " +
+	"currency_address, price, maker, taker, quantity_remaining, size, order_type, salt"
+
+// OrderValidation records why an active order was found unfillable on chain.
+type OrderValidation struct {
+	Id                int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	OrderID           string `gorm:"column:order_id;NOT NULL;uniqueIndex" json:"order_id"`
+	CollectionAddress string `gorm:"column:collection_address;NOT NULL" json:"collection_address"`
+	Reason            string `gorm:"column:reason;NOT NULL" json:"reason"`
+	CheckTime         int64  `gorm:"column:check_time" json:"check_time"`
+	CreateTime        int64  `gorm:"column:create_time" json:"create_time"`
+	UpdateTime        int64  `gorm:"column:update_time" json:"update_time"`
+}
+
+func OrderValidationTableName(chain string) string {
+	return fmt.Sprintf("ob_order_validation_%s", strings.ToLower(chain))
+}
+
+func (d *Dao) QueryOrdersByIDs(ctx context.Context, chain string, orderIDs []string) ([]multi.Order, error) {
+	var orders []multi.Order
+	if len(orderIDs) == 0 {
+		return orders, nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Select(orderFields).
+		Where("order_id in (?)", orderIDs).
+		Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query orders")
+	}
+
+	return orders, nil
+}
+
+// QueryActiveOrders returns a batch of active listings and bids ordered by id.
+func (d *Dao) QueryActiveOrders(ctx context.Context, chain string, cursor int64, limit int) ([]multi.Order, error) {
+	var orders []multi.Order
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Select(orderFields).
+		Where("id > ? and order_status = ? and order_type in (?,?,?,?)", cursor, multi.OrderStatusActive,
+			multi.ListingOrder, multi.CollectionBidOrder, multi.ItemBidOrder, TraitBidOrder).
+		Order("id asc").
+		Limit(limit).
+		Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query active orders")
+	}
+
+	return orders, nil
+}
+
+// QueryInvalidatedOrders returns a batch of orders disabled by the validator.
+func (d *Dao) QueryInvalidatedOrders(ctx context.Context, chain string, cursor int64, limit int) ([]multi.Order, error) {
+	var orders []multi.Order
+	if err := d.DB.WithContext(ctx).
+		Table(fmt.Sprintf("%s as co", multi.OrderTableName(chain))).
+		Select("co.id as id, co.marketplace_id as marketplace_id, co.collection_address as collection_address, "+
+			"co.token_id as token_id, co.order_id as order_id, co.order_status as order_status, "+
+			"co.event_time as event_time, co.expire_time as expire_time, co.currency_address as currency_address, "+
+			"co.price as price, co.maker as maker, co.taker as taker, co.quantity_remaining as quantity_remaining, "+
+			"co.size as size, co.order_type as order_type, co.salt as salt").
+		Joins(fmt.Sprintf("join %s ov on ov.order_id = co.order_id", OrderValidationTableName(chain))).
+		Where("co.id > ? and co.order_status = ?", cursor, multi.OrderStatusInactive).
+		Order("co.id asc").
+		Limit(limit).
+		Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query invalidated orders")
+	}
+
+	return orders, nil
+}
+
+// QueryMakerBids returns the active bids of a maker in a currency, oldest
+// first.
+func (d *Dao) QueryMakerBids(ctx context.Context, chain string, maker, currencyAddr string) ([]multi.Order, error) {
+	var orders []multi.Order
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Select(orderFields).
+		Where("maker = ? and currency_address = ? and order_status = ? and order_type in (?,?,?) and expire_time > ?",
+			strings.ToLower(maker), currencyAddr, multi.OrderStatusActive,
+			multi.CollectionBidOrder, multi.ItemBidOrder, TraitBidOrder, time.Now().Unix()).
+		Order("id asc").
+		Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query maker bids")
+	}
+
+	return orders, nil
+}
+
+// QueryValidatedOrderIDs returns which of the orders have a validation
+// recorded, those the validator disabled.
+func (d *Dao) QueryValidatedOrderIDs(ctx context.Context, chain string, orderIDs []string) (map[string]bool, error) {
+	validated := make(map[string]bool)
+	if len(orderIDs) == 0 {
+		return validated, nil
+	}
+
+	var ids []string
+	if err := d.DB.WithContext(ctx).Table(OrderValidationTableName(chain)).
+		Where("order_id in (?)", orderIDs).
+		Pluck("order_id", &ids).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query order validations")
+	}
+
+	for _, id := range ids {
+		validated[id] = true
+	}
+	return validated, nil
+}
+
+// ReactivateOrders moves orders disabled by the validator back to active.
+// Orders made inactive otherwise, without a validation recorded, are left
+// as they are.
+func (d *Dao) ReactivateOrders(ctx context.Context, chain string, orderIDs []string) error {
+	if len(orderIDs) == 0 {
+		return nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Where("order_id in (?) and order_status = ?", orderIDs, multi.OrderStatusInactive).
+		Where(fmt.Sprintf("order_id in (select order_id from %s)", OrderValidationTableName(chain))).
+		Updates(map[string]interface{}{
+			"order_status": multi.OrderStatusActive,
+			"update_time":  time.Now().UnixMilli(),
+		}).Error; err != nil {
+		return errors.Wrap(err, "failed on reactivate orders")
+	}
+
+	return nil
+}
+
+func (d *Dao) UpdateOrdersStatus(ctx context.Context, chain string, orderIDs []string, status int) error {
+	if len(orderIDs) == 0 {
+		return nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Where("order_id in (?)", orderIDs).
+		Updates(map[string]interface{}{
+			"order_status": status,
+			"update_time":  time.Now().UnixMilli(),
+		}).Error; err != nil {
+		return errors.Wrap(err, "failed on update orders status")
+	}
+
+	return nil
+}
+
+func (d *Dao) SaveOrderValidations(ctx context.Context, chain string, validations []OrderValidation) error {
+	if len(validations) == 0 {
+		return nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(OrderValidationTableName(chain)).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "order_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"reason", "check_time", "update_time"}),
+		}).
+		Create(&validations).Error; err != nil {
+		return errors.Wrap(err, "failed on save order validations")
+	}
+
+	return nil
+}
+
+func (d *Dao) DeleteOrderValidations(ctx context.Context, chain string, orderIDs []string) error {
+	if len(orderIDs) == 0 {
+		return nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(OrderValidationTableName(chain)).
+		Where("order_id in (?)", orderIDs).
+		Delete(&OrderValidation{}).Error; err != nil {
+		return errors.Wrap(err, "failed on delete order validations")
+	}
+
+	return nil
+}
+
+// QueryUsedSalts returns the salts of the maker already used by an order.
+func (d *Dao) QueryUsedSalts(ctx context.Context, chain string, maker string, salts []int64) ([]int64, error) {
+	var used []int64
+	if len(salts) == 0 {
+		return used, nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Where("maker = ? and salt in (?)", maker, salts).
+		Pluck("salt", &used).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query used salts")
+	}
+
+	return used, nil
+}
+
+// OrderSalt reserves a salt of a maker for an order submitted through the
+// api, the unique index rejects a salt reused by concurrent submissions.
+type OrderSalt struct {
+	Id         int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	Maker      string `gorm:"column:maker;NOT NULL;uniqueIndex:idx_maker_salt" json:"maker"`
+	Salt       int64  `gorm:"column:salt;uniqueIndex:idx_maker_salt" json:"salt"`
+	OrderID    string `gorm:"column:order_id;NOT NULL" json:"order_id"`
+	CreateTime int64  `gorm:"column:create_time" json:"create_time"`
+}
+
+func OrderSaltTableName(chain string) string {
+	return fmt.Sprintf("ob_order_salt_%s", strings.ToLower(chain))
+}
+
+var ErrSaltUsed = errors.New("salt already used")
+
+// CreateOrder stores an order submitted by its maker, with the criteria of a
+// trait bid. The salt is reserved in the same transaction, ErrSaltUsed is
+// returned when another order took it.
+func (d *Dao) CreateOrder(ctx context.Context, chain string, order *multi.Order, criteria []types.ItemTrait) error {
+	return d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		reserve := tx.Table(OrderSaltTableName(chain)).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&OrderSalt{
+				Maker:      order.Maker,
+				Salt:       order.Salt,
+				OrderID:    order.OrderID,
+				CreateTime: time.Now().UnixMilli(),
+			})
+		if reserve.Error != nil {
+			return errors.Wrap(reserve.Error, "failed on reserve order salt")
+		}
+		if reserve.RowsAffected == 0 {
+			return ErrSaltUsed
+		}
+
+		if err := tx.Table(multi.OrderTableName(chain)).
+			Create(order).Error; err != nil {
+			return errors.Wrap(err, "failed on create order")
+		}
+
+		if order.OrderType == TraitBidOrder {
+			return saveTraitBidCriteria(tx, chain, order.OrderID, order.CollectionAddress, criteria)
+		}
+		return nil
+	})
+}
+
+// QueryExpiredOrders returns a batch of open orders whose expire time passed.
+func (d *Dao) QueryExpiredOrders(ctx context.Context, chain string, now int64, limit int) ([]multi.Order, error) {
+	var orders []multi.Order
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Select(orderFields).
+		Where("order_status in (?,?) and expire_time <= ?", multi.OrderStatusActive, multi.OrderStatusInactive, now).
+		Order("id asc").
+		Limit(limit).
+		Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query expired orders")
+	}
+
+	return orders, nil
+}
+
+// ExpireOrders moves the given orders that are still open to expired and
+// returns the ids of the orders it moved. The orders are locked in between,
+// so an order filled or cancelled meanwhile is not reported as expired.
+func (d *Dao) ExpireOrders(ctx context.Context, chain string, orderIDs []string) ([]string, error) {
+	var expired []string
+	if len(orderIDs) == 0 {
+		return expired, nil
+	}
+
+	if err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(multi.OrderTableName(chain)).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id in (?) and order_status in (?,?)", orderIDs, multi.OrderStatusActive, multi.OrderStatusInactive).
+			Pluck("order_id", &expired).Error; err != nil {
+			return err
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+
+		return tx.Table(multi.OrderTableName(chain)).
+			Where("order_id in (?)", expired).
+			Updates(map[string]interface{}{
+				"order_status": multi.OrderStatusExpired,
+				"update_time":  time.Now().UnixMilli(),
+			}).Error
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed on expire orders")
+	}
+
+	return expired, nil
+}
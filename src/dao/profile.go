@@ -0,0 +1,93 @@
+package dao
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+)
+
+// UserProfile holds the public profile of a user. UsernameKey is the lower
+// cased username, it is nil while no username is set so that the unique
+// index only applies to set usernames.
+type UserProfile struct {
+	Id          int64   `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	Address     string  `gorm:"column:address;NOT NULL;uniqueIndex" json:"address"`
+	Username    string  `gorm:"column:username" json:"username"`
+	UsernameKey *string `gorm:"column:username_key;uniqueIndex" json:"username_key"`
+	AvatarKey   string  `gorm:"column:avatar_key" json:"avatar_key"`
+	Bio         string  `gorm:"column:bio" json:"bio"`
+	Twitter     string  `gorm:"column:twitter" json:"twitter"`
+	Website     string  `gorm:"column:website" json:"website"`
+	CreateTime  int64   `gorm:"column:create_time" json:"create_time"`
+	UpdateTime  int64   `gorm:"column:update_time" json:"update_time"`
+}
+
+func UserProfileTableName() string {
+	return "ob_user_profile"
+}
+
+func (d *Dao) QueryUserProfile(ctx context.Context, userAddr string) (*UserProfile, error) {
+	return d.queryUserProfile(ctx, "address = ?", strings.ToLower(userAddr))
+}
+
+func (d *Dao) QueryUserProfileByUsername(ctx context.Context, username string) (*UserProfile, error) {
+	return d.queryUserProfile(ctx, "username_key = ?", strings.ToLower(username))
+}
+
+func (d *Dao) queryUserProfile(ctx context.Context, query string, arg string) (*UserProfile, error) {
+	var profiles []UserProfile
+	if err := d.DB.WithContext(ctx).Table(UserProfileTableName()).
+		Where(query, arg).
+		Limit(1).
+		Find(&profiles).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query user profile")
+	}
+
+	if len(profiles) == 0 {
+		return nil, nil
+	}
+	return &profiles[0], nil
+}
+
+// QueryUserProfiles returns the profiles of addrs keyed by lower cased address.
+func (d *Dao) QueryUserProfiles(ctx context.Context, addrs []string) (map[string]UserProfile, error) {
+	profiles := make(map[string]UserProfile)
+
+	var lowerAddrs []string
+	for _, addr := range addrs {
+		if addr != "" {
+			lowerAddrs = append(lowerAddrs, strings.ToLower(addr))
+		}
+	}
+	if len(lowerAddrs) == 0 {
+		return profiles, nil
+	}
+
+	var userProfiles []UserProfile
+	if err := d.DB.WithContext(ctx).Table(UserProfileTableName()).
+		Select("address, username, avatar_key").
+		Where("address in (?)", removeRepeatedElement(lowerAddrs)).
+		Scan(&userProfiles).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query user profiles")
+	}
+
+	for _, profile := range userProfiles {
+		profiles[profile.Address] = profile
+	}
+	return profiles, nil
+}
+
+func (d *Dao) SaveUserProfile(ctx context.Context, profile *UserProfile) error {
+	if err := d.DB.WithContext(ctx).Table(UserProfileTableName()).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "address"}},
+			DoUpdates: clause.AssignmentColumns([]string{"username", "username_key", "avatar_key", "bio", "twitter", "website", "update_time"}),
+		}).
+		Create(profile).Error; err != nil {
+		return errors.Wrap(err, "failed on save user profile")
+	}
+
+	return nil
+}
@@ -23,6 +23,9 @@ func GenRankingKey(project, chain string, period int) string {
 	return fmt.Sprintf("cache:%s:%s:ranking:volume:%d", strings.ToLower(project), strings.ToLower(chain), period)
 }
 
+// EpochMinutes is the length of an epoch of periodToEpoch.
+const EpochMinutes = 5
+
 type periodEpochMap map[string]int
 
 var periodToEpoch = periodEpochMap{
@@ -35,21 +38,32 @@ var periodToEpoch = periodEpochMap{
 	"30d": 8640,
 }
 
+// periodWindow returns the bounds of a period ending at now and the start of
+// the period before it, in unix seconds like the event_time of activities.
+func periodWindow(period string, now time.Time) (start, end, prevStart int64, err error) {
+	epoch, ok := periodToEpoch[period]
+	if !ok {
+		return 0, 0, 0, errors.Errorf("invalid period: %s", period)
+	}
+
+	length := int64(epoch) * EpochMinutes * 60
+	end = now.Unix()
+	start = end - length
+	return start, end, start - length, nil
+}
+
 func (d *Dao) GetTradeInfoByCollection(chain, collectionAddr, period string) (*CollectionTrade, error) {
 	var tradeCount int64
 	var totalVolume decimal.Decimal
 	var floorPrice decimal.Decimal
 
-	epoch, ok := periodToEpoch[period]
-	if !ok {
-		return nil, errors.Errorf("invalid period: %s", period)
+	startTime, endTime, prevStartTime, err := periodWindow(period, time.Now())
+	if err != nil {
+		return nil, err
 	}
 
-	startTime := time.Now().Add(-time.Duration(epoch) * time.Minute)
-	endTime := time.Now()
-
-	err := d.DB.WithContext(d.ctx).Table(multi.ActivityTableName(chain)).
-		Where("collectioin_address = ? AND activity_type = ? AND event_time >= ? AND event_time <= ?",
+	err = d.DB.WithContext(d.ctx).Table(multi.ActivityTableName(chain)).
+		Where("collection_address = ? AND activity_type = ? AND event_time >= ? AND event_time <= ?",
 			collectionAddr, multi.Sale, startTime, endTime).
 		Select("COUNT(*) as trade_count, COALESCE(SUM(price), 0) as total_volume").
 		Row().Scan(&tradeCount, &totalVolume)
@@ -66,7 +80,6 @@ func (d *Dao) GetTradeInfoByCollection(chain, collectionAddr, period string) (*C
 		return nil, errors.Wrap(err, "failed to get floor price")
 	}
 
-	prevStartTime := startTime.Add(-time.Duration(epoch) * time.Minute)
 	prevEndTime := startTime
 
 	var prevVolume decimal.Decimal
@@ -113,15 +126,11 @@ func (d *Dao) GetTradeInfoByCollection(chain, collectionAddr, period string) (*C
 }
 
 func (d *Dao) GetCollectionRankingByActivity(chain, period string) ([]*CollectionTrade, error) {
-	epoch, ok := periodToEpoch[period]
-	if !ok {
-		return nil, errors.Errorf("invalid period: %s", period)
+	startTime, endTime, prevStartTime, err := periodWindow(period, time.Now())
+	if err != nil {
+		return nil, err
 	}
-	startTime := time.Now().Add(-time.Duration(epoch) * time.Minute)
-	endTime := time.Now()
-
 	prevEndTime := startTime
-	prevStartTime := startTime.Add(-time.Duration(epoch) * time.Minute)
 
 	type TradeStats struct {
 		CollectionAddress string
@@ -131,7 +140,7 @@ func (d *Dao) GetCollectionRankingByActivity(chain, period string) ([]*Collectio
 	}
 
 	var currentStats []TradeStats
-	err := d.DB.WithContext(d.ctx).Table(multi.ActivityTableName(chain)).
+	err = d.DB.WithContext(d.ctx).Table(multi.ActivityTableName(chain)).
 		Select("collection_address, COUNT(*) as item_count, COALESCE(SUM(price), 0) as volume, COALESCE(MIN(price), 0) as floor_price").
 		Where("activity_type = ? AND event_time >= ? AND event_time <= ? ", multi.Sale, startTime, endTime).
 		Group("collection_address").
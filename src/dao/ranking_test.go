@@ -0,0 +1,28 @@
+package dao
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodWindow(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	for period, length := range map[string]int64{
+		"15m": 15 * 60,
+		"1h":  60 * 60,
+		"24h": 24 * 60 * 60,
+		"7d":  7 * 24 * 60 * 60,
+	} {
+		start, end, prevStart, err := periodWindow(period, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if end != now.Unix() || start != end-length || prevStart != start-length {
+			t.Fatalf("%s window is [%d, %d] after %d, want %d seconds long", period, start, end, prevStart, length)
+		}
+	}
+
+	if _, _, _, err := periodWindow("2h", now); err == nil {
+		t.Fatal("expected an unknown period to be rejected")
+	}
+}
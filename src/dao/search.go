@@ -0,0 +1,132 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/base"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const MaxBatchReadItems = 1000
+
+func (d *Dao) QueryCollectionsUpdatedSince(ctx context.Context, chain string, since int64, cursor int64, limit int) ([]multi.Collection, error) {
+	var collections []multi.Collection
+	if err := d.DB.WithContext(ctx).Table(multi.CollectionTableName(chain)).
+		Select("id, chain_id, name, symbol, address, image_uri, item_amount, update_time").
+		Where("update_time > ? and id > ?", since, cursor).
+		Order("id asc").
+		Limit(limit).
+		Scan(&collections).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query updated collections")
+	}
+
+	return collections, nil
+}
+
+func (d *Dao) QueryItemsUpdatedSince(ctx context.Context, chain string, since int64, cursor int64, limit int) ([]multi.Item, error) {
+	var items []multi.Item
+	if err := d.DB.WithContext(ctx).Table(multi.ItemTableName(chain)).
+		Select("id, chain_id, collection_address, token_id, name, owner, update_time").
+		Where("update_time > ? and id > ?", since, cursor).
+		Order("id asc").
+		Limit(limit).
+		Scan(&items).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query updated items")
+	}
+
+	return items, nil
+}
+
+// QueryTraitItemsUpdatedSince returns the item traits updated after since, in
+// update order. Pages continue after the (cursorTime, cursorID) of the last
+// row of the previous page, both zero for the first page.
+func (d *Dao) QueryTraitItemsUpdatedSince(ctx context.Context, chain string, since int64, cursorTime, cursorID int64, limit int) ([]multi.ItemTrait, error) {
+	var traits []multi.ItemTrait
+	if err := d.DB.WithContext(ctx).Table(multi.ItemTraitTableName(chain)).
+		Select("id, collection_address, token_id, update_time").
+		Where("update_time > ?", since).
+		Where("(update_time, id) > (?, ?)", cursorTime, cursorID).
+		Order("update_time asc, id asc").
+		Limit(limit).
+		Scan(&traits).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query updated item traits")
+	}
+
+	return traits, nil
+}
+
+func (d *Dao) QueryItemsByInfos(ctx context.Context, chain string, itemInfos []types.ItemInfo) ([]multi.Item, error) {
+	var items []multi.Item
+	if len(itemInfos) == 0 {
+		return items, nil
+	}
+
+	var conditions []clause.Expr
+	for _, info := range itemInfos {
+		conditions = append(conditions, gorm.Expr("(?, ?)", info.CollectionAddress, info.TokenID))
+	}
+
+	if err := d.DB.WithContext(ctx).Table(multi.ItemTableName(chain)).
+		Select("id, chain_id, collection_address, token_id, name, owner, update_time").
+		Where("(collection_address, token_id) in (?)", conditions).
+		Scan(&items).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query items info")
+	}
+
+	return items, nil
+}
+
+func (d *Dao) QueryTraitsByItems(ctx context.Context, chain string, itemInfos []types.ItemInfo) ([]multi.ItemTrait, error) {
+	var itemTraits []multi.ItemTrait
+	if len(itemInfos) == 0 {
+		return itemTraits, nil
+	}
+
+	var conditions []clause.Expr
+	for _, info := range itemInfos {
+		conditions = append(conditions, gorm.Expr("(?, ?)", info.CollectionAddress, info.TokenID))
+	}
+
+	if err := d.DB.WithContext(ctx).Table(multi.ItemTraitTableName(chain)).
+		Select("collection_address, token_id, trait, trait_value").
+		Where("(collection_address, token_id) in (?)", conditions).
+		Scan(&itemTraits).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query items trait info")
+	}
+
+	return itemTraits, nil
+}
+
+// QueryUserProfilesUpdatedSince returns the profiles updated after since, in
+// id order after cursor.
+func (d *Dao) QueryUserProfilesUpdatedSince(ctx context.Context, since int64, cursor int64, limit int) ([]UserProfile, error) {
+	var profiles []UserProfile
+	if err := d.DB.WithContext(ctx).Table(UserProfileTableName()).
+		Select("id, address, username, update_time").
+		Where("update_time > ? and id > ?", since, cursor).
+		Order("id asc").
+		Limit(limit).
+		Scan(&profiles).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query updated user profiles")
+	}
+
+	return profiles, nil
+}
+
+func (d *Dao) QueryUsersUpdatedSince(ctx context.Context, since int64, cursor int64, limit int) ([]base.User, error) {
+	var users []base.User
+	if err := d.DB.WithContext(ctx).Table(base.UserTableName()).
+		Select("id, address, update_time").
+		Where("update_time > ? and id > ?", since, cursor).
+		Order("id asc").
+		Limit(limit).
+		Scan(&users).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query updated users")
+	}
+
+	return users, nil
+}
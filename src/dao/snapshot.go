@@ -0,0 +1,107 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm/clause"
+)
+
+// PortfolioSnapshot is the value of the items a user held in a collection at
+// the end of a day. Day is the unix time of the start of the UTC day.
+type PortfolioSnapshot struct {
+	Id                int64           `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	UserAddress       string          `gorm:"column:user_address;NOT NULL;uniqueIndex:idx_user_collection_day" json:"user_address"`
+	ChainID           int             `gorm:"column:chain_id;uniqueIndex:idx_user_collection_day" json:"chain_id"`
+	CollectionAddress string          `gorm:"column:collection_address;NOT NULL;uniqueIndex:idx_user_collection_day" json:"collection_address"`
+	Day               int64           `gorm:"column:day;uniqueIndex:idx_user_collection_day" json:"day"`
+	ItemCount         int64           `gorm:"column:item_count" json:"item_count"`
+	FloorPrice        decimal.Decimal `gorm:"column:floor_price" json:"floor_price"`
+	Value             decimal.Decimal `gorm:"column:value" json:"value"`
+	IsBackfill        bool            `gorm:"column:is_backfill" json:"is_backfill"`
+	CreateTime        int64           `gorm:"column:create_time" json:"create_time"`
+	UpdateTime        int64           `gorm:"column:update_time" json:"update_time"`
+}
+
+func PortfolioSnapshotTableName() string {
+	return "ob_portfolio_snapshot"
+}
+
+// SavePortfolioSnapshots upserts snapshots. A backfilled value never replaces
+// a recorded one.
+func (d *Dao) SavePortfolioSnapshots(ctx context.Context, snapshots []PortfolioSnapshot, backfill bool) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	onConflict := clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_address"}, {Name: "chain_id"}, {Name: "collection_address"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"item_count", "floor_price", "value", "is_backfill", "update_time",
+		}),
+	}
+	if backfill {
+		onConflict = clause.OnConflict{DoNothing: true}
+	}
+
+	if err := d.DB.WithContext(ctx).Table(PortfolioSnapshotTableName()).
+		Clauses(onConflict).
+		Create(&snapshots).Error; err != nil {
+		return errors.Wrap(err, "failed on save portfolio snapshots")
+	}
+
+	return nil
+}
+
+func (d *Dao) QueryPortfolioSnapshots(ctx context.Context, userAddrs []string, chainIDs []int, collectionAddrs []string, from, to int64) ([]PortfolioSnapshot, error) {
+	db := d.DB.WithContext(ctx).Table(PortfolioSnapshotTableName()).
+		Where("user_address in (?) and day >= ? and day <= ?", userAddrs, from, to)
+	if len(chainIDs) > 0 {
+		db.Where("chain_id in (?)", chainIDs)
+	}
+	if len(collectionAddrs) > 0 {
+		db.Where("collection_address in (?)", collectionAddrs)
+	}
+
+	var snapshots []PortfolioSnapshot
+	if err := db.Order("day asc").
+		Find(&snapshots).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query portfolio snapshots")
+	}
+
+	return snapshots, nil
+}
+
+func (d *Dao) HasPortfolioSnapshots(ctx context.Context, userAddr string) (bool, error) {
+	var snapshots []PortfolioSnapshot
+	if err := d.DB.WithContext(ctx).Table(PortfolioSnapshotTableName()).
+		Select("id").
+		Where("user_address = ?", userAddr).
+		Limit(1).
+		Find(&snapshots).Error; err != nil {
+		return false, errors.Wrap(err, "failed on query portfolio snapshots")
+	}
+
+	return len(snapshots) > 0, nil
+}
+
+// QueryFloorPriceHistory returns the floor prices recorded for collections
+// up to `to`, oldest first.
+func (d *Dao) QueryFloorPriceHistory(ctx context.Context, chain string, collectionAddrs []string, from, to int64) ([]multi.CollectionFloorPrice, error) {
+	var floorPrices []multi.CollectionFloorPrice
+	if len(collectionAddrs) == 0 {
+		return floorPrices, nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(multi.CollectionFloorPriceTableName(chain)).
+		Select("collection_address, price, event_time").
+		Where("collection_address in (?) and event_time >= ? and event_time <= ?", collectionAddrs, from, to).
+		Order("event_time asc").
+		Scan(&floorPrices).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query floor price history")
+	}
+
+	return floorPrices, nil
+}
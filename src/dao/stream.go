@@ -0,0 +1,38 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+)
+
+// QueryOrdersUpdatedSince returns the orders changed after the (update_time, id)
+// watermark, oldest first.
+func (d *Dao) QueryOrdersUpdatedSince(ctx context.Context, chain string, since int64, cursor int64, limit int) ([]multi.Order, error) {
+	var orders []multi.Order
+	if err := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Select(orderFields+", update_time").
+		Where("update_time > ? or (update_time = ? and id > ?)", since, since, cursor).
+		Order("update_time asc, id asc").
+		Limit(limit).
+		Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query updated orders")
+	}
+
+	return orders, nil
+}
+
+func (d *Dao) QueryFloorPricesSince(ctx context.Context, chain string, cursor int64, limit int) ([]multi.CollectionFloorPrice, error) {
+	var floorPrices []multi.CollectionFloorPrice
+	if err := d.DB.WithContext(ctx).Table(multi.CollectionFloorPriceTableName(chain)).
+		Select("id, collection_address, price, event_time").
+		Where("id > ?", cursor).
+		Order("id asc").
+		Limit(limit).
+		Scan(&floorPrices).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query collection floor prices")
+	}
+
+	return floorPrices, nil
+}
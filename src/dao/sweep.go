@@ -0,0 +1,47 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+)
+
+// QueryCheapestListings returns the active listings of a collection whose
+// maker still owns the item, cheapest first. Items must have every given trait.
+func (d *Dao) QueryCheapestListings(ctx context.Context, chain string, collectionAddr string, traits []types.ItemTrait, markets []int, limit, offset int) ([]multi.Order, error) {
+	db := d.DB.WithContext(ctx).
+		Table(fmt.Sprintf("%s as co", multi.OrderTableName(chain))).
+		Select("co.marketplace_id as marketplace_id, co.collection_address as collection_address, "+
+			"co.token_id as token_id, co.order_id as order_id, co.price as price, co.maker as maker, "+
+			"co.salt as salt, co.event_time as event_time, co.expire_time as expire_time, "+
+			"co.currency_address as currency_address, co.order_type as order_type").
+		Joins(fmt.Sprintf("join %s ci on ci.collection_address = co.collection_address and ci.token_id = co.token_id",
+			multi.ItemTableName(chain))).
+		Where("co.collection_address = ? and co.order_type = ? and co.order_status = ? "+
+			"and "+listedByHolder(chain, "co", "ci")+" and co.expire_time > ?",
+			collectionAddr, multi.ListingOrder, multi.OrderStatusActive, time.Now().Unix())
+
+	if len(markets) > 0 {
+		db.Where("co.marketplace_id in (?)", markets)
+	}
+
+	for _, trait := range traits {
+		db.Where(fmt.Sprintf("exists (select 1 from %s it where it.collection_address = co.collection_address "+
+			"and it.token_id = co.token_id and it.trait = ? and it.trait_value = ?)", multi.ItemTraitTableName(chain)),
+			trait.Key, trait.Value)
+	}
+
+	var orders []multi.Order
+	if err := db.Order("co.price asc, co.id asc").
+		Limit(limit).
+		Offset(offset).
+		Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query cheapest listings")
+	}
+
+	return orders, nil
+}
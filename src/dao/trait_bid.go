@@ -0,0 +1,229 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// TraitBidOrder is a bid on any item of a collection whose traits match all
+// the criteria stored for the order.
+//
+// The traits are only enforced off chain: the maker signs a collection bid
+// and the order book contract settles it as one, so whoever holds the signed
+// order can fill it with any item of the collection. Until the contract checks
+// the criteria the API lists trait bids but never quotes them for a fill, and
+// makers should be warned that the bid binds them to the whole collection.
+const TraitBidOrder = 5
+
+type TraitBidCriteria struct {
+	Id                int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	OrderID           string `gorm:"column:order_id;NOT NULL" json:"order_id"`
+	CollectionAddress string `gorm:"column:collection_address;NOT NULL" json:"collection_address"`
+	Trait             string `gorm:"column:trait;NOT NULL" json:"trait"`
+	TraitValue        string `gorm:"column:trait_value;NOT NULL" json:"trait_value"`
+	CreateTime        int64  `gorm:"column:create_time" json:"create_time"`
+	UpdateTime        int64  `gorm:"column:update_time" json:"update_time"`
+}
+
+func TraitBidCriteriaTableName(chain string) string {
+	return fmt.Sprintf("ob_trait_bid_criteria_%s", strings.ToLower(chain))
+}
+
+type TraitBid struct {
+	multi.Order
+	Criteria []types.ItemTrait
+}
+
+// Match reports whether the item traits satisfy every criterion of the bid.
+func (b *TraitBid) Match(itemTraits []multi.ItemTrait) bool {
+	if len(b.Criteria) == 0 {
+		return false
+	}
+
+	for _, criterion := range b.Criteria {
+		matched := false
+		for _, trait := range itemTraits {
+			if strings.EqualFold(trait.Trait, criterion.Key) && strings.EqualFold(trait.TraitValue, criterion.Value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func saveTraitBidCriteria(tx *gorm.DB, chain string, orderID string, collectionAddr string, criteria []types.ItemTrait) error {
+	if len(criteria) == 0 {
+		return errors.New("trait bid without criteria")
+	}
+
+	now := time.Now().UnixMilli()
+	var rows []TraitBidCriteria
+	for _, criterion := range criteria {
+		rows = append(rows, TraitBidCriteria{
+			OrderID:           orderID,
+			CollectionAddress: collectionAddr,
+			Trait:             criterion.Key,
+			TraitValue:        criterion.Value,
+			CreateTime:        now,
+			UpdateTime:        now,
+		})
+	}
+
+	if err := tx.Table(TraitBidCriteriaTableName(chain)).
+		Create(&rows).Error; err != nil {
+		return errors.Wrap(err, "failed on save trait bid criteria")
+	}
+
+	return nil
+}
+
+// QueryTraitBids returns the active trait bids of a collection with their
+// criteria, best price first. Bids made by userAddr are skipped.
+func (d *Dao) QueryTraitBids(ctx context.Context, chain string, userAddr string, collectionAddr string) ([]TraitBid, error) {
+	var orders []multi.Order
+	db := d.DB.WithContext(ctx).Table(multi.OrderTableName(chain)).
+		Select("marketplace_id, collection_address, order_id, price, event_time, expire_time, salt, maker, "+
+			"order_type, quantity_remaining, size").
+		Where("collection_address = ? and order_type = ? and order_status = ? "+
+			"and quantity_remaining > 0 and expire_time > ?",
+			collectionAddr, TraitBidOrder, multi.OrderStatusActive, time.Now().Unix())
+	if userAddr != "" {
+		db.Where("maker != ?", userAddr)
+	}
+
+	if err := db.Order("price desc").Scan(&orders).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query trait bids")
+	}
+
+	if len(orders) == 0 {
+		return nil, nil
+	}
+
+	var orderIDs []string
+	for _, order := range orders {
+		orderIDs = append(orderIDs, order.OrderID)
+	}
+
+	orderCriteria, err := d.QueryTraitBidCriteria(ctx, chain, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var traitBids []TraitBid
+	for _, order := range orders {
+		c, ok := orderCriteria[order.OrderID]
+		if !ok {
+			continue
+		}
+		traitBids = append(traitBids, TraitBid{
+			Order:    order,
+			Criteria: c,
+		})
+	}
+
+	return traitBids, nil
+}
+
+// QueryTraitBidCriteria returns the criteria of the trait bids keyed by order id.
+func (d *Dao) QueryTraitBidCriteria(ctx context.Context, chain string, orderIDs []string) (map[string][]types.ItemTrait, error) {
+	orderCriteria := make(map[string][]types.ItemTrait)
+	if len(orderIDs) == 0 {
+		return orderCriteria, nil
+	}
+
+	var criteria []TraitBidCriteria
+	if err := d.DB.WithContext(ctx).Table(TraitBidCriteriaTableName(chain)).
+		Select("order_id, trait, trait_value").
+		Where("order_id in (?)", orderIDs).
+		Scan(&criteria).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query trait bid criteria")
+	}
+
+	for _, criterion := range criteria {
+		orderCriteria[criterion.OrderID] = append(orderCriteria[criterion.OrderID], types.ItemTrait{
+			Key:   criterion.Trait,
+			Value: criterion.TraitValue,
+		})
+	}
+
+	return orderCriteria, nil
+}
+
+// QueryItemTraitBids returns the active trait bids matching the traits of an item.
+func (d *Dao) QueryItemTraitBids(ctx context.Context, chain string, userAddr string, collectionAddr string, tokenID string) ([]TraitBid, error) {
+	traitBids, err := d.QueryTraitBids(ctx, chain, userAddr, collectionAddr)
+	if err != nil {
+		return nil, err
+	}
+	if len(traitBids) == 0 {
+		return nil, nil
+	}
+
+	itemTraits, err := d.QueryItemTraits(ctx, chain, collectionAddr, tokenID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on query item traits")
+	}
+
+	var matched []TraitBid
+	for _, bid := range traitBids {
+		if bid.Match(itemTraits) {
+			matched = append(matched, bid)
+		}
+	}
+
+	return matched, nil
+}
+
+// QueryCollectionTraitBids aggregates the trait bid depth of a collection per
+// trait value and price.
+func (d *Dao) QueryCollectionTraitBids(ctx context.Context, chain string, collectionAddr string, trait string, page, pageSize int) ([]types.TraitBids, int64, error) {
+	db := d.DB.WithContext(ctx).
+		Table(fmt.Sprintf("%s as co", multi.OrderTableName(chain))).
+		Joins(fmt.Sprintf("join %s tc on tc.order_id = co.order_id", TraitBidCriteriaTableName(chain))).
+		Where("co.collection_address = ? and co.order_type = ? and co.order_status = ? "+
+			"and co.expire_time > ? and co.quantity_remaining > 0",
+			collectionAddr, TraitBidOrder, multi.OrderStatusActive, time.Now().Unix())
+	if trait != "" {
+		db.Where("tc.trait = ?", trait)
+	}
+
+	var count int64
+	if err := db.Session(&gorm.Session{}).
+		Distinct("tc.trait", "tc.trait_value", "co.price").
+		Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "failed on count trait bids")
+	}
+
+	var bids []types.TraitBids
+	if count == 0 {
+		return bids, 0, nil
+	}
+
+	if err := db.Select(`tc.trait as trait,
+			tc.trait_value as trait_value,
+			co.price as price,
+			sum(co.quantity_remaining) AS size,
+			sum(co.quantity_remaining)*co.price as total,
+			COUNT(DISTINCT co.maker) AS bidders`).
+		Group("tc.trait, tc.trait_value, co.price").
+		Order("tc.trait asc, tc.trait_value asc, co.price desc").
+		Limit(pageSize).
+		Offset(pageSize * (page - 1)).
+		Scan(&bids).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "failed on query collection trait bids")
+	}
+
+	return bids, count, nil
+}
@@ -20,19 +20,24 @@ func (d *Dao) GetUserSigStatus(ctx context.Context, userAddr string) (bool, erro
 	return userInfo.IsSigned, nil
 }
 
-func (d *Dao) QueryUserBids(ctx context.Context, chain string, userAddrs []string, contractAddrs []string) ([]multi.Order, error) {
+// QueryUserBids returns the active bids of users. Bids on hidden collections
+// of the chain are left out when chainID is set.
+func (d *Dao) QueryUserBids(ctx context.Context, chain string, chainID int, userAddrs []string, contractAddrs []string) ([]multi.Order, error) {
 	var userBids []multi.Order
 
 	db := d.DB.WithContext(ctx).
 		Table(multi.OrderTableName(chain)).
 		Select("collection_address, token_id, order_id, token_id, order_type, "+
 			"quantity_remaining, size, event_time, price, salt, expire_time").
-		Where("maker in (?) and order_type in (?,?) and order_status = ? and quantity_remaining > 0",
-			userAddrs, multi.ItemBidOrder, multi.CollectionBidOrder, multi.OrderStatusActive)
+		Where("maker in (?) and order_type in (?,?,?) and order_status = ? and quantity_remaining > 0",
+			userAddrs, multi.ItemBidOrder, multi.CollectionBidOrder, TraitBidOrder, multi.OrderStatusActive)
 
 	if len(contractAddrs) != 0 {
 		db.Where("collection_address in (?)", contractAddrs)
 	}
+	if chainID != 0 {
+		db.Where(notHidden(chainID, multi.OrderTableName(chain)+".collection_address"))
+	}
 
 	if err := db.Scan(&userBids).Error; err != nil {
 		return nil, errors.Wrap(err, "failed on get user bids")
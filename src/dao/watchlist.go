@@ -0,0 +1,175 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PriceAlertFloorBelow   = "floor_below"
+	PriceAlertFloorAbove   = "floor_above"
+	PriceAlertVolumeChange = "volume_change"
+)
+
+type Watchlist struct {
+	Id                int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	UserAddress       string `gorm:"column:user_address;NOT NULL;uniqueIndex:idx_user_collection" json:"user_address"`
+	ChainID           int    `gorm:"column:chain_id;uniqueIndex:idx_user_collection" json:"chain_id"`
+	CollectionAddress string `gorm:"column:collection_address;NOT NULL;uniqueIndex:idx_user_collection" json:"collection_address"`
+	CreateTime        int64  `gorm:"column:create_time" json:"create_time"`
+	UpdateTime        int64  `gorm:"column:update_time" json:"update_time"`
+}
+
+// PriceAlert fires once when its condition becomes true and is re-armed when
+// the condition stops holding. Threshold is a price for the floor alerts and
+// a percentage for the volume change alert.
+type PriceAlert struct {
+	Id                int64           `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	UserAddress       string          `gorm:"column:user_address;NOT NULL;index" json:"user_address"`
+	ChainID           int             `gorm:"column:chain_id" json:"chain_id"`
+	CollectionAddress string          `gorm:"column:collection_address;NOT NULL" json:"collection_address"`
+	AlertType         string          `gorm:"column:alert_type;NOT NULL" json:"alert_type"`
+	Threshold         decimal.Decimal `gorm:"column:threshold" json:"threshold"`
+	IsActive          bool            `gorm:"column:is_active" json:"is_active"`
+	IsTriggered       bool            `gorm:"column:is_triggered" json:"is_triggered"`
+	TriggerCount      int64           `gorm:"column:trigger_count" json:"trigger_count"`
+	LastTriggerTime   int64           `gorm:"column:last_trigger_time" json:"last_trigger_time"`
+	CreateTime        int64           `gorm:"column:create_time" json:"create_time"`
+	UpdateTime        int64           `gorm:"column:update_time" json:"update_time"`
+}
+
+func WatchlistTableName() string {
+	return "ob_watchlist"
+}
+
+func PriceAlertTableName() string {
+	return "ob_price_alert"
+}
+
+func (d *Dao) AddWatchlist(ctx context.Context, watchlist *Watchlist) error {
+	if err := d.DB.WithContext(ctx).Table(WatchlistTableName()).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(watchlist).Error; err != nil {
+		return errors.Wrap(err, "failed on add watchlist")
+	}
+
+	return nil
+}
+
+func (d *Dao) RemoveWatchlist(ctx context.Context, userAddrs []string, chainID int, collectionAddr string) error {
+	if err := d.DB.WithContext(ctx).Table(WatchlistTableName()).
+		Where("user_address in (?) and chain_id = ? and collection_address = ?", userAddrs, chainID, collectionAddr).
+		Delete(&Watchlist{}).Error; err != nil {
+		return errors.Wrap(err, "failed on remove watchlist")
+	}
+
+	return nil
+}
+
+func (d *Dao) QueryWatchlist(ctx context.Context, userAddrs []string) ([]Watchlist, error) {
+	var watchlist []Watchlist
+	if err := d.DB.WithContext(ctx).Table(WatchlistTableName()).
+		Where("user_address in (?)", userAddrs).
+		Order("id desc").
+		Find(&watchlist).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query watchlist")
+	}
+
+	return watchlist, nil
+}
+
+func (d *Dao) CountWatchlist(ctx context.Context, userAddrs []string) (int64, error) {
+	var count int64
+	if err := d.DB.WithContext(ctx).Table(WatchlistTableName()).
+		Where("user_address in (?)", userAddrs).
+		Count(&count).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on count watchlist")
+	}
+
+	return count, nil
+}
+
+func (d *Dao) CreatePriceAlert(ctx context.Context, alert *PriceAlert) error {
+	if err := d.DB.WithContext(ctx).Table(PriceAlertTableName()).
+		Create(alert).Error; err != nil {
+		return errors.Wrap(err, "failed on create price alert")
+	}
+
+	return nil
+}
+
+func (d *Dao) QueryPriceAlerts(ctx context.Context, userAddrs []string) ([]PriceAlert, error) {
+	var alerts []PriceAlert
+	if err := d.DB.WithContext(ctx).Table(PriceAlertTableName()).
+		Where("user_address in (?) and is_active = ?", userAddrs, true).
+		Order("id desc").
+		Find(&alerts).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query price alerts")
+	}
+
+	return alerts, nil
+}
+
+func (d *Dao) CountPriceAlerts(ctx context.Context, userAddrs []string) (int64, error) {
+	var count int64
+	if err := d.DB.WithContext(ctx).Table(PriceAlertTableName()).
+		Where("user_address in (?) and is_active = ?", userAddrs, true).
+		Count(&count).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on count price alerts")
+	}
+
+	return count, nil
+}
+
+func (d *Dao) DeletePriceAlert(ctx context.Context, userAddrs []string, id int64) (bool, error) {
+	db := d.DB.WithContext(ctx).Table(PriceAlertTableName()).
+		Where("id = ? and user_address in (?) and is_active = ?", id, userAddrs, true).
+		Updates(map[string]interface{}{
+			"is_active":   false,
+			"update_time": time.Now().UnixMilli(),
+		})
+	if db.Error != nil {
+		return false, errors.Wrap(db.Error, "failed on delete price alert")
+	}
+
+	return db.RowsAffected > 0, nil
+}
+
+func (d *Dao) QueryActivePriceAlerts(ctx context.Context, cursor int64, limit int) ([]PriceAlert, error) {
+	var alerts []PriceAlert
+	if err := d.DB.WithContext(ctx).Table(PriceAlertTableName()).
+		Where("id > ? and is_active = ?", cursor, true).
+		Order("id asc").
+		Limit(limit).
+		Find(&alerts).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query active price alerts")
+	}
+
+	return alerts, nil
+}
+
+// SetPriceAlertTriggered records a state change of an alert. Firing bumps the
+// trigger count, which is part of the notification dedupe key.
+func (d *Dao) SetPriceAlertTriggered(ctx context.Context, alert *PriceAlert, triggered bool) error {
+	now := time.Now().UnixMilli()
+	updates := map[string]interface{}{
+		"is_triggered": triggered,
+		"update_time":  now,
+	}
+	if triggered {
+		updates["trigger_count"] = alert.TriggerCount + 1
+		updates["last_trigger_time"] = now
+	}
+
+	if err := d.DB.WithContext(ctx).Table(PriceAlertTableName()).
+		Where("id = ? and is_triggered = ?", alert.Id, !triggered).
+		Updates(updates).Error; err != nil {
+		return errors.Wrap(err, "failed on update price alert")
+	}
+
+	return nil
+}
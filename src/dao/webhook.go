@@ -0,0 +1,234 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryRetrying  = "retrying"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead"
+)
+
+// Webhook is an endpoint receiving the activities matching its filters.
+// EventTypes and Collections are comma separated, empty matches everything.
+type Webhook struct {
+	Id          int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	Owner       string `gorm:"column:owner;NOT NULL;index" json:"owner"`
+	URL         string `gorm:"column:url;NOT NULL" json:"url"`
+	Secret      string `gorm:"column:secret;NOT NULL" json:"secret"`
+	ChainID     int    `gorm:"column:chain_id" json:"chain_id"`
+	EventTypes  string `gorm:"column:event_types" json:"event_types"`
+	Collections string `gorm:"column:collections" json:"collections"`
+	IsActive    bool   `gorm:"column:is_active" json:"is_active"`
+	CreateTime  int64  `gorm:"column:create_time" json:"create_time"`
+	UpdateTime  int64  `gorm:"column:update_time" json:"update_time"`
+}
+
+type WebhookDelivery struct {
+	Id              int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	WebhookID       int64  `gorm:"column:webhook_id;NOT NULL;uniqueIndex:idx_webhook_event" json:"webhook_id"`
+	EventID         string `gorm:"column:event_id;NOT NULL;uniqueIndex:idx_webhook_event" json:"event_id"`
+	EventType       string `gorm:"column:event_type" json:"event_type"`
+	Payload         string `gorm:"column:payload;type:text" json:"payload"`
+	Status          string `gorm:"column:status;NOT NULL;index" json:"status"`
+	Attempts        int    `gorm:"column:attempts" json:"attempts"`
+	NextAttemptTime int64  `gorm:"column:next_attempt_time" json:"next_attempt_time"`
+	ResponseCode    int    `gorm:"column:response_code" json:"response_code"`
+	LastError       string `gorm:"column:last_error" json:"last_error"`
+	CreateTime      int64  `gorm:"column:create_time" json:"create_time"`
+	UpdateTime      int64  `gorm:"column:update_time" json:"update_time"`
+}
+
+func WebhookTableName() string {
+	return "ob_webhook"
+}
+
+func WebhookDeliveryTableName() string {
+	return "ob_webhook_delivery"
+}
+
+func (d *Dao) CreateWebhook(ctx context.Context, webhook *Webhook) error {
+	if err := d.DB.WithContext(ctx).Table(WebhookTableName()).
+		Create(webhook).Error; err != nil {
+		return errors.Wrap(err, "failed on create webhook")
+	}
+
+	return nil
+}
+
+func (d *Dao) QueryWebhooks(ctx context.Context, owners []string) ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := d.DB.WithContext(ctx).Table(WebhookTableName()).
+		Where("owner in (?) and is_active = ?", owners, true).
+		Order("id desc").
+		Find(&webhooks).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query webhooks")
+	}
+
+	return webhooks, nil
+}
+
+// QueryWebhook returns the webhook with id if it belongs to one of owners, or nil.
+func (d *Dao) QueryWebhook(ctx context.Context, owners []string, id int64) (*Webhook, error) {
+	var webhooks []Webhook
+	if err := d.DB.WithContext(ctx).Table(WebhookTableName()).
+		Where("id = ? and owner in (?) and is_active = ?", id, owners, true).
+		Limit(1).
+		Find(&webhooks).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query webhook")
+	}
+
+	if len(webhooks) == 0 {
+		return nil, nil
+	}
+	return &webhooks[0], nil
+}
+
+func (d *Dao) CountWebhooks(ctx context.Context, owners []string) (int64, error) {
+	var count int64
+	if err := d.DB.WithContext(ctx).Table(WebhookTableName()).
+		Where("owner in (?) and is_active = ?", owners, true).
+		Count(&count).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on count webhooks")
+	}
+
+	return count, nil
+}
+
+// DeleteWebhook deactivates a webhook, its delivery history is kept.
+func (d *Dao) DeleteWebhook(ctx context.Context, owners []string, id int64) (bool, error) {
+	db := d.DB.WithContext(ctx).Table(WebhookTableName()).
+		Where("id = ? and owner in (?) and is_active = ?", id, owners, true).
+		Updates(map[string]interface{}{
+			"is_active":   false,
+			"update_time": time.Now().UnixMilli(),
+		})
+	if db.Error != nil {
+		return false, errors.Wrap(db.Error, "failed on delete webhook")
+	}
+
+	return db.RowsAffected > 0, nil
+}
+
+func (d *Dao) QueryActiveWebhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := d.DB.WithContext(ctx).Table(WebhookTableName()).
+		Where("is_active = ?", true).
+		Find(&webhooks).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query active webhooks")
+	}
+
+	return webhooks, nil
+}
+
+// CreateWebhookDeliveries enqueues deliveries, skipping the events already
+// enqueued for a webhook.
+func (d *Dao) CreateWebhookDeliveries(ctx context.Context, deliveries []WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := d.DB.WithContext(ctx).Table(WebhookDeliveryTableName()).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&deliveries).Error; err != nil {
+		return errors.Wrap(err, "failed on create webhook deliveries")
+	}
+
+	return nil
+}
+
+func (d *Dao) QueryDueWebhookDeliveries(ctx context.Context, now int64, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	if err := d.DB.WithContext(ctx).Table(WebhookDeliveryTableName()).
+		Where("status in (?) and next_attempt_time <= ?",
+			[]string{WebhookDeliveryPending, WebhookDeliveryRetrying}, now).
+		Order("next_attempt_time asc, id asc").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query due webhook deliveries")
+	}
+
+	return deliveries, nil
+}
+
+func (d *Dao) UpdateWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	if err := d.DB.WithContext(ctx).Table(WebhookDeliveryTableName()).
+		Where("id = ?", delivery.Id).
+		Updates(map[string]interface{}{
+			"status":            delivery.Status,
+			"attempts":          delivery.Attempts,
+			"next_attempt_time": delivery.NextAttemptTime,
+			"response_code":     delivery.ResponseCode,
+			"last_error":        delivery.LastError,
+			"update_time":       time.Now().UnixMilli(),
+		}).Error; err != nil {
+		return errors.Wrap(err, "failed on update webhook delivery")
+	}
+
+	return nil
+}
+
+// DeadLetterWebhookDeliveries moves the queued deliveries of a deleted
+// webhook to the dead state.
+func (d *Dao) DeadLetterWebhookDeliveries(ctx context.Context, webhookID int64, reason string) error {
+	if err := d.DB.WithContext(ctx).Table(WebhookDeliveryTableName()).
+		Where("webhook_id = ? and status in (?)", webhookID,
+			[]string{WebhookDeliveryPending, WebhookDeliveryRetrying}).
+		Updates(map[string]interface{}{
+			"status":      WebhookDeliveryDead,
+			"last_error":  reason,
+			"update_time": time.Now().UnixMilli(),
+		}).Error; err != nil {
+		return errors.Wrap(err, "failed on dead letter webhook deliveries")
+	}
+
+	return nil
+}
+
+func (d *Dao) QueryWebhookDeliveries(ctx context.Context, webhookID int64, status string, page, pageSize int) ([]WebhookDelivery, int64, error) {
+	db := d.DB.WithContext(ctx).Table(WebhookDeliveryTableName()).
+		Where("webhook_id = ?", webhookID)
+	if status != "" {
+		db.Where("status = ?", status)
+	}
+
+	var count int64
+	if err := db.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "failed on count webhook deliveries")
+	}
+
+	var deliveries []WebhookDelivery
+	if err := db.Order("id desc").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&deliveries).Error; err != nil {
+		return nil, 0, errors.Wrap(err, "failed on query webhook deliveries")
+	}
+
+	return deliveries, count, nil
+}
+
+// RetryWebhookDelivery puts a dead delivery back in the queue.
+func (d *Dao) RetryWebhookDelivery(ctx context.Context, webhookID, deliveryID int64) (bool, error) {
+	now := time.Now().UnixMilli()
+	db := d.DB.WithContext(ctx).Table(WebhookDeliveryTableName()).
+		Where("id = ? and webhook_id = ? and status = ?", deliveryID, webhookID, WebhookDeliveryDead).
+		Updates(map[string]interface{}{
+			"status":            WebhookDeliveryPending,
+			"attempts":          0,
+			"next_attempt_time": now,
+			"update_time":       now,
+		})
+	if db.Error != nil {
+		return false, errors.Wrap(db.Error, "failed on retry webhook delivery")
+	}
+
+	return db.RowsAffected > 0, nil
+}
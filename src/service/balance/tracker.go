@@ -0,0 +1,198 @@
+package balance
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/contract"
+	"github.com/SimonHofman/EasySwapBase/chain/nftchainservice"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultInterval  = time.Minute
+	DefaultBatchSize = 200
+	// MaxBatchesPerRun bounds the work done in one tick so a large backlog
+	// does not block the next run.
+	MaxBatchesPerRun = 20
+	// Workers bounds the concurrent balance reads of a batch.
+	Workers = 8
+)
+
+const CacheBalanceCursorKey = "cache:es:balance:%s:cursor"
+
+// Tracker keeps the ERC-1155 balances of the item balance tables up to date
+// from the activities. An activity carries no amount, so the balances of the
+// parties of every new mint, transfer and trade are read on chain. A new
+// cursor starts at the beginning of the activity table, which fills in the
+// balances of the past holders.
+type Tracker struct {
+	dao       *dao.Dao
+	store     *xkv.Store
+	nodeSrvs  map[int64]*nftchainservice.Service
+	chains    []*config.ChainSupported
+	batchSize int
+}
+
+func NewTracker(d *dao.Dao, store *xkv.Store, nodeSrvs map[int64]*nftchainservice.Service, chains []*config.ChainSupported, batchSize int) *Tracker {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &Tracker{
+		dao:       d,
+		store:     store,
+		nodeSrvs:  nodeSrvs,
+		chains:    chains,
+		batchSize: batchSize,
+	}
+}
+
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, chain := range t.chains {
+				if err := t.Track(ctx, chain); err != nil {
+					xzap.WithContext(ctx).Error("failed on track item balances", zap.String("chain", chain.Name), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// Track reads the balances touched by the activities after the cursor of a
+// chain and stores them. The cursor only moves past a batch once all its
+// balances are saved, a failed read is retried on the next run.
+func (t *Tracker) Track(ctx context.Context, chain *config.ChainSupported) error {
+	nodeSrv, ok := t.nodeSrvs[int64(chain.ChainID)]
+	if !ok {
+		return nil
+	}
+
+	cursor, err := t.cursor(chain.Name)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < MaxBatchesPerRun; i++ {
+		activities, err := t.dao.QueryBalanceActivitiesSince(ctx, chain.Name, cursor, t.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(activities) == 0 {
+			return nil
+		}
+
+		balances, err := t.readBalances(ctx, nodeSrv, holdings(activities))
+		if err != nil {
+			return err
+		}
+		if err := t.dao.SaveItemBalances(ctx, chain.Name, balances); err != nil {
+			return err
+		}
+
+		cursor = activities[len(activities)-1].Id
+		if err := t.store.Set(fmt.Sprintf(CacheBalanceCursorKey, chain.Name), strconv.FormatInt(cursor, 10)); err != nil {
+			return errors.Wrap(err, "failed on set balance cursor")
+		}
+		if len(activities) < t.batchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// readBalances reads onchain the balances of holdings, with Workers
+// concurrent calls.
+func (t *Tracker) readBalances(ctx context.Context, nodeSrv *nftchainservice.Service, holdings []dao.ItemBalance) ([]dao.ItemBalance, error) {
+	errs := make([]error, len(holdings))
+	sem := make(chan struct{}, Workers)
+	var wg sync.WaitGroup
+	for i := range holdings {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(holding *dao.ItemBalance, i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			balance, err := contract.BalanceOf1155(ctx, nodeSrv.ChainClient, holding.CollectionAddress, holding.Owner, holding.TokenId)
+			if err != nil {
+				errs[i] = errors.Wrap(err, fmt.Sprintf("failed on fetch balance of %s", holding.Owner))
+				return
+			}
+			holding.Balance = balance.Int64()
+		}(&holdings[i], i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return holdings, nil
+}
+
+func (t *Tracker) cursor(chain string) (int64, error) {
+	value, err := t.store.Get(fmt.Sprintf(CacheBalanceCursorKey, chain))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed on get balance cursor")
+	}
+	if value == "" {
+		return 0, nil
+	}
+
+	cursor, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid balance cursor")
+	}
+	return cursor, nil
+}
+
+// holdings returns the tokens and parties of activities, once each. The zero
+// address of mints and burns holds nothing.
+func holdings(activities []multi.Activity) []dao.ItemBalance {
+	seen := make(map[string]bool)
+	var result []dao.ItemBalance
+	for _, activity := range activities {
+		for _, party := range []string{activity.Maker, activity.Taker} {
+			if party == "" || common.HexToAddress(party) == (common.Address{}) {
+				continue
+			}
+
+			key := strings.ToLower(activity.CollectionAddress + ":" + dao.TokenBalanceKey(activity.TokenId, party))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, dao.ItemBalance{
+				CollectionAddress: activity.CollectionAddress,
+				TokenId:           activity.TokenId,
+				Owner:             strings.ToLower(party),
+			})
+		}
+	}
+	return result
+}
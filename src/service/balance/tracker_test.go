@@ -0,0 +1,31 @@
+package balance
+
+import (
+	"testing"
+
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+)
+
+func TestHoldings(t *testing.T) {
+	const (
+		collection = "0xcollection"
+		alice      = "0x00000000000000000000000000000000000000a1"
+		bob        = "0x00000000000000000000000000000000000000B2"
+		zero       = "0x0000000000000000000000000000000000000000"
+	)
+	holdings := holdings([]multi.Activity{
+		{ActivityType: multi.Mint, Maker: zero, Taker: alice, CollectionAddress: collection, TokenId: "1"},
+		{ActivityType: multi.Transfer, Maker: alice, Taker: bob, CollectionAddress: collection, TokenId: "1"},
+		{ActivityType: multi.Transfer, Maker: "0x00000000000000000000000000000000000000b2", Taker: alice, CollectionAddress: collection, TokenId: "2"},
+	})
+
+	want := []string{"1:" + alice, "1:0x00000000000000000000000000000000000000b2", "2:0x00000000000000000000000000000000000000b2", "2:" + alice}
+	if len(holdings) != len(want) {
+		t.Fatalf("expected %d holdings, got %+v", len(want), holdings)
+	}
+	for i, holding := range holdings {
+		if got := holding.TokenId + ":" + holding.Owner; got != want[i] {
+			t.Fatalf("holding %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package contract
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBase/chain/chainclient"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+var (
+	ownerOfSelector          = common.FromHex("0x6352211e") // ownerOf(uint256)
+	getApprovedSelector      = common.FromHex("0x081812fc") // getApproved(uint256)
+	isApprovedForAllSelector = common.FromHex("0xe985e9c5") // isApprovedForAll(address,address)
+	balanceOfSelector        = common.FromHex("0x70a08231") // balanceOf(address)
+	allowanceSelector        = common.FromHex("0xdd62ed3e") // allowance(address,address)
+	balanceOf1155Selector    = common.FromHex("0x00fdd58e") // balanceOf(address,uint256)
+	tokenURISelector         = common.FromHex("0xc87b56dd") // tokenURI(uint256)
+	uriSelector              = common.FromHex("0x0e89341c") // uri(uint256)
+	totalSupply1155Selector  = common.FromHex("0xbd85b039") // totalSupply(uint256)
+)
+
+var ErrEmptyResult = errors.New("empty contract call result")
+
+func Call(ctx context.Context, client chainclient.ChainClient, contractAddr string, selector []byte, args ...[]byte) ([]byte, error) {
+	data := append([]byte{}, selector...)
+	for _, arg := range args {
+		data = append(data, common.LeftPadBytes(arg, 32)...)
+	}
+
+	to := common.HexToAddress(contractAddr)
+	result, err := client.CallContract(ctx, ethereum.CallMsg{
+		To:   &to,
+		Data: data,
+	}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on call contract")
+	}
+
+	if len(result) < 32 {
+		return nil, ErrEmptyResult
+	}
+
+	return result, nil
+}
+
+func AddressArg(addr string) []byte {
+	return common.HexToAddress(addr).Bytes()
+}
+
+func Uint256Arg(value string) []byte {
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return []byte{}
+	}
+	return n.Bytes()
+}
+
+func OwnerOf(ctx context.Context, client chainclient.ChainClient, collectionAddr, tokenID string) (common.Address, error) {
+	result, err := Call(ctx, client, collectionAddr, ownerOfSelector, Uint256Arg(tokenID))
+	if err != nil {
+		return common.Address{}, err
+	}
+	return common.BytesToAddress(result[:32]), nil
+}
+
+func GetApproved(ctx context.Context, client chainclient.ChainClient, collectionAddr, tokenID string) (common.Address, error) {
+	result, err := Call(ctx, client, collectionAddr, getApprovedSelector, Uint256Arg(tokenID))
+	if err != nil {
+		return common.Address{}, err
+	}
+	return common.BytesToAddress(result[:32]), nil
+}
+
+func IsApprovedForAll(ctx context.Context, client chainclient.ChainClient, collectionAddr, owner, operator string) (bool, error) {
+	result, err := Call(ctx, client, collectionAddr, isApprovedForAllSelector, AddressArg(owner), AddressArg(operator))
+	if err != nil {
+		return false, err
+	}
+	return new(big.Int).SetBytes(result[:32]).Sign() != 0, nil
+}
+
+func BalanceOf(ctx context.Context, client chainclient.ChainClient, tokenAddr, owner string) (*big.Int, error) {
+	result, err := Call(ctx, client, tokenAddr, balanceOfSelector, AddressArg(owner))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(result[:32]), nil
+}
+
+// BalanceOf1155 returns the amount of an ERC-1155 token held by owner.
+func BalanceOf1155(ctx context.Context, client chainclient.ChainClient, collectionAddr, owner, tokenID string) (*big.Int, error) {
+	result, err := Call(ctx, client, collectionAddr, balanceOf1155Selector, AddressArg(owner), Uint256Arg(tokenID))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(result[:32]), nil
+}
+
+// TotalSupply1155 returns the minted amount of an ERC-1155 token. It is part
+// of the optional ERC1155Supply extension, so the call fails on collections
+// without it.
+func TotalSupply1155(ctx context.Context, client chainclient.ChainClient, collectionAddr, tokenID string) (*big.Int, error) {
+	result, err := Call(ctx, client, collectionAddr, totalSupply1155Selector, Uint256Arg(tokenID))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(result[:32]), nil
+}
+
+// TokenURI returns the metadata uri of an ERC-721 token.
+func TokenURI(ctx context.Context, client chainclient.ChainClient, collectionAddr, tokenID string) (string, error) {
+	result, err := Call(ctx, client, collectionAddr, tokenURISelector, Uint256Arg(tokenID))
+	if err != nil {
+		return "", err
+	}
+	return decodeString(result)
+}
+
+// URI returns the metadata uri of an ERC-1155 token, with the {id}
+// placeholder replaced by the token id.
+func URI(ctx context.Context, client chainclient.ChainClient, collectionAddr, tokenID string) (string, error) {
+	result, err := Call(ctx, client, collectionAddr, uriSelector, Uint256Arg(tokenID))
+	if err != nil {
+		return "", err
+	}
+
+	uri, err := decodeString(result)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(uri, "{id}", hex.EncodeToString(common.LeftPadBytes(Uint256Arg(tokenID), 32))), nil
+}
+
+// decodeString decodes an abi encoded string return value.
+func decodeString(result []byte) (string, error) {
+	offset := new(big.Int).SetBytes(result[:32])
+	if !offset.IsInt64() || offset.Int64()+32 > int64(len(result)) {
+		return "", errors.New("invalid string offset")
+	}
+
+	start := offset.Int64() + 32
+	length := new(big.Int).SetBytes(result[offset.Int64():start])
+	if !length.IsInt64() || start+length.Int64() > int64(len(result)) {
+		return "", errors.New("invalid string length")
+	}
+	return string(result[start : start+length.Int64()]), nil
+}
+
+func Allowance(ctx context.Context, client chainclient.ChainClient, tokenAddr, owner, spender string) (*big.Int, error) {
+	result, err := Call(ctx, client, tokenAddr, allowanceSelector, AddressArg(owner), AddressArg(spender))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(result[:32]), nil
+}
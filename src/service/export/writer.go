@@ -0,0 +1,111 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "jsonl"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// IncompleteMessage is the message of the record ending an export that failed
+// after its first records were sent.
+const IncompleteMessage = "export failed, the records above are incomplete"
+
+// errorRecord ends a failed export. It is a JSON line of its own, and a row
+// starting with the ErrorColumn marker in CSV.
+type errorRecord struct {
+	Error string `json:"error"`
+}
+
+const ErrorColumn = "#error"
+
+// Record is a row of an export. It is encoded as is in JSON lines, and as
+// CSVRecord under the CSVHeader columns in CSV.
+type Record interface {
+	CSVHeader() []string
+	CSVRecord() []string
+}
+
+// Writer encodes records to out as they come, so an export never holds more
+// than a batch in memory.
+type Writer struct {
+	out    io.Writer
+	csv    *csv.Writer
+	json   *json.Encoder
+	header bool
+}
+
+func NewWriter(format string, out io.Writer) (*Writer, error) {
+	w := &Writer{out: out}
+	switch format {
+	case FormatCSV:
+		w.csv = csv.NewWriter(out)
+	case FormatJSON:
+		w.json = json.NewEncoder(out)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	return w, nil
+}
+
+func IsFormat(format string) bool {
+	return format == FormatCSV || format == FormatJSON
+}
+
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+func (w *Writer) Write(record Record) error {
+	if w.json != nil {
+		return w.json.Encode(record)
+	}
+
+	if !w.header {
+		if err := w.csv.Write(record.CSVHeader()); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	return w.csv.Write(record.CSVRecord())
+}
+
+// Flush sends the written records to the client.
+func (w *Writer) Flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+
+	if flusher, ok := w.out.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// WriteError ends the export with an error record and flushes it, so a client
+// can tell a failed export from a complete one.
+func (w *Writer) WriteError(message string) error {
+	if w.json != nil {
+		if err := w.json.Encode(errorRecord{Error: message}); err != nil {
+			return err
+		}
+	} else if err := w.csv.Write([]string{ErrorColumn, message}); err != nil {
+		return err
+	}
+	return w.Flush()
+}
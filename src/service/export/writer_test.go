@@ -0,0 +1,35 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+)
+
+type testRecord struct {
+	Name string `json:"name"`
+}
+
+func (r testRecord) CSVHeader() []string { return []string{"name"} }
+func (r testRecord) CSVRecord() []string { return []string{r.Name} }
+
+func TestWriteError(t *testing.T) {
+	for format, want := range map[string]string{
+		FormatCSV:  "name\na\n#error,failed\n",
+		FormatJSON: "{\"name\":\"a\"}\n{\"error\":\"failed\"}\n",
+	} {
+		var out bytes.Buffer
+		w, err := NewWriter(format, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(testRecord{Name: "a"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteError("failed"); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Fatalf("%s export ended with %q, want %q", format, out.String(), want)
+		}
+	}
+}
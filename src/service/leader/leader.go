@@ -0,0 +1,145 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const DefaultLockSeconds = 30
+
+// releaseScript deletes the lock only while it is still held by the instance,
+// a lock taken over after it expired is left to its new owner.
+const releaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+// Locker is a lock held by at most one instance at a time.
+type Locker interface {
+	// Lead acquires the lock, or renews it when the instance holds it
+	// already, and reports whether the instance holds it.
+	Lead() (bool, error)
+	Release() error
+}
+
+// Lock is a Locker backed by a redis key holding the id of its owner. It
+// expires unless renewed, so a crashed owner loses it after seconds.
+type Lock struct {
+	store    *xkv.Store
+	key      string
+	instance string
+	seconds  int
+}
+
+func NewLock(store *xkv.Store, key string, seconds int) *Lock {
+	if seconds <= 0 {
+		seconds = DefaultLockSeconds
+	}
+
+	return &Lock{
+		store:    store,
+		key:      key,
+		instance: uuid.NewString(),
+		seconds:  seconds,
+	}
+}
+
+func (l *Lock) Lead() (bool, error) {
+	ok, err := l.store.SetnxEx(l.key, l.instance, l.seconds)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return true, nil
+	}
+
+	owner, err := l.store.Get(l.key)
+	if err != nil {
+		return false, err
+	}
+	if owner != l.instance {
+		return false, nil
+	}
+
+	return true, l.store.Expire(l.key, l.seconds)
+}
+
+func (l *Lock) Release() error {
+	_, err := l.store.Eval(releaseScript, l.key, l.instance)
+	return err
+}
+
+// RenewInterval returns how often a lock held for seconds is renewed, often
+// enough to survive a failed renewal.
+func RenewInterval(seconds int) time.Duration {
+	if seconds <= 0 {
+		seconds = DefaultLockSeconds
+	}
+	return time.Duration(seconds) * time.Second / 3
+}
+
+// Run runs work while the instance holds lock, and stands by for it
+// otherwise. The context of work is cancelled as soon as a renewal fails, and
+// Run waits for work to return before it competes for the lock again or
+// returns itself once ctx is done.
+func Run(ctx context.Context, lock Locker, renew time.Duration, work func(ctx context.Context)) {
+	ticker := time.NewTicker(renew)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		ok, err := lock.Lead()
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on acquire leader lock", zap.Error(err))
+		}
+		if ok {
+			lead(ctx, lock, ticker, work)
+			if err := lock.Release(); err != nil {
+				xzap.WithContext(ctx).Error("failed on release leader lock", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead runs work until ctx is done or the lock can not be renewed.
+func lead(ctx context.Context, lock Locker, ticker *time.Ticker, work func(ctx context.Context)) {
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		work(workCtx)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-done
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			ok, err := lock.Lead()
+			if err != nil {
+				xzap.WithContext(ctx).Error("failed on renew leader lock", zap.Error(err))
+			}
+			if !ok {
+				cancel()
+				<-done
+				return
+			}
+		}
+	}
+}
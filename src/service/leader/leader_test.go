@@ -0,0 +1,108 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeLock struct {
+	mu       sync.Mutex
+	held     bool
+	lost     bool
+	released int
+}
+
+func (l *fakeLock) Lead() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lost {
+		l.held = false
+		return false, nil
+	}
+	l.held = true
+	return true, nil
+}
+
+func (l *fakeLock) Release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.held = false
+	l.released++
+	return nil
+}
+
+func (l *fakeLock) lose() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lost = true
+}
+
+func TestRunStopsWorkWhenLockIsLost(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lock := &fakeLock{}
+	started := make(chan struct{}, 1)
+	stopped := make(chan struct{}, 1)
+	go Run(ctx, lock, 10*time.Millisecond, func(ctx context.Context) {
+		started <- struct{}{}
+		<-ctx.Done()
+		stopped <- struct{}{}
+	})
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("work was not started while leading")
+	}
+
+	lock.lose()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("work was not stopped after the lock was lost")
+	}
+
+	select {
+	case <-started:
+		t.Fatal("work was restarted without the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRunWaitsForWorkOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	lock := &fakeLock{}
+	started := make(chan struct{})
+	var finished bool
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		Run(ctx, lock, 10*time.Millisecond, func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+			time.Sleep(20 * time.Millisecond)
+			finished = true
+		})
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return on shutdown")
+	}
+	if !finished {
+		t.Fatal("Run returned before work")
+	}
+
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+	if lock.released == 0 || lock.held {
+		t.Fatal("lock was not released on shutdown")
+	}
+}
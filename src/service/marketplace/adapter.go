@@ -0,0 +1,86 @@
+package marketplace
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+)
+
+const TypeFile = "file"
+
+// Adapter reads the open orders of an external marketplace. Orders are
+// returned in the order table format; the marketplace id and status are set
+// by the ingester.
+type Adapter interface {
+	MarketplaceID() int
+	Orders(ctx context.Context, chain *config.ChainSupported) ([]multi.Order, error)
+}
+
+// Registry holds the adapters of the external marketplaces by marketplace id.
+type Registry struct {
+	adapters map[int]Adapter
+}
+
+func NewRegistry() *Registry {
+	return &Registry{adapters: make(map[int]Adapter)}
+}
+
+// New creates the registry of the configured adapters.
+func New(c *config.Marketplace) (*Registry, error) {
+	registry := NewRegistry()
+	if c == nil {
+		return registry, nil
+	}
+
+	for _, adapterConf := range c.Adapters {
+		var adapter Adapter
+		switch strings.ToLower(adapterConf.Type) {
+		case TypeFile:
+			adapter = NewFileAdapter(adapterConf.MarketplaceID, adapterConf.Path)
+		default:
+			return nil, errors.Errorf("unsupported marketplace adapter type: %s", adapterConf.Type)
+		}
+
+		if err := registry.Register(adapter); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+// Register adds an adapter. The orders of our own order book are synced from
+// chain and cannot be replaced by an adapter.
+func (r *Registry) Register(adapter Adapter) error {
+	id := adapter.MarketplaceID()
+	if id == multi.OrderBookDex {
+		return errors.Errorf("marketplace %d is the local order book", id)
+	}
+	if _, ok := r.adapters[id]; ok {
+		return errors.Errorf("marketplace %d already registered", id)
+	}
+
+	r.adapters[id] = adapter
+	return nil
+}
+
+func (r *Registry) Get(marketplaceID int) (Adapter, bool) {
+	adapter, ok := r.adapters[marketplaceID]
+	return adapter, ok
+}
+
+// Adapters returns the registered adapters ordered by marketplace id.
+func (r *Registry) Adapters() []Adapter {
+	var adapters []Adapter
+	for _, adapter := range r.adapters {
+		adapters = append(adapters, adapter)
+	}
+	sort.Slice(adapters, func(i, j int) bool {
+		return adapters[i].MarketplaceID() < adapters[j].MarketplaceID()
+	})
+	return adapters
+}
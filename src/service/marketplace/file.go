@@ -0,0 +1,45 @@
+package marketplace
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+)
+
+// FileAdapter reads the orders of a marketplace from a JSON file holding the
+// orders of every chain by chain name, e.g. {"sepolia": [{"order_id": ...}]}.
+// The file is read on every call so it can be edited between runs, which
+// makes it a stand-in for a real marketplace in tests and local setups.
+type FileAdapter struct {
+	marketplaceID int
+	path          string
+}
+
+func NewFileAdapter(marketplaceID int, path string) *FileAdapter {
+	return &FileAdapter{
+		marketplaceID: marketplaceID,
+		path:          path,
+	}
+}
+
+func (f *FileAdapter) MarketplaceID() int {
+	return f.marketplaceID
+}
+
+func (f *FileAdapter) Orders(ctx context.Context, chain *config.ChainSupported) ([]multi.Order, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on read marketplace orders file")
+	}
+
+	var chainOrders map[string][]multi.Order
+	if err := json.Unmarshal(data, &chainOrders); err != nil {
+		return nil, errors.Wrap(err, "failed on parse marketplace orders file")
+	}
+
+	return chainOrders[chain.Name], nil
+}
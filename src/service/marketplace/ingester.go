@@ -0,0 +1,206 @@
+package marketplace
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/ordermanager"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"go.uber.org/zap"
+)
+
+const DefaultInterval = 5 * time.Minute
+
+// Ingester copies the open orders of the registered marketplaces into the
+// order table. Every run upserts the orders returned by a marketplace,
+// expires its open orders that expired and cancels the ones that were not
+// returned anymore. It must run on one instance at a time.
+type Ingester struct {
+	dao      *dao.Dao
+	store    *xkv.Store
+	registry *Registry
+	chains   []*config.ChainSupported
+}
+
+func NewIngester(d *dao.Dao, store *xkv.Store, registry *Registry, chains []*config.ChainSupported) *Ingester {
+	return &Ingester{
+		dao:      d,
+		store:    store,
+		registry: registry,
+		chains:   chains,
+	}
+}
+
+func (in *Ingester) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, chain := range in.chains {
+				for _, adapter := range in.registry.Adapters() {
+					if err := in.Ingest(ctx, chain, adapter); err != nil {
+						xzap.WithContext(ctx).Error("failed on ingest marketplace orders", zap.String("chain", chain.Name),
+							zap.Int("marketplace_id", adapter.MarketplaceID()), zap.Error(err))
+					}
+				}
+			}
+		}
+	}
+}
+
+// Ingest syncs the orders of one marketplace on a chain. Nothing is swept
+// when the marketplace cannot be read, so an outage does not drop its orders.
+func (in *Ingester) Ingest(ctx context.Context, chain *config.ChainSupported, adapter Adapter) error {
+	upstream, err := adapter.Orders(ctx, chain)
+	if err != nil {
+		return err
+	}
+
+	open, err := in.dao.QueryOpenMarketplaceOrders(ctx, chain.Name, adapter.MarketplaceID())
+	if err != nil {
+		return err
+	}
+
+	sync := classifyOrders(upstream, open, time.Now().Unix())
+	if err := in.dao.SaveMarketplaceOrders(ctx, chain.Name, adapter.MarketplaceID(), sync.orders); err != nil {
+		return err
+	}
+	if err := in.dao.UpdateMarketplaceOrdersStatus(ctx, chain.Name, adapter.MarketplaceID(), sync.expired,
+		multi.OrderStatusExpired); err != nil {
+		return err
+	}
+	if err := in.dao.UpdateMarketplaceOrdersStatus(ctx, chain.Name, adapter.MarketplaceID(), sync.gone,
+		multi.OrderStatusCancelled); err != nil {
+		return err
+	}
+	if err := in.dao.DeleteOrderValidations(ctx, chain.Name, append(sync.expired, sync.gone...)); err != nil {
+		xzap.WithContext(ctx).Error("failed on delete order validations", zap.Error(err))
+	}
+
+	in.refreshCollections(ctx, chain.Name, sync.collections)
+	return nil
+}
+
+// orderSync is the outcome of comparing the orders of a marketplace with its
+// open orders in the order table.
+type orderSync struct {
+	// orders are the valid open orders of the marketplace to save.
+	orders []multi.Order
+	// expired are the open orders that expired, either upstream or by their
+	// stored expire time when the marketplace dropped them.
+	expired []string
+	// gone are the open orders the marketplace dropped before they expired.
+	gone []string
+	// collections are the collections whose listings changed.
+	collections map[string]bool
+}
+
+func classifyOrders(upstream, open []multi.Order, now int64) orderSync {
+	sync := orderSync{collections: make(map[string]bool)}
+	seen := make(map[string]bool)
+	expired := make(map[string]bool)
+	for _, order := range upstream {
+		if !normalizeOrder(&order, now) || seen[order.OrderID] {
+			continue
+		}
+		seen[order.OrderID] = true
+		if order.ExpireTime <= now {
+			expired[order.OrderID] = true
+			continue
+		}
+		sync.orders = append(sync.orders, order)
+		if order.OrderType == multi.ListingOrder {
+			sync.collections[order.CollectionAddress] = true
+		}
+	}
+
+	for _, order := range open {
+		switch {
+		case expired[order.OrderID] || (!seen[order.OrderID] && order.ExpireTime <= now):
+			sync.expired = append(sync.expired, order.OrderID)
+		case !seen[order.OrderID]:
+			sync.gone = append(sync.gone, order.OrderID)
+		default:
+			continue
+		}
+		if order.OrderType == multi.ListingOrder {
+			sync.collections[strings.ToLower(order.CollectionAddress)] = true
+		}
+	}
+
+	return sync
+}
+
+// normalizeOrder lowercases the addresses of an upstream order and reports
+// whether it is a well formed order. Expired orders are well formed, they are
+// told apart by the caller.
+func normalizeOrder(order *multi.Order, now int64) bool {
+	order.Id = 0
+	order.CollectionAddress = strings.ToLower(order.CollectionAddress)
+	order.Maker = strings.ToLower(order.Maker)
+	order.Taker = strings.ToLower(order.Taker)
+	order.CurrencyAddress = strings.ToLower(order.CurrencyAddress)
+	order.OrderStatus = multi.OrderStatusActive
+	if order.Size <= 0 {
+		order.Size = 1
+	}
+	if order.QuantityRemaining <= 0 || order.QuantityRemaining > order.Size {
+		order.QuantityRemaining = order.Size
+	}
+	if order.EventTime == 0 {
+		order.EventTime = now
+	}
+
+	if order.OrderID == "" || order.CollectionAddress == "" || order.Maker == "" {
+		return false
+	}
+	if !order.Price.IsPositive() || order.ExpireTime <= 0 {
+		return false
+	}
+
+	switch order.OrderType {
+	case multi.ListingOrder, multi.ItemBidOrder:
+		return order.TokenId != ""
+	case multi.CollectionBidOrder:
+		return true
+	default:
+		return false
+	}
+}
+
+func (in *Ingester) refreshCollections(ctx context.Context, chain string, collections map[string]bool) {
+	for collectionAddr := range collections {
+		listed, err := in.dao.QueryListedAmount(ctx, chain, collectionAddr)
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on get listed count", zap.Error(err))
+		} else if err := in.dao.CacheCollectionsListed(ctx, chain, collectionAddr, int(listed)); err != nil {
+			xzap.WithContext(ctx).Error("failed on cache collection listed", zap.Error(err))
+		}
+
+		floorPrice, err := in.dao.QueryFloorPrice(ctx, chain, collectionAddr)
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on get floor price", zap.Error(err))
+			continue
+		}
+
+		if err := ordermanager.AddUpdatePriceEvent(in.store, &ordermanager.TradeEvent{
+			EventType:      ordermanager.UpdateCollection,
+			CollectionAddr: collectionAddr,
+			Price:          floorPrice,
+		}, chain); err != nil {
+			xzap.WithContext(ctx).Error("failed on update floor price", zap.Error(err))
+		}
+	}
+}
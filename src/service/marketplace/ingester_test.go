@@ -0,0 +1,143 @@
+package marketplace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/shopspring/decimal"
+)
+
+const testMarketplace = 7
+
+func writeOrders(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileAdapterOrders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	writeOrders(t, path, `{
+		"sepolia": [{"order_id": "0x01", "collection_address": "0xAA", "token_id": "1", "price": "1.5"}],
+		"mainnet": [{"order_id": "0x02"}]
+	}`)
+
+	adapter := NewFileAdapter(testMarketplace, path)
+	sepolia := &config.ChainSupported{Name: "sepolia"}
+	orders, err := adapter.Orders(context.Background(), sepolia)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].OrderID != "0x01" || !orders[0].Price.Equal(decimal.RequireFromString("1.5")) {
+		t.Fatalf("unexpected sepolia orders: %+v", orders)
+	}
+
+	orders, err = adapter.Orders(context.Background(), &config.ChainSupported{Name: "base"})
+	if err != nil || len(orders) != 0 {
+		t.Fatalf("expected no orders of an unlisted chain, got %+v, %v", orders, err)
+	}
+
+	// the file is read again on every call
+	writeOrders(t, path, `{"sepolia": []}`)
+	orders, err = adapter.Orders(context.Background(), sepolia)
+	if err != nil || len(orders) != 0 {
+		t.Fatalf("expected the edited file to be read, got %+v, %v", orders, err)
+	}
+
+	writeOrders(t, path, `not json`)
+	if _, err := adapter.Orders(context.Background(), sepolia); err == nil {
+		t.Fatal("expected an error on a malformed file")
+	}
+	if _, err := NewFileAdapter(testMarketplace, filepath.Join(t.TempDir(), "missing.json")).
+		Orders(context.Background(), sepolia); err == nil {
+		t.Fatal("expected an error on a missing file")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry, err := New(&config.Marketplace{Adapters: []*config.MarketplaceAdapter{
+		{MarketplaceID: 9, Type: "FILE", Path: "b.json"},
+		{MarketplaceID: testMarketplace, Type: TypeFile, Path: "a.json"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adapters := registry.Adapters()
+	if len(adapters) != 2 || adapters[0].MarketplaceID() != testMarketplace || adapters[1].MarketplaceID() != 9 {
+		t.Fatalf("adapters not ordered by marketplace id: %+v", adapters)
+	}
+
+	if err := registry.Register(NewFileAdapter(testMarketplace, "c.json")); err == nil {
+		t.Fatal("expected a second adapter of a marketplace to be rejected")
+	}
+	if err := registry.Register(NewFileAdapter(multi.OrderBookDex, "d.json")); err == nil {
+		t.Fatal("expected the local order book to be rejected")
+	}
+	if _, err := New(&config.Marketplace{Adapters: []*config.MarketplaceAdapter{{Type: "http"}}}); err == nil {
+		t.Fatal("expected an unsupported adapter type to be rejected")
+	}
+}
+
+func TestClassifyOrders(t *testing.T) {
+	const now = int64(1_000)
+	listing := func(orderID string, expireTime int64) multi.Order {
+		return multi.Order{
+			OrderID:           orderID,
+			CollectionAddress: "0xAA",
+			TokenId:           "1",
+			Maker:             "0xBB",
+			Price:             decimal.NewFromInt(1),
+			ExpireTime:        expireTime,
+			OrderType:         multi.ListingOrder,
+		}
+	}
+
+	upstream := []multi.Order{
+		listing("live", now+60),
+		listing("live", now+60),
+		listing("new", now+60),
+		listing("expired-upstream", now-1),
+		{OrderID: "malformed", ExpireTime: now + 60, OrderType: multi.ListingOrder},
+	}
+	open := []multi.Order{
+		listing("live", now+60),
+		listing("expired-upstream", now+60),
+		listing("expired-dropped", now-1),
+		listing("cancelled", now+60),
+		listing("malformed", now+60),
+	}
+
+	sync := classifyOrders(upstream, open, now)
+
+	var saved []string
+	for _, order := range sync.orders {
+		saved = append(saved, order.OrderID)
+		if order.CollectionAddress != "0xaa" || order.Maker != "0xbb" || order.Id != 0 {
+			t.Fatalf("order not normalized: %+v", order)
+		}
+	}
+	sort.Strings(saved)
+	if len(saved) != 2 || saved[0] != "live" || saved[1] != "new" {
+		t.Fatalf("unexpected saved orders: %v", saved)
+	}
+
+	sort.Strings(sync.expired)
+	if len(sync.expired) != 2 || sync.expired[0] != "expired-dropped" || sync.expired[1] != "expired-upstream" {
+		t.Fatalf("unexpected expired orders: %v", sync.expired)
+	}
+
+	sort.Strings(sync.gone)
+	if len(sync.gone) != 2 || sync.gone[0] != "cancelled" || sync.gone[1] != "malformed" {
+		t.Fatalf("unexpected cancelled orders: %v", sync.gone)
+	}
+
+	if len(sync.collections) != 1 || !sync.collections["0xaa"] {
+		t.Fatalf("unexpected collections: %v", sync.collections)
+	}
+}
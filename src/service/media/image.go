@@ -0,0 +1,173 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+
+	_ "image/gif"
+	_ "image/jpeg"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/pkg/errors"
+)
+
+const (
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+// Encoder writes a thumbnail in an output format.
+type Encoder interface {
+	ContentType() string
+	Encode(w io.Writer, img image.Image) error
+}
+
+type pngEncoder struct{}
+
+func (pngEncoder) ContentType() string { return "image/png" }
+
+func (pngEncoder) Encode(w io.Writer, img image.Image) error {
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	return encoder.Encode(w, img)
+}
+
+// webpEncoder writes lossless webp, which is smaller than png for the flat
+// artwork of most collections.
+type webpEncoder struct{}
+
+func (webpEncoder) ContentType() string { return "image/webp" }
+
+func (webpEncoder) Encode(w io.Writer, img image.Image) error {
+	return nativewebp.Encode(w, img, nil)
+}
+
+// encoders are the thumbnail formats by name, more can be added with
+// RegisterEncoder.
+var encoders = map[string]Encoder{
+	FormatPNG:  pngEncoder{},
+	FormatWebP: webpEncoder{},
+}
+
+func RegisterEncoder(format string, encoder Encoder) {
+	encoders[strings.ToLower(format)] = encoder
+}
+
+func encoderOf(format string) (Encoder, bool) {
+	encoder, ok := encoders[strings.ToLower(format)]
+	return encoder, ok
+}
+
+// SniffType returns the media type of content, telling svg documents apart
+// from other xml and text.
+func SniffType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if strings.HasPrefix(contentType, "text/xml") || strings.HasPrefix(contentType, "text/plain") {
+		head := data
+		if len(head) > 1024 {
+			head = head[:1024]
+		}
+		if bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+			return "image/svg+xml"
+		}
+	}
+	if idx := strings.Index(contentType, ";"); idx >= 0 {
+		contentType = contentType[:idx]
+	}
+	return contentType
+}
+
+// IsRaster reports whether a media type can be decoded into thumbnails.
+func IsRaster(contentType string) bool {
+	switch contentType {
+	case "image/png", "image/jpeg", "image/gif":
+		return true
+	default:
+		return false
+	}
+}
+
+func IsVideo(contentType string) bool {
+	return strings.HasPrefix(contentType, "video/")
+}
+
+// videoExtensions are the file extensions of the video types sniffed by
+// SniffType.
+var videoExtensions = map[string]string{
+	"video/mp4":  "mp4",
+	"video/webm": "webm",
+	"video/avi":  "avi",
+}
+
+// VideoExtension returns the file extension of a video type, "" when it is
+// not known.
+func VideoExtension(contentType string) string {
+	return videoExtensions[contentType]
+}
+
+// Thumbnail scales img down to fit in a size x size square and encodes it.
+// Smaller images are kept at their size.
+func Thumbnail(img image.Image, size int, format string) ([]byte, string, error) {
+	encoder, ok := encoderOf(format)
+	if !ok {
+		return nil, "", errors.New(fmt.Sprintf("unsupported thumbnail format %s", format))
+	}
+
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, resize(img, size)); err != nil {
+		return nil, "", errors.Wrap(err, "failed on encode thumbnail")
+	}
+	return buf.Bytes(), encoder.ContentType(), nil
+}
+
+// resize scales img to fit in a size x size square by averaging the source
+// pixels covered by each target pixel.
+func resize(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= size && srcH <= size {
+		return img
+	}
+
+	dstW, dstH := size, size
+	if srcW > srcH {
+		dstH = max(1, srcH*size/srcW)
+	} else {
+		dstW = max(1, srcW*size/srcH)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcW/dstW)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			// average premultiplied values, then un-premultiply
+			if a == 0 {
+				continue
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r * 0xff / a),
+				G: uint8(g * 0xff / a),
+				B: uint8(b * 0xff / a),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,293 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/metadata"
+	"github.com/SimonHofman/EasySwapBackend/src/service/storage"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultInterval  = time.Minute
+	DefaultBatchSize = 50
+	// RetryAfter is how long a failed item waits before it is processed again.
+	RetryAfter = time.Hour
+	// MaxAttempts is the number of failed attempts after which an item is
+	// abandoned.
+	MaxAttempts = 5
+	// MaxPixels bounds the decoded size of an image, a small file can
+	// declare a huge canvas.
+	MaxPixels = 64 << 20
+)
+
+const CacheMediaAttemptsKey = "cache:es:media:%s:%d:attempts"
+
+var DefaultSizes = []int{128, 256, 512}
+
+// ThumbnailKey returns the object key of a thumbnail of an item image. The
+// key holds the hash of the image content, so a changed image gets new keys
+// and the objects can be served as immutable.
+func ThumbnailKey(chain, collectionAddr, tokenID, contentHash string, size int, format string) string {
+	return fmt.Sprintf("media/%s/%s/%s/%s-%d.%s", chain, strings.ToLower(collectionAddr), tokenID, contentHash, size, strings.ToLower(format))
+}
+
+// ThumbnailURL returns the url of the thumbnail of a size next to the
+// largest thumbnail at largestURL, "" when largestURL is not a thumbnail of
+// the largest size and format, as after a change of the configured sizes.
+func ThumbnailURL(largestURL string, largest, size int, format string) string {
+	suffix := fmt.Sprintf("-%d.%s", largest, strings.ToLower(format))
+	if !strings.HasSuffix(largestURL, suffix) {
+		return ""
+	}
+	return strings.TrimSuffix(largestURL, suffix) + fmt.Sprintf("-%d.%s", size, strings.ToLower(format))
+}
+
+// VideoKey returns the object key of the copy of an item animation, named by
+// the hash of its content like the thumbnails.
+func VideoKey(chain, collectionAddr, tokenID, contentHash, extension string) string {
+	return fmt.Sprintf("media/%s/%s/%s/%s.%s", chain, strings.ToLower(collectionAddr), tokenID, contentHash, extension)
+}
+
+// Pipeline downloads the images and animations of items, writes resized
+// thumbnails and a copy of the animation to the object storage and marks the
+// item external row as uploaded, so clients no longer load the originals
+// from ipfs gateways.
+type Pipeline struct {
+	dao       *dao.Dao
+	store     *xkv.Store
+	storage   storage.Storage
+	fetcher   *metadata.Fetcher
+	chains    []*config.ChainSupported
+	sizes     []int
+	format    string
+	batchSize int
+}
+
+func NewPipeline(d *dao.Dao, store *xkv.Store, s storage.Storage, fetcher *metadata.Fetcher, chains []*config.ChainSupported,
+	sizes []int, format string, batchSize int) (*Pipeline, error) {
+	if len(sizes) == 0 {
+		sizes = DefaultSizes
+	}
+	if format == "" {
+		format = FormatWebP
+	}
+	if _, ok := encoderOf(format); !ok {
+		return nil, errors.New(fmt.Sprintf("unsupported thumbnail format %s", format))
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &Pipeline{
+		dao:       d,
+		store:     store,
+		storage:   s,
+		fetcher:   fetcher,
+		chains:    chains,
+		sizes:     sizes,
+		format:    strings.ToLower(format),
+		batchSize: batchSize,
+	}, nil
+}
+
+// Format is the format thumbnails are written in.
+func (p *Pipeline) Format() string {
+	return p.format
+}
+
+// Sizes are the thumbnail sizes written for every image.
+func (p *Pipeline) Sizes() []int {
+	return p.sizes
+}
+
+// MaxSize is the size of the largest thumbnail, the one saved as the oss uri
+// of an item.
+func (p *Pipeline) MaxSize() int {
+	return maxSize(p.sizes)
+}
+
+func (p *Pipeline) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, chain := range p.chains {
+				if err := p.Process(ctx, chain.Name); err != nil {
+					xzap.WithContext(ctx).Error("failed on process item media", zap.String("chain", chain.Name), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// Process handles every pending item of a chain. The result of each item is
+// saved, failed items are retried after RetryAfter until they fail
+// MaxAttempts times.
+func (p *Pipeline) Process(ctx context.Context, chain string) error {
+	retryBefore := time.Now().Add(-RetryAfter).UnixMilli()
+	var cursor int64
+	for {
+		items, err := p.dao.QueryPendingMediaItems(ctx, chain, retryBefore, cursor, p.batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			media := p.processItem(ctx, chain, item)
+			media.UploadStatus = p.countAttempt(ctx, chain, item, media.UploadStatus)
+			if err := p.dao.SaveItemMedia(ctx, chain, item.Id, media); err != nil {
+				return err
+			}
+		}
+
+		if len(items) < p.batchSize {
+			return nil
+		}
+		cursor = items[len(items)-1].Id
+	}
+}
+
+// countAttempt counts a failed attempt of an item and returns the status to
+// save, MediaAbandoned once the item ran out of attempts. Attempts are kept
+// in redis, as the item external table has no column for them.
+func (p *Pipeline) countAttempt(ctx context.Context, chain string, item multi.ItemExternal, status int) int {
+	key := fmt.Sprintf(CacheMediaAttemptsKey, chain, item.Id)
+	if status != dao.MediaFailed {
+		if item.UploadStatus == dao.MediaFailed {
+			if _, err := p.store.Del(key); err != nil {
+				xzap.WithContext(ctx).Warn("failed on reset media attempts", zap.Error(err))
+			}
+		}
+		return status
+	}
+
+	attempts, err := p.store.Incr(key)
+	if err != nil {
+		xzap.WithContext(ctx).Warn("failed on count media attempts", zap.Error(err))
+		return status
+	}
+	_ = p.store.Expire(key, int(RetryAfter.Seconds())*MaxAttempts*2)
+	if attempts >= MaxAttempts {
+		return dao.MediaAbandoned
+	}
+	return status
+}
+
+func (p *Pipeline) processItem(ctx context.Context, chain string, item multi.ItemExternal) dao.ItemMedia {
+	var media dao.ItemMedia
+	status, ossURI, err := p.processImage(ctx, chain, item)
+	if err != nil {
+		xzap.WithContext(ctx).Warn("failed on process item image", zap.String("chain", chain),
+			zap.String("collection_addr", item.CollectionAddress), zap.String("token_id", item.TokenId), zap.Error(err))
+	}
+	media.UploadStatus = status
+	media.OssUri = ossURI
+
+	if item.VideoUri != "" {
+		videoURI, err := p.processVideo(ctx, chain, item)
+		if err != nil {
+			xzap.WithContext(ctx).Warn("failed on process item video", zap.String("chain", chain),
+				zap.String("collection_addr", item.CollectionAddress), zap.String("token_id", item.TokenId), zap.Error(err))
+		} else if videoURI != "" {
+			media.VideoOssUri = videoURI
+			media.IsVideoUploaded = true
+		}
+	}
+
+	return media
+}
+
+// processImage writes the thumbnails of an item image and returns the url of
+// the largest one. Images that can not be decoded, such as svg, are left to
+// be served from their original uri.
+func (p *Pipeline) processImage(ctx context.Context, chain string, item multi.ItemExternal) (int, string, error) {
+	data, _, err := p.fetcher.Fetch(ctx, item.ImageUri)
+	if err != nil {
+		return dao.MediaFailed, "", errors.Wrap(err, "failed on fetch image")
+	}
+
+	contentType := SniffType(data)
+	if !IsRaster(contentType) {
+		return dao.MediaUnsupported, "", nil
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return dao.MediaUnsupported, "", nil
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return dao.MediaUnsupported, "", nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return dao.MediaUnsupported, "", nil
+	}
+
+	contentHash := hash(data)
+	for _, size := range p.sizes {
+		thumbnail, thumbnailType, err := Thumbnail(img, size, p.format)
+		if err != nil {
+			return dao.MediaFailed, "", err
+		}
+
+		key := ThumbnailKey(chain, item.CollectionAddress, item.TokenId, contentHash, size, p.format)
+		if err := p.storage.Put(ctx, key, bytes.NewReader(thumbnail), thumbnailType); err != nil {
+			return dao.MediaFailed, "", errors.Wrap(err, "failed on put thumbnail")
+		}
+	}
+
+	// the largest thumbnail stands in for the original image
+	key := ThumbnailKey(chain, item.CollectionAddress, item.TokenId, contentHash, maxSize(p.sizes), p.format)
+	return dao.MediaUploaded, p.storage.URL(key), nil
+}
+
+// processVideo copies an item animation to the object storage. Animations
+// that are not videos of a known type, such as html pages, are skipped.
+func (p *Pipeline) processVideo(ctx context.Context, chain string, item multi.ItemExternal) (string, error) {
+	data, _, err := p.fetcher.Fetch(ctx, item.VideoUri)
+	if err != nil {
+		return "", errors.Wrap(err, "failed on fetch video")
+	}
+
+	contentType := SniffType(data)
+	extension := VideoExtension(contentType)
+	if !IsVideo(contentType) || extension == "" {
+		return "", nil
+	}
+
+	key := VideoKey(chain, item.CollectionAddress, item.TokenId, hash(data), extension)
+	if err := p.storage.Put(ctx, key, bytes.NewReader(data), contentType); err != nil {
+		return "", errors.Wrap(err, "failed on put video")
+	}
+	return p.storage.URL(key), nil
+}
+
+func maxSize(sizes []int) int {
+	var result int
+	for _, size := range sizes {
+		result = max(result, size)
+	}
+	return result
+}
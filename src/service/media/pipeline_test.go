@@ -0,0 +1,38 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestThumbnailURL(t *testing.T) {
+	largest := "/api/v1/objects/" + ThumbnailKey("eth", "0xABC", "1", "cafe", 512, FormatWebP)
+	if got := ThumbnailURL(largest, 512, 128, FormatWebP); got != "/api/v1/objects/media/eth/0xabc/1/cafe-128.webp" {
+		t.Fatalf("unexpected thumbnail url %s", got)
+	}
+	if got := ThumbnailURL(largest, 1024, 128, FormatWebP); got != "" {
+		t.Fatalf("url of another largest size resolved to %s", got)
+	}
+}
+
+func TestWebPThumbnail(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+
+	data, contentType, err := Thumbnail(img, 128, FormatWebP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "image/webp" {
+		t.Fatalf("unexpected content type %s", contentType)
+	}
+	if len(data) < 12 || !bytes.Equal(data[:4], []byte("RIFF")) || !bytes.Equal(data[8:12], []byte("WEBP")) {
+		t.Fatal("thumbnail is not a webp file")
+	}
+}
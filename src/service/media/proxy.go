@@ -0,0 +1,214 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/metadata"
+	"github.com/SimonHofman/EasySwapBackend/src/service/storage"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// ContentSecurityPolicy is sent with proxied media. Sandboxed documents
+	// run no scripts and load nothing but inline styles and data images.
+	ContentSecurityPolicy = "default-src 'none'; img-src data:; style-src 'unsafe-inline'; sandbox"
+
+	CacheMediaProxyKey       = "cache:es:media:proxy:%s"
+	CacheMediaObjectKey      = "cache:es:media:object:%s:%d"
+	CacheMediaProxyFailedKey = "cache:es:media:proxy:failed:%s"
+	MediaProxyCacheTTL       = 7 * 24 * 60 * 60
+	// MediaProxyFailedTTL is how long a uri that could not be fetched or
+	// made safe is answered from the cache before it is fetched again.
+	MediaProxyFailedTTL = 10 * 60
+)
+
+var (
+	ErrUnsupportedMedia = errors.New("unsupported media type")
+	ErrMediaUnavailable = errors.New("media is unavailable")
+)
+
+// Object is a proxied media object, ETag is the hash of its content.
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	ETag        string
+}
+
+type proxyEntry struct {
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+	Hash        string `json:"hash"`
+}
+
+// Proxy serves item media from a uri after making it safe to show: svg is
+// sanitized, html is left to the sandbox of ContentSecurityPolicy and any
+// other type than images and videos is refused. Results are stored by the
+// hash of the fetched content, so media reached through several uris or
+// gateways is made safe once, and uris are mapped to them. Failures are
+// cached for MediaProxyFailedTTL.
+type Proxy struct {
+	store   *xkv.Store
+	storage storage.Storage
+	fetcher *metadata.Fetcher
+}
+
+func NewProxy(store *xkv.Store, s storage.Storage, fetcher *metadata.Fetcher) *Proxy {
+	return &Proxy{
+		store:   store,
+		storage: s,
+		fetcher: fetcher,
+	}
+}
+
+// Get returns the safe media of a uri. A positive rasterize renders svg into
+// an image of that size when a rasterizer is registered, the sanitized svg
+// is served otherwise.
+func (p *Proxy) Get(ctx context.Context, uri string, rasterize int) (*Object, error) {
+	if uri == "" {
+		return nil, ErrUnsupportedMedia
+	}
+	if !CanRasterize() {
+		rasterize = 0
+	}
+
+	uriHash := hash([]byte(fmt.Sprintf("%s|%d", uri, rasterize)))
+	cacheKey := fmt.Sprintf(CacheMediaProxyKey, uriHash)
+	if object, _, ok := p.cached(ctx, cacheKey); ok {
+		return object, nil
+	}
+
+	failedKey := fmt.Sprintf(CacheMediaProxyFailedKey, uriHash)
+	if reason, err := p.store.Get(failedKey); err == nil && reason != "" {
+		if reason == ErrUnsupportedMedia.Error() {
+			return nil, ErrUnsupportedMedia
+		}
+		return nil, ErrMediaUnavailable
+	}
+
+	object, value, err := p.load(ctx, uri, rasterize)
+	if err != nil {
+		reason := ErrMediaUnavailable.Error()
+		if errors.Is(err, ErrUnsupportedMedia) || errors.Is(err, ErrInvalidSVG) {
+			reason = ErrUnsupportedMedia.Error()
+		}
+		if err := p.store.Setex(failedKey, reason, MediaProxyFailedTTL); err != nil {
+			xzap.WithContext(ctx).Error("failed on cache media failure", zap.Error(err))
+		}
+		if reason == ErrUnsupportedMedia.Error() {
+			return nil, err
+		}
+		return nil, errors.WithMessage(ErrMediaUnavailable, err.Error())
+	}
+
+	if err := p.store.Setex(cacheKey, value, MediaProxyCacheTTL); err != nil {
+		xzap.WithContext(ctx).Error("failed on cache media entry", zap.Error(err))
+	}
+	return object, nil
+}
+
+// load fetches the media of a uri and returns its safe object, made from the
+// stored one when the same content was proxied before, with its cache entry.
+func (p *Proxy) load(ctx context.Context, uri string, rasterize int) (*Object, string, error) {
+	source, _, err := p.fetcher.Fetch(ctx, uri)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed on fetch media")
+	}
+
+	sourceHash := hash(source)
+	objectKey := fmt.Sprintf(CacheMediaObjectKey, sourceHash, rasterize)
+	if object, value, ok := p.cached(ctx, objectKey); ok {
+		return object, value, nil
+	}
+
+	data, contentType, err := safeMedia(source, rasterize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entry := proxyEntry{
+		Key:         fmt.Sprintf("proxy/%s/%d", sourceHash, rasterize),
+		ContentType: contentType,
+		Hash:        hash(data),
+	}
+	if err := p.storage.Put(ctx, entry.Key, bytes.NewReader(data), contentType); err != nil {
+		return nil, "", errors.Wrap(err, "failed on put media")
+	}
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed on marshal media entry")
+	}
+	if err := p.store.Setex(objectKey, string(value), MediaProxyCacheTTL); err != nil {
+		xzap.WithContext(ctx).Error("failed on cache media object", zap.Error(err))
+	}
+
+	return &Object{
+		Body:        io.NopCloser(bytes.NewReader(data)),
+		ContentType: contentType,
+		ETag:        entry.Hash,
+	}, string(value), nil
+}
+
+// cached returns the stored object of a cache entry with the entry.
+func (p *Proxy) cached(ctx context.Context, cacheKey string) (*Object, string, bool) {
+	value, err := p.store.Get(cacheKey)
+	if err != nil || value == "" {
+		return nil, "", false
+	}
+
+	var entry proxyEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, "", false
+	}
+
+	body, _, err := p.storage.Get(ctx, entry.Key)
+	if err != nil {
+		if err != storage.ErrNotFound {
+			xzap.WithContext(ctx).Error("failed on get cached media", zap.Error(err))
+		}
+		return nil, "", false
+	}
+
+	return &Object{
+		Body:        body,
+		ContentType: entry.ContentType,
+		ETag:        entry.Hash,
+	}, value, true
+}
+
+// safeMedia returns the content to serve for fetched media and its type,
+// sniffed rather than trusted from the source.
+func safeMedia(data []byte, rasterize int) ([]byte, string, error) {
+	contentType := SniffType(data)
+	switch {
+	case contentType == "image/svg+xml":
+		sanitized, err := SanitizeSVG(data)
+		if err != nil {
+			return nil, "", err
+		}
+		if rasterize > 0 {
+			return RasterizeSVG(sanitized, rasterize, FormatPNG)
+		}
+		return sanitized, contentType, nil
+	case contentType == "text/html":
+		return data, "text/html; charset=utf-8", nil
+	case IsRaster(contentType), contentType == "image/webp", IsVideo(contentType):
+		return data, contentType, nil
+	default:
+		return nil, "", ErrUnsupportedMedia
+	}
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
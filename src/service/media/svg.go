@@ -0,0 +1,219 @@
+package media
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidSVG = errors.New("invalid svg document")
+
+// unsafeElements are dropped from svg documents together with their content.
+var unsafeElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"object":        true,
+	"embed":         true,
+	"audio":         true,
+	"video":         true,
+	"handler":       true,
+	"listener":      true,
+}
+
+// SanitizeSVG rewrites an svg document without scripts, event handlers and
+// references to anything outside the document, so it can be shown without
+// running code or leaking requests. Comments, processing instructions and
+// doctypes are dropped as well.
+func SanitizeSVG(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Entity = xml.HTMLEntity
+
+	var buf bytes.Buffer
+	var stack []xml.Name
+	var skipDepth int
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithMessage(ErrInvalidSVG, err.Error())
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name)
+			if skipDepth > 0 {
+				continue
+			}
+			local := strings.ToLower(t.Name.Local)
+			if len(stack) == 1 && (local != "svg" || buf.Len() > 0) {
+				return nil, ErrInvalidSVG
+			}
+			if unsafeElements[local] || !safeAnimation(t) {
+				skipDepth = len(stack)
+				continue
+			}
+			writeStart(&buf, t)
+		case xml.EndElement:
+			// RawToken leaves matching the elements to the caller
+			if len(stack) == 0 || stack[len(stack)-1] != t.Name {
+				return nil, ErrInvalidSVG
+			}
+			stack = stack[:len(stack)-1]
+			if skipDepth > 0 {
+				if len(stack) < skipDepth {
+					skipDepth = 0
+				}
+				continue
+			}
+			buf.WriteString("</" + qualifiedName(t.Name) + ">")
+		case xml.CharData:
+			if skipDepth > 0 || len(stack) == 0 {
+				continue
+			}
+			if strings.EqualFold(stack[len(stack)-1].Local, "style") && !safeCSS(string(t)) {
+				continue
+			}
+			_ = xml.EscapeText(&buf, t)
+		}
+	}
+
+	if buf.Len() == 0 || len(stack) > 0 {
+		return nil, ErrInvalidSVG
+	}
+	return buf.Bytes(), nil
+}
+
+// safeAnimation rejects animations that rewrite a link, they can point it
+// at a script after the document was sanitized.
+func safeAnimation(t xml.StartElement) bool {
+	switch strings.ToLower(t.Name.Local) {
+	case "set", "animate":
+	default:
+		return true
+	}
+	for _, attr := range t.Attr {
+		if strings.EqualFold(attr.Name.Local, "attributeName") &&
+			strings.HasSuffix(strings.ToLower(attr.Value), "href") {
+			return false
+		}
+	}
+	return true
+}
+
+func writeStart(buf *bytes.Buffer, t xml.StartElement) {
+	buf.WriteString("<" + qualifiedName(t.Name))
+	for _, attr := range t.Attr {
+		if !safeAttr(attr) {
+			continue
+		}
+		buf.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		_ = xml.EscapeText(buf, []byte(attr.Value))
+		buf.WriteString(`"`)
+	}
+	buf.WriteString(">")
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func safeAttr(attr xml.Attr) bool {
+	local := strings.ToLower(attr.Name.Local)
+	value := strings.ToLower(strings.TrimSpace(attr.Value))
+
+	// namespace declarations are names, not references
+	if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && local == "xmlns") {
+		return true
+	}
+	if strings.HasPrefix(local, "on") {
+		return false
+	}
+	if strings.Contains(value, "javascript:") || strings.Contains(value, "vbscript:") {
+		return false
+	}
+
+	switch local {
+	case "href", "src":
+		return safeReference(value)
+	case "style":
+		return safeCSS(value)
+	}
+	return !strings.Contains(value, "url(") || safeCSS(value)
+}
+
+// safeReference allows links to fragments of the document and embedded
+// raster images only.
+func safeReference(value string) bool {
+	if strings.HasPrefix(value, "#") {
+		return true
+	}
+	for _, prefix := range []string{"data:image/png", "data:image/jpeg", "data:image/gif", "data:image/webp"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// safeCSS rejects styles that import, run or fetch anything. Escapes are
+// rejected as they can spell any of these.
+func safeCSS(css string) bool {
+	css = strings.ToLower(css)
+	for _, unsafe := range []string{"\\", "@import", "expression(", "behavior:", "-moz-binding", "javascript:"} {
+		if strings.Contains(css, unsafe) {
+			return false
+		}
+	}
+
+	for rest := css; ; {
+		idx := strings.Index(rest, "url(")
+		if idx < 0 {
+			return true
+		}
+		rest = rest[idx+len("url("):]
+		ref := strings.TrimLeft(rest, " \t\r\n'\"")
+		if !safeReference(ref) {
+			return false
+		}
+	}
+}
+
+// Rasterizer renders an svg document into an image of at most size x size.
+type Rasterizer func(svg []byte, size int) (image.Image, error)
+
+// rasterizer is not set by default, the standard library can not render
+// svg. One can be installed with RegisterRasterizer.
+var rasterizer Rasterizer
+
+func RegisterRasterizer(r Rasterizer) {
+	rasterizer = r
+}
+
+// CanRasterize reports whether svg documents can be rendered to images.
+func CanRasterize() bool {
+	return rasterizer != nil
+}
+
+// RasterizeSVG renders a sanitized svg document and encodes it as a
+// thumbnail.
+func RasterizeSVG(svg []byte, size int, format string) ([]byte, string, error) {
+	if rasterizer == nil {
+		return nil, "", errors.New("no svg rasterizer registered")
+	}
+
+	img, err := rasterizer(svg, size)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed on rasterize svg")
+	}
+	return Thumbnail(img, size, format)
+}
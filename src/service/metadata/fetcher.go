@@ -0,0 +1,204 @@
+package metadata
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/netguard"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultTimeout = 15 * time.Second
+	DefaultMaxSize = 10 << 20
+)
+
+var (
+	DefaultIPFSGateways    = []string{"https://ipfs.io/ipfs/", "https://cloudflare-ipfs.com/ipfs/", "https://dweb.link/ipfs/"}
+	DefaultArweaveGateways = []string{"https://arweave.net/"}
+)
+
+var (
+	ErrTooLarge        = errors.New("content exceeds the size limit")
+	ErrUnsupportedURI  = errors.New("unsupported uri scheme")
+	ErrAllGatewaysFail = errors.New("failed on every gateway")
+)
+
+// Fetcher downloads token metadata and media. ipfs:// and ar:// uris, and
+// http uris of an ipfs gateway, are tried on every configured gateway in
+// turn; data: uris are decoded in place.
+type Fetcher struct {
+	client          *http.Client
+	maxSize         int64
+	ipfsGateways    []string
+	arweaveGateways []string
+}
+
+func NewFetcher(timeout time.Duration, maxSize int64, ipfsGateways, arweaveGateways []string, allowPrivate bool) *Fetcher {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if len(ipfsGateways) == 0 {
+		ipfsGateways = DefaultIPFSGateways
+	}
+	if len(arweaveGateways) == 0 {
+		arweaveGateways = DefaultArweaveGateways
+	}
+
+	return &Fetcher{
+		client:          netguard.NewClient(timeout, allowPrivate),
+		maxSize:         maxSize,
+		ipfsGateways:    withSlash(ipfsGateways),
+		arweaveGateways: withSlash(arweaveGateways),
+	}
+}
+
+func withSlash(gateways []string) []string {
+	var result []string
+	for _, gateway := range gateways {
+		if !strings.HasSuffix(gateway, "/") {
+			gateway += "/"
+		}
+		result = append(result, gateway)
+	}
+	return result
+}
+
+// Fetch returns the content of a uri and its content type.
+func (f *Fetcher) Fetch(ctx context.Context, uri string) ([]byte, string, error) {
+	uri = strings.TrimSpace(uri)
+	if strings.HasPrefix(uri, "data:") {
+		return DecodeDataURI(uri, f.maxSize)
+	}
+
+	candidates, err := f.Candidates(uri)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var lastErr error
+	for _, candidate := range candidates {
+		data, contentType, err := f.get(ctx, candidate)
+		if err == nil {
+			return data, contentType, nil
+		}
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		lastErr = err
+	}
+	return nil, "", errors.Wrap(lastErr, ErrAllGatewaysFail.Error())
+}
+
+// Candidates returns the http urls a uri can be downloaded from, in order.
+func (f *Fetcher) Candidates(uri string) ([]string, error) {
+	switch {
+	case strings.HasPrefix(uri, "ipfs://"):
+		path := strings.TrimPrefix(strings.TrimPrefix(uri, "ipfs://"), "ipfs/")
+		return gatewayURLs(f.ipfsGateways, path), nil
+	case strings.HasPrefix(uri, "ar://"):
+		return gatewayURLs(f.arweaveGateways, strings.TrimPrefix(uri, "ar://")), nil
+	case strings.HasPrefix(uri, "http://"), strings.HasPrefix(uri, "https://"):
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid uri")
+		}
+		// a gateway of the uri may be down or rate limited, try ours as well
+		if idx := strings.Index(u.Path, "/ipfs/"); idx >= 0 {
+			path := u.Path[idx+len("/ipfs/"):]
+			if u.RawQuery != "" {
+				path += "?" + u.RawQuery
+			}
+			return append([]string{uri}, gatewayURLs(f.ipfsGateways, path)...), nil
+		}
+		return []string{uri}, nil
+	default:
+		return nil, ErrUnsupportedURI
+	}
+}
+
+func gatewayURLs(gateways []string, path string) []string {
+	var urls []string
+	for _, gateway := range gateways {
+		urls = append(urls, gateway+path)
+	}
+	return urls
+}
+
+func (f *Fetcher) get(ctx context.Context, rawURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed on create request")
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed on request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", errors.New(fmt.Sprintf("unexpected status %d", resp.StatusCode))
+	}
+	if resp.ContentLength > f.maxSize {
+		return nil, "", ErrTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxSize+1))
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed on read response")
+	}
+	if int64(len(data)) > f.maxSize {
+		return nil, "", ErrTooLarge
+	}
+
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// DecodeDataURI decodes a data: uri, base64 or percent encoded.
+func DecodeDataURI(uri string, maxSize int64) ([]byte, string, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, "", errors.New("invalid data uri")
+	}
+
+	params := strings.Split(header, ";")
+	contentType := params[0]
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	var data []byte
+	if params[len(params)-1] == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			// some contracts leave out the padding
+			decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(payload, "="))
+			if err != nil {
+				return nil, "", errors.Wrap(err, "invalid base64 data uri")
+			}
+		}
+		data = decoded
+	} else {
+		decoded, err := url.PathUnescape(payload)
+		if err != nil {
+			// on-chain json is often left unescaped
+			decoded = payload
+		}
+		data = []byte(decoded)
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, "", ErrTooLarge
+	}
+	return data, contentType, nil
+}
@@ -0,0 +1,172 @@
+package metadata
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/pkg/errors"
+)
+
+var (
+	DefaultNameTags       = []string{"name", "title"}
+	DefaultImageTags      = []string{"image", "image_url", "imageUrl", "image_data"}
+	DefaultAttributesTags = []string{"attributes", "traits", "properties"}
+	DefaultTraitNameTags  = []string{"trait_type", "type", "key"}
+	DefaultTraitValueTags = []string{"value"}
+	AnimationTags         = []string{"animation_url", "animation"}
+)
+
+// Metadata is the part of a token metadata document kept in the item tables.
+type Metadata struct {
+	Name         string
+	Image        string
+	AnimationURL string
+	Traits       []Trait
+}
+
+type Trait struct {
+	Name  string
+	Value string
+}
+
+// Parser extracts metadata fields with the tag lists of config.MetadataParse,
+// taking the first tag present in the document.
+type Parser struct {
+	nameTags       []string
+	imageTags      []string
+	attributesTags []string
+	traitNameTags  []string
+	traitValueTags []string
+}
+
+func NewParser(c *config.MetadataParse) *Parser {
+	p := &Parser{
+		nameTags:       DefaultNameTags,
+		imageTags:      DefaultImageTags,
+		attributesTags: DefaultAttributesTags,
+		traitNameTags:  DefaultTraitNameTags,
+		traitValueTags: DefaultTraitValueTags,
+	}
+	if c == nil {
+		return p
+	}
+
+	if len(c.NameTags) > 0 {
+		p.nameTags = c.NameTags
+	}
+	if len(c.ImageTags) > 0 {
+		p.imageTags = c.ImageTags
+	}
+	if len(c.AttributesTags) > 0 {
+		p.attributesTags = c.AttributesTags
+	}
+	if len(c.TraitNameTags) > 0 {
+		p.traitNameTags = c.TraitNameTags
+	}
+	if len(c.TraitValueTags) > 0 {
+		p.traitValueTags = c.TraitValueTags
+	}
+	return p
+}
+
+func (p *Parser) Parse(data []byte) (*Metadata, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "invalid metadata json")
+	}
+
+	metadata := &Metadata{
+		Name:         firstString(doc, p.nameTags),
+		Image:        imageURI(firstString(doc, p.imageTags)),
+		AnimationURL: firstString(doc, AnimationTags),
+	}
+
+	for _, tag := range p.attributesTags {
+		if attributes, ok := doc[tag]; ok {
+			metadata.Traits = p.traits(attributes)
+			break
+		}
+	}
+
+	return metadata, nil
+}
+
+// traits reads a list of {trait_type, value} objects, or a name to value
+// object.
+func (p *Parser) traits(attributes interface{}) []Trait {
+	var traits []Trait
+	switch attrs := attributes.(type) {
+	case []interface{}:
+		for _, attr := range attrs {
+			obj, ok := attr.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name := firstString(obj, p.traitNameTags)
+			value := firstString(obj, p.traitValueTags)
+			if name != "" && value != "" {
+				traits = append(traits, Trait{Name: name, Value: value})
+			}
+		}
+	case map[string]interface{}:
+		for name, value := range attrs {
+			if str := stringValue(value); name != "" && str != "" {
+				traits = append(traits, Trait{Name: name, Value: str})
+			}
+		}
+		sort.Slice(traits, func(i, j int) bool {
+			return traits[i].Name < traits[j].Name
+		})
+	}
+	return traits
+}
+
+func firstString(obj map[string]interface{}, tags []string) string {
+	for _, tag := range tags {
+		if value, ok := obj[tag]; ok {
+			if str := stringValue(value); str != "" {
+				return str
+			}
+		}
+	}
+	return ""
+}
+
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64, bool, json.Number:
+		return fmt.Sprint(v)
+	default:
+		return ""
+	}
+}
+
+// imageURI turns raw svg markup, as kept in image_data, into a data uri.
+func imageURI(image string) string {
+	if strings.HasPrefix(image, "<svg") || strings.HasPrefix(image, "<?xml") {
+		return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(image))
+	}
+	return image
+}
+
+// VideoType returns the type of an animation url from its extension or data
+// uri media type.
+func VideoType(uri string) string {
+	if strings.HasPrefix(uri, "data:") {
+		mediaType, _, _ := strings.Cut(strings.TrimPrefix(uri, "data:"), ";")
+		mediaType, _, _ = strings.Cut(mediaType, ",")
+		return mediaType
+	}
+
+	if u, _, ok := strings.Cut(uri, "?"); ok {
+		uri = u
+	}
+	return strings.TrimPrefix(strings.ToLower(path.Ext(uri)), ".")
+}
@@ -0,0 +1,202 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/contract"
+	"github.com/SimonHofman/EasySwapBackend/src/service/mq"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/chain/nftchainservice"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultConcurrency = 4
+	ReadBatchSize      = 10
+	ReadBlock          = 5 * time.Second
+)
+
+// Worker consumes the metadata refresh queue: it reads the metadata uri of
+// each token from chain, downloads and parses the document and stores the
+// item, item external and trait rows.
+type Worker struct {
+	queue       *mq.RefreshQueue
+	store       *xkv.Store
+	dao         *dao.Dao
+	nodeSrvs    map[int64]*nftchainservice.Service
+	chains      []*config.ChainSupported
+	fetcher     *Fetcher
+	parser      *Parser
+	concurrency int
+	consumer    string
+}
+
+func NewWorker(queue *mq.RefreshQueue, store *xkv.Store, d *dao.Dao, nodeSrvs map[int64]*nftchainservice.Service,
+	chains []*config.ChainSupported, fetcher *Fetcher, parser *Parser, concurrency int) *Worker {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	consumer, err := os.Hostname()
+	if err != nil || consumer == "" {
+		consumer = uuid.NewString()
+	}
+
+	return &Worker{
+		queue:       queue,
+		store:       store,
+		dao:         d,
+		nodeSrvs:    nodeSrvs,
+		chains:      chains,
+		fetcher:     fetcher,
+		parser:      parser,
+		concurrency: concurrency,
+		consumer:    fmt.Sprintf("%s-%d", consumer, os.Getpid()),
+	}
+}
+
+// Run consumes the refresh queues of every chain until ctx is done, and
+// returns once the consumers have finished their messages in flight.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, chain := range w.chains {
+		for i := 0; i < w.concurrency; i++ {
+			wg.Add(1)
+			go func(chain *config.ChainSupported, consumer string) {
+				defer wg.Done()
+				w.consume(ctx, chain, consumer)
+			}(chain, fmt.Sprintf("%s-%d", w.consumer, i))
+		}
+	}
+	wg.Wait()
+}
+
+func (w *Worker) consume(ctx context.Context, chain *config.ChainSupported, consumer string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		msgs, err := w.queue.Read(ctx, chain.Name, consumer, ReadBatchSize, ReadBlock)
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on read refresh queue", zap.String("chain", chain.Name), zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ReadBlock):
+			}
+			continue
+		}
+
+		for _, msg := range msgs {
+			w.handle(ctx, chain, msg)
+		}
+	}
+}
+
+// handle refreshes a token and settles its message. The result is reported
+// to the refresh job of the token on success or on the last attempt only.
+func (w *Worker) handle(ctx context.Context, chain *config.ChainSupported, msg mq.RefreshMessage) {
+	err := w.Refresh(ctx, chain, msg.Item)
+	if err == nil {
+		if err := w.queue.Ack(ctx, chain.Name, msg); err != nil {
+			xzap.WithContext(ctx).Error("failed on ack refresh item", zap.Error(err))
+		}
+		if err := mq.ReportRefreshResult(w.store, msg.Item.JobID, msg.Item.TokenID, true); err != nil {
+			xzap.WithContext(ctx).Error("failed on report refresh result", zap.Error(err))
+		}
+		return
+	}
+
+	xzap.WithContext(ctx).Warn("failed on refresh item metadata", zap.String("chain", chain.Name),
+		zap.String("collection_addr", msg.Item.CollectionAddr), zap.String("token_id", msg.Item.TokenID),
+		zap.Int("attempt", msg.Attempt), zap.Error(err))
+	if err := w.queue.Fail(ctx, chain.Name, msg, err.Error()); err != nil {
+		xzap.WithContext(ctx).Error("failed on requeue refresh item", zap.Error(err))
+	}
+	if msg.Attempt >= w.queue.MaxAttempts() {
+		if err := mq.ReportRefreshResult(w.store, msg.Item.JobID, msg.Item.TokenID, false); err != nil {
+			xzap.WithContext(ctx).Error("failed on report refresh result", zap.Error(err))
+		}
+	}
+}
+
+// Refresh fetches the metadata of a token and stores it.
+func (w *Worker) Refresh(ctx context.Context, chain *config.ChainSupported, item types.RefreshItem) error {
+	nodeSrv, ok := w.nodeSrvs[int64(chain.ChainID)]
+	if !ok || nodeSrv == nil {
+		return errors.New("chain node not configured")
+	}
+
+	collection, err := w.dao.QueryCollectionInfo(ctx, chain.Name, item.CollectionAddr)
+	if err != nil {
+		return err
+	}
+
+	var uri string
+	if collection.TokenStandard == dao.TokenStandardERC1155 {
+		uri, err = contract.URI(ctx, nodeSrv.ChainClient, item.CollectionAddr, item.TokenID)
+	} else {
+		uri, err = contract.TokenURI(ctx, nodeSrv.ChainClient, item.CollectionAddr, item.TokenID)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed on read token uri")
+	}
+	if uri == "" {
+		return errors.New("empty token uri")
+	}
+
+	data, _, err := w.fetcher.Fetch(ctx, uri)
+	if err != nil {
+		return errors.Wrap(err, "failed on fetch metadata")
+	}
+
+	parsed, err := w.parser.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	metadata := &dao.ItemMetadata{
+		ChainID:           chain.ChainID,
+		CollectionAddress: item.CollectionAddr,
+		TokenId:           item.TokenID,
+		Name:              parsed.Name,
+		ImageUri:          parsed.Image,
+		VideoUri:          parsed.AnimationURL,
+	}
+	if collection.TokenStandard == dao.TokenStandardERC1155 {
+		supply, err := contract.TotalSupply1155(ctx, nodeSrv.ChainClient, item.CollectionAddr, item.TokenID)
+		if err == nil && supply.IsInt64() {
+			metadata.Supply = supply.Int64()
+		}
+	}
+	// on-chain metadata is kept in the document itself, not as its uri
+	if !strings.HasPrefix(uri, "data:") {
+		metadata.MetaDataUri = uri
+	}
+	if parsed.AnimationURL != "" {
+		metadata.VideoType = VideoType(parsed.AnimationURL)
+	}
+	for _, trait := range parsed.Traits {
+		metadata.Traits = append(metadata.Traits, multi.ItemTrait{
+			Trait:      trait.Name,
+			TraitValue: trait.Value,
+		})
+	}
+
+	return w.dao.SaveItemMetadata(ctx, chain.Name, metadata)
+}
@@ -22,6 +22,9 @@ func GetRefreshSingleItemMetadataKey(project, chain string) string {
 const CacheRefreshPreventReentrancyKeyPrefix = "cache:es:item:refresh:prevent:reentrancy:%d:%s:%s"
 const PreventReentrancyPeriod = 10
 
+// AddSingleItemToRefreshMetadataQueue pushes an item to the legacy refresh
+// set. The set is drained into the refresh stream by RefreshQueue consumers,
+// new producers should use RefreshQueue.AddItem.
 func AddSingleItemToRefreshMetadataQueue(kvStore *xkv.Store, project, chainName string, chainID int64, collectionAddr, tokenID string) error {
 	isRefresshed, err := kvStore.Get(fmt.Sprintf(CacheRefreshPreventReentrancyKeyPrefix, chainID, collectionAddr, tokenID))
 	if err != nil {
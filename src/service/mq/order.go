@@ -0,0 +1,78 @@
+package mq
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const CacheOrderExpiredEventKey = "cache:%s:%s:order:expired:event"
+
+func GetOrderExpiredEventKey(project, chain string) string {
+	return fmt.Sprintf(CacheOrderExpiredEventKey, strings.ToLower(project), strings.ToLower(chain))
+}
+
+type OrderExpiredEvent struct {
+	OrderID           string          `json:"order_id"`
+	OrderType         int64           `json:"order_type"`
+	CollectionAddress string          `json:"collection_address"`
+	TokenID           string          `json:"token_id"`
+	Maker             string          `json:"maker"`
+	Price             decimal.Decimal `json:"price"`
+	ExpireTime        int64           `json:"expire_time"`
+}
+
+func AddOrderExpiredEvents(kvStore *xkv.Store, project, chainName string, events []OrderExpiredEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var values []interface{}
+	for _, event := range events {
+		rawEvent, err := json.Marshal(&event)
+		if err != nil {
+			return errors.Wrap(err, "failed on marshal order expired event")
+		}
+		values = append(values, string(rawEvent))
+	}
+
+	if _, err := kvStore.Rpush(GetOrderExpiredEventKey(project, chainName), values...); err != nil {
+		return errors.Wrap(err, "failed on push order expired events")
+	}
+
+	return nil
+}
+
+// QueryOrderExpiredEvents returns up to count of the oldest order expired
+// events of a chain along with their raw values, which are passed to
+// RemoveOrderExpiredEvents once the events are handled.
+func QueryOrderExpiredEvents(kvStore *xkv.Store, project, chainName string, count int) ([]OrderExpiredEvent, []string, error) {
+	rawEvents, err := kvStore.Lrange(GetOrderExpiredEventKey(project, chainName), 0, count-1)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed on read order expired events")
+	}
+
+	var events []OrderExpiredEvent
+	for _, rawEvent := range rawEvents {
+		var event OrderExpiredEvent
+		// a malformed event is removed along with the others
+		if err := json.Unmarshal([]byte(rawEvent), &event); err == nil {
+			events = append(events, event)
+		}
+	}
+
+	return events, rawEvents, nil
+}
+
+func RemoveOrderExpiredEvents(kvStore *xkv.Store, project, chainName string, rawEvents []string) error {
+	for _, rawEvent := range rawEvents {
+		if _, err := kvStore.Lrem(GetOrderExpiredEventKey(project, chainName), 1, rawEvent); err != nil {
+			return errors.Wrap(err, "failed on remove order expired event")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	RefreshJobQueuing   = "queuing"
+	RefreshJobQueued    = "queued"
+	RefreshJobCompleted = "completed"
+	RefreshJobCancelled = "cancelled"
+	RefreshJobFailed    = "failed"
+)
+
+// RefreshJobTTL is how long the progress of a refresh job is kept, in seconds.
+const RefreshJobTTL = 7 * 24 * 3600
+
+// RefreshJobStaleSeconds is how long a queuing job may go without queuing a
+// batch before it is taken for orphaned by an instance that stopped.
+const RefreshJobStaleSeconds = 5 * 60
+
+const (
+	CacheRefreshJobKey       = "cache:es:refresh:job:%s"
+	CacheRefreshJobFailedKey = "cache:es:refresh:job:%s:failed"
+	// CacheRefreshJobsQueuingKey is the set of the jobs still queuing tokens.
+	CacheRefreshJobsQueuingKey = "cache:es:refresh:jobs:queuing"
+)
+
+func refreshJobKey(jobID string) string {
+	return fmt.Sprintf(CacheRefreshJobKey, jobID)
+}
+
+func refreshJobFailedKey(jobID string) string {
+	return fmt.Sprintf(CacheRefreshJobFailedKey, jobID)
+}
+
+func CreateRefreshJob(kvStore *xkv.Store, job *types.RefreshJob) error {
+	key := refreshJobKey(job.JobID)
+	if err := kvStore.Hmset(key, map[string]string{
+		"chain_id":        strconv.FormatInt(job.ChainID, 10),
+		"chain":           job.Chain,
+		"collection_addr": job.CollectionAddr,
+		"caller":          job.Caller,
+		"status":          job.Status,
+		"total":           strconv.FormatInt(job.Total, 10),
+		"queued":          "0",
+		"done":            "0",
+		"failed":          "0",
+		"create_time":     strconv.FormatInt(job.CreateTime, 10),
+		"update_time":     strconv.FormatInt(job.UpdateTime, 10),
+	}); err != nil {
+		return errors.Wrap(err, "failed on create refresh job")
+	}
+	if err := kvStore.Expire(key, RefreshJobTTL); err != nil {
+		return errors.Wrap(err, "failed on create refresh job")
+	}
+
+	if job.Status == RefreshJobQueuing {
+		if _, err := kvStore.Sadd(CacheRefreshJobsQueuingKey, job.JobID); err != nil {
+			return errors.Wrap(err, "failed on track refresh job")
+		}
+	}
+	return nil
+}
+
+// GetRefreshJob returns the progress of a refresh job, nil when it does not
+// exist or expired.
+func GetRefreshJob(kvStore *xkv.Store, jobID string) (*types.RefreshJob, error) {
+	fields, err := kvStore.Hgetall(refreshJobKey(jobID))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get refresh job")
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	parseInt := func(field string) int64 {
+		n, _ := strconv.ParseInt(fields[field], 10, 64)
+		return n
+	}
+	return &types.RefreshJob{
+		JobID:          jobID,
+		ChainID:        parseInt("chain_id"),
+		Chain:          fields["chain"],
+		CollectionAddr: fields["collection_addr"],
+		Caller:         fields["caller"],
+		Status:         fields["status"],
+		Total:          parseInt("total"),
+		Queued:         parseInt("queued"),
+		Done:           parseInt("done"),
+		Failed:         parseInt("failed"),
+		Error:          fields["error"],
+		CreateTime:     parseInt("create_time"),
+		UpdateTime:     parseInt("update_time"),
+	}, nil
+}
+
+func SetRefreshJobStatus(kvStore *xkv.Store, jobID, status, reason string) error {
+	fields := map[string]string{
+		"status":      status,
+		"update_time": strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	if reason != "" {
+		fields["error"] = reason
+	}
+
+	if err := kvStore.Hmset(refreshJobKey(jobID), fields); err != nil {
+		return errors.Wrap(err, "failed on update refresh job status")
+	}
+
+	if status != RefreshJobQueuing {
+		if _, err := kvStore.Srem(CacheRefreshJobsQueuingKey, jobID); err != nil {
+			return errors.Wrap(err, "failed on untrack refresh job")
+		}
+	}
+	return nil
+}
+
+// ExpireOrphanedRefreshJobs fails the queuing jobs that have not queued a
+// batch for RefreshJobStaleSeconds, their queuing stopped with the instance
+// running it. Tokens already queued are still refreshed.
+func ExpireOrphanedRefreshJobs(kvStore *xkv.Store) ([]string, error) {
+	jobIDs, err := kvStore.Smembers(CacheRefreshJobsQueuingKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get queuing refresh jobs")
+	}
+
+	staleBefore := time.Now().Unix() - RefreshJobStaleSeconds
+	var expired []string
+	for _, jobID := range jobIDs {
+		job, err := GetRefreshJob(kvStore, jobID)
+		if err != nil {
+			return expired, err
+		}
+
+		switch {
+		case job == nil || job.Status != RefreshJobQueuing:
+			if _, err := kvStore.Srem(CacheRefreshJobsQueuingKey, jobID); err != nil {
+				return expired, errors.Wrap(err, "failed on untrack refresh job")
+			}
+		case job.UpdateTime < staleBefore:
+			if err := SetRefreshJobStatus(kvStore, jobID, RefreshJobFailed, "queuing was interrupted"); err != nil {
+				return expired, err
+			}
+			expired = append(expired, jobID)
+		}
+	}
+
+	return expired, nil
+}
+
+// WatchRefreshJobs expires the orphaned refresh jobs on start, and again every
+// RefreshJobStaleSeconds until ctx is done.
+func WatchRefreshJobs(ctx context.Context, kvStore *xkv.Store) {
+	ticker := time.NewTicker(RefreshJobStaleSeconds * time.Second)
+	defer ticker.Stop()
+	for {
+		expired, err := ExpireOrphanedRefreshJobs(kvStore)
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on expire orphaned refresh jobs", zap.Error(err))
+		}
+		if len(expired) > 0 {
+			xzap.WithContext(ctx).Info("expired orphaned refresh jobs", zap.Strings("job_ids", expired))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// AddJobItems queues a batch of tokens of a refresh job. Job items skip the
+// per token reentrancy lock, jobs are rate limited as a whole. Retried items
+// are not counted as queued again.
+func (q *RefreshQueue) AddJobItems(ctx context.Context, chain string, job *types.RefreshJob, tokenIDs []string, retry bool) error {
+	if len(tokenIDs) == 0 {
+		return nil
+	}
+
+	var items []types.RefreshItem
+	for _, tokenID := range tokenIDs {
+		items = append(items, types.RefreshItem{
+			ChainID:        job.ChainID,
+			CollectionAddr: job.CollectionAddr,
+			TokenID:        tokenID,
+			JobID:          job.JobID,
+		})
+	}
+	if err := q.AddItems(ctx, chain, items); err != nil {
+		return err
+	}
+
+	if !retry {
+		if _, err := q.kvStore.Hincrby(refreshJobKey(job.JobID), "queued", len(tokenIDs)); err != nil {
+			return errors.Wrap(err, "failed on update refresh job progress")
+		}
+	}
+	// the update time tells a live job from an orphaned one
+	if err := q.kvStore.Hset(refreshJobKey(job.JobID), "update_time", strconv.FormatInt(time.Now().Unix(), 10)); err != nil {
+		return errors.Wrap(err, "failed on update refresh job progress")
+	}
+	return nil
+}
+
+// ReportRefreshResult records the refresh of a token of a job. The job is
+// completed once every queued token is reported.
+func ReportRefreshResult(kvStore *xkv.Store, jobID, tokenID string, ok bool) error {
+	if jobID == "" {
+		return nil
+	}
+
+	field := "done"
+	if !ok {
+		field = "failed"
+		if _, err := kvStore.Sadd(refreshJobFailedKey(jobID), tokenID); err != nil {
+			return errors.Wrap(err, "failed on record refresh failure")
+		}
+		_ = kvStore.Expire(refreshJobFailedKey(jobID), RefreshJobTTL)
+	}
+	if _, err := kvStore.Hincrby(refreshJobKey(jobID), field, 1); err != nil {
+		return errors.Wrap(err, "failed on update refresh job progress")
+	}
+
+	job, err := GetRefreshJob(kvStore, jobID)
+	if err != nil || job == nil {
+		return err
+	}
+	if job.Status == RefreshJobQueued && job.Done+job.Failed >= job.Queued {
+		return SetRefreshJobStatus(kvStore, jobID, RefreshJobCompleted, "")
+	}
+	return nil
+}
+
+// TakeRefreshJobFailures returns the failed tokens of a job and clears them,
+// so they can be queued again.
+func TakeRefreshJobFailures(kvStore *xkv.Store, jobID string) ([]string, error) {
+	tokenIDs, err := kvStore.Smembers(refreshJobFailedKey(jobID))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get refresh job failures")
+	}
+	if len(tokenIDs) == 0 {
+		return nil, nil
+	}
+
+	if _, err := kvStore.Del(refreshJobFailedKey(jobID)); err != nil {
+		return nil, errors.Wrap(err, "failed on clear refresh job failures")
+	}
+	if _, err := kvStore.Hincrby(refreshJobKey(jobID), "failed", -len(tokenIDs)); err != nil {
+		return nil, errors.Wrap(err, "failed on update refresh job progress")
+	}
+	return tokenIDs, nil
+}
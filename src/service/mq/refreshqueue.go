@@ -0,0 +1,421 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/pkg/errors"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// the stream and dead-letter keys of a chain share a hash tag, so the
+	// transactions moving messages between them stay in one cluster slot
+	CacheRefreshStreamKey     = "cache:{%s:%s}:item:refresh:stream"
+	CacheRefreshDeadLetterKey = "cache:{%s:%s}:item:refresh:dead"
+	RefreshConsumerGroup      = "metadata-refresh"
+
+	DefaultMaxAttempts = 5
+	// DefaultReclaimIdle is how long a delivered message stays unacknowledged
+	// before it is taken over from its consumer, presumed crashed.
+	DefaultReclaimIdle  = 5 * time.Minute
+	MaxRefreshStreamLen = 1000000
+)
+
+// RefreshMessage is a refresh item read from the stream, along with the
+// number of the current attempt.
+type RefreshMessage struct {
+	ID      string
+	Item    types.RefreshItem
+	Attempt int
+}
+
+// DeadLetter is a refresh item that failed on every attempt.
+type DeadLetter struct {
+	ID       string            `json:"id"`
+	Item     types.RefreshItem `json:"item"`
+	Attempts int               `json:"attempts"`
+	Reason   string            `json:"reason"`
+	FailTime int64             `json:"fail_time"`
+}
+
+// RefreshQueue is the metadata refresh queue of every chain, a Redis stream
+// read by a consumer group. Items pushed to the legacy refresh set by older
+// producers are moved into the stream by the consumers. Without a consumer in
+// the deployment, legacy is set and items are written to the legacy set read
+// by the sync service instead.
+type RefreshQueue struct {
+	client      goredis.UniversalClient
+	kvStore     *xkv.Store
+	project     string
+	maxAttempts int
+	reclaimIdle time.Duration
+	legacy      bool
+}
+
+func NewRefreshQueue(client goredis.UniversalClient, kvStore *xkv.Store, project string, maxAttempts int, reclaimIdle time.Duration,
+	legacy bool) *RefreshQueue {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if reclaimIdle <= 0 {
+		reclaimIdle = DefaultReclaimIdle
+	}
+
+	return &RefreshQueue{
+		client:      client,
+		kvStore:     kvStore,
+		project:     strings.ToLower(project),
+		maxAttempts: maxAttempts,
+		reclaimIdle: reclaimIdle,
+		legacy:      legacy,
+	}
+}
+
+// MaxAttempts is the number of attempts of a message before it is
+// dead-lettered.
+func (q *RefreshQueue) MaxAttempts() int {
+	return q.maxAttempts
+}
+
+// Legacy reports whether items are written to the legacy set, whose consumer
+// does not report the results of refresh jobs.
+func (q *RefreshQueue) Legacy() bool {
+	return q.legacy
+}
+
+func (q *RefreshQueue) streamKey(chain string) string {
+	return fmt.Sprintf(CacheRefreshStreamKey, q.project, strings.ToLower(chain))
+}
+
+func (q *RefreshQueue) deadLetterKey(chain string) string {
+	return fmt.Sprintf(CacheRefreshDeadLetterKey, q.project, strings.ToLower(chain))
+}
+
+// AddItem queues a single item, at most once every PreventReentrancyPeriod.
+func (q *RefreshQueue) AddItem(ctx context.Context, chain string, item types.RefreshItem) error {
+	reentrancyKey := fmt.Sprintf(CacheRefreshPreventReentrancyKeyPrefix, item.ChainID, item.CollectionAddr, item.TokenID)
+	added, err := q.kvStore.SetnxEx(reentrancyKey, "true", PreventReentrancyPeriod)
+	if err != nil {
+		return errors.Wrap(err, "failed on check reentrancy status")
+	}
+	if !added {
+		xzap.WithContext(ctx).Info("refresh within 10s", zap.String("collection_addr", item.CollectionAddr), zap.String("token_id", item.TokenID))
+		return nil
+	}
+
+	return q.AddItems(ctx, chain, []types.RefreshItem{item})
+}
+
+// AddItems queues items without the reentrancy check.
+func (q *RefreshQueue) AddItems(ctx context.Context, chain string, items []types.RefreshItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	if q.legacy {
+		return q.addLegacyItems(chain, items)
+	}
+
+	pipe := q.client.Pipeline()
+	for _, item := range items {
+		rawItem, err := json.Marshal(&item)
+		if err != nil {
+			return errors.Wrap(err, "failed on marshal item info")
+		}
+		pipe.XAdd(ctx, q.addArgs(chain, string(rawItem), 1))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.Wrap(err, "failed on push items to refresh stream")
+	}
+
+	return nil
+}
+
+func (q *RefreshQueue) addLegacyItems(chain string, items []types.RefreshItem) error {
+	var rawItems []interface{}
+	for _, item := range items {
+		rawItem, err := json.Marshal(&item)
+		if err != nil {
+			return errors.Wrap(err, "failed on marshal item info")
+		}
+		rawItems = append(rawItems, string(rawItem))
+	}
+
+	if _, err := q.kvStore.Sadd(GetRefreshSingleItemMetadataKey(q.project, chain), rawItems...); err != nil {
+		return errors.Wrap(err, "failed on push items to refresh metadata queue")
+	}
+	return nil
+}
+
+func (q *RefreshQueue) addArgs(chain, rawItem string, attempt int) *goredis.XAddArgs {
+	return &goredis.XAddArgs{
+		Stream: q.streamKey(chain),
+		MaxLen: MaxRefreshStreamLen,
+		Approx: true,
+		Values: map[string]interface{}{"item": rawItem, "attempt": attempt},
+	}
+}
+
+func (q *RefreshQueue) ensureGroup(ctx context.Context, chain string) error {
+	err := q.client.XGroupCreateMkStream(ctx, q.streamKey(chain), RefreshConsumerGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return errors.Wrap(err, "failed on create refresh consumer group")
+	}
+	return nil
+}
+
+// migrateLegacy moves up to count items of the legacy refresh set into the
+// stream. An item leaves the set only once it is in the stream, so a failure
+// in between may queue it twice but never loses it.
+func (q *RefreshQueue) migrateLegacy(ctx context.Context, chain string, count int) error {
+	setKey := GetRefreshSingleItemMetadataKey(q.project, chain)
+	rawItems, err := q.kvStore.Srandmember(setKey, count)
+	if err != nil {
+		if err == goredis.Nil {
+			return nil
+		}
+		return errors.Wrap(err, "failed on read legacy refresh items")
+	}
+
+	for _, rawItem := range rawItems {
+		if err := q.client.XAdd(ctx, q.addArgs(chain, rawItem, 1)).Err(); err != nil {
+			return errors.Wrap(err, "failed on move legacy refresh item")
+		}
+		if _, err := q.kvStore.Srem(setKey, rawItem); err != nil {
+			return errors.Wrap(err, "failed on remove legacy refresh item")
+		}
+	}
+	return nil
+}
+
+// Read returns up to count messages for the consumer: first the messages
+// left unacknowledged by crashed consumers, then new ones, waiting up to
+// block for them.
+func (q *RefreshQueue) Read(ctx context.Context, chain, consumer string, count int, block time.Duration) ([]RefreshMessage, error) {
+	if err := q.ensureGroup(ctx, chain); err != nil {
+		return nil, err
+	}
+	if err := q.migrateLegacy(ctx, chain, count); err != nil {
+		xzap.WithContext(ctx).Error("failed on migrate legacy refresh items", zap.String("chain", chain), zap.Error(err))
+	}
+
+	claimed, _, err := q.client.XAutoClaim(ctx, &goredis.XAutoClaimArgs{
+		Stream:   q.streamKey(chain),
+		Group:    RefreshConsumerGroup,
+		MinIdle:  q.reclaimIdle,
+		Start:    "0-0",
+		Count:    int64(count),
+		Consumer: consumer,
+	}).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on reclaim refresh items")
+	}
+	if len(claimed) > 0 {
+		return q.messages(ctx, chain, claimed, true)
+	}
+
+	streams, err := q.client.XReadGroup(ctx, &goredis.XReadGroupArgs{
+		Group:    RefreshConsumerGroup,
+		Consumer: consumer,
+		Streams:  []string{q.streamKey(chain), ">"},
+		Count:    int64(count),
+		Block:    block,
+	}).Result()
+	if err != nil {
+		if err == goredis.Nil {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed on read refresh items")
+	}
+
+	var msgs []goredis.XMessage
+	for _, stream := range streams {
+		msgs = append(msgs, stream.Messages...)
+	}
+	return q.messages(ctx, chain, msgs, false)
+}
+
+// messages decodes stream messages. A reclaimed message counts the deliveries
+// to crashed consumers as failed attempts; messages that cannot be decoded or
+// ran out of attempts are dead-lettered.
+func (q *RefreshQueue) messages(ctx context.Context, chain string, msgs []goredis.XMessage, reclaimed bool) ([]RefreshMessage, error) {
+	retries := make(map[string]int64)
+	if reclaimed && len(msgs) > 0 {
+		pipe := q.client.Pipeline()
+		var cmds []*goredis.XPendingExtCmd
+		for _, msg := range msgs {
+			cmds = append(cmds, pipe.XPendingExt(ctx, &goredis.XPendingExtArgs{
+				Stream: q.streamKey(chain),
+				Group:  RefreshConsumerGroup,
+				Start:  msg.ID,
+				End:    msg.ID,
+				Count:  1,
+			}))
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, errors.Wrap(err, "failed on read pending refresh items")
+		}
+		for _, cmd := range cmds {
+			for _, entry := range cmd.Val() {
+				retries[entry.ID] = entry.RetryCount
+			}
+		}
+	}
+
+	var result []RefreshMessage
+	for _, msg := range msgs {
+		message := RefreshMessage{ID: msg.ID, Attempt: 1}
+		if attempt, err := strconv.Atoi(fmt.Sprint(msg.Values["attempt"])); err == nil && attempt > 0 {
+			message.Attempt = attempt
+		}
+		if retry := retries[msg.ID]; retry > 1 {
+			message.Attempt += int(retry) - 1
+		}
+
+		rawItem, _ := msg.Values["item"].(string)
+		if err := json.Unmarshal([]byte(rawItem), &message.Item); err != nil {
+			if err := q.Fail(ctx, chain, message, "invalid refresh item"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if message.Attempt > q.maxAttempts {
+			if err := q.Fail(ctx, chain, message, "consumer crashed on every attempt"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		result = append(result, message)
+	}
+
+	return result, nil
+}
+
+// Ack removes a processed message.
+func (q *RefreshQueue) Ack(ctx context.Context, chain string, msg RefreshMessage) error {
+	pipe := q.client.TxPipeline()
+	pipe.XAck(ctx, q.streamKey(chain), RefreshConsumerGroup, msg.ID)
+	pipe.XDel(ctx, q.streamKey(chain), msg.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.Wrap(err, "failed on ack refresh item")
+	}
+	return nil
+}
+
+// Fail queues a failed message again for its next attempt, or moves it to
+// the dead-letter stream after the last one.
+func (q *RefreshQueue) Fail(ctx context.Context, chain string, msg RefreshMessage, reason string) error {
+	rawItem, err := json.Marshal(&msg.Item)
+	if err != nil {
+		return errors.Wrap(err, "failed on marshal item info")
+	}
+
+	pipe := q.client.TxPipeline()
+	if msg.Attempt >= q.maxAttempts {
+		pipe.XAdd(ctx, &goredis.XAddArgs{
+			Stream: q.deadLetterKey(chain),
+			MaxLen: MaxRefreshStreamLen,
+			Approx: true,
+			Values: map[string]interface{}{
+				"item":      string(rawItem),
+				"attempt":   msg.Attempt,
+				"reason":    reason,
+				"fail_time": time.Now().Unix(),
+			},
+		})
+	} else {
+		pipe.XAdd(ctx, q.addArgs(chain, string(rawItem), msg.Attempt+1))
+	}
+	pipe.XAck(ctx, q.streamKey(chain), RefreshConsumerGroup, msg.ID)
+	pipe.XDel(ctx, q.streamKey(chain), msg.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.Wrap(err, "failed on requeue refresh item")
+	}
+
+	return nil
+}
+
+// DeadLetters returns up to count dead-lettered items after the cursor id.
+func (q *RefreshQueue) DeadLetters(ctx context.Context, chain, cursor string, count int) ([]DeadLetter, error) {
+	start := "-"
+	if cursor != "" {
+		start = "(" + cursor
+	}
+
+	msgs, err := q.client.XRangeN(ctx, q.deadLetterKey(chain), start, "+", int64(count)).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on read dead letters")
+	}
+
+	var letters []DeadLetter
+	for _, msg := range msgs {
+		letter := DeadLetter{ID: msg.ID}
+		rawItem, _ := msg.Values["item"].(string)
+		_ = json.Unmarshal([]byte(rawItem), &letter.Item)
+		if attempt, ok := msg.Values["attempt"].(string); ok {
+			letter.Attempts, _ = strconv.Atoi(attempt)
+		}
+		letter.Reason, _ = msg.Values["reason"].(string)
+		if failTime, ok := msg.Values["fail_time"].(string); ok {
+			letter.FailTime, _ = strconv.ParseInt(failTime, 10, 64)
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+// Replay queues dead-lettered items again with fresh attempts and returns
+// how many were found.
+func (q *RefreshQueue) Replay(ctx context.Context, chain string, ids []string) (int, error) {
+	var replayed int
+	for _, id := range ids {
+		msgs, err := q.client.XRange(ctx, q.deadLetterKey(chain), id, id).Result()
+		if err != nil {
+			return replayed, errors.Wrap(err, "failed on read dead letter")
+		}
+		if len(msgs) == 0 {
+			continue
+		}
+
+		rawItem, _ := msgs[0].Values["item"].(string)
+		pipe := q.client.TxPipeline()
+		pipe.XAdd(ctx, q.addArgs(chain, rawItem, 1))
+		pipe.XDel(ctx, q.deadLetterKey(chain), id)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return replayed, errors.Wrap(err, "failed on replay dead letter")
+		}
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+// Purge deletes the given dead letters, or all of them when ids is empty.
+func (q *RefreshQueue) Purge(ctx context.Context, chain string, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		count, err := q.client.XLen(ctx, q.deadLetterKey(chain)).Result()
+		if err != nil {
+			return 0, errors.Wrap(err, "failed on count dead letters")
+		}
+		if err := q.client.Del(ctx, q.deadLetterKey(chain)).Err(); err != nil {
+			return 0, errors.Wrap(err, "failed on purge dead letters")
+		}
+		return count, nil
+	}
+
+	count, err := q.client.XDel(ctx, q.deadLetterKey(chain), ids...).Result()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed on purge dead letters")
+	}
+	return count, nil
+}
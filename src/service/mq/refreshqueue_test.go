@@ -0,0 +1,30 @@
+package mq
+
+import (
+	"strings"
+	"testing"
+)
+
+// hashTag returns the part of key redis cluster hashes to pick the slot.
+func hashTag(key string) string {
+	start := strings.Index(key, "{")
+	if start < 0 {
+		return key
+	}
+	end := strings.Index(key[start+1:], "}")
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+func TestRefreshKeysShareSlot(t *testing.T) {
+	q := NewRefreshQueue(nil, nil, "EasySwap", 0, 0, false)
+	stream, dead := q.streamKey("Sepolia"), q.deadLetterKey("sepolia")
+	if hashTag(stream) != "easyswap:sepolia" || hashTag(stream) != hashTag(dead) {
+		t.Fatalf("stream %q and dead letters %q hash to different slots", stream, dead)
+	}
+	if hashTag(q.streamKey("eth")) == hashTag(stream) {
+		t.Fatal("the streams of every chain share one slot")
+	}
+}
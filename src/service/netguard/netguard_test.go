@@ -0,0 +1,24 @@
+package netguard
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsPrivateIP(t *testing.T) {
+	for addr, private := range map[string]bool{
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"169.254.1.1":     true,
+		"100.64.0.1":      true,
+		"100.127.255.254": true,
+		"100.128.0.1":     false,
+		"8.8.8.8":         false,
+		"::1":             true,
+		"fd00::1":         true,
+	} {
+		if got := IsPrivateIP(net.ParseIP(addr)); got != private {
+			t.Fatalf("IsPrivateIP(%s) = %v, want %v", addr, got, private)
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/mq"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultInterval      = 30 * time.Second
+	DefaultExpiringHours = 24
+	BatchSize            = 500
+)
+
+const CacheNotificationCursorKey = "cache:es:notification:%s:%s:cursor"
+
+const (
+	cursorActivity = "activity"
+	cursorOrder    = "order"
+)
+
+// Notifier watches activities and orders of every chain and turns the ones
+// concerning registered users into notifications.
+type Notifier struct {
+	dao           *dao.Dao
+	store         *xkv.Store
+	project       string
+	chains        []*config.ChainSupported
+	expiringHours int
+}
+
+func NewNotifier(d *dao.Dao, store *xkv.Store, project string, chains []*config.ChainSupported, expiringHours int) *Notifier {
+	if expiringHours <= 0 {
+		expiringHours = DefaultExpiringHours
+	}
+
+	return &Notifier{
+		dao:           d,
+		store:         store,
+		project:       project,
+		chains:        chains,
+		expiringHours: expiringHours,
+	}
+}
+
+func (n *Notifier) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, chain := range n.chains {
+				if err := n.Notify(ctx, chain); err != nil {
+					xzap.WithContext(ctx).Error("failed on generate notifications", zap.String("chain", chain.Name), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+func (n *Notifier) Notify(ctx context.Context, chain *config.ChainSupported) error {
+	if err := n.notifySales(ctx, chain); err != nil {
+		return errors.Wrap(err, "failed on notify sales")
+	}
+	if err := n.notifyBids(ctx, chain); err != nil {
+		return errors.Wrap(err, "failed on notify bids")
+	}
+	if err := n.notifyExpiringListings(ctx, chain); err != nil {
+		return errors.Wrap(err, "failed on notify expiring listings")
+	}
+	if err := n.notifyExpiredOrders(ctx, chain); err != nil {
+		return errors.Wrap(err, "failed on notify expired orders")
+	}
+	return nil
+}
+
+func (n *Notifier) notifySales(ctx context.Context, chain *config.ChainSupported) error {
+	cursor, err := n.cursor(ctx, chain.Name, cursorActivity, multi.ActivityTableName(chain.Name))
+	if err != nil {
+		return err
+	}
+
+	for {
+		activities, err := n.dao.QueryActivitiesSince(ctx, chain.Name, cursor, []int{multi.Sale}, BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(activities) == 0 {
+			return nil
+		}
+
+		var notifications []dao.Notification
+		for _, activity := range activities {
+			// the maker of a sale activity is the seller
+			notifications = append(notifications, newNotification(chain, dao.NotificationSold, activity.Maker,
+				fmt.Sprintf("%s:%s:%d", dao.NotificationSold, chain.Name, activity.Id), func(notification *dao.Notification) {
+					notification.CollectionAddress = activity.CollectionAddress
+					notification.TokenID = activity.TokenId
+					notification.Price = activity.Price
+					notification.Counterparty = activity.Taker
+					notification.EventTime = activity.EventTime
+				}))
+		}
+
+		if err := n.save(ctx, notifications); err != nil {
+			return err
+		}
+
+		cursor = activities[len(activities)-1].Id
+		if err := n.setCursor(chain.Name, cursorActivity, cursor); err != nil {
+			return err
+		}
+		if len(activities) < BatchSize {
+			return nil
+		}
+	}
+}
+
+func (n *Notifier) notifyBids(ctx context.Context, chain *config.ChainSupported) error {
+	cursor, err := n.cursor(ctx, chain.Name, cursorOrder, multi.OrderTableName(chain.Name))
+	if err != nil {
+		return err
+	}
+
+	for {
+		bids, err := n.dao.QueryOrdersSince(ctx, chain.Name, cursor,
+			[]int64{multi.ItemBidOrder, multi.CollectionBidOrder, dao.TraitBidOrder}, BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(bids) == 0 {
+			return nil
+		}
+
+		var itemInfos []types.ItemInfo
+		for _, bid := range bids {
+			if bid.OrderType == multi.ItemBidOrder {
+				itemInfos = append(itemInfos, types.ItemInfo{CollectionAddress: bid.CollectionAddress, TokenID: bid.TokenId})
+			}
+		}
+		items, err := n.dao.QueryItemsByInfos(ctx, chain.Name, itemInfos)
+		if err != nil {
+			return err
+		}
+		owners := make(map[string]string)
+		for _, item := range items {
+			owners[strings.ToLower(item.CollectionAddress+":"+item.TokenId)] = strings.ToLower(item.Owner)
+		}
+
+		var notifications []dao.Notification
+		for _, bid := range bids {
+			if bid.OrderType == multi.ItemBidOrder {
+				owner, ok := owners[strings.ToLower(bid.CollectionAddress+":"+bid.TokenId)]
+				if !ok || strings.EqualFold(owner, bid.Maker) {
+					continue
+				}
+				notifications = append(notifications, bidNotification(chain, dao.NotificationOfferReceived, owner,
+					fmt.Sprintf("%s:%s:%s", dao.NotificationOfferReceived, chain.Name, bid.OrderID), bid))
+				continue
+			}
+
+			received, err := n.offersReceived(ctx, chain, bid)
+			if err != nil {
+				return err
+			}
+			notifications = append(notifications, received...)
+
+			displaced, err := n.displacedBids(ctx, chain.Name, bid)
+			if err != nil {
+				return err
+			}
+			for _, outbid := range displaced {
+				notifications = append(notifications, bidNotification(chain, dao.NotificationOutbid, outbid.Maker,
+					fmt.Sprintf("%s:%s:%s:%s", dao.NotificationOutbid, chain.Name, bid.OrderID, outbid.OrderID), bid))
+			}
+		}
+
+		if err := n.save(ctx, notifications); err != nil {
+			return err
+		}
+
+		cursor = bids[len(bids)-1].Id
+		if err := n.setCursor(chain.Name, cursorOrder, cursor); err != nil {
+			return err
+		}
+		if len(bids) < BatchSize {
+			return nil
+		}
+	}
+}
+
+// offersReceived notifies the registered users holding an item a collection
+// or trait bid can be filled with, besides its maker. Only registered users
+// are looked up, the notifications of others would be dropped on save.
+func (n *Notifier) offersReceived(ctx context.Context, chain *config.ChainSupported, bid multi.Order) ([]dao.Notification, error) {
+	var criteria []types.ItemTrait
+	if bid.OrderType == dao.TraitBidOrder {
+		orderCriteria, err := n.dao.QueryTraitBidCriteria(ctx, chain.Name, []string{bid.OrderID})
+		if err != nil {
+			return nil, err
+		}
+		// a trait bid without criteria is not offered to anyone
+		if criteria = orderCriteria[bid.OrderID]; len(criteria) == 0 {
+			return nil, nil
+		}
+	}
+
+	holders, err := n.dao.QueryRegisteredHolders(ctx, chain.Name, bid.CollectionAddress, criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	var notifications []dao.Notification
+	for _, holder := range holders {
+		if strings.EqualFold(holder, bid.Maker) {
+			continue
+		}
+		notifications = append(notifications, bidNotification(chain, dao.NotificationOfferReceived, holder,
+			fmt.Sprintf("%s:%s:%s:%s", dao.NotificationOfferReceived, chain.Name, bid.OrderID, holder), bid))
+	}
+	return notifications, nil
+}
+
+// displacedBids returns the bids a new collection or trait bid took the top
+// price from. Trait bids only compete with the bids on the same traits.
+func (n *Notifier) displacedBids(ctx context.Context, chain string, bid multi.Order) ([]multi.Order, error) {
+	competing, err := n.dao.QueryCompetingBids(ctx, chain, bid.CollectionAddress, bid.OrderType, bid.Id)
+	if err != nil {
+		return nil, err
+	}
+	if bid.OrderType != dao.TraitBidOrder || len(competing) == 0 {
+		return topBids(bid, competing), nil
+	}
+
+	orderIDs := []string{bid.OrderID}
+	for _, order := range competing {
+		orderIDs = append(orderIDs, order.OrderID)
+	}
+	criteria, err := n.dao.QueryTraitBidCriteria(ctx, chain, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	key := criteriaKey(criteria[bid.OrderID])
+	var sameTraits []multi.Order
+	for _, order := range competing {
+		if criteriaKey(criteria[order.OrderID]) == key {
+			sameTraits = append(sameTraits, order)
+		}
+	}
+	return topBids(bid, sameTraits), nil
+}
+
+// topBids returns the bids of competing, best price first, that held the top
+// price before bid beat it, one per maker. Nobody is displaced when bid does
+// not beat the top price or its maker held it.
+func topBids(bid multi.Order, competing []multi.Order) []multi.Order {
+	if len(competing) == 0 || !bid.Price.GreaterThan(competing[0].Price) {
+		return nil
+	}
+
+	top := competing[0].Price
+	makers := make(map[string]bool)
+	var displaced []multi.Order
+	for _, order := range competing {
+		if !order.Price.Equal(top) {
+			break
+		}
+		if strings.EqualFold(order.Maker, bid.Maker) || makers[strings.ToLower(order.Maker)] {
+			continue
+		}
+		makers[strings.ToLower(order.Maker)] = true
+		displaced = append(displaced, order)
+	}
+	return displaced
+}
+
+// criteriaKey identifies a set of trait criteria regardless of its order.
+func criteriaKey(criteria []types.ItemTrait) string {
+	var traits []string
+	for _, criterion := range criteria {
+		traits = append(traits, strings.ToLower(criterion.Key)+"="+strings.ToLower(criterion.Value))
+	}
+	sort.Strings(traits)
+	return strings.Join(traits, ";")
+}
+
+func (n *Notifier) notifyExpiringListings(ctx context.Context, chain *config.ChainSupported) error {
+	now := time.Now()
+	until := now.Add(time.Duration(n.expiringHours) * time.Hour)
+
+	cursor := int64(0)
+	for {
+		listings, err := n.dao.QueryExpiringListings(ctx, chain.Name, now.Unix(), until.Unix(), cursor, BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(listings) == 0 {
+			return nil
+		}
+
+		var notifications []dao.Notification
+		for _, listing := range listings {
+			notifications = append(notifications, newNotification(chain, dao.NotificationListingExpiring, listing.Maker,
+				fmt.Sprintf("%s:%s:%s", dao.NotificationListingExpiring, chain.Name, listing.OrderID), func(notification *dao.Notification) {
+					notification.CollectionAddress = listing.CollectionAddress
+					notification.TokenID = listing.TokenId
+					notification.OrderID = listing.OrderID
+					notification.Price = listing.Price
+					notification.EventTime = listing.ExpireTime
+				}))
+		}
+
+		if err := n.save(ctx, notifications); err != nil {
+			return err
+		}
+
+		if len(listings) < BatchSize {
+			return nil
+		}
+		cursor = listings[len(listings)-1].Id
+	}
+}
+
+// notifyExpiredOrders tells makers about the orders the expiry sweeper moved
+// to expired.
+func (n *Notifier) notifyExpiredOrders(ctx context.Context, chain *config.ChainSupported) error {
+	for {
+		events, rawEvents, err := mq.QueryOrderExpiredEvents(n.store, n.project, chain.Name, BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(rawEvents) == 0 {
+			return nil
+		}
+
+		var notifications []dao.Notification
+		for _, event := range events {
+			notificationType := dao.NotificationBidExpired
+			if event.OrderType == multi.ListingOrder {
+				notificationType = dao.NotificationListingExpired
+			}
+			notifications = append(notifications, newNotification(chain, notificationType, event.Maker,
+				fmt.Sprintf("%s:%s:%s", notificationType, chain.Name, event.OrderID), func(notification *dao.Notification) {
+					notification.CollectionAddress = event.CollectionAddress
+					notification.TokenID = event.TokenID
+					notification.OrderID = event.OrderID
+					notification.Price = event.Price
+					notification.EventTime = event.ExpireTime
+				}))
+		}
+
+		if err := n.save(ctx, notifications); err != nil {
+			return err
+		}
+		if err := mq.RemoveOrderExpiredEvents(n.store, n.project, chain.Name, rawEvents); err != nil {
+			return err
+		}
+
+		if len(rawEvents) < BatchSize {
+			return nil
+		}
+	}
+}
+
+// save keeps only the notifications of users who logged in through UserLogin.
+func (n *Notifier) save(ctx context.Context, notifications []dao.Notification) error {
+	var addrs []string
+	for _, notification := range notifications {
+		addrs = append(addrs, notification.UserAddress)
+	}
+
+	registered, err := n.dao.QueryRegisteredUsers(ctx, addrs)
+	if err != nil {
+		return err
+	}
+
+	var userNotifications []dao.Notification
+	for _, notification := range notifications {
+		if registered[notification.UserAddress] {
+			userNotifications = append(userNotifications, notification)
+		}
+	}
+
+	return n.dao.CreateNotifications(ctx, userNotifications)
+}
+
+// cursor returns the last processed id. A new cursor starts at the current end
+// of the table, so history is not replayed as notifications.
+func (n *Notifier) cursor(ctx context.Context, chain, kind, table string) (int64, error) {
+	value, err := n.store.Get(fmt.Sprintf(CacheNotificationCursorKey, chain, kind))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed on get notification cursor")
+	}
+
+	if value != "" {
+		cursor, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			return cursor, nil
+		}
+	}
+
+	cursor, err := n.dao.QueryMaxID(ctx, table)
+	if err != nil {
+		return 0, err
+	}
+	return cursor, n.setCursor(chain, kind, cursor)
+}
+
+func (n *Notifier) setCursor(chain, kind string, cursor int64) error {
+	if err := n.store.Set(fmt.Sprintf(CacheNotificationCursorKey, chain, kind), strconv.FormatInt(cursor, 10)); err != nil {
+		return errors.Wrap(err, "failed on set notification cursor")
+	}
+	return nil
+}
+
+func newNotification(chain *config.ChainSupported, notificationType, userAddr, dedupeKey string, fill func(*dao.Notification)) dao.Notification {
+	now := time.Now().UnixMilli()
+	notification := dao.Notification{
+		UserAddress:      strings.ToLower(userAddr),
+		ChainID:          chain.ChainID,
+		NotificationType: notificationType,
+		DedupeKey:        dedupeKey,
+		CreateTime:       now,
+		UpdateTime:       now,
+	}
+	fill(&notification)
+	return notification
+}
+
+func bidNotification(chain *config.ChainSupported, notificationType, userAddr, dedupeKey string, bid multi.Order) dao.Notification {
+	return newNotification(chain, notificationType, userAddr, dedupeKey, func(notification *dao.Notification) {
+		notification.CollectionAddress = bid.CollectionAddress
+		notification.TokenID = bid.TokenId
+		notification.OrderID = bid.OrderID
+		notification.Price = bid.Price
+		notification.Counterparty = bid.Maker
+		notification.EventTime = bid.EventTime
+	})
+}
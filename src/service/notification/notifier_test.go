@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/shopspring/decimal"
+)
+
+func testBid(orderID, maker string, price int64) multi.Order {
+	return multi.Order{OrderID: orderID, Maker: maker, Price: decimal.NewFromInt(price)}
+}
+
+func TestTopBids(t *testing.T) {
+	competing := []multi.Order{
+		testBid("a", "0xaa", 5),
+		testBid("b", "0xbb", 5),
+		testBid("c", "0xAA", 5),
+		testBid("d", "0xdd", 3),
+	}
+
+	displaced := topBids(testBid("new", "0xee", 6), competing)
+	if len(displaced) != 2 || displaced[0].OrderID != "a" || displaced[1].OrderID != "b" {
+		t.Fatalf("expected the top bidders to be displaced once each, got %+v", displaced)
+	}
+
+	if displaced := topBids(testBid("new", "0xee", 5), competing); len(displaced) != 0 {
+		t.Fatalf("expected a tie not to displace anyone, got %+v", displaced)
+	}
+	if displaced := topBids(testBid("new", "0xee", 4), competing); len(displaced) != 0 {
+		t.Fatalf("expected a lower bid not to displace anyone, got %+v", displaced)
+	}
+
+	displaced = topBids(testBid("new", "0xBB", 6), competing)
+	if len(displaced) != 1 || displaced[0].OrderID != "a" {
+		t.Fatalf("expected a maker not to outbid itself, got %+v", displaced)
+	}
+}
+
+func TestCriteriaKey(t *testing.T) {
+	a := criteriaKey([]types.ItemTrait{{Key: "Background", Value: "Blue"}, {Key: "Eyes", Value: "Laser"}})
+	b := criteriaKey([]types.ItemTrait{{Key: "eyes", Value: "laser"}, {Key: "background", Value: "blue"}})
+	if a != b {
+		t.Fatalf("expected the same criteria to match: %q != %q", a, b)
+	}
+
+	c := criteriaKey([]types.ItemTrait{{Key: "Background", Value: "Blue"}})
+	if a == c {
+		t.Fatal("expected a subset of the criteria not to match")
+	}
+}
@@ -0,0 +1,79 @@
+package orderbook
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+var cancelTypeHash = crypto.Keccak256([]byte(
+	"CancelOrders(address maker,bytes32[] orderKeys,uint64 expiry)"))
+
+// CancelRequest is the message a maker signs to cancel orders without a
+// transaction.
+type CancelRequest struct {
+	Maker     common.Address
+	OrderKeys []common.Hash
+	Expiry    uint64
+}
+
+func (r *CancelRequest) Hash() common.Hash {
+	var keys []byte
+	for _, key := range r.OrderKeys {
+		keys = append(keys, key.Bytes()...)
+	}
+
+	return common.BytesToHash(crypto.Keccak256(
+		cancelTypeHash,
+		common.LeftPadBytes(r.Maker.Bytes(), 32),
+		crypto.Keccak256(keys),
+		word(new(big.Int).SetUint64(r.Expiry)),
+	))
+}
+
+func (r *CancelRequest) Digest(chainID int64, verifyingContract common.Address) []byte {
+	return crypto.Keccak256(
+		[]byte("\x19\x01"),
+		DomainSeparator(chainID, verifyingContract),
+		r.Hash().Bytes(),
+	)
+}
+
+func (r *CancelRequest) VerifySignature(chainID int64, verifyingContract common.Address, signature string) error {
+	signer, err := recoverSigner(r.Digest(chainID, verifyingContract), signature)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(signer.Hex(), r.Maker.Hex()) {
+		return errors.New("signature does not match maker")
+	}
+
+	return nil
+}
+
+func recoverSigner(digest []byte, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return common.Address{}, errors.Wrap(err, "invalid signature encoding")
+	}
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, errors.New("invalid signature length")
+	}
+
+	sig = append([]byte{}, sig...)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pubKey, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		return common.Address{}, errors.Wrap(err, "failed on recover signer")
+	}
+
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
@@ -0,0 +1,77 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	testCancelHash      = "0x073d6b7e55085d02b5b816e2379c60263d4586308e226c35b632e1ac4893930e"
+	testCancelDigest    = "0x04be3e054c3f5f19fd134bfc84d36e4e0ab64a88686a92d3b8f574753ac29a71"
+	testCancelSignature = "0xc302ef771cfd2660ef1d770bd51c588d49736e0db95cb4390bc41381da833b2977b220b1640181c1495d86f8adb7d0ff924af91e53071e64d3c911fb43c210661b"
+)
+
+func testCancelRequest() *CancelRequest {
+	return &CancelRequest{
+		Maker:     common.HexToAddress(testMaker),
+		OrderKeys: []common.Hash{common.HexToHash(testOrderHash), common.HexToHash("0x02")},
+		Expiry:    1893456000,
+	}
+}
+
+func TestCancelRequestHash(t *testing.T) {
+	request := testCancelRequest()
+	if got := request.Hash().Hex(); got != testCancelHash {
+		t.Fatalf("hash = %s, want %s", got, testCancelHash)
+	}
+
+	orderBook := common.HexToAddress(testOrderBook)
+	if got := hexutil.Encode(request.Digest(testChainID, orderBook)); got != testCancelDigest {
+		t.Fatalf("digest = %s, want %s", got, testCancelDigest)
+	}
+}
+
+func TestCancelRequestSignature(t *testing.T) {
+	orderBook := common.HexToAddress(testOrderBook)
+
+	if err := testCancelRequest().VerifySignature(testChainID, orderBook, testCancelSignature); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+
+	// The recovery id may come as 0/1 as well as 27/28.
+	sig := hexutil.MustDecode(testCancelSignature)
+	sig[64] -= 27
+	if err := testCancelRequest().VerifySignature(testChainID, orderBook, hexutil.Encode(sig)); err != nil {
+		t.Fatalf("signature with a 0/1 recovery id rejected: %v", err)
+	}
+
+	tampered := testCancelRequest()
+	tampered.OrderKeys = tampered.OrderKeys[:1]
+	if err := tampered.VerifySignature(testChainID, orderBook, testCancelSignature); err == nil {
+		t.Fatal("signature over other order keys accepted")
+	}
+
+	expired := testCancelRequest()
+	expired.Expiry++
+	if err := expired.VerifySignature(testChainID, orderBook, testCancelSignature); err == nil {
+		t.Fatal("signature over another expiry accepted")
+	}
+
+	other := testCancelRequest()
+	other.Maker = common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	if err := other.VerifySignature(testChainID, orderBook, testCancelSignature); err == nil {
+		t.Fatal("signature of another maker accepted")
+	}
+
+	if err := testCancelRequest().VerifySignature(1, orderBook, testCancelSignature); err == nil {
+		t.Fatal("signature replayed on another chain accepted")
+	}
+
+	for _, signature := range []string{"", "0x1234", "not hex", testCancelSignature + "00"} {
+		if err := testCancelRequest().VerifySignature(testChainID, orderBook, signature); err == nil {
+			t.Fatalf("malformed signature %q accepted", signature)
+		}
+	}
+}
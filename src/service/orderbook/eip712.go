@@ -0,0 +1,116 @@
+package orderbook
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+const (
+	DomainName    = "EasySwapOrderBook"
+	DomainVersion = "1"
+)
+
+const (
+	SideList = 0
+	SideBid  = 1
+)
+
+const (
+	SaleKindForCollection = 0
+	SaleKindForItem       = 1
+)
+
+var (
+	domainTypeHash = crypto.Keccak256([]byte(
+		"EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	assetTypeHash = crypto.Keccak256([]byte(
+		"Asset(uint256 tokenId,address collection,uint96 amount)"))
+	orderTypeHash = crypto.Keccak256([]byte(
+		"Order(uint8 side,uint8 saleKind,address maker,Asset nft,uint128 price,uint64 expiry,uint64 salt)" +
+			"Asset(uint256 tokenId,address collection,uint96 amount)"))
+)
+
+// Asset and Order mirror the structs signed by makers of the EasySwap order book.
+type Asset struct {
+	TokenID    *big.Int
+	Collection common.Address
+	Amount     *big.Int
+}
+
+type Order struct {
+	Side     uint8
+	SaleKind uint8
+	Maker    common.Address
+	Nft      Asset
+	Price    *big.Int
+	Expiry   uint64
+	Salt     uint64
+}
+
+func DomainSeparator(chainID int64, verifyingContract common.Address) []byte {
+	return crypto.Keccak256(
+		domainTypeHash,
+		crypto.Keccak256([]byte(DomainName)),
+		crypto.Keccak256([]byte(DomainVersion)),
+		word(big.NewInt(chainID)),
+		common.LeftPadBytes(verifyingContract.Bytes(), 32),
+	)
+}
+
+// Hash returns the struct hash of the order, which is also its order key on chain.
+func (o *Order) Hash() common.Hash {
+	assetHash := crypto.Keccak256(
+		assetTypeHash,
+		word(o.Nft.TokenID),
+		common.LeftPadBytes(o.Nft.Collection.Bytes(), 32),
+		word(o.Nft.Amount),
+	)
+
+	return common.BytesToHash(crypto.Keccak256(
+		orderTypeHash,
+		word(big.NewInt(int64(o.Side))),
+		word(big.NewInt(int64(o.SaleKind))),
+		common.LeftPadBytes(o.Maker.Bytes(), 32),
+		assetHash,
+		word(o.Price),
+		word(new(big.Int).SetUint64(o.Expiry)),
+		word(new(big.Int).SetUint64(o.Salt)),
+	))
+}
+
+func (o *Order) Digest(chainID int64, verifyingContract common.Address) []byte {
+	return crypto.Keccak256(
+		[]byte("\x19\x01"),
+		DomainSeparator(chainID, verifyingContract),
+		o.Hash().Bytes(),
+	)
+}
+
+// Signer recovers the address that signed the typed data of the order.
+func (o *Order) Signer(chainID int64, verifyingContract common.Address, signature string) (common.Address, error) {
+	return recoverSigner(o.Digest(chainID, verifyingContract), signature)
+}
+
+func (o *Order) VerifySignature(chainID int64, verifyingContract common.Address, signature string) error {
+	signer, err := o.Signer(chainID, verifyingContract, signature)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(signer.Hex(), o.Maker.Hex()) {
+		return errors.New("signature does not match maker")
+	}
+
+	return nil
+}
+
+func word(n *big.Int) []byte {
+	if n == nil {
+		return make([]byte, 32)
+	}
+	return common.LeftPadBytes(n.Bytes(), 32)
+}
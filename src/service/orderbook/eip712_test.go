@@ -0,0 +1,81 @@
+package orderbook
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// The vectors are computed with the eth_signTypedData_v4 encoding of
+// go-ethereum's signer and signed by the well known key
+// 0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80.
+const (
+	testChainID   = 11155111
+	testOrderBook = "0xcEE5AA84032D4a53a0F9d2c33F36701c3eAD5895"
+	testMaker     = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+
+	testOrderHash      = "0xa756608dd920fd2c100f06292633ed81fe301fc1b568596189afec9b18567614"
+	testOrderDigest    = "0x00ee3ceafabcc1eea3c8dd40d2350abcd6a4600ab0962ed79e355ebfa9396b90"
+	testOrderSignature = "0xd7e3b716cdb8d53cf95ac4a7054cd80e41d32dd3954d5ff43b4e892639b077bb5939bb6ae86022402ce76b6eafb4f5e2e3b8841918e9c0ff42d624bf307c9c7e1c"
+)
+
+func testOrder() *Order {
+	return &Order{
+		Side:     0,
+		SaleKind: 1,
+		Maker:    common.HexToAddress(testMaker),
+		Nft: Asset{
+			TokenID:    big.NewInt(42),
+			Collection: common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"),
+			Amount:     big.NewInt(1),
+		},
+		Price:  big.NewInt(1e18),
+		Expiry: 1893456000,
+		Salt:   7,
+	}
+}
+
+func TestOrderHash(t *testing.T) {
+	order := testOrder()
+	if got := order.Hash().Hex(); got != testOrderHash {
+		t.Fatalf("hash = %s, want %s", got, testOrderHash)
+	}
+
+	orderBook := common.HexToAddress(testOrderBook)
+	if got := hexutil.Encode(order.Digest(testChainID, orderBook)); got != testOrderDigest {
+		t.Fatalf("digest = %s, want %s", got, testOrderDigest)
+	}
+}
+
+func TestOrderSignature(t *testing.T) {
+	orderBook := common.HexToAddress(testOrderBook)
+
+	if err := testOrder().VerifySignature(testChainID, orderBook, testOrderSignature); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+
+	signer, err := testOrder().Signer(testChainID, orderBook, testOrderSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer != common.HexToAddress(testMaker) {
+		t.Fatalf("signer = %s, want %s", signer.Hex(), testMaker)
+	}
+
+	tampered := testOrder()
+	tampered.Price = big.NewInt(1)
+	if err := tampered.VerifySignature(testChainID, orderBook, testOrderSignature); err == nil {
+		t.Fatal("signature over another price accepted")
+	}
+
+	if err := testOrder().VerifySignature(1, orderBook, testOrderSignature); err == nil {
+		t.Fatal("signature replayed on another chain accepted")
+	}
+
+	other := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	if err := testOrder().VerifySignature(testChainID, other, testOrderSignature); err == nil {
+		t.Fatal("signature bound to another order book accepted")
+	}
+}
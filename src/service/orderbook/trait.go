@@ -0,0 +1,72 @@
+package orderbook
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+var (
+	traitTypeHash = crypto.Keccak256([]byte(
+		"Trait(string key,string value)"))
+	traitCriteriaTypeHash = crypto.Keccak256([]byte(
+		"TraitCriteria(bytes32 orderKey,Trait[] traits)" +
+			"Trait(string key,string value)"))
+)
+
+// Trait is a trait an item must have to fill a trait bid.
+type Trait struct {
+	Key   string
+	Value string
+}
+
+// TraitCriteria is the message a maker signs next to a collection bid to
+// restrict it to the items with all the traits. The order book settles trait
+// bids as collection bids, so the criteria are bound to the bid by its order
+// key rather than signed as part of the order. The signature only proves the
+// maker asked for the traits, nothing stops a fill of the bid with another
+// item of the collection on chain.
+type TraitCriteria struct {
+	OrderKey common.Hash
+	Traits   []Trait
+}
+
+func (c *TraitCriteria) Hash() common.Hash {
+	var traits []byte
+	for _, trait := range c.Traits {
+		traits = append(traits, crypto.Keccak256(
+			traitTypeHash,
+			crypto.Keccak256([]byte(trait.Key)),
+			crypto.Keccak256([]byte(trait.Value)),
+		)...)
+	}
+
+	return common.BytesToHash(crypto.Keccak256(
+		traitCriteriaTypeHash,
+		c.OrderKey.Bytes(),
+		crypto.Keccak256(traits),
+	))
+}
+
+func (c *TraitCriteria) Digest(chainID int64, verifyingContract common.Address) []byte {
+	return crypto.Keccak256(
+		[]byte("\x19\x01"),
+		DomainSeparator(chainID, verifyingContract),
+		c.Hash().Bytes(),
+	)
+}
+
+func (c *TraitCriteria) VerifySignature(chainID int64, verifyingContract common.Address, maker common.Address, signature string) error {
+	signer, err := recoverSigner(c.Digest(chainID, verifyingContract), signature)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(signer.Hex(), maker.Hex()) {
+		return errors.New("trait signature does not match maker")
+	}
+
+	return nil
+}
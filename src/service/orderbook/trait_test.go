@@ -0,0 +1,44 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestTraitCriteriaSignature(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	maker := crypto.PubkeyToAddress(key.PublicKey)
+	orderBook := common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	criteria := TraitCriteria{
+		OrderKey: common.HexToHash("0x01"),
+		Traits:   []Trait{{Key: "Background", Value: "Blue"}},
+	}
+	sig, err := crypto.Sign(criteria.Digest(1, orderBook), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := hexutil.Encode(sig)
+
+	if err := criteria.VerifySignature(1, orderBook, maker, signature); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+
+	tampered := criteria
+	tampered.Traits = []Trait{{Key: "Background", Value: "Gold"}}
+	if err := tampered.VerifySignature(1, orderBook, maker, signature); err == nil {
+		t.Fatal("signature accepted for other traits")
+	}
+
+	rebound := criteria
+	rebound.OrderKey = common.HexToHash("0x02")
+	if err := rebound.VerifySignature(1, orderBook, maker, signature); err == nil {
+		t.Fatal("signature accepted for another order")
+	}
+}
@@ -0,0 +1,166 @@
+package orderexpiry
+
+import (
+	"context"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/mq"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/ordermanager"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultInterval  = time.Minute
+	DefaultBatchSize = 500
+	// MaxBatchesPerRun bounds the work done in one tick so a large backlog
+	// does not block the next run.
+	MaxBatchesPerRun = 20
+)
+
+// Sweeper moves past-due orders of a chain to expired and refreshes the
+// listing state of the affected collections. The expired orders reach the
+// stream through their update time and the notifier through the order
+// expired events.
+type Sweeper struct {
+	dao       *dao.Dao
+	store     *xkv.Store
+	project   string
+	chain     string
+	batchSize int
+}
+
+func NewSweeper(d *dao.Dao, store *xkv.Store, project string, chain *config.ChainSupported, batchSize int) *Sweeper {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &Sweeper{
+		dao:       d,
+		store:     store,
+		project:   project,
+		chain:     chain.Name,
+		batchSize: batchSize,
+	}
+}
+
+func (s *Sweeper) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Sweep(ctx); err != nil {
+				xzap.WithContext(ctx).Error("failed on sweep expired orders", zap.String("chain", s.chain), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *Sweeper) Sweep(ctx context.Context) error {
+	collections := make(map[string]bool)
+	defer s.refreshCollections(ctx, collections)
+
+	now := time.Now().Unix()
+	for i := 0; i < MaxBatchesPerRun; i++ {
+		orders, err := s.dao.QueryExpiredOrders(ctx, s.chain, now, s.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(orders) == 0 {
+			return nil
+		}
+
+		var orderIDs []string
+		for _, order := range orders {
+			orderIDs = append(orderIDs, order.OrderID)
+		}
+
+		// orders filled or cancelled since the query are left out
+		expiredIDs, err := s.dao.ExpireOrders(ctx, s.chain, orderIDs)
+		if err != nil {
+			return err
+		}
+		expired := make(map[string]bool)
+		for _, orderID := range expiredIDs {
+			expired[orderID] = true
+		}
+
+		var events []mq.OrderExpiredEvent
+		for _, order := range orders {
+			if !expired[order.OrderID] {
+				continue
+			}
+			events = append(events, mq.OrderExpiredEvent{
+				OrderID:           order.OrderID,
+				OrderType:         order.OrderType,
+				CollectionAddress: order.CollectionAddress,
+				TokenID:           order.TokenId,
+				Maker:             order.Maker,
+				Price:             order.Price,
+				ExpireTime:        order.ExpireTime,
+			})
+			if order.OrderType == multi.ListingOrder {
+				collections[order.CollectionAddress] = true
+			}
+		}
+
+		if err := s.dao.DeleteOrderValidations(ctx, s.chain, expiredIDs); err != nil {
+			xzap.WithContext(ctx).Error("failed on delete order validations", zap.Error(err))
+		}
+		if err := mq.AddOrderExpiredEvents(s.store, s.project, s.chain, events); err != nil {
+			xzap.WithContext(ctx).Error("failed on publish order expired events", zap.Error(err))
+		}
+
+		if len(orders) < s.batchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (s *Sweeper) refreshCollections(ctx context.Context, collections map[string]bool) {
+	for collectionAddr := range collections {
+		listed, err := s.dao.QueryListedAmount(ctx, s.chain, collectionAddr)
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on get listed count", zap.Error(err))
+		} else if err := s.dao.CacheCollectionsListed(ctx, s.chain, collectionAddr, int(listed)); err != nil {
+			xzap.WithContext(ctx).Error("failed on cache collection listed", zap.Error(err))
+		}
+
+		collection, err := s.dao.QueryCollectionInfo(ctx, s.chain, collectionAddr)
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on get collection info", zap.Error(err))
+			continue
+		}
+
+		floorPrice, err := s.dao.QueryFloorPrice(ctx, s.chain, collectionAddr)
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on get floor price", zap.Error(err))
+			continue
+		}
+
+		if floorPrice.Equal(collection.FloorPrice) {
+			continue
+		}
+
+		if err := ordermanager.AddUpdatePriceEvent(s.store, &ordermanager.TradeEvent{
+			EventType:      ordermanager.UpdateCollection,
+			CollectionAddr: collectionAddr,
+			Price:          floorPrice,
+		}, s.chain); err != nil {
+			xzap.WithContext(ctx).Error("failed on update floor price", zap.Error(err))
+		}
+	}
+}
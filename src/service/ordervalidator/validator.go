@@ -0,0 +1,593 @@
+package ordervalidator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/contract"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/chain/nftchainservice"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/ordermanager"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultInterval  = 5 * time.Minute
+	DefaultBatchSize = 200
+)
+
+const (
+	ReasonNotFound              = "order not found"
+	ReasonNotActive             = "order is not active"
+	ReasonExpired               = "order expired"
+	ReasonNotOwner              = "maker no longer owns the item"
+	ReasonNotApproved           = "item is not approved to the marketplace"
+	ReasonInsufficientBalance   = "bidder balance is insufficient"
+	ReasonInsufficientAllowance = "bidder allowance is insufficient"
+	ReasonCheckFailed           = "failed on read chain state"
+	ReasonInvalidQuantity       = "quantity exceeds the supply of an ERC-721 token"
+)
+
+// Validator checks active orders against chain state and disables the ones
+// that can no longer be filled.
+type Validator struct {
+	dao       *dao.Dao
+	store     *xkv.Store
+	nodeSrvs  map[int64]*nftchainservice.Service
+	chains    []*config.ChainSupported
+	batchSize int
+
+	cursors        map[string]int64
+	invalidCursors map[string]int64
+}
+
+func New(d *dao.Dao, store *xkv.Store, nodeSrvs map[int64]*nftchainservice.Service, chains []*config.ChainSupported, batchSize int) *Validator {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &Validator{
+		dao:            d,
+		store:          store,
+		nodeSrvs:       nodeSrvs,
+		chains:         chains,
+		batchSize:      batchSize,
+		cursors:        make(map[string]int64),
+		invalidCursors: make(map[string]int64),
+	}
+}
+
+func (v *Validator) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	// a sweep leaves half the interval idle, shared by the chains
+	var budget time.Duration
+	if len(v.chains) > 0 {
+		budget = interval / time.Duration(2*len(v.chains))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, chain := range v.chains {
+				if err := v.Sweep(ctx, chain, budget); err != nil {
+					xzap.WithContext(ctx).Error("failed on sweep orders", zap.String("chain", chain.Name), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// Sweep validates the active orders and re-checks the invalidated ones, so
+// orders become active again once fixed on chain. It goes through them batch
+// by batch until both cursors wrap or budget is spent, the next sweep resumes
+// from the cursors.
+func (v *Validator) Sweep(ctx context.Context, chain *config.ChainSupported, budget time.Duration) error {
+	deadline := time.Now().Add(budget)
+	activeDone, invalidDone := false, false
+	for !activeDone || !invalidDone {
+		var orders []multi.Order
+		if !activeDone {
+			activeOrders, err := v.dao.QueryActiveOrders(ctx, chain.Name, v.cursors[chain.Name], v.batchSize)
+			if err != nil {
+				return err
+			}
+			if len(activeOrders) < v.batchSize {
+				v.cursors[chain.Name] = 0
+				activeDone = true
+			} else {
+				v.cursors[chain.Name] = activeOrders[len(activeOrders)-1].Id
+			}
+			orders = append(orders, activeOrders...)
+		}
+
+		if !invalidDone {
+			invalidOrders, err := v.dao.QueryInvalidatedOrders(ctx, chain.Name, v.invalidCursors[chain.Name], v.batchSize)
+			if err != nil {
+				return err
+			}
+			if len(invalidOrders) < v.batchSize {
+				v.invalidCursors[chain.Name] = 0
+				invalidDone = true
+			} else {
+				v.invalidCursors[chain.Name] = invalidOrders[len(invalidOrders)-1].Id
+			}
+			orders = append(orders, invalidOrders...)
+		}
+
+		if _, err := v.validateAndApply(ctx, chain, orders); err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil || !time.Now().Before(deadline) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// ValidateOrders checks the given orders and updates their status.
+func (v *Validator) ValidateOrders(ctx context.Context, chainID int, orderIDs []string) ([]types.OrderValidity, error) {
+	chain := v.Chain(chainID)
+	if chain == nil {
+		return nil, errors.New(fmt.Sprintf("chain %d not supported", chainID))
+	}
+
+	orders, err := v.dao.QueryOrdersByIDs(ctx, chain.Name, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	checked := make(map[string]bool)
+	var validOrders []multi.Order
+	for _, order := range orders {
+		if order.OrderStatus == multi.OrderStatusActive || order.OrderStatus == multi.OrderStatusInactive {
+			validOrders = append(validOrders, order)
+		}
+		checked[order.OrderID] = true
+	}
+
+	results, err := v.validateAndApply(ctx, chain, validOrders)
+	if err != nil {
+		return nil, err
+	}
+	resultMap := make(map[string]types.OrderValidity)
+	for _, result := range results {
+		resultMap[result.OrderID] = result
+	}
+
+	var validities []types.OrderValidity
+	for _, orderID := range orderIDs {
+		if result, ok := resultMap[orderID]; ok {
+			validities = append(validities, result)
+		} else if checked[orderID] {
+			validities = append(validities, types.OrderValidity{OrderID: orderID, Reason: ReasonNotActive})
+		} else {
+			validities = append(validities, types.OrderValidity{OrderID: orderID, Reason: ReasonNotFound})
+		}
+	}
+
+	return validities, nil
+}
+
+func (v *Validator) Chain(chainID int) *config.ChainSupported {
+	for _, supported := range v.chains {
+		if supported.ChainID == chainID {
+			return supported
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validateAndApply(ctx context.Context, chain *config.ChainSupported, orders []multi.Order) ([]types.OrderValidity, error) {
+	if len(orders) == 0 {
+		return nil, nil
+	}
+
+	results := v.Validate(ctx, chain, orders)
+	if err := v.apply(ctx, chain, orders, results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Validate checks every order without changing its status.
+func (v *Validator) Validate(ctx context.Context, chain *config.ChainSupported, orders []multi.Order) []types.OrderValidity {
+	checker := &checker{
+		ctx:         ctx,
+		dao:         v.dao,
+		chain:       chain.Name,
+		vault:       chain.VaultAddress,
+		owners:      make(map[string]common.Address),
+		approvals:   make(map[string]bool),
+		funds:       make(map[string]*big.Int),
+		standards:   v.tokenStandards(ctx, chain.Name, orders),
+		commitments: make(map[string]*makerCommitments),
+	}
+	if nodeSrv, ok := v.nodeSrvs[int64(chain.ChainID)]; ok && nodeSrv != nil {
+		checker.client = nodeSrv
+	}
+
+	now := time.Now().Unix()
+	var results []types.OrderValidity
+	for _, order := range orders {
+		result := types.OrderValidity{OrderID: order.OrderID, Valid: true}
+		if order.ExpireTime <= now {
+			result.Valid = false
+			result.Reason = ReasonExpired
+		} else if reason, err := checker.check(order); err != nil {
+			xzap.WithContext(ctx).Warn("failed on check order", zap.String("order_id", order.OrderID), zap.Error(err))
+			result.Valid = false
+			result.Unknown = true
+			result.Reason = ReasonCheckFailed
+		} else if reason != "" {
+			result.Valid = false
+			result.Reason = reason
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// tokenStandards returns the token standard of the collections of listings
+// and item bids.
+func (v *Validator) tokenStandards(ctx context.Context, chain string, orders []multi.Order) map[string]int64 {
+	var collectionAddrs []string
+	for _, order := range orders {
+		if order.OrderType == multi.ListingOrder || order.OrderType == multi.ItemBidOrder {
+			collectionAddrs = append(collectionAddrs, order.CollectionAddress)
+		}
+	}
+
+	standards := make(map[string]int64)
+	if len(collectionAddrs) == 0 {
+		return standards
+	}
+
+	collections, err := v.dao.QueryCollectionsInfo(ctx, chain, collectionAddrs)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on get collections info", zap.Error(err))
+		return standards
+	}
+	for _, collection := range collections {
+		standards[strings.ToLower(collection.Address)] = collection.TokenStandard
+	}
+	return standards
+}
+
+func (v *Validator) apply(ctx context.Context, chain *config.ChainSupported, orders []multi.Order, results []types.OrderValidity) error {
+	orderMap := make(map[string]multi.Order)
+	for _, order := range orders {
+		orderMap[order.OrderID] = order
+	}
+
+	// only inactive orders the validator disabled are checked again, others
+	// may have been cancelled or disabled by the order sync
+	var inactive []string
+	for _, order := range orders {
+		if order.OrderStatus == multi.OrderStatusInactive {
+			inactive = append(inactive, order.OrderID)
+		}
+	}
+	validated, err := v.dao.QueryValidatedOrderIDs(ctx, chain.Name, inactive)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	var expired, invalid, reactivated []string
+	var validations []dao.OrderValidation
+	changedCollections := make(map[string]bool)
+	for _, result := range results {
+		order := orderMap[result.OrderID]
+		switch {
+		case result.Unknown:
+			continue
+		case result.Reason == ReasonExpired:
+			// the expiry sweeper moves the order to expired and notifies its
+			// maker, only its validation is dropped
+			expired = append(expired, order.OrderID)
+			continue
+		case order.OrderStatus == multi.OrderStatusInactive && !validated[order.OrderID]:
+			continue
+		case !result.Valid:
+			if order.OrderStatus == multi.OrderStatusActive {
+				invalid = append(invalid, order.OrderID)
+			}
+			validations = append(validations, dao.OrderValidation{
+				OrderID:           order.OrderID,
+				CollectionAddress: order.CollectionAddress,
+				Reason:            result.Reason,
+				CheckTime:         now,
+				CreateTime:        now,
+				UpdateTime:        now,
+			})
+		case result.Reason == "" && order.OrderStatus == multi.OrderStatusInactive:
+			reactivated = append(reactivated, order.OrderID)
+		default:
+			continue
+		}
+
+		if order.OrderType == multi.ListingOrder {
+			changedCollections[order.CollectionAddress] = true
+		}
+	}
+
+	if err := v.dao.SaveOrderValidations(ctx, chain.Name, validations); err != nil {
+		return err
+	}
+	if err := v.dao.UpdateOrdersStatus(ctx, chain.Name, invalid, multi.OrderStatusInactive); err != nil {
+		return err
+	}
+	if err := v.dao.ReactivateOrders(ctx, chain.Name, reactivated); err != nil {
+		return err
+	}
+	if err := v.dao.DeleteOrderValidations(ctx, chain.Name, append(expired, reactivated...)); err != nil {
+		return err
+	}
+
+	for collectionAddr := range changedCollections {
+		floorPrice, err := v.dao.QueryFloorPrice(ctx, chain.Name, collectionAddr)
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on get floor price", zap.Error(err))
+			continue
+		}
+
+		if err := ordermanager.AddUpdatePriceEvent(v.store, &ordermanager.TradeEvent{
+			EventType:      ordermanager.UpdateCollection,
+			CollectionAddr: collectionAddr,
+			Price:          floorPrice,
+		}, chain.Name); err != nil {
+			xzap.WithContext(ctx).Error("failed on update floor price", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+type checker struct {
+	ctx    context.Context
+	dao    *dao.Dao
+	chain  string
+	client *nftchainservice.Service
+	vault  string
+
+	owners      map[string]common.Address
+	approvals   map[string]bool
+	funds       map[string]*big.Int
+	standards   map[string]int64
+	commitments map[string]*makerCommitments
+}
+
+// commitment is what bids of a maker commit of its balance, and of its
+// allowance to the vault.
+type commitment struct {
+	balance   *big.Int
+	allowance *big.Int
+}
+
+// makerCommitments are the commitments of the active bids of a maker in a
+// currency, oldest first, up to and including each bid.
+type makerCommitments struct {
+	byOrder map[string]commitment
+	total   commitment
+}
+
+func (c *checker) check(order multi.Order) (string, error) {
+	if c.client == nil {
+		return "", errors.New("chain node not configured")
+	}
+
+	// only ERC-1155 tokens are listed or bid on by quantity, collections
+	// that are not indexed yet are taken as ERC-721
+	if (order.OrderType == multi.ListingOrder || order.OrderType == multi.ItemBidOrder) &&
+		order.QuantityRemaining > 1 &&
+		c.standards[strings.ToLower(order.CollectionAddress)] != dao.TokenStandardERC1155 {
+		return ReasonInvalidQuantity, nil
+	}
+
+	if order.OrderType == multi.ListingOrder {
+		return c.checkListing(order)
+	}
+	return c.checkBid(order)
+}
+
+func (c *checker) checkListing(order multi.Order) (string, error) {
+	if c.standards[strings.ToLower(order.CollectionAddress)] == dao.TokenStandardERC1155 {
+		return c.checkMultiTokenListing(order)
+	}
+
+	tokenKey := strings.ToLower(order.CollectionAddress + ":" + order.TokenId)
+	owner, ok := c.owners[tokenKey]
+	if !ok {
+		var err error
+		owner, err = contract.OwnerOf(c.ctx, c.client.ChainClient, order.CollectionAddress, order.TokenId)
+		if err != nil {
+			return "", err
+		}
+		c.owners[tokenKey] = owner
+	}
+
+	if owner != common.HexToAddress(order.Maker) {
+		return ReasonNotOwner, nil
+	}
+
+	// only orders of our own order book are settled through the vault
+	if c.vault == "" || order.MarketplaceId != multi.OrderBookDex {
+		return "", nil
+	}
+
+	approvalKey := strings.ToLower(order.CollectionAddress + ":" + order.Maker)
+	approved, ok := c.approvals[approvalKey]
+	if !ok {
+		var err error
+		approved, err = contract.IsApprovedForAll(c.ctx, c.client.ChainClient, order.CollectionAddress, order.Maker, c.vault)
+		if err != nil {
+			return "", err
+		}
+		c.approvals[approvalKey] = approved
+	}
+
+	if !approved {
+		operator, err := contract.GetApproved(c.ctx, c.client.ChainClient, order.CollectionAddress, order.TokenId)
+		if err != nil {
+			return "", err
+		}
+		if operator != common.HexToAddress(c.vault) {
+			return ReasonNotApproved, nil
+		}
+	}
+
+	return "", nil
+}
+
+// checkMultiTokenListing checks an ERC-1155 listing: the maker must hold the
+// remaining quantity, and have approved the vault for all its tokens. The read
+// balance is kept as the maker's balance of the token.
+func (c *checker) checkMultiTokenListing(order multi.Order) (string, error) {
+	balance, err := contract.BalanceOf1155(c.ctx, c.client.ChainClient, order.CollectionAddress, order.Maker, order.TokenId)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.dao.SaveItemBalances(c.ctx, c.chain, []dao.ItemBalance{{
+		CollectionAddress: strings.ToLower(order.CollectionAddress),
+		TokenId:           order.TokenId,
+		Owner:             order.Maker,
+		Balance:           balance.Int64(),
+	}}); err != nil {
+		xzap.WithContext(c.ctx).Error("failed on save item balance", zap.String("order_id", order.OrderID), zap.Error(err))
+	}
+
+	if balance.Cmp(big.NewInt(order.QuantityRemaining)) < 0 {
+		return ReasonNotOwner, nil
+	}
+
+	if c.vault == "" || order.MarketplaceId != multi.OrderBookDex {
+		return "", nil
+	}
+
+	approvalKey := strings.ToLower(order.CollectionAddress + ":" + order.Maker)
+	approved, ok := c.approvals[approvalKey]
+	if !ok {
+		approved, err = contract.IsApprovedForAll(c.ctx, c.client.ChainClient, order.CollectionAddress, order.Maker, c.vault)
+		if err != nil {
+			return "", err
+		}
+		c.approvals[approvalKey] = approved
+	}
+	if !approved {
+		return ReasonNotApproved, nil
+	}
+
+	return "", nil
+}
+
+func (c *checker) checkBid(order multi.Order) (string, error) {
+	// bids in the native currency are escrowed by the vault when created
+	if order.CurrencyAddress == "" || common.HexToAddress(order.CurrencyAddress) == (common.Address{}) {
+		return "", nil
+	}
+
+	// a maker's bids draw on the same balance, the bid is funded when it is
+	// covered together with the maker's older bids in the currency
+	required, err := c.committed(order)
+	if err != nil {
+		return "", err
+	}
+
+	balanceKey := strings.ToLower("balance:" + order.CurrencyAddress + ":" + order.Maker)
+	balance, ok := c.funds[balanceKey]
+	if !ok {
+		balance, err = contract.BalanceOf(c.ctx, c.client.ChainClient, order.CurrencyAddress, order.Maker)
+		if err != nil {
+			return "", err
+		}
+		c.funds[balanceKey] = balance
+	}
+	if balance.Cmp(required.balance) < 0 {
+		return ReasonInsufficientBalance, nil
+	}
+
+	if c.vault == "" || order.MarketplaceId != multi.OrderBookDex {
+		return "", nil
+	}
+
+	allowanceKey := strings.ToLower("allowance:" + order.CurrencyAddress + ":" + order.Maker)
+	allowance, ok := c.funds[allowanceKey]
+	if !ok {
+		allowance, err = contract.Allowance(c.ctx, c.client.ChainClient, order.CurrencyAddress, order.Maker, c.vault)
+		if err != nil {
+			return "", err
+		}
+		c.funds[allowanceKey] = allowance
+	}
+	if allowance.Cmp(required.allowance) < 0 {
+		return ReasonInsufficientAllowance, nil
+	}
+
+	return "", nil
+}
+
+// committed returns what the bid commits together with the older active bids
+// of its maker in its currency. A bid that is not active, re-checked after
+// it was disabled, comes after all active ones.
+func (c *checker) committed(order multi.Order) (commitment, error) {
+	key := strings.ToLower(order.CurrencyAddress + ":" + order.Maker)
+	commitments, ok := c.commitments[key]
+	if !ok {
+		bids, err := c.dao.QueryMakerBids(c.ctx, c.chain, order.Maker, order.CurrencyAddress)
+		if err != nil {
+			return commitment{}, err
+		}
+
+		commitments = &makerCommitments{
+			byOrder: make(map[string]commitment),
+			total:   commitment{balance: new(big.Int), allowance: new(big.Int)},
+		}
+		for _, bid := range bids {
+			commitments.total = commitments.total.add(bid)
+			commitments.byOrder[bid.OrderID] = commitments.total
+		}
+		c.commitments[key] = commitments
+	}
+
+	if committed, ok := commitments.byOrder[order.OrderID]; ok {
+		return committed, nil
+	}
+	return commitments.total.add(order), nil
+}
+
+// add returns the commitment with the bid added. Prices are stored in ether
+// units, erc20 bid currencies use 18 decimals.
+func (c commitment) add(bid multi.Order) commitment {
+	amount := bid.Price.Mul(decimal.NewFromInt(bid.QuantityRemaining)).Shift(18).BigInt()
+	result := commitment{
+		balance:   new(big.Int).Add(c.balance, amount),
+		allowance: c.allowance,
+	}
+	if bid.MarketplaceId == multi.OrderBookDex {
+		result.allowance = new(big.Int).Add(c.allowance, amount)
+	}
+	return result
+}
@@ -0,0 +1,32 @@
+package ordervalidator
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/shopspring/decimal"
+)
+
+func TestCommitmentAdd(t *testing.T) {
+	total := commitment{balance: new(big.Int), allowance: new(big.Int)}
+	total = total.add(multi.Order{
+		MarketplaceId:     multi.OrderBookDex,
+		Price:             decimal.RequireFromString("0.5"),
+		QuantityRemaining: 2,
+	})
+	total = total.add(multi.Order{
+		MarketplaceId:     multi.OrderBookDex + 1,
+		Price:             decimal.RequireFromString("1"),
+		QuantityRemaining: 1,
+	})
+
+	ether := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	if want := new(big.Int).Mul(big.NewInt(2), ether); total.balance.Cmp(want) != 0 {
+		t.Fatalf("balance = %s, want %s", total.balance, want)
+	}
+	// only bids of our own order book are settled through the vault
+	if total.allowance.Cmp(ether) != 0 {
+		t.Fatalf("allowance = %s, want %s", total.allowance, ether)
+	}
+}
@@ -0,0 +1,321 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/leader"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultInterval     = time.Hour
+	DefaultBackfillDays = 30
+	BatchSize           = 200
+	Day                 = int64(24 * 60 * 60)
+
+	SnapshotLockSeconds = 60 * 60
+	SnapshotDoneSeconds = 3 * 24 * 60 * 60
+)
+
+const (
+	CacheSnapshotLockKey = "cache:es:portfolio:snapshot:%d:lock"
+	CacheSnapshotDoneKey = "cache:es:portfolio:snapshot:%d:done"
+)
+
+// Snapshotter records once a day the value of the collections every
+// registered user holds, at the collection floor price, under the last closed
+// day. Users without any
+// snapshot get their previous days backfilled from the floor price history.
+type Snapshotter struct {
+	dao          *dao.Dao
+	store        *xkv.Store
+	chains       []*config.ChainSupported
+	backfillDays int
+}
+
+type floorHistory struct {
+	prices []multi.CollectionFloorPrice
+}
+
+func NewSnapshotter(d *dao.Dao, store *xkv.Store, chains []*config.ChainSupported, backfillDays int) *Snapshotter {
+	if backfillDays <= 0 {
+		backfillDays = DefaultBackfillDays
+	}
+
+	return &Snapshotter{
+		dao:          d,
+		store:        store,
+		chains:       chains,
+		backfillDays: backfillDays,
+	}
+}
+
+// DayStart returns the start of the UTC day of a unix time.
+func DayStart(t int64) int64 {
+	return t - t%Day
+}
+
+// ClosedDay returns the start of the last UTC day that ended before a unix
+// time. A day is snapshotted once it is over, so its value is not taken from
+// a partial day.
+func ClosedDay(t int64) int64 {
+	return DayStart(t) - Day
+}
+
+func (s *Snapshotter) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Snapshot(ctx, ClosedDay(time.Now().Unix())); err != nil {
+				xzap.WithContext(ctx).Error("failed on snapshot portfolios", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Snapshot records the portfolios of the given day, once across instances.
+// The day is locked as well, so a leader that lost its lock and has not
+// stopped yet does not snapshot it along with the next one.
+// A user whose portfolio fails is logged and skipped, and the day is retried
+// by the next run.
+func (s *Snapshotter) Snapshot(ctx context.Context, day int64) error {
+	doneKey := fmt.Sprintf(CacheSnapshotDoneKey, day)
+	done, err := s.store.Get(doneKey)
+	if err != nil {
+		return errors.Wrap(err, "failed on get snapshot status")
+	}
+	if done != "" {
+		return nil
+	}
+
+	lock := leader.NewLock(s.store, fmt.Sprintf(CacheSnapshotLockKey, day), SnapshotLockSeconds)
+	ok, err := lock.Lead()
+	if err != nil {
+		return errors.Wrap(err, "failed on acquire snapshot lock")
+	}
+	if !ok {
+		return nil
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			xzap.WithContext(ctx).Error("failed on release snapshot lock", zap.Error(err))
+		}
+	}()
+
+	hidden, err := s.hiddenCollections(ctx)
+	if err != nil {
+		return err
+	}
+
+	histories := make(map[string]*floorHistory)
+	failed := 0
+	cursor := int64(0)
+	for {
+		users, err := s.dao.QueryUsersUpdatedSince(ctx, 0, cursor, BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if err := s.snapshotUser(ctx, strings.ToLower(user.Address), day, hidden, histories); err != nil {
+				xzap.WithContext(ctx).Error("failed on snapshot portfolio", zap.String("user", user.Address),
+					zap.Int64("day", day), zap.Error(err))
+				failed++
+			}
+		}
+
+		if len(users) < BatchSize {
+			break
+		}
+		cursor = users[len(users)-1].Id
+	}
+	// the day is left open so the next run retries it, snapshots are upserted
+	if failed > 0 {
+		return errors.Errorf("failed on snapshot %d portfolios", failed)
+	}
+
+	if _, err := s.store.SetnxEx(doneKey, "1", SnapshotDoneSeconds); err != nil {
+		return errors.Wrap(err, "failed on set snapshot status")
+	}
+	return nil
+}
+
+// hiddenCollections returns the hidden collections of the snapshotted chains,
+// keyed by collectionKey.
+func (s *Snapshotter) hiddenCollections(ctx context.Context) (map[string]bool, error) {
+	var chainIDs []int
+	for _, chain := range s.chains {
+		chainIDs = append(chainIDs, chain.ChainID)
+	}
+
+	curations, err := s.dao.QueryCollectionCurations(ctx, chainIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on query collection curations")
+	}
+
+	hidden := make(map[string]bool)
+	for _, curation := range curations {
+		if curation.IsHidden {
+			hidden[collectionKey(curation.ChainID, curation.CollectionAddress)] = true
+		}
+	}
+	return hidden, nil
+}
+
+func collectionKey(chainID int, collectionAddr string) string {
+	return fmt.Sprintf("%d:%s", chainID, strings.ToLower(collectionAddr))
+}
+
+func (s *Snapshotter) snapshotUser(ctx context.Context, userAddr string, day int64, hidden map[string]bool, histories map[string]*floorHistory) error {
+	var chainIDs []int
+	var chainNames []string
+	for _, chain := range s.chains {
+		chainIDs = append(chainIDs, chain.ChainID)
+		chainNames = append(chainNames, chain.Name)
+	}
+
+	all, err := s.dao.QueryMultiChainUserCollectionInfos(ctx, chainIDs, chainNames, []string{userAddr})
+	if err != nil {
+		return err
+	}
+	var holdings []types.UserCollections
+	for _, holding := range all {
+		if !hidden[collectionKey(holding.ChainID, holding.Address)] {
+			holdings = append(holdings, holding)
+		}
+	}
+	if len(holdings) == 0 {
+		return nil
+	}
+
+	snapshotted, err := s.dao.HasPortfolioSnapshots(ctx, userAddr)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	var snapshots []dao.PortfolioSnapshot
+	for _, holding := range holdings {
+		snapshots = append(snapshots, dao.PortfolioSnapshot{
+			UserAddress:       userAddr,
+			ChainID:           holding.ChainID,
+			CollectionAddress: strings.ToLower(holding.Address),
+			Day:               day,
+			ItemCount:         holding.ItemCount,
+			FloorPrice:        holding.FloorPrice,
+			Value:             holding.FloorPrice.Mul(decimal.NewFromInt(holding.ItemCount)),
+			CreateTime:        now,
+			UpdateTime:        now,
+		})
+	}
+	if err := s.dao.SavePortfolioSnapshots(ctx, snapshots, false); err != nil {
+		return err
+	}
+
+	if snapshotted {
+		return nil
+	}
+	return s.backfill(ctx, userAddr, day, holdings, histories)
+}
+
+// backfill values the current holdings of a user on the days before its first
+// snapshot, at the last floor price recorded before the end of each day.
+// Past transfers are unknown, so the holdings are assumed unchanged.
+func (s *Snapshotter) backfill(ctx context.Context, userAddr string, day int64, holdings []types.UserCollections,
+	histories map[string]*floorHistory) error {
+	from := day - int64(s.backfillDays)*Day
+
+	now := time.Now().Unix()
+	var snapshots []dao.PortfolioSnapshot
+	for _, holding := range holdings {
+		chain := s.chainName(holding.ChainID)
+		if chain == "" {
+			continue
+		}
+
+		history, err := s.floorHistory(ctx, chain, strings.ToLower(holding.Address), from, day, histories)
+		if err != nil {
+			return err
+		}
+
+		i := 0
+		var price decimal.Decimal
+		found := false
+		for d := from; d < day; d += Day {
+			for i < len(history.prices) && history.prices[i].EventTime < d+Day {
+				price = history.prices[i].Price
+				found = true
+				i++
+			}
+			if !found {
+				continue
+			}
+
+			snapshots = append(snapshots, dao.PortfolioSnapshot{
+				UserAddress:       userAddr,
+				ChainID:           holding.ChainID,
+				CollectionAddress: strings.ToLower(holding.Address),
+				Day:               d,
+				ItemCount:         holding.ItemCount,
+				FloorPrice:        price,
+				Value:             price.Mul(decimal.NewFromInt(holding.ItemCount)),
+				IsBackfill:        true,
+				CreateTime:        now,
+				UpdateTime:        now,
+			})
+		}
+	}
+
+	return s.dao.SavePortfolioSnapshots(ctx, snapshots, true)
+}
+
+// floorHistory loads the floor price history of a collection once per run,
+// as many users hold the same collections.
+func (s *Snapshotter) floorHistory(ctx context.Context, chain, collectionAddr string, from, to int64,
+	histories map[string]*floorHistory) (*floorHistory, error) {
+	key := chain + ":" + collectionAddr
+	if history, ok := histories[key]; ok {
+		return history, nil
+	}
+
+	prices, err := s.dao.QueryFloorPriceHistory(ctx, chain, []string{collectionAddr}, from, to)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(prices, func(i, j int) bool {
+		return prices[i].EventTime < prices[j].EventTime
+	})
+
+	history := &floorHistory{prices: prices}
+	histories[key] = history
+	return history, nil
+}
+
+func (s *Snapshotter) chainName(chainID int) string {
+	for _, chain := range s.chains {
+		if chain.ChainID == chainID {
+			return chain.Name
+		}
+	}
+	return ""
+}
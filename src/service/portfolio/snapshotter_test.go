@@ -0,0 +1,12 @@
+package portfolio
+
+import "testing"
+
+func TestClosedDay(t *testing.T) {
+	const day = 20_000 * Day
+	for _, now := range []int64{day, day + 1, day + Day - 1} {
+		if got := ClosedDay(now); got != day-Day {
+			t.Fatalf("ClosedDay(%d) = %d, want %d", now, got, day-Day)
+		}
+	}
+}
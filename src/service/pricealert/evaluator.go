@@ -0,0 +1,167 @@
+package pricealert
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultInterval = time.Minute
+	BatchSize       = 500
+	VolumePeriod    = "24h"
+)
+
+// Evaluator checks the price alerts of users against the current floor price
+// and 24h volume of their collections and notifies the alerts that fire.
+type Evaluator struct {
+	dao    *dao.Dao
+	chains map[int]string
+}
+
+type collectionStats struct {
+	floorPrice   decimal.Decimal
+	volume       decimal.Decimal
+	volumeChange int
+	err          error
+}
+
+func NewEvaluator(d *dao.Dao, chains []*config.ChainSupported) *Evaluator {
+	chainNames := make(map[int]string)
+	for _, chain := range chains {
+		chainNames[chain.ChainID] = chain.Name
+	}
+
+	return &Evaluator{
+		dao:    d,
+		chains: chainNames,
+	}
+}
+
+func (e *Evaluator) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Evaluate(ctx); err != nil {
+				xzap.WithContext(ctx).Error("failed on evaluate price alerts", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (e *Evaluator) Evaluate(ctx context.Context) error {
+	stats := make(map[string]*collectionStats)
+
+	cursor := int64(0)
+	for {
+		alerts, err := e.dao.QueryActivePriceAlerts(ctx, cursor, BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range alerts {
+			if err := e.evaluate(ctx, &alerts[i], stats); err != nil {
+				return err
+			}
+		}
+
+		if len(alerts) < BatchSize {
+			return nil
+		}
+		cursor = alerts[len(alerts)-1].Id
+	}
+}
+
+func (e *Evaluator) evaluate(ctx context.Context, alert *dao.PriceAlert, stats map[string]*collectionStats) error {
+	chain, ok := e.chains[alert.ChainID]
+	if !ok {
+		return nil
+	}
+
+	key := strings.ToLower(chain + ":" + alert.CollectionAddress)
+	stat, ok := stats[key]
+	if !ok {
+		stat = e.collectionStats(ctx, chain, alert.CollectionAddress)
+		stats[key] = stat
+	}
+	if stat.err != nil {
+		xzap.WithContext(ctx).Error("failed on get collection stats", zap.String("collection", alert.CollectionAddress), zap.Error(stat.err))
+		return nil
+	}
+
+	var fired bool
+	value := stat.floorPrice
+	switch alert.AlertType {
+	case dao.PriceAlertFloorBelow:
+		// a zero floor means nothing is listed
+		fired = stat.floorPrice.IsPositive() && stat.floorPrice.LessThanOrEqual(alert.Threshold)
+	case dao.PriceAlertFloorAbove:
+		fired = stat.floorPrice.GreaterThanOrEqual(alert.Threshold)
+	case dao.PriceAlertVolumeChange:
+		value = stat.volume
+		fired = decimal.NewFromInt(int64(stat.volumeChange)).Abs().GreaterThanOrEqual(alert.Threshold)
+	}
+
+	if fired == alert.IsTriggered {
+		return nil
+	}
+
+	if fired {
+		now := time.Now().UnixMilli()
+		if err := e.dao.CreateNotifications(ctx, []dao.Notification{{
+			UserAddress:       alert.UserAddress,
+			ChainID:           alert.ChainID,
+			NotificationType:  dao.NotificationPriceAlert,
+			CollectionAddress: alert.CollectionAddress,
+			Price:             value,
+			Detail:            detail(alert, stat),
+			DedupeKey:         fmt.Sprintf("%s:%d:%d", dao.NotificationPriceAlert, alert.Id, alert.TriggerCount+1),
+			EventTime:         now,
+			CreateTime:        now,
+			UpdateTime:        now,
+		}}); err != nil {
+			return err
+		}
+	}
+
+	return e.dao.SetPriceAlertTriggered(ctx, alert, fired)
+}
+
+func (e *Evaluator) collectionStats(ctx context.Context, chain, collectionAddr string) *collectionStats {
+	floorPrice, err := e.dao.QueryFloorPrice(ctx, chain, collectionAddr)
+	if err != nil {
+		return &collectionStats{err: err}
+	}
+
+	stat := &collectionStats{floorPrice: floorPrice}
+	trade, err := e.dao.GetTradeInfoByCollection(chain, collectionAddr, VolumePeriod)
+	if err != nil {
+		return &collectionStats{err: err}
+	}
+	stat.volume = trade.Volume
+	stat.volumeChange = trade.VolumeChange
+
+	return stat
+}
+
+func detail(alert *dao.PriceAlert, stat *collectionStats) string {
+	if alert.AlertType == dao.PriceAlertVolumeChange {
+		return fmt.Sprintf("%s:%s:%d", alert.AlertType, alert.Threshold.String(), stat.volumeChange)
+	}
+	return fmt.Sprintf("%s:%s", alert.AlertType, alert.Threshold.String())
+}
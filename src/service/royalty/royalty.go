@@ -0,0 +1,135 @@
+package royalty
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBase/chain/nftchainservice"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const CacheRoyaltyRateKey = "cache:es:%s:collection:%s:royalty"
+
+// CacheStoredRoyaltyKey keeps the last rate read from the chain without
+// expiry, as a fallback while the chain node is unavailable.
+const CacheStoredRoyaltyKey = "cache:es:%s:collection:%s:royalty:stored"
+const RoyaltyRateCachePeriod = 24 * 60 * 60
+
+// BasisPoints is used as sale price when reading the royalty, so the returned
+// amount is the rate in basis points.
+const BasisPoints = 10000
+
+// royaltyInfo(uint256,uint256) of EIP-2981
+var royaltyInfoSelector = common.FromHex("0x2a55205a")
+
+// revertErrorCode is the JSON-RPC error code of a call that reverted.
+const revertErrorCode = 3
+
+func GetRoyaltyRateKey(chain, collectionAddr string) string {
+	return fmt.Sprintf(CacheRoyaltyRateKey, strings.ToLower(chain), strings.ToLower(collectionAddr))
+}
+
+func GetStoredRoyaltyKey(chain, collectionAddr string) string {
+	return fmt.Sprintf(CacheStoredRoyaltyKey, strings.ToLower(chain), strings.ToLower(collectionAddr))
+}
+
+// Fetcher reads EIP-2981 royalty rates from the chain node and caches them per
+// collection.
+type Fetcher struct {
+	store    *xkv.Store
+	nodeSrvs map[int64]*nftchainservice.Service
+}
+
+func NewFetcher(store *xkv.Store, nodeSrvs map[int64]*nftchainservice.Service) *Fetcher {
+	return &Fetcher{
+		store:    store,
+		nodeSrvs: nodeSrvs,
+	}
+}
+
+// RoyaltyRate returns the royalty of a collection as a fraction of the sale
+// price. Collections not implementing EIP-2981 have a zero rate, a royalty
+// above the sale price is an error.
+func (f *Fetcher) RoyaltyRate(ctx context.Context, chainID int64, chain, collectionAddr, tokenID string) (decimal.Decimal, error) {
+	key := GetRoyaltyRateKey(chain, collectionAddr)
+	cached, err := f.store.Get(key)
+	if err == nil && cached != "" {
+		if bps, err := decimal.NewFromString(cached); err == nil {
+			return bps.Div(decimal.NewFromInt(BasisPoints)), nil
+		}
+	}
+
+	bps, err := f.fetchRoyaltyBps(ctx, chainID, collectionAddr, tokenID)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	_ = f.store.Setex(key, bps.String(), RoyaltyRateCachePeriod)
+	_ = f.store.Set(GetStoredRoyaltyKey(chain, collectionAddr), bps.String())
+
+	return bps.Div(decimal.NewFromInt(BasisPoints)), nil
+}
+
+// StoredRoyaltyRate returns the last royalty rate of a collection read from
+// the chain, and whether one was ever read.
+func (f *Fetcher) StoredRoyaltyRate(chain, collectionAddr string) (decimal.Decimal, bool) {
+	stored, err := f.store.Get(GetStoredRoyaltyKey(chain, collectionAddr))
+	if err != nil || stored == "" {
+		return decimal.Zero, false
+	}
+
+	bps, err := decimal.NewFromString(stored)
+	if err != nil {
+		return decimal.Zero, false
+	}
+	return bps.Div(decimal.NewFromInt(BasisPoints)), true
+}
+
+func (f *Fetcher) fetchRoyaltyBps(ctx context.Context, chainID int64, collectionAddr, tokenID string) (decimal.Decimal, error) {
+	nodeSrv, ok := f.nodeSrvs[chainID]
+	if !ok {
+		return decimal.Zero, errors.New(fmt.Sprintf("chain %d not supported", chainID))
+	}
+
+	id, ok := new(big.Int).SetString(tokenID, 10)
+	if !ok {
+		id = big.NewInt(0)
+	}
+
+	data := append([]byte{}, royaltyInfoSelector...)
+	data = append(data, common.LeftPadBytes(id.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(BasisPoints).Bytes(), 32)...)
+
+	to := common.HexToAddress(collectionAddr)
+	result, err := nodeSrv.ChainClient.CallContract(ctx, ethereum.CallMsg{
+		To:   &to,
+		Data: data,
+	}, nil)
+	if err != nil {
+		// a collection without royaltyInfo reverts the call
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == revertErrorCode {
+			return decimal.Zero, nil
+		}
+		return decimal.Zero, errors.Wrap(err, "failed on call royalty info")
+	}
+
+	// (address receiver, uint256 royaltyAmount)
+	if len(result) < 64 {
+		return decimal.Zero, nil
+	}
+
+	amount := new(big.Int).SetBytes(result[32:64])
+	if amount.Cmp(big.NewInt(BasisPoints)) > 0 {
+		return decimal.Zero, errors.Errorf("royalty of %s bps exceeds the sale price", amount)
+	}
+
+	return decimal.NewFromBigInt(amount, 0), nil
+}
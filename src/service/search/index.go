@@ -0,0 +1,133 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	KindCollection = "collection"
+	KindItem       = "item"
+	KindUser       = "user"
+)
+
+const (
+	MatchExact  = 3
+	MatchPrefix = 2
+	MatchFuzzy  = 1
+)
+
+const (
+	TitleWeight   = 3
+	KeywordWeight = 1
+)
+
+type Document struct {
+	ID       string   `json:"id"`
+	Kind     string   `json:"kind"`
+	ChainID  int      `json:"chain_id"`
+	Address  string   `json:"address"`
+	TokenID  string   `json:"token_id"`
+	Title    string   `json:"title"`
+	Subtitle string   `json:"subtitle"`
+	ImageURI string   `json:"image_uri"`
+	Keywords []string `json:"keywords"`
+	Boost    float64  `json:"boost"`
+}
+
+// Query is a search of the index. The collections and items of collections
+// whose CollectionDocID is in ExcludeCollections are left out.
+type Query struct {
+	Text               string
+	ChainIDs           []int
+	Kinds              []string
+	Limit              int
+	ExcludeCollections map[string]bool
+}
+
+type Hit struct {
+	Document
+	Score float64 `json:"score"`
+}
+
+// Index is the storage behind the search service. The in-process MemoryIndex
+// is the default, other engines only need to implement this interface.
+type Index interface {
+	Upsert(docs ...*Document) error
+	Delete(ids ...string) error
+	Search(q Query) ([]Hit, error)
+	Suggest(q Query) ([]Hit, error)
+	Count() int
+}
+
+func CollectionDocID(chainID int, collectionAddr string) string {
+	return fmt.Sprintf("%s:%d:%s", KindCollection, chainID, strings.ToLower(collectionAddr))
+}
+
+func ItemDocID(chainID int, collectionAddr, tokenID string) string {
+	return fmt.Sprintf("%s:%d:%s:%s", KindItem, chainID, strings.ToLower(collectionAddr), tokenID)
+}
+
+func UserDocID(address string) string {
+	return fmt.Sprintf("%s:%s", KindUser, strings.ToLower(address))
+}
+
+// Tokenize lower-cases the text and splits it on everything that is not a
+// letter or digit. Hex addresses are kept as a single token.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// maxEdits returns the number of typos tolerated for a query term.
+func maxEdits(term string) int {
+	n := len([]rune(term))
+	if n <= 3 {
+		return 0
+	} else if n <= 6 {
+		return 1
+	}
+	return 2
+}
+
+func levenshtein(a, b []rune, limit int) int {
+	if abs(len(a)-len(b)) > limit {
+		return limit + 1
+	}
+
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
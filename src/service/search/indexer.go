@@ -0,0 +1,417 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const DefaultSyncInterval = 30 * time.Second
+const DirtyItemRetention = 5 * time.Minute
+
+type watermark struct {
+	collections int64
+	items       int64
+	traits      int64
+}
+
+// Indexer keeps the search index in sync with the collection, item, trait
+// and user tables of every supported chain.
+type Indexer struct {
+	index  Index
+	dao    *dao.Dao
+	chains []*config.ChainSupported
+
+	mu                sync.Mutex
+	watermarks        map[string]*watermark
+	usersWatermark    int64
+	profilesWatermark int64
+	collectionNames   map[string]string
+	dirtyItems        map[string]dirtyItem
+}
+
+type dirtyItem struct {
+	chain    string
+	item     types.ItemInfo
+	expireAt time.Time
+}
+
+func NewIndexer(index Index, d *dao.Dao, chains []*config.ChainSupported) *Indexer {
+	return &Indexer{
+		index:           index,
+		dao:             d,
+		chains:          chains,
+		watermarks:      make(map[string]*watermark),
+		collectionNames: make(map[string]string),
+		dirtyItems:      make(map[string]dirtyItem),
+	}
+}
+
+func (i *Indexer) Index() Index {
+	return i.index
+}
+
+func (i *Indexer) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSyncInterval
+	}
+
+	if err := i.Sync(ctx); err != nil {
+		xzap.WithContext(ctx).Error("failed on build search index", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := i.Sync(ctx); err != nil {
+				xzap.WithContext(ctx).Error("failed on sync search index", zap.Error(err))
+			}
+		}
+	}
+}
+
+// MarkItemDirty schedules an item to be re-read on the next syncs. It is
+// called when a metadata refresh is requested, because the refresh itself is
+// processed asynchronously and may not touch the item row.
+func (i *Indexer) MarkItemDirty(chain, collectionAddr, tokenID string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	key := strings.ToLower(fmt.Sprintf("%s:%s:%s", chain, collectionAddr, tokenID))
+	i.dirtyItems[key] = dirtyItem{
+		chain:    chain,
+		item:     types.ItemInfo{CollectionAddress: collectionAddr, TokenID: tokenID},
+		expireAt: time.Now().Add(DirtyItemRetention),
+	}
+}
+
+// Sync indexes every row updated since the last sync. The first call builds
+// the whole index.
+func (i *Indexer) Sync(ctx context.Context) error {
+	for _, chain := range i.chains {
+		if err := i.syncChain(ctx, chain); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed on sync chain %s", chain.Name))
+		}
+	}
+
+	if err := i.syncUsers(ctx); err != nil {
+		return errors.Wrap(err, "failed on sync users")
+	}
+
+	if err := i.syncProfiles(ctx); err != nil {
+		return errors.Wrap(err, "failed on sync user profiles")
+	}
+
+	return i.syncDirtyItems(ctx)
+}
+
+func (i *Indexer) syncChain(ctx context.Context, chain *config.ChainSupported) error {
+	i.mu.Lock()
+	mark, ok := i.watermarks[chain.Name]
+	if !ok {
+		mark = &watermark{}
+		i.watermarks[chain.Name] = mark
+	}
+	i.mu.Unlock()
+
+	cursor := int64(0)
+	collectionsMark := mark.collections
+	for {
+		collections, err := i.dao.QueryCollectionsUpdatedSince(ctx, chain.Name, collectionsMark, cursor, dao.MaxBatchReadCollections)
+		if err != nil {
+			return err
+		}
+
+		var docs []*Document
+		for _, collection := range collections {
+			docs = append(docs, collectionDocument(chain.ChainID, collection))
+			i.setCollectionName(chain.Name, collection.Address, collection.Name)
+			if collection.UpdateTime > mark.collections {
+				mark.collections = collection.UpdateTime
+			}
+		}
+		if err := i.index.Upsert(docs...); err != nil {
+			return errors.Wrap(err, "failed on index collections")
+		}
+
+		if len(collections) < dao.MaxBatchReadCollections {
+			break
+		}
+		cursor = collections[len(collections)-1].Id
+	}
+
+	cursor = 0
+	itemsMark := mark.items
+	for {
+		items, err := i.dao.QueryItemsUpdatedSince(ctx, chain.Name, itemsMark, cursor, dao.MaxBatchReadItems)
+		if err != nil {
+			return err
+		}
+
+		if err := i.indexItems(ctx, chain, items); err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			if item.UpdateTime > mark.items {
+				mark.items = item.UpdateTime
+			}
+		}
+
+		if len(items) < dao.MaxBatchReadItems {
+			break
+		}
+		cursor = items[len(items)-1].Id
+	}
+
+	// a full build already indexed the traits together with their items
+	if mark.traits == 0 {
+		mark.traits = time.Now().UnixMilli()
+		return nil
+	}
+
+	now := time.Now().UnixMilli()
+	var cursorTime, cursorID int64
+	for {
+		traits, err := i.dao.QueryTraitItemsUpdatedSince(ctx, chain.Name, mark.traits, cursorTime, cursorID, dao.MaxBatchReadItems)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[types.ItemInfo]bool)
+		var traitItems []types.ItemInfo
+		for _, trait := range traits {
+			item := types.ItemInfo{CollectionAddress: trait.CollectionAddress, TokenID: trait.TokenId}
+			if !seen[item] {
+				seen[item] = true
+				traitItems = append(traitItems, item)
+			}
+		}
+		if err := i.reindexItems(ctx, chain, traitItems); err != nil {
+			return err
+		}
+
+		if len(traits) < dao.MaxBatchReadItems {
+			break
+		}
+		cursorTime, cursorID = traits[len(traits)-1].UpdateTime, traits[len(traits)-1].Id
+	}
+	mark.traits = now
+
+	return nil
+}
+
+func (i *Indexer) syncUsers(ctx context.Context) error {
+	cursor := int64(0)
+	for {
+		users, err := i.dao.QueryUsersUpdatedSince(ctx, i.usersWatermark, cursor, dao.MaxBatchReadItems)
+		if err != nil {
+			return err
+		}
+
+		var addrs []string
+		for _, user := range users {
+			addrs = append(addrs, user.Address)
+		}
+		profiles, err := i.dao.QueryUserProfiles(ctx, addrs)
+		if err != nil {
+			return err
+		}
+
+		var docs []*Document
+		for _, user := range users {
+			docs = append(docs, userDocument(user.Address, profiles[strings.ToLower(user.Address)].Username))
+			if user.UpdateTime > i.usersWatermark {
+				i.usersWatermark = user.UpdateTime
+			}
+		}
+		if err := i.index.Upsert(docs...); err != nil {
+			return errors.Wrap(err, "failed on index users")
+		}
+
+		if len(users) < dao.MaxBatchReadItems {
+			return nil
+		}
+		cursor = users[len(users)-1].Id
+	}
+}
+
+// syncProfiles indexes the usernames of the profiles saved on other instances.
+func (i *Indexer) syncProfiles(ctx context.Context) error {
+	cursor := int64(0)
+	for {
+		profiles, err := i.dao.QueryUserProfilesUpdatedSince(ctx, i.profilesWatermark, cursor, dao.MaxBatchReadItems)
+		if err != nil {
+			return err
+		}
+
+		var docs []*Document
+		for _, profile := range profiles {
+			docs = append(docs, userDocument(profile.Address, profile.Username))
+			if profile.UpdateTime > i.profilesWatermark {
+				i.profilesWatermark = profile.UpdateTime
+			}
+		}
+		if err := i.index.Upsert(docs...); err != nil {
+			return errors.Wrap(err, "failed on index user profiles")
+		}
+
+		if len(profiles) < dao.MaxBatchReadItems {
+			return nil
+		}
+		cursor = profiles[len(profiles)-1].Id
+	}
+}
+
+// IndexProfile indexes the username of a user once its profile is saved.
+func (i *Indexer) IndexProfile(address, username string) error {
+	if err := i.index.Upsert(userDocument(address, username)); err != nil {
+		return errors.Wrap(err, "failed on index user profile")
+	}
+	return nil
+}
+
+func (i *Indexer) syncDirtyItems(ctx context.Context) error {
+	i.mu.Lock()
+	chainItems := make(map[string][]types.ItemInfo)
+	now := time.Now()
+	for key, dirty := range i.dirtyItems {
+		if now.After(dirty.expireAt) {
+			delete(i.dirtyItems, key)
+		}
+		chainItems[dirty.chain] = append(chainItems[dirty.chain], dirty.item)
+	}
+	i.mu.Unlock()
+
+	for _, chain := range i.chains {
+		items, ok := chainItems[chain.Name]
+		if !ok {
+			continue
+		}
+		if err := i.reindexItems(ctx, chain, items); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *Indexer) reindexItems(ctx context.Context, chain *config.ChainSupported, itemInfos []types.ItemInfo) error {
+	if len(itemInfos) == 0 {
+		return nil
+	}
+
+	items, err := i.dao.QueryItemsByInfos(ctx, chain.Name, itemInfos)
+	if err != nil {
+		return err
+	}
+
+	return i.indexItems(ctx, chain, items)
+}
+
+func (i *Indexer) indexItems(ctx context.Context, chain *config.ChainSupported, items []multi.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	var itemInfos []types.ItemInfo
+	for _, item := range items {
+		itemInfos = append(itemInfos, types.ItemInfo{
+			CollectionAddress: item.CollectionAddress,
+			TokenID:           item.TokenId,
+		})
+	}
+
+	traits, err := i.dao.QueryTraitsByItems(ctx, chain.Name, itemInfos)
+	if err != nil {
+		return err
+	}
+
+	itemTraits := make(map[string][]string)
+	for _, trait := range traits {
+		key := strings.ToLower(trait.CollectionAddress + trait.TokenId)
+		itemTraits[key] = append(itemTraits[key], trait.TraitValue)
+	}
+
+	var docs []*Document
+	for _, item := range items {
+		collectionName := i.collectionName(chain.Name, item.CollectionAddress)
+		title := item.Name
+		if title == "" {
+			title = fmt.Sprintf("%s #%s", collectionName, item.TokenId)
+		}
+
+		docs = append(docs, &Document{
+			ID:       ItemDocID(chain.ChainID, item.CollectionAddress, item.TokenId),
+			Kind:     KindItem,
+			ChainID:  chain.ChainID,
+			Address:  item.CollectionAddress,
+			TokenID:  item.TokenId,
+			Title:    title,
+			Subtitle: collectionName,
+			Keywords: itemTraits[strings.ToLower(item.CollectionAddress+item.TokenId)],
+			Boost:    0.8,
+		})
+	}
+
+	if err := i.index.Upsert(docs...); err != nil {
+		return errors.Wrap(err, "failed on index items")
+	}
+	return nil
+}
+
+func (i *Indexer) setCollectionName(chain, collectionAddr, name string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.collectionNames[strings.ToLower(chain+":"+collectionAddr)] = name
+}
+
+func (i *Indexer) collectionName(chain, collectionAddr string) string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.collectionNames[strings.ToLower(chain+":"+collectionAddr)]
+}
+
+// userDocument is found by the address and the username of the user, which
+// is its title when set.
+func userDocument(address, username string) *Document {
+	doc := &Document{
+		ID:      UserDocID(address),
+		Kind:    KindUser,
+		Address: strings.ToLower(address),
+		Title:   strings.ToLower(address),
+	}
+	if username != "" {
+		doc.Title = username
+		doc.Subtitle = doc.Address
+	}
+	return doc
+}
+
+func collectionDocument(chainID int, collection multi.Collection) *Document {
+	return &Document{
+		ID:       CollectionDocID(chainID, collection.Address),
+		Kind:     KindCollection,
+		ChainID:  chainID,
+		Address:  collection.Address,
+		Title:    collection.Name,
+		Subtitle: collection.Symbol,
+		ImageURI: collection.ImageUri,
+		Keywords: []string{collection.Symbol},
+		Boost:    1.2,
+	}
+}
@@ -0,0 +1,33 @@
+package search
+
+import "testing"
+
+func TestIndexProfile(t *testing.T) {
+	const addr = "0xAbC0000000000000000000000000000000000001"
+	indexer := NewIndexer(NewMemoryIndex(), nil, nil)
+	if err := indexer.IndexProfile(addr, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.IndexProfile(addr, "satoshi_fan"); err != nil {
+		t.Fatal(err)
+	}
+	if count := indexer.Index().Count(); count != 1 {
+		t.Fatalf("expected the profile to replace the user document, got %d documents", count)
+	}
+
+	hits, err := indexer.Index().Search(Query{Text: "satoshi", Kinds: []string{KindUser}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Title != "satoshi_fan" || hits[0].Subtitle != "0xabc0000000000000000000000000000000000001" {
+		t.Fatalf("user not found by username: %+v", hits)
+	}
+
+	if err := indexer.IndexProfile(addr, ""); err != nil {
+		t.Fatal(err)
+	}
+	hits, err = indexer.Index().Search(Query{Text: "satoshi", Kinds: []string{KindUser}, Limit: 10})
+	if err != nil || len(hits) != 0 {
+		t.Fatalf("cleared username still found: %+v, %v", hits, err)
+	}
+}
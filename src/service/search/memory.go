@@ -0,0 +1,320 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const DefaultLimit = 20
+
+type posting struct {
+	weight int
+}
+
+// MemoryIndex is an in-process inverted index supporting exact, prefix and
+// typo tolerant matching. The terms are also indexed by their bigrams, which
+// narrow down the terms compared for a typo.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[string]*Document
+	postings map[string]map[string]posting
+	bigrams  map[string]map[string]bool
+	terms    []string
+	dirty    bool
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     make(map[string]*Document),
+		postings: make(map[string]map[string]posting),
+		bigrams:  make(map[string]map[string]bool),
+	}
+}
+
+func (m *MemoryIndex) Upsert(docs ...*Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, doc := range docs {
+		if doc == nil || doc.ID == "" {
+			continue
+		}
+		m.remove(doc.ID)
+
+		m.docs[doc.ID] = doc
+		for term, weight := range docTerms(doc) {
+			docIDs, ok := m.postings[term]
+			if !ok {
+				docIDs = make(map[string]posting)
+				m.postings[term] = docIDs
+				m.dirty = true
+				for _, gram := range bigrams(term) {
+					if m.bigrams[gram] == nil {
+						m.bigrams[gram] = make(map[string]bool)
+					}
+					m.bigrams[gram][term] = true
+				}
+			}
+			docIDs[doc.ID] = posting{weight: weight}
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryIndex) Delete(ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range ids {
+		m.remove(id)
+	}
+	return nil
+}
+
+func (m *MemoryIndex) Count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.docs)
+}
+
+func (m *MemoryIndex) Search(q Query) ([]Hit, error) {
+	return m.query(q, false)
+}
+
+// Suggest treats the last query term as an unfinished word, so only prefix
+// matches are considered for it.
+func (m *MemoryIndex) Suggest(q Query) ([]Hit, error) {
+	return m.query(q, true)
+}
+
+func (m *MemoryIndex) remove(id string) {
+	doc, ok := m.docs[id]
+	if !ok {
+		return
+	}
+
+	for term := range docTerms(doc) {
+		docIDs, ok := m.postings[term]
+		if !ok {
+			continue
+		}
+		delete(docIDs, id)
+		if len(docIDs) == 0 {
+			delete(m.postings, term)
+			m.dirty = true
+			for _, gram := range bigrams(term) {
+				delete(m.bigrams[gram], term)
+				if len(m.bigrams[gram]) == 0 {
+					delete(m.bigrams, gram)
+				}
+			}
+		}
+	}
+	delete(m.docs, id)
+}
+
+func (m *MemoryIndex) sortedTerms() []string {
+	m.mu.RLock()
+	if !m.dirty {
+		terms := m.terms
+		m.mu.RUnlock()
+		return terms
+	}
+	m.mu.RUnlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dirty {
+		terms := make([]string, 0, len(m.postings))
+		for term := range m.postings {
+			terms = append(terms, term)
+		}
+		sort.Strings(terms)
+		m.terms = terms
+		m.dirty = false
+	}
+	return m.terms
+}
+
+func (m *MemoryIndex) query(q Query, autocomplete bool) ([]Hit, error) {
+	queryTerms := Tokenize(q.Text)
+	if len(queryTerms) == 0 {
+		return []Hit{}, nil
+	}
+
+	terms := m.sortedTerms()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+	for i, queryTerm := range queryTerms {
+		prefixOnly := autocomplete && i == len(queryTerms)-1
+		termScores := make(map[string]float64)
+
+		for term, match := range m.matchTerms(terms, queryTerm, prefixOnly) {
+			for docID, p := range m.postings[term] {
+				score := float64(match * p.weight)
+				if score > termScores[docID] {
+					termScores[docID] = score
+				}
+			}
+		}
+
+		for docID, score := range termScores {
+			scores[docID] += score
+			matched[docID]++
+		}
+	}
+
+	chainIDs := make(map[int]bool)
+	for _, id := range q.ChainIDs {
+		chainIDs[id] = true
+	}
+	kinds := make(map[string]bool)
+	for _, kind := range q.Kinds {
+		kinds[strings.ToLower(kind)] = true
+	}
+
+	var hits []Hit
+	for docID, score := range scores {
+		if matched[docID] != len(queryTerms) {
+			continue
+		}
+
+		doc := m.docs[docID]
+		if len(kinds) > 0 && !kinds[doc.Kind] {
+			continue
+		}
+		if len(chainIDs) > 0 && doc.Kind != KindUser && !chainIDs[doc.ChainID] {
+			continue
+		}
+		if doc.Kind != KindUser && q.ExcludeCollections[CollectionDocID(doc.ChainID, doc.Address)] {
+			continue
+		}
+
+		if doc.Boost > 0 {
+			score *= doc.Boost
+		}
+		hits = append(hits, Hit{Document: *doc, Score: score})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if len(hits[i].Title) != len(hits[j].Title) {
+			return len(hits[i].Title) < len(hits[j].Title)
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits, nil
+}
+
+// matchTerms returns the indexed terms matching the query term together with
+// the match quality.
+func (m *MemoryIndex) matchTerms(terms []string, queryTerm string, prefixOnly bool) map[string]int {
+	matches := make(map[string]int)
+
+	start := sort.SearchStrings(terms, queryTerm)
+	for i := start; i < len(terms) && strings.HasPrefix(terms[i], queryTerm); i++ {
+		if terms[i] == queryTerm {
+			matches[terms[i]] = MatchExact
+		} else {
+			matches[terms[i]] = MatchPrefix
+		}
+	}
+
+	if prefixOnly {
+		return matches
+	}
+
+	edits := maxEdits(queryTerm)
+	if edits == 0 {
+		return matches
+	}
+
+	query := []rune(queryTerm)
+	for _, term := range m.fuzzyCandidates(queryTerm, edits) {
+		if _, ok := matches[term]; ok {
+			continue
+		}
+		// compare against the head of long terms as well, so typos in a
+		// partially typed word are still found
+		candidate := []rune(term)
+		if len(candidate) > len(query)+edits {
+			candidate = candidate[:len(query)]
+		}
+		if levenshtein(query, candidate, edits) <= edits {
+			matches[term] = MatchFuzzy
+		}
+	}
+
+	return matches
+}
+
+// fuzzyCandidates returns the terms that may be within edits of the query
+// term. An edit changes at most two bigrams, so a term within reach shares
+// all but 2*edits of the distinct bigrams of the query term, and at least one.
+func (m *MemoryIndex) fuzzyCandidates(queryTerm string, edits int) []string {
+	queryGrams := make(map[string]bool)
+	for _, gram := range bigrams(queryTerm) {
+		queryGrams[gram] = true
+	}
+
+	shared := make(map[string]int)
+	for gram := range queryGrams {
+		for term := range m.bigrams[gram] {
+			shared[term]++
+		}
+	}
+
+	required := max(len(queryGrams)-2*edits, 1)
+	var candidates []string
+	for term, count := range shared {
+		if count >= required {
+			candidates = append(candidates, term)
+		}
+	}
+	return candidates
+}
+
+// bigrams returns the pairs of consecutive runes of the term.
+func bigrams(term string) []string {
+	runes := []rune(term)
+	var grams []string
+	for i := 0; i+1 < len(runes); i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
+
+func docTerms(doc *Document) map[string]int {
+	terms := make(map[string]int)
+	for _, term := range Tokenize(doc.Title) {
+		terms[term] = TitleWeight
+	}
+
+	keywords := append([]string{doc.Address, doc.TokenID}, doc.Keywords...)
+	for _, keyword := range keywords {
+		for _, term := range Tokenize(keyword) {
+			if _, ok := terms[term]; !ok {
+				terms[term] = KeywordWeight
+			}
+		}
+	}
+
+	return terms
+}
@@ -0,0 +1,36 @@
+package search
+
+import "testing"
+
+func TestMemoryIndexFuzzy(t *testing.T) {
+	index := NewMemoryIndex()
+	if err := index.Upsert(
+		&Document{ID: "1", Kind: KindCollection, Title: "Azuki"},
+		&Document{ID: "2", Kind: KindCollection, Title: "Moonbirds"},
+		&Document{ID: "3", Kind: KindCollection, Title: "Pudgy Penguins"},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	for text, id := range map[string]string{
+		"azuky":     "1",
+		"moonbrids": "2",
+		"pengiuns":  "3",
+		"mooj":      "2",
+	} {
+		hits, err := index.Search(Query{Text: text, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hits) != 1 || hits[0].ID != id {
+			t.Fatalf("%q: expected document %s, got %+v", text, id, hits)
+		}
+	}
+
+	if err := index.Delete("2"); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.bigrams["mo"]) != 0 {
+		t.Fatalf("bigrams of removed terms kept: %v", index.bigrams["mo"])
+	}
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultLocalDir     = "./data/objects"
+	DefaultLocalBaseURL = "/api/v1/objects/"
+)
+
+// Local keeps objects on the local filesystem. It stands in for an object
+// store in development, the objects are served by the api.
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocal(dir, baseURL string) *Local {
+	if dir == "" {
+		dir = DefaultLocalDir
+	}
+	if baseURL == "" {
+		baseURL = DefaultLocalBaseURL
+	}
+
+	return &Local{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/",
+	}
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	file, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return errors.Wrap(err, "failed on create object dir")
+	}
+
+	// write to a temp file first so readers never see a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".upload-*")
+	if err != nil {
+		return errors.Wrap(err, "failed on create object")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed on write object")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed on write object")
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return errors.Wrap(err, "failed on save object")
+	}
+	return nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	file, err := l.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", errors.Wrap(err, "failed on open object")
+	}
+
+	return f, typeByExtension(path.Ext(key)), nil
+}
+
+// extensionTypes are the media types of the extensions missing from the
+// builtin table of the mime package, which only holds more with the system
+// mime files.
+var extensionTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".webm": "video/webm",
+	".avi":  "video/avi",
+}
+
+func typeByExtension(ext string) string {
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return extensionTypes[strings.ToLower(ext)]
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	file, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed on delete object")
+	}
+	return nil
+}
+
+func (l *Local) URL(key string) string {
+	return l.baseURL + key
+}
+
+// path maps a key into the storage dir, rejecting keys escaping it.
+func (l *Local) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || cleaned != "/"+key {
+		return "", errors.Errorf("invalid object key: %s", key)
+	}
+
+	return filepath.Join(l.dir, filepath.FromSlash(cleaned)), nil
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/pkg/errors"
+)
+
+const TypeLocal = "local"
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage stores user uploaded objects such as avatars. Keys are slash
+// separated paths, URL returns where clients can download an object.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, string, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+func New(c *config.Storage) (Storage, error) {
+	if c == nil {
+		return NewLocal(DefaultLocalDir, DefaultLocalBaseURL), nil
+	}
+
+	switch strings.ToLower(c.Type) {
+	case "", TypeLocal:
+		return NewLocal(c.LocalDir, c.BaseURL), nil
+	default:
+		return nil, errors.Errorf("unsupported storage type: %s", c.Type)
+	}
+}
@@ -0,0 +1,208 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/pkg/errors"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	StreamKey     = "cache:%s:stream:events"
+	ChannelKey    = "%s:stream:events"
+	MaxStreamLen  = 100000
+	ReplayBatch   = 500
+	SubscriberBuf = 256
+)
+
+// Broker publishes events to Redis and fans the events received from Redis
+// pub/sub out to the subscribers of this replica. Events are also appended to
+// a capped Redis stream so clients can resume after a reconnect.
+type Broker struct {
+	client    goredis.UniversalClient
+	streamKey string
+	channel   string
+
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	C      chan *Event
+	filter Filter
+	once   sync.Once
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		close(s.C)
+	})
+}
+
+func NewBroker(client goredis.UniversalClient, project string) *Broker {
+	project = strings.ToLower(project)
+	return &Broker{
+		client:    client,
+		streamKey: fmt.Sprintf(StreamKey, project),
+		channel:   fmt.Sprintf(ChannelKey, project),
+		subs:      make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *Broker) Publish(ctx context.Context, event *Event) error {
+	if event.Time == 0 {
+		event.Time = time.Now().UnixMilli()
+	}
+
+	rawEvent, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed on marshal stream event")
+	}
+
+	id, err := b.client.XAdd(ctx, &goredis.XAddArgs{
+		Stream: b.streamKey,
+		MaxLen: MaxStreamLen,
+		Approx: true,
+		Values: map[string]interface{}{"event": string(rawEvent)},
+	}).Result()
+	if err != nil {
+		return errors.Wrap(err, "failed on append stream event")
+	}
+
+	event.ID = id
+	rawEvent, err = json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed on marshal stream event")
+	}
+
+	if err := b.client.Publish(ctx, b.channel, string(rawEvent)).Err(); err != nil {
+		return errors.Wrap(err, "failed on publish stream event")
+	}
+
+	return nil
+}
+
+// Run receives the events published by every replica until ctx is done.
+func (b *Broker) Run(ctx context.Context) {
+	for {
+		pubsub := b.client.Subscribe(ctx, b.channel)
+		b.receive(ctx, pubsub)
+		_ = pubsub.Close()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (b *Broker) receive(ctx context.Context, pubsub *goredis.PubSub) {
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				xzap.WithContext(ctx).Warn("stream subscription closed, resubscribing")
+				return
+			}
+
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				xzap.WithContext(ctx).Error("failed on unmarshal stream event", zap.Error(err))
+				continue
+			}
+			b.dispatch(&event)
+		}
+	}
+}
+
+// dispatch fans an event out to the matching subscribers. Subscribers that
+// do not keep up are dropped and have to reconnect with their cursor. They
+// are removed before their channel is closed, a send on a closed channel
+// panics even in a select.
+func (b *Broker) dispatch(event *Event) {
+	var slow []*Subscription
+	b.mu.RLock()
+	for sub := range b.subs {
+		if !sub.filter.Match(event) {
+			continue
+		}
+
+		select {
+		case sub.C <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range slow {
+		b.Unsubscribe(sub)
+	}
+}
+
+func (b *Broker) Subscribe(filter Filter) *Subscription {
+	sub := &Subscription{
+		C:      make(chan *Event, SubscriberBuf),
+		filter: filter,
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	delete(b.subs, sub)
+	b.mu.Unlock()
+
+	sub.close()
+}
+
+// Replay calls fn for every stored event after cursor matching the filter.
+func (b *Broker) Replay(ctx context.Context, cursor string, filter Filter, fn func(*Event) error) error {
+	start := "(" + cursor
+	for {
+		msgs, err := b.client.XRangeN(ctx, b.streamKey, start, "+", ReplayBatch).Result()
+		if err != nil {
+			return errors.Wrap(err, "failed on read stream events")
+		}
+
+		for _, msg := range msgs {
+			rawEvent, ok := msg.Values["event"].(string)
+			if !ok {
+				continue
+			}
+
+			var event Event
+			if err := json.Unmarshal([]byte(rawEvent), &event); err != nil {
+				continue
+			}
+			event.ID = msg.ID
+
+			if filter.Match(&event) {
+				if err := fn(&event); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(msgs) < ReplayBatch {
+			return nil
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+}
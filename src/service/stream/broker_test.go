@@ -0,0 +1,33 @@
+package stream
+
+import "testing"
+
+func TestDispatchDropsSlowSubscriber(t *testing.T) {
+	b := NewBroker(nil, "test")
+	slow := b.Subscribe(Filter{})
+	fast := b.Subscribe(Filter{})
+
+	for i := 0; i < SubscriberBuf+1; i++ {
+		b.dispatch(&Event{Kind: KindActivity})
+		// drain the fast subscriber so only the slow one falls behind
+		<-fast.C
+	}
+
+	b.mu.RLock()
+	_, slowSubscribed := b.subs[slow]
+	_, fastSubscribed := b.subs[fast]
+	b.mu.RUnlock()
+	if slowSubscribed {
+		t.Fatal("slow subscriber was not removed")
+	}
+	if !fastSubscribed {
+		t.Fatal("fast subscriber was removed")
+	}
+
+	// more events must not send on the closed channel of the slow subscriber
+	b.dispatch(&Event{Kind: KindActivity})
+	<-fast.C
+
+	for range slow.C {
+	}
+}
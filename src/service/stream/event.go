@@ -0,0 +1,108 @@
+package stream
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+const (
+	KindActivity = "activity"
+	KindListing  = "listing"
+	KindBid      = "bid"
+	KindFloor    = "floor"
+)
+
+// Event is a message pushed to stream subscribers. ID is the position of the
+// event in the Redis stream and is used as resume cursor.
+type Event struct {
+	ID                string          `json:"id"`
+	Kind              string          `json:"kind"`
+	EventType         string          `json:"event_type"`
+	ChainID           int             `json:"chain_id"`
+	CollectionAddress string          `json:"collection_address"`
+	TokenID           string          `json:"token_id"`
+	Users             []string        `json:"users"`
+	Data              json.RawMessage `json:"data"`
+	Time              int64           `json:"time"`
+}
+
+// Filter selects the events of a subscription. Empty fields match everything.
+type Filter struct {
+	ChainIDs    []int
+	Collections []string
+	TokenID     string
+	Users       []string
+	EventTypes  []string
+}
+
+func (f *Filter) Match(e *Event) bool {
+	if len(f.ChainIDs) > 0 && !containsInt(f.ChainIDs, e.ChainID) {
+		return false
+	}
+
+	if len(f.Collections) > 0 && !containsFold(f.Collections, e.CollectionAddress) {
+		return false
+	}
+
+	if f.TokenID != "" && f.TokenID != e.TokenID {
+		return false
+	}
+
+	if len(f.Users) > 0 {
+		matched := false
+		for _, user := range e.Users {
+			if containsFold(f.Users, user) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.EventTypes) > 0 && !containsFold(f.EventTypes, e.Kind) && !containsFold(f.EventTypes, e.EventType) {
+		return false
+	}
+
+	return true
+}
+
+// After reports whether stream id a comes after stream id b.
+func After(a, b string) bool {
+	aMs, aSeq := splitID(a)
+	bMs, bSeq := splitID(b)
+	if aMs != bMs {
+		return aMs > bMs
+	}
+	return aSeq > bSeq
+}
+
+func splitID(id string) (int64, int64) {
+	parts := strings.SplitN(id, "-", 2)
+	ms, _ := strconv.ParseInt(parts[0], 10, 64)
+	var seq int64
+	if len(parts) == 2 {
+		seq, _ = strconv.ParseInt(parts[1], 10, 64)
+	}
+	return ms, seq
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,319 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultFeedInterval = 2 * time.Second
+	FeedBatchSize       = 500
+)
+
+const CacheFeederCursorKey = "cache:es:stream:%s:%s:cursor"
+
+const (
+	cursorActivity = "activity"
+	cursorOrder    = "order"
+	cursorFloor    = "floor"
+)
+
+var activityTypes = []int{
+	multi.Sale, multi.Transfer, multi.MakeOffer, multi.CancelOffer, multi.CancelListing, multi.Listing,
+	multi.Mint, multi.Buy, multi.CollectionBid, multi.ItemBid, multi.CancelCollectionBid, multi.CancelItemBid,
+}
+
+var orderStatusEvents = map[int]string{
+	multi.OrderStatusActive:    "active",
+	multi.OrderStatusInactive:  "inactive",
+	multi.OrderStatusExpired:   "expired",
+	multi.OrderStatusCancelled: "cancelled",
+	multi.OrderStatusFilled:    "filled",
+}
+
+type FloorChange struct {
+	CollectionAddress string          `json:"collection_address"`
+	Price             decimal.Decimal `json:"price"`
+	PreviousPrice     decimal.Decimal `json:"previous_price"`
+	EventTime         int64           `json:"event_time"`
+}
+
+// Feeder tails the activity, order and floor price tables of every chain and
+// publishes the new rows to the broker. It must run on one replica at a time,
+// the others fan the events out from Redis.
+type Feeder struct {
+	broker *Broker
+	dao    *dao.Dao
+	store  *xkv.Store
+	chains []*config.ChainSupported
+	floors map[string]decimal.Decimal
+}
+
+func NewFeeder(broker *Broker, d *dao.Dao, store *xkv.Store, chains []*config.ChainSupported) *Feeder {
+	return &Feeder{
+		broker: broker,
+		dao:    d,
+		store:  store,
+		chains: chains,
+		floors: make(map[string]decimal.Decimal),
+	}
+}
+
+func (f *Feeder) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultFeedInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, chain := range f.chains {
+				if err := f.Feed(ctx, chain); err != nil {
+					xzap.WithContext(ctx).Error("failed on feed stream", zap.String("chain", chain.Name), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+func (f *Feeder) Feed(ctx context.Context, chain *config.ChainSupported) error {
+	if err := f.feedActivities(ctx, chain); err != nil {
+		return errors.Wrap(err, "failed on feed activities")
+	}
+	if err := f.feedOrders(ctx, chain); err != nil {
+		return errors.Wrap(err, "failed on feed orders")
+	}
+	if err := f.feedFloorPrices(ctx, chain); err != nil {
+		return errors.Wrap(err, "failed on feed floor prices")
+	}
+	return nil
+}
+
+func (f *Feeder) feedActivities(ctx context.Context, chain *config.ChainSupported) error {
+	cursor, err := f.idCursor(ctx, chain.Name, cursorActivity, multi.ActivityTableName(chain.Name))
+	if err != nil {
+		return err
+	}
+
+	for {
+		activities, err := f.dao.QueryActivitiesSince(ctx, chain.Name, cursor, activityTypes, FeedBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(activities) == 0 {
+			return nil
+		}
+
+		var chainActivities []dao.ActivityMultiChainInfo
+		for _, activity := range activities {
+			chainActivities = append(chainActivities, dao.ActivityMultiChainInfo{Activity: activity, ChainName: chain.Name})
+		}
+		infos, err := f.dao.QueryMultiChainActivityExternalInfo(ctx, []int{chain.ChainID}, []string{chain.Name}, chainActivities)
+		if err != nil {
+			return err
+		}
+
+		for i, info := range infos {
+			if err := f.publish(ctx, &Event{
+				Kind:              KindActivity,
+				EventType:         info.EventType,
+				ChainID:           chain.ChainID,
+				CollectionAddress: info.CollectionAddress,
+				TokenID:           info.TokenID,
+				Users:             users(info.Maker, info.Taker),
+				Time:              activities[i].EventTime,
+			}, info); err != nil {
+				return err
+			}
+		}
+
+		cursor = activities[len(activities)-1].Id
+		if err := f.setCursor(chain.Name, cursorActivity, strconv.FormatInt(cursor, 10)); err != nil {
+			return err
+		}
+		if len(activities) < FeedBatchSize {
+			return nil
+		}
+	}
+}
+
+func (f *Feeder) feedOrders(ctx context.Context, chain *config.ChainSupported) error {
+	since, cursor, err := f.orderCursor(chain.Name)
+	if err != nil {
+		return err
+	}
+
+	for {
+		orders, err := f.dao.QueryOrdersUpdatedSince(ctx, chain.Name, since, cursor, FeedBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(orders) == 0 {
+			return nil
+		}
+
+		for _, order := range orders {
+			kind := KindBid
+			if order.OrderType == multi.ListingOrder {
+				kind = KindListing
+			}
+
+			if err := f.publish(ctx, &Event{
+				Kind:              kind,
+				EventType:         orderStatusEvents[order.OrderStatus],
+				ChainID:           chain.ChainID,
+				CollectionAddress: order.CollectionAddress,
+				TokenID:           order.TokenId,
+				Users:             users(order.Maker, order.Taker),
+				Time:              order.UpdateTime,
+			}, order); err != nil {
+				return err
+			}
+		}
+
+		last := orders[len(orders)-1]
+		since, cursor = last.UpdateTime, last.Id
+		if err := f.setCursor(chain.Name, cursorOrder, fmt.Sprintf("%d:%d", since, cursor)); err != nil {
+			return err
+		}
+		if len(orders) < FeedBatchSize {
+			return nil
+		}
+	}
+}
+
+func (f *Feeder) feedFloorPrices(ctx context.Context, chain *config.ChainSupported) error {
+	cursor, err := f.idCursor(ctx, chain.Name, cursorFloor, multi.CollectionFloorPriceTableName(chain.Name))
+	if err != nil {
+		return err
+	}
+
+	for {
+		floorPrices, err := f.dao.QueryFloorPricesSince(ctx, chain.Name, cursor, FeedBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(floorPrices) == 0 {
+			return nil
+		}
+
+		for _, floorPrice := range floorPrices {
+			key := strings.ToLower(chain.Name + ":" + floorPrice.CollectionAddress)
+			previous, ok := f.floors[key]
+			f.floors[key] = floorPrice.Price
+			// floor prices are recorded periodically, only changes are pushed
+			if ok && previous.Equal(floorPrice.Price) {
+				continue
+			}
+
+			if err := f.publish(ctx, &Event{
+				Kind:              KindFloor,
+				EventType:         "floor_change",
+				ChainID:           chain.ChainID,
+				CollectionAddress: floorPrice.CollectionAddress,
+				Time:              floorPrice.EventTime,
+			}, FloorChange{
+				CollectionAddress: floorPrice.CollectionAddress,
+				Price:             floorPrice.Price,
+				PreviousPrice:     previous,
+				EventTime:         floorPrice.EventTime,
+			}); err != nil {
+				return err
+			}
+		}
+
+		cursor = floorPrices[len(floorPrices)-1].Id
+		if err := f.setCursor(chain.Name, cursorFloor, strconv.FormatInt(cursor, 10)); err != nil {
+			return err
+		}
+		if len(floorPrices) < FeedBatchSize {
+			return nil
+		}
+	}
+}
+
+func (f *Feeder) publish(ctx context.Context, event *Event, data interface{}) error {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrap(err, "failed on marshal stream event data")
+	}
+	event.Data = rawData
+
+	return f.broker.Publish(ctx, event)
+}
+
+// idCursor returns the last published id. A new cursor starts at the current
+// end of the table, history is served by the REST endpoints.
+func (f *Feeder) idCursor(ctx context.Context, chain, kind, table string) (int64, error) {
+	value, err := f.store.Get(fmt.Sprintf(CacheFeederCursorKey, chain, kind))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed on get stream cursor")
+	}
+
+	if value != "" {
+		cursor, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			return cursor, nil
+		}
+	}
+
+	cursor, err := f.dao.QueryMaxID(ctx, table)
+	if err != nil {
+		return 0, err
+	}
+	return cursor, f.setCursor(chain, kind, strconv.FormatInt(cursor, 10))
+}
+
+// orderCursor returns the (update_time, id) watermark of the order feed.
+func (f *Feeder) orderCursor(chain string) (int64, int64, error) {
+	value, err := f.store.Get(fmt.Sprintf(CacheFeederCursorKey, chain, cursorOrder))
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed on get stream cursor")
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) == 2 {
+		since, sinceErr := strconv.ParseInt(parts[0], 10, 64)
+		cursor, cursorErr := strconv.ParseInt(parts[1], 10, 64)
+		if sinceErr == nil && cursorErr == nil {
+			return since, cursor, nil
+		}
+	}
+
+	since := time.Now().UnixMilli()
+	return since, 0, f.setCursor(chain, cursorOrder, fmt.Sprintf("%d:%d", since, 0))
+}
+
+func (f *Feeder) setCursor(chain, kind, value string) error {
+	if err := f.store.Set(fmt.Sprintf(CacheFeederCursorKey, chain, kind), value); err != nil {
+		return errors.Wrap(err, "failed on set stream cursor")
+	}
+	return nil
+}
+
+func users(addrs ...string) []string {
+	var result []string
+	for _, addr := range addrs {
+		if addr != "" {
+			result = append(result, strings.ToLower(addr))
+		}
+	}
+	return result
+}
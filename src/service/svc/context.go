@@ -2,20 +2,54 @@ package svc
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/SimonHofman/EasySwapBackend/src/config"
 	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/balance"
+	"github.com/SimonHofman/EasySwapBackend/src/service/leader"
+	"github.com/SimonHofman/EasySwapBackend/src/service/marketplace"
+	"github.com/SimonHofman/EasySwapBackend/src/service/media"
+	"github.com/SimonHofman/EasySwapBackend/src/service/metadata"
+	"github.com/SimonHofman/EasySwapBackend/src/service/mq"
+	"github.com/SimonHofman/EasySwapBackend/src/service/notification"
+	"github.com/SimonHofman/EasySwapBackend/src/service/orderexpiry"
+	"github.com/SimonHofman/EasySwapBackend/src/service/ordervalidator"
+	"github.com/SimonHofman/EasySwapBackend/src/service/portfolio"
+	"github.com/SimonHofman/EasySwapBackend/src/service/pricealert"
+	"github.com/SimonHofman/EasySwapBackend/src/service/royalty"
+	"github.com/SimonHofman/EasySwapBackend/src/service/search"
+	"github.com/SimonHofman/EasySwapBackend/src/service/storage"
+	"github.com/SimonHofman/EasySwapBackend/src/service/stream"
+	"github.com/SimonHofman/EasySwapBackend/src/service/webhook"
 	"github.com/SimonHofman/EasySwapBase/chain/nftchainservice"
 	"github.com/SimonHofman/EasySwapBase/logger/xzap"
 	"github.com/SimonHofman/EasySwapBase/stores/gdb"
 	"github.com/SimonHofman/EasySwapBase/stores/xkv"
 	"github.com/pkg/errors"
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/zeromicro/go-zero/core/stores/cache"
 	"github.com/zeromicro/go-zero/core/stores/kv"
 	"github.com/zeromicro/go-zero/core/stores/redis"
 	"gorm.io/gorm"
 )
 
+const (
+	CacheValidatorLeaderKey  = "cache:es:ordervalidator:leader:lock"
+	CacheNotifierLeaderKey   = "cache:es:notification:leader:lock"
+	CachePriceAlertLeaderKey = "cache:es:pricealert:leader:lock"
+	CacheMediaLeaderKey      = "cache:es:media:leader:lock"
+	CacheExpiryLeaderKey     = "cache:es:orderexpiry:%s:leader:lock"
+	CacheIngesterLeaderKey   = "cache:es:marketplace:leader:lock"
+	CacheBalanceLeaderKey    = "cache:es:balance:leader:lock"
+	CacheWebhookLeaderKey    = "cache:es:webhook:leader:lock"
+	CacheFeederLeaderKey     = "cache:es:stream:feeder:leader:lock"
+	CacheSnapshotLeaderKey   = "cache:es:portfolio:snapshot:leader:lock"
+)
+
 type ServerCtx struct {
 	C        *config.Config
 	DB       *gorm.DB
@@ -23,6 +57,21 @@ type ServerCtx struct {
 	KvStore  *xkv.Store
 	RankKey  string
 	NodeSrvs map[int64]*nftchainservice.Service
+	Search   *search.Indexer
+	Royalty  *royalty.Fetcher
+	Orders   *ordervalidator.Validator
+	Stream   *stream.Broker
+	Webhooks *webhook.Dispatcher
+	Storage  storage.Storage
+
+	Marketplaces *marketplace.Registry
+	RefreshQueue *mq.RefreshQueue
+	Media        *media.Pipeline
+	MediaProxy   *media.Proxy
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
 }
 
 func NewServiceContext(c *config.Config) (*ServerCtx, error) {
@@ -33,6 +82,11 @@ func NewServiceContext(c *config.Config) (*ServerCtx, error) {
 		return nil, err
 	}
 
+	// the project name prefixes the queues shared with the sync service
+	if c.ProjectCfg == nil || c.ProjectCfg.Name == "" {
+		return nil, errors.New("project_cfg.name is required")
+	}
+
 	var kvConf kv.KvConf
 	for _, con := range c.Kv.Redis {
 		kvConf = append(kvConf, cache.NodeConf{
@@ -63,14 +117,283 @@ func NewServiceContext(c *config.Config) (*ServerCtx, error) {
 	}
 
 	dao := dao.New(context.Background(), db, store)
+
+	var chains []string
+	for _, supported := range c.ChainSupported {
+		chains = append(chains, supported.Name)
+	}
+	pending, err := dao.PendingMigrations(context.Background(), chains)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on check schema migrations")
+	}
+	if len(pending) > 0 {
+		return nil, errors.Errorf("%d schema migrations pending, starting with %s, run cmd/migrate", len(pending), pending[0])
+	}
 	serverCtx := NewServerCtx(
 		WithDB(db),
 		WithKv(store),
 		WithDao(dao),
 	)
 	serverCtx.C = c
+	serverCtx.ctx, serverCtx.cancel = context.WithCancel(context.Background())
+	defer func() {
+		// stop the workers already started when the context fails to build
+		if err != nil {
+			serverCtx.cancel()
+		}
+	}()
 
 	serverCtx.NodeSrvs = nodeSrvs
+	serverCtx.Royalty = royalty.NewFetcher(store, nodeSrvs)
+
+	serverCtx.Storage, err = storage.New(c.Storage)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on create object storage")
+	}
+
+	var syncInterval time.Duration
+	if c.Search != nil {
+		syncInterval = time.Duration(c.Search.SyncInterval) * time.Second
+	}
+	serverCtx.Search = search.NewIndexer(search.NewMemoryIndex(), dao, c.ChainSupported)
+	serverCtx.goWorker(func(ctx context.Context) {
+		serverCtx.Search.Run(ctx, syncInterval)
+	})
+
+	var validateInterval time.Duration
+	var validateBatchSize int
+	if c.OrderValidator != nil {
+		validateInterval = time.Duration(c.OrderValidator.Interval) * time.Second
+		validateBatchSize = c.OrderValidator.BatchSize
+	}
+	serverCtx.Orders = ordervalidator.New(dao, store, nodeSrvs, c.ChainSupported, validateBatchSize)
+	serverCtx.goLeader(CacheValidatorLeaderKey, func(ctx context.Context) {
+		serverCtx.Orders.Run(ctx, validateInterval)
+	})
+
+	var balanceInterval time.Duration
+	var balanceBatchSize int
+	if c.Balance != nil {
+		balanceInterval = time.Duration(c.Balance.Interval) * time.Second
+		balanceBatchSize = c.Balance.BatchSize
+	}
+	tracker := balance.NewTracker(dao, store, nodeSrvs, c.ChainSupported, balanceBatchSize)
+	serverCtx.goLeader(CacheBalanceLeaderKey, func(ctx context.Context) {
+		tracker.Run(ctx, balanceInterval)
+	})
+
+	var expiryInterval time.Duration
+	var expiryBatchSize int
+	if c.OrderExpiry != nil {
+		expiryInterval = time.Duration(c.OrderExpiry.Interval) * time.Second
+		expiryBatchSize = c.OrderExpiry.BatchSize
+	}
+	for _, supported := range c.ChainSupported {
+		sweeper := orderexpiry.NewSweeper(dao, store, c.ProjectCfg.Name, supported, expiryBatchSize)
+		serverCtx.goLeader(fmt.Sprintf(CacheExpiryLeaderKey, supported.Name), func(ctx context.Context) {
+			sweeper.Run(ctx, expiryInterval)
+		})
+	}
+
+	var notifyInterval time.Duration
+	var expiringHours int
+	if c.Notification != nil {
+		notifyInterval = time.Duration(c.Notification.Interval) * time.Second
+		expiringHours = c.Notification.ExpiringHours
+	}
+	notifier := notification.NewNotifier(dao, store, c.ProjectCfg.Name, c.ChainSupported, expiringHours)
+	serverCtx.goLeader(CacheNotifierLeaderKey, func(ctx context.Context) {
+		notifier.Run(ctx, notifyInterval)
+	})
+
+	var alertInterval time.Duration
+	if c.PriceAlert != nil {
+		alertInterval = time.Duration(c.PriceAlert.Interval) * time.Second
+	}
+	evaluator := pricealert.NewEvaluator(dao, c.ChainSupported)
+	serverCtx.goLeader(CachePriceAlertLeaderKey, func(ctx context.Context) {
+		evaluator.Run(ctx, alertInterval)
+	})
+
+	redisOpts, err := redisOptions(c.Kv.Redis)
+	if err != nil {
+		return nil, err
+	}
+	redisClient := goredis.NewUniversalClient(redisOpts)
+	serverCtx.Stream = stream.NewBroker(redisClient, c.ProjectCfg.Name)
+	serverCtx.goWorker(serverCtx.Stream.Run)
+
+	var maxAttempts int
+	var reclaimIdle time.Duration
+	if c.RefreshQueue != nil {
+		maxAttempts = c.RefreshQueue.MaxAttempts
+		reclaimIdle = time.Duration(c.RefreshQueue.ReclaimIdle) * time.Second
+	}
+	// without the in-process worker the refresh items are left to the sync service
+	workerEnabled := c.MetadataWorker != nil && c.MetadataWorker.Enabled
+	serverCtx.RefreshQueue = mq.NewRefreshQueue(redisClient, store, c.ProjectCfg.Name, maxAttempts, reclaimIdle, !workerEnabled)
+	serverCtx.goWorker(func(ctx context.Context) {
+		mq.WatchRefreshJobs(ctx, store)
+	})
+
+	fetcher := metadata.NewFetcher(0, 0, nil, nil, false)
+	if c.MetadataWorker != nil {
+		fetcher = metadata.NewFetcher(time.Duration(c.MetadataWorker.Timeout)*time.Second, c.MetadataWorker.MaxSize,
+			c.MetadataWorker.IPFSGateways, c.MetadataWorker.ArweaveGateways, c.MetadataWorker.AllowPrivateURL)
+	}
+	if workerEnabled {
+		worker := metadata.NewWorker(serverCtx.RefreshQueue, store, dao, nodeSrvs, c.ChainSupported, fetcher,
+			metadata.NewParser(c.MetadataParse), c.MetadataWorker.Concurrency)
+		serverCtx.goWorker(worker.Run)
+	}
+
+	if c.Media != nil && c.Media.Enabled {
+		serverCtx.Media, err = media.NewPipeline(dao, store, serverCtx.Storage, fetcher, c.ChainSupported,
+			c.Media.Sizes, c.Media.Format, c.Media.BatchSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on create media pipeline")
+		}
+		mediaInterval := time.Duration(c.Media.Interval) * time.Second
+		serverCtx.goLeader(CacheMediaLeaderKey, func(ctx context.Context) {
+			serverCtx.Media.Run(ctx, mediaInterval)
+		})
+	}
+	if c.Media != nil && c.Media.Proxy {
+		serverCtx.MediaProxy = media.NewProxy(store, serverCtx.Storage, fetcher)
+	}
+
+	var feedInterval time.Duration
+	if c.Stream != nil {
+		feedInterval = time.Duration(c.Stream.FeedInterval) * time.Second
+	}
+	feeder := stream.NewFeeder(serverCtx.Stream, dao, store, c.ChainSupported)
+	serverCtx.goLeader(CacheFeederLeaderKey, func(ctx context.Context) {
+		feeder.Run(ctx, feedInterval)
+	})
+
+	var webhookInterval, webhookTimeout time.Duration
+	var webhookAttempts int
+	var allowPrivateURL bool
+	if c.Webhook != nil {
+		webhookInterval = time.Duration(c.Webhook.Interval) * time.Second
+		webhookTimeout = time.Duration(c.Webhook.Timeout) * time.Second
+		webhookAttempts = c.Webhook.MaxAttempts
+		allowPrivateURL = c.Webhook.AllowPrivateURL
+	}
+	serverCtx.Webhooks = webhook.NewDispatcher(dao, store, c.ChainSupported, webhookTimeout, webhookAttempts, allowPrivateURL)
+	serverCtx.goLeader(CacheWebhookLeaderKey, func(ctx context.Context) {
+		serverCtx.Webhooks.Run(ctx, webhookInterval)
+	})
+
+	var snapshotInterval time.Duration
+	var backfillDays int
+	if c.Snapshot != nil {
+		snapshotInterval = time.Duration(c.Snapshot.Interval) * time.Second
+		backfillDays = c.Snapshot.BackfillDays
+	}
+	snapshotter := portfolio.NewSnapshotter(dao, store, c.ChainSupported, backfillDays)
+	serverCtx.goLeader(CacheSnapshotLeaderKey, func(ctx context.Context) {
+		snapshotter.Run(ctx, snapshotInterval)
+	})
+
+	serverCtx.Marketplaces, err = marketplace.New(c.Marketplace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on create marketplace adapters")
+	}
+	var ingestInterval time.Duration
+	if c.Marketplace != nil {
+		ingestInterval = time.Duration(c.Marketplace.Interval) * time.Second
+	}
+	ingester := marketplace.NewIngester(dao, store, serverCtx.Marketplaces, c.ChainSupported)
+	serverCtx.goLeader(CacheIngesterLeaderKey, func(ctx context.Context) {
+		ingester.Run(ctx, ingestInterval)
+	})
 
 	return serverCtx, nil
 }
+
+// redisOptions returns the client options of the redis deployment of the kv
+// store: a single node, the sentinels of a master or the nodes of a cluster,
+// with the comma separated addresses of a host the way go-zero reads them.
+// Streams and scripts need the deployment as a whole, so a kv store sharded
+// over several independent nodes is refused.
+func redisOptions(nodes []*config.Redis) (*goredis.UniversalOptions, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("kv.redis is required")
+	}
+
+	first := nodes[0]
+	opts := &goredis.UniversalOptions{
+		Password:   first.Pass,
+		MasterName: first.MasterName,
+	}
+	for _, node := range nodes {
+		if node.Type != first.Type || node.Pass != first.Pass || node.MasterName != first.MasterName {
+			return nil, errors.New("redis nodes must share the type, password and master name")
+		}
+		for _, addr := range strings.Split(node.Host, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				opts.Addrs = append(opts.Addrs, addr)
+			}
+		}
+	}
+
+	switch first.Type {
+	case redis.ClusterType:
+		opts.IsClusterMode = true
+	case redis.NodeType, "":
+		if first.MasterName == "" && len(opts.Addrs) > 1 {
+			return nil, errors.New("redis nodes sharding the kv store are not supported, use a single node, a sentinel master or a cluster")
+		}
+	default:
+		return nil, errors.Errorf("unsupported redis type %q", first.Type)
+	}
+
+	return opts, nil
+}
+
+// goWorker runs worker in the background until Shutdown.
+func (s *ServerCtx) goWorker(worker func(ctx context.Context)) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		worker(s.ctx)
+	}()
+}
+
+// Go runs a background task started by a request until it returns or
+// Shutdown.
+func (s *ServerCtx) Go(task func(ctx context.Context)) {
+	s.goWorker(task)
+}
+
+// goLeader runs worker in the background on the one instance holding the
+// leader lock key, until Shutdown.
+func (s *ServerCtx) goLeader(key string, worker func(ctx context.Context)) {
+	lock := leader.NewLock(s.KvStore, key, leader.DefaultLockSeconds)
+	s.goWorker(func(ctx context.Context) {
+		leader.Run(ctx, lock, leader.RenewInterval(leader.DefaultLockSeconds), worker)
+	})
+}
+
+// Shutdown stops the background workers and waits for them to return, or
+// for ctx to be done.
+func (s *ServerCtx) Shutdown(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "failed on wait for background workers")
+	}
+}
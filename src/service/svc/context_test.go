@@ -0,0 +1,89 @@
+package svc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+)
+
+func TestRedisOptions(t *testing.T) {
+	opts, err := redisOptions([]*config.Redis{{Host: "127.0.0.1:6379", Type: "node", Pass: "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.Addrs) != 1 || opts.Password != "secret" || opts.IsClusterMode {
+		t.Fatalf("unexpected node options: %+v", opts)
+	}
+
+	opts, err = redisOptions([]*config.Redis{
+		{Host: "127.0.0.1:7000,127.0.0.1:7001", Type: "cluster"},
+		{Host: "127.0.0.1:7002", Type: "cluster"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.Addrs) != 3 || !opts.IsClusterMode {
+		t.Fatalf("unexpected cluster options: %+v", opts)
+	}
+
+	opts, err = redisOptions([]*config.Redis{
+		{Host: "127.0.0.1:26379", Type: "node", Pass: "secret", MasterName: "master"},
+		{Host: "127.0.0.1:26380", Type: "node", Pass: "secret", MasterName: "master"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.Addrs) != 2 || opts.MasterName != "master" {
+		t.Fatalf("unexpected sentinel options: %+v", opts)
+	}
+
+	for name, nodes := range map[string][]*config.Redis{
+		"shards":           {{Host: "127.0.0.1:6379", Type: "node"}, {Host: "127.0.0.1:6380", Type: "node"}},
+		"mixed types":      {{Host: "127.0.0.1:6379", Type: "node"}, {Host: "127.0.0.1:7000", Type: "cluster"}},
+		"mixed passwords":  {{Host: "127.0.0.1:7000", Type: "cluster", Pass: "secret"}, {Host: "127.0.0.1:7001", Type: "cluster"}},
+		"unsupported type": {{Host: "127.0.0.1:6379", Type: "ring"}},
+		"none":             nil,
+	} {
+		if _, err := redisOptions(nodes); err == nil {
+			t.Fatalf("%s accepted", name)
+		}
+	}
+}
+
+func TestShutdownWaitsForWorkers(t *testing.T) {
+	serverCtx := &ServerCtx{}
+	serverCtx.ctx, serverCtx.cancel = context.WithCancel(context.Background())
+
+	stopped := false
+	serverCtx.goWorker(func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		stopped = true
+	})
+
+	if err := serverCtx.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !stopped {
+		t.Fatal("Shutdown returned before the worker")
+	}
+}
+
+func TestShutdownTimesOut(t *testing.T) {
+	serverCtx := &ServerCtx{}
+	serverCtx.ctx, serverCtx.cancel = context.WithCancel(context.Background())
+
+	release := make(chan struct{})
+	defer close(release)
+	serverCtx.goWorker(func(ctx context.Context) {
+		<-release
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := serverCtx.Shutdown(ctx); err == nil {
+		t.Fatal("Shutdown did not time out on a stuck worker")
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"strings"
 
 	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
 	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
@@ -9,7 +10,7 @@ import (
 )
 
 func GetMultiChainActivities(ctx context.Context, svcCtx *svc.ServerCtx, chainID []int, chainName []string, collectionAddrs []string, tokenID string, userAddrs []string, eventTypes []string, page, pageSize int) (*types.ActivityResp, error) {
-	activities, total, err := svcCtx.Dao.QueryMultiChainActivities(ctx, chainName, collectionAddrs, tokenID, userAddrs, eventTypes, page, pageSize)
+	activities, total, err := svcCtx.Dao.QueryMultiChainActivities(ctx, chainName, collectionAddrs, curatedChains(svcCtx), tokenID, userAddrs, eventTypes, page, pageSize)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed on query multi-chain activity")
 	}
@@ -26,6 +27,16 @@ func GetMultiChainActivities(ctx context.Context, svcCtx *svc.ServerCtx, chainID
 		return nil, errors.Wrap(err, "failed on query activity external info")
 	}
 
+	var addrs []string
+	for _, result := range results {
+		addrs = append(addrs, result.Maker, result.Taker)
+	}
+	names := displayNames(ctx, svcCtx, addrs)
+	for i := range results {
+		results[i].MakerName = names[strings.ToLower(results[i].Maker)]
+		results[i].TakerName = names[strings.ToLower(results[i].Taker)]
+	}
+
 	return &types.ActivityResp{
 		Result: results,
 		Count:  total,
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/mq"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+)
+
+func GetDeadLetters(ctx context.Context, svcCtx *svc.ServerCtx, chain, cursor string, limit int) ([]mq.DeadLetter, error) {
+	letters, err := svcCtx.RefreshQueue.DeadLetters(ctx, chain, cursor, limit)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on get dead letters", zap.String("chain", chain), zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	return letters, nil
+}
+
+// ReplayDeadLetters queues the given dead-lettered refresh items again.
+func ReplayDeadLetters(ctx context.Context, svcCtx *svc.ServerCtx, chain string, ids []string) (*types.DeadLetterResult, error) {
+	count, err := svcCtx.RefreshQueue.Replay(ctx, chain, ids)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on replay dead letters", zap.String("chain", chain), zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	return &types.DeadLetterResult{Count: int64(count)}, nil
+}
+
+// PurgeDeadLetters deletes the given dead-lettered refresh items, all of them
+// when ids is empty.
+func PurgeDeadLetters(ctx context.Context, svcCtx *svc.ServerCtx, chain string, ids []string) (*types.DeadLetterResult, error) {
+	count, err := svcCtx.RefreshQueue.Purge(ctx, chain, ids)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on purge dead letters", zap.String("chain", chain), zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	return &types.DeadLetterResult{Count: count}, nil
+}
@@ -7,7 +7,8 @@ import (
 	"sync"
 
 	"github.com/SimonHofman/EasySwapBackend/src/dao"
-	"github.com/SimonHofman/EasySwapBackend/src/service/mq"
+	"github.com/SimonHofman/EasySwapBackend/src/service/contract"
+	"github.com/SimonHofman/EasySwapBackend/src/service/media"
 	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
 	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
 	"github.com/SimonHofman/EasySwapBase/errcode"
@@ -20,8 +21,27 @@ import (
 	"go.uber.org/zap"
 )
 
-func GetBids(ctx context.Context, svcCtx *svc.ServerCtx, chain string, collectionAddr string, page, pageSize int) (*types.CollectionBidsResp, error) {
-	bids, count, err := svcCtx.Dao.QueryCollectionBids(ctx, chain, collectionAddr, page, pageSize)
+const (
+	MaxSweepCount  = 100
+	SweepBatchSize = 200
+)
+
+// GetBids returns the collection bid depth of a collection per price.
+// Filtered by trait, it returns the depth of the trait bids on that trait per
+// trait value and price instead.
+func GetBids(ctx context.Context, svcCtx *svc.ServerCtx, chain string, collectionAddr string, filter types.CollectionBidFilterParams) (*types.CollectionBidsResp, error) {
+	if filter.Trait != "" {
+		bids, count, err := svcCtx.Dao.QueryCollectionTraitBids(ctx, chain, collectionAddr, filter.Trait, filter.Page, filter.PageSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on get trait bids")
+		}
+		return &types.CollectionBidsResp{
+			Result: bids,
+			Count:  count,
+		}, nil
+	}
+
+	bids, count, err := svcCtx.Dao.QueryCollectionBids(ctx, chain, collectionAddr, filter.Page, filter.PageSize)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed on get item info")
 	}
@@ -50,10 +70,15 @@ func GetItems(ctx context.Context, svcCtx *svc.ServerCtx, chain string, filter t
 		}
 
 		if item.Listing {
+			// the cheapest listing of an erc-1155 token may be of another holder
+			maker := item.ListMaker
+			if maker == "" {
+				maker = item.Owner
+			}
 			itemPrice = append(itemPrice, types.ItemPriceInfo{
 				CollectionAddress: item.CollectionAddress,
 				TokenID:           item.TokenId,
-				Maker:             item.Owner,
+				Maker:             maker,
 				Price:             item.ListPrice,
 				OrderStatus:       multi.OrderStatusActive,
 			})
@@ -156,11 +181,25 @@ func GetItems(ctx context.Context, svcCtx *svc.ServerCtx, chain string, filter t
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		collectionBestBid, err = svcCtx.Dao.QueryCollectionBestBid(ctx, chain, filter.UserAddress, collectionAddr)
+		bid, err := svcCtx.Dao.QueryCollectionBestBid(ctx, chain, filter.UserAddress, collectionAddr)
 		if err != nil {
 			queryErr = errors.Wrap(err, "failed on get items last sale info")
 			return
 		}
+		collectionBestBid = bid
+	}()
+
+	// items of a collection that is not indexed yet are taken as ERC-721
+	tokenStandard := int64(dao.TokenStandardERC721)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		collection, err := svcCtx.Dao.QueryCollectionInfo(ctx, chain, collectionAddr)
+		if err != nil {
+			xzap.WithContext(ctx).Warn("failed on get collection info", zap.Error(err))
+			return
+		}
+		tokenStandard = collection.TokenStandard
 	}()
 
 	wg.Wait()
@@ -168,6 +207,16 @@ func GetItems(ctx context.Context, svcCtx *svc.ServerCtx, chain string, filter t
 		return nil, errors.Wrap(queryErr, "failed on get items info")
 	}
 
+	// the owned amount of an erc-1155 token is the balance of its owner
+	var tokenBalances map[string]int64
+	if tokenStandard == dao.TokenStandardERC1155 {
+		tokenBalances, err = svcCtx.Dao.QueryTokenBalances(ctx, chain, collectionAddr, ItemIds, removeRepeatedElement(ItemOwners))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on get token balances")
+		}
+	}
+
+	chainID := chainIDOf(svcCtx, chain)
 	var respItems []*types.NFTListingInfo
 	for _, item := range items {
 		nameStr := item.Name
@@ -196,6 +245,9 @@ func GetItems(ctx context.Context, svcCtx *svc.ServerCtx, chain string, filter t
 		listOrder, ok := ordersInfo[strings.ToLower(item.CollectionAddress+item.TokenId)]
 		if ok {
 			respItem.ListTime = listOrder.EventTime
+			respItem.ListMaker = listOrder.Maker
+			respItem.ListSize = listOrder.Size
+			respItem.ListUnfilled = listOrder.QuantityRemaining
 			respItem.ListOrderID = listOrder.OrderID
 			respItem.ListExpireTime = listOrder.ExpireTime
 			respItem.ListSalt = listOrder.Salt
@@ -218,18 +270,10 @@ func GetItems(ctx context.Context, svcCtx *svc.ServerCtx, chain string, filter t
 
 		itemExternal, ok := ItemsExternal[strings.ToLower(item.TokenId)]
 		if ok {
-			if itemExternal.IsUploadedOss {
-				respItem.ImageURI = itemExternal.OssUri
-			} else {
-				respItem.ImageURI = itemExternal.ImageUri
-			}
+			respItem.ImageURI = itemImageURI(svcCtx, chainID, itemExternal)
 			if len(itemExternal.VideoUri) > 0 {
 				respItem.VideoType = itemExternal.VideoType
-				if itemExternal.IsVideoUploaded {
-					respItem.VideoURI = itemExternal.VideoOssUri
-				} else {
-					respItem.VideoURI = itemExternal.VideoUri
-				}
+				respItem.VideoURI = itemVideoURI(svcCtx, chainID, itemExternal)
 			}
 		}
 
@@ -237,6 +281,9 @@ func GetItems(ctx context.Context, svcCtx *svc.ServerCtx, chain string, filter t
 		if ok {
 			respItem.OwnerOwnedAmount = count
 		}
+		if tokenBalances != nil {
+			respItem.OwnerOwnedAmount = tokenBalances[dao.TokenBalanceKey(item.TokenId, item.Owner)]
+		}
 
 		price, ok := lastSales[strings.ToLower(item.TokenId)]
 		if ok {
@@ -246,6 +293,16 @@ func GetItems(ctx context.Context, svcCtx *svc.ServerCtx, chain string, filter t
 		respItems = append(respItems, respItem)
 	}
 
+	var makers []string
+	for _, respItem := range respItems {
+		makers = append(makers, respItem.ListMaker, respItem.BidMaker)
+	}
+	names := displayNames(ctx, svcCtx, makers)
+	for i := range respItems {
+		respItems[i].ListMakerName = names[strings.ToLower(respItems[i].ListMaker)]
+		respItems[i].BidMakerName = names[strings.ToLower(respItems[i].BidMaker)]
+	}
+
 	return &types.NFTListingInfoResp{
 		Result: respItems,
 		Count:  count,
@@ -350,6 +407,20 @@ func GetItem(ctx context.Context, svcCtx *svc.ServerCtx, chain string, chainID i
 		collectionBestBid = bid
 	}()
 
+	var traitBestBid *dao.TraitBid
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		traitBids, err := svcCtx.Dao.QueryItemTraitBids(ctx, chain, "", collectionAddr, tokenID)
+		if err != nil {
+			queryErr = errors.Wrap(err, "failed on get item trait bids")
+			return
+		}
+		if len(traitBids) > 0 {
+			traitBestBid = &traitBids[0]
+		}
+	}()
+
 	wg.Wait()
 	if queryErr != nil {
 		return nil, errors.Wrap(queryErr, "failed on get items info")
@@ -389,6 +460,18 @@ func GetItem(ctx context.Context, svcCtx *svc.ServerCtx, chain string, chainID i
 		}
 	}
 
+	if traitBestBid != nil && traitBestBid.Price.GreaterThan(itemDetail.BidPrice) {
+		itemDetail.BidOrderID = traitBestBid.OrderID
+		itemDetail.BidExpireTime = traitBestBid.ExpireTime
+		itemDetail.BidPrice = traitBestBid.Price
+		itemDetail.BidTime = traitBestBid.EventTime
+		itemDetail.BidSalt = traitBestBid.Salt
+		itemDetail.BidMaker = traitBestBid.Maker
+		itemDetail.BidType = getBidType(traitBestBid.OrderType)
+		itemDetail.BidSize = traitBestBid.Size
+		itemDetail.BidUnfilled = traitBestBid.QuantityRemaining
+	}
+
 	if itemListInfo != nil {
 		itemDetail.ListPrice = itemListInfo.ListPrice
 		itemDetail.MarketplaceID = itemListInfo.MarketID
@@ -397,9 +480,22 @@ func GetItem(ctx context.Context, svcCtx *svc.ServerCtx, chain string, chainID i
 		itemDetail.ListExpireTime = itemListInfo.ListExpireTime
 		itemDetail.ListSalt = itemListInfo.ListSalt
 		itemDetail.ListMaker = itemListInfo.ListMaker
+		itemDetail.ListSize = itemListInfo.ListSize
+		itemDetail.ListUnfilled = itemListInfo.ListUnfilled
 	}
 
 	if collection != nil {
+		itemDetail.TokenStandard = collection.TokenStandard
+		if collection.TokenStandard == dao.TokenStandardERC1155 {
+			holders, err := svcCtx.Dao.QueryItemHolders(ctx, chain, collectionAddr, tokenID)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed on get item holders")
+			}
+			for _, holder := range holders {
+				itemDetail.Holders = append(itemDetail.Holders, types.ItemHolder{Owner: holder.Owner, Balance: holder.Balance})
+			}
+		}
+
 		itemDetail.CollectionName = collection.Name
 		itemDetail.FloorPrice = collection.FloorPrice
 		itemDetail.CollectionImageURI = collection.ImageUri
@@ -415,20 +511,20 @@ func GetItem(ctx context.Context, svcCtx *svc.ServerCtx, chain string, chainID i
 
 	itemExternal, ok := ItemExternals[strings.ToLower(tokenID)]
 	if ok {
-		itemDetail.ImageURI = itemExternal.ImageUri
+		itemDetail.ImageURI = itemImageURI(svcCtx, chainID, itemExternal)
 		if itemExternal.IsUploadedOss {
-			itemDetail.ImageURI = itemExternal.OssUri
+			itemDetail.Thumbnails = itemThumbnails(svcCtx, itemExternal.OssUri)
 		}
 		if len(itemExternal.VideoUri) > 0 {
 			itemDetail.VideoType = itemExternal.VideoType
-			if itemExternal.IsVideoUploaded {
-				itemDetail.VideoURI = itemExternal.VideoOssUri
-			} else {
-				itemDetail.VideoURI = itemExternal.VideoUri
-			}
+			itemDetail.VideoURI = itemVideoURI(svcCtx, chainID, itemExternal)
 		}
 	}
 
+	names := displayNames(ctx, svcCtx, []string{itemDetail.ListMaker, itemDetail.BidMaker})
+	itemDetail.ListMakerName = names[strings.ToLower(itemDetail.ListMaker)]
+	itemDetail.BidMakerName = names[strings.ToLower(itemDetail.BidMaker)]
+
 	return &types.ItemDetailInfoResp{
 		Result: itemDetail,
 	}, nil
@@ -517,7 +613,27 @@ func GetHistorySalesPrice(ctx context.Context, svcCtx *svc.ServerCtx, chain, col
 	return res, nil
 }
 
+// MaxHolderRefresh bounds the balances read onchain by an owner lookup of an
+// ERC-1155 token, the stored balances are returned past it. The balances of a
+// token are read at most once per HolderRefreshPeriod seconds, by
+// HolderRefreshWorkers concurrent calls.
+const (
+	MaxHolderRefresh     = 50
+	HolderRefreshPeriod  = 300
+	HolderRefreshWorkers = 8
+
+	CacheHolderRefreshKey = "cache:es:item:holders:%d:%s:%s"
+)
+
 func GetItemOwner(ctx context.Context, svcCtx *svc.ServerCtx, chainID int64, chain, collectionAddr, tokenID string) (*types.ItemOwner, error) {
+	// a collection that is not indexed yet is taken as ERC-721
+	collection, err := svcCtx.Dao.QueryCollectionInfo(ctx, chain, collectionAddr)
+	if err != nil {
+		xzap.WithContext(ctx).Warn("failed on query collection info", zap.Error(err))
+	} else if collection.TokenStandard == dao.TokenStandardERC1155 {
+		return getItemHolders(ctx, svcCtx, chainID, chain, collectionAddr, tokenID)
+	}
+
 	address, err := svcCtx.NodeSrvs[chainID].FetchNftOwner(collectionAddr, tokenID)
 	if err != nil {
 		xzap.WithContext(ctx).Error("failed on fetch nft owner onchain", zap.Error(err))
@@ -538,9 +654,108 @@ func GetItemOwner(ctx context.Context, svcCtx *svc.ServerCtx, chainID int64, cha
 		CollectionAddress: collectionAddr,
 		TokenID:           tokenID,
 		Owner:             owner,
+		Holders:           []types.ItemHolder{{Owner: owner, Balance: 1}},
 	}, nil
 }
 
+// getItemHolders returns the holders of an ERC-1155 token. The balances of
+// the addresses that may hold it are refreshed onchain first, unless they
+// were refreshed recently, and the largest holder is kept as the owner of the
+// item.
+func getItemHolders(ctx context.Context, svcCtx *svc.ServerCtx, chainID int64, chain, collectionAddr, tokenID string) (*types.ItemOwner, error) {
+	refreshItemHolders(ctx, svcCtx, chainID, chain, collectionAddr, tokenID)
+
+	holders, err := svcCtx.Dao.QueryItemHolders(ctx, chain, collectionAddr, tokenID)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on query item holders", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	itemOwner := &types.ItemOwner{
+		CollectionAddress: collectionAddr,
+		TokenID:           tokenID,
+		Holders:           []types.ItemHolder{},
+	}
+	for _, holder := range holders {
+		itemOwner.Holders = append(itemOwner.Holders, types.ItemHolder{Owner: holder.Owner, Balance: holder.Balance})
+	}
+	if len(holders) == 0 {
+		return itemOwner, nil
+	}
+
+	itemOwner.Owner, err = eip.ToCheckSumAddress(holders[0].Owner)
+	if err != nil {
+		xzap.WithContext(ctx).Error("invalid address", zap.Error(err), zap.String("address", holders[0].Owner))
+		return nil, errcode.ErrUnexpected
+	}
+	if err := svcCtx.Dao.UpdateItemOwner(ctx, chain, collectionAddr, tokenID, itemOwner.Owner); err != nil {
+		xzap.WithContext(ctx).Error("failed on update item owner", zap.Error(err), zap.String("address", itemOwner.Owner))
+	}
+
+	return itemOwner, nil
+}
+
+// refreshItemHolders reads onchain the balances of the addresses that may
+// hold an ERC-1155 token and stores them. Failures are logged, the stored
+// balances are served then.
+func refreshItemHolders(ctx context.Context, svcCtx *svc.ServerCtx, chainID int64, chain, collectionAddr, tokenID string) {
+	nodeSrv, ok := svcCtx.NodeSrvs[chainID]
+	if !ok {
+		return
+	}
+
+	refreshKey := fmt.Sprintf(CacheHolderRefreshKey, chainID, strings.ToLower(collectionAddr), tokenID)
+	if ok, err := svcCtx.KvStore.SetnxEx(refreshKey, "1", HolderRefreshPeriod); err != nil || !ok {
+		return
+	}
+
+	candidates, err := svcCtx.Dao.QueryItemHolderCandidates(ctx, chain, collectionAddr, tokenID)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on query item holder candidates", zap.Error(err))
+		return
+	}
+	if len(candidates) > MaxHolderRefresh {
+		return
+	}
+
+	balances := make([]dao.ItemBalance, len(candidates))
+	errs := make([]error, len(candidates))
+	sem := make(chan struct{}, HolderRefreshWorkers)
+	var wg sync.WaitGroup
+	for i, candidate := range candidates {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, candidate string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			balance, err := contract.BalanceOf1155(ctx, nodeSrv.ChainClient, collectionAddr, candidate, tokenID)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			balances[i] = dao.ItemBalance{
+				CollectionAddress: collectionAddr,
+				TokenId:           tokenID,
+				Owner:             candidate,
+				Balance:           balance.Int64(),
+			}
+		}(i, candidate)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on fetch nft balance onchain", zap.Error(err), zap.String("address", candidates[i]))
+			return
+		}
+	}
+	if err := svcCtx.Dao.SaveItemBalances(ctx, chain, balances); err != nil {
+		xzap.WithContext(ctx).Error("failed on save item balances", zap.Error(err))
+	}
+}
+
 func GetItemTraits(ctx context.Context, svcCtx *svc.ServerCtx, chain, collectionAddr, tokenID string) ([]types.TraitInfo, error) {
 	var traitInfos []types.TraitInfo
 	var itemTraits []multi.ItemTrait
@@ -681,20 +896,32 @@ func GetCollectionDetail(ctx context.Context, svcCtx *svc.ServerCtx, chain strin
 		allVol = collectionVol
 	}
 
+	var royaltyFeeRate string
+	rate, err := svcCtx.Royalty.RoyaltyRate(ctx, int64(collection.ChainId), chain, collectionAddr, "")
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on get royalty rate", zap.Error(err))
+	} else {
+		royaltyFeeRate = rate.String()
+	}
+
 	detail := types.CollectionDetail{
-		ImageUri:    collection.ImageUri,
-		Name:        collection.Name,
-		Address:     collection.Address,
-		ChainId:     collection.ChainId,
-		FloorPrice:  floorPrice,
-		SellPrice:   collectionSell.SalePrice.String(),
-		VolumeTotal: allVol,
-		Volume24h:   volume24h,
-		Sold24h:     sold,
-		ListAmount:  listed,
-		TotalSupply: collection.ItemAmount,
-		OwnerAmount: collection.OwnerAmount,
+		ImageUri:       collection.ImageUri,
+		Name:           collection.Name,
+		Address:        collection.Address,
+		ChainId:        collection.ChainId,
+		FloorPrice:     floorPrice,
+		SellPrice:      collectionSell.SalePrice.String(),
+		VolumeTotal:    allVol,
+		Volume24h:      volume24h,
+		Sold24h:        sold,
+		ListAmount:     listed,
+		TotalSupply:    collection.ItemAmount,
+		OwnerAmount:    collection.OwnerAmount,
+		RoyaltyFeeRate: royaltyFeeRate,
 	}
+	curation := curationOf(ctx, svcCtx, collection.ChainId, collectionAddr)
+	detail.IsVerified = curation.IsVerified
+	detail.IsFeatured = curation.IsFeatured
 
 	return &types.CollectionDetailResp{
 		Result: detail,
@@ -702,11 +929,17 @@ func GetCollectionDetail(ctx context.Context, svcCtx *svc.ServerCtx, chain strin
 }
 
 func RefreshItemMetadata(ctx context.Context, svcCtx *svc.ServerCtx, chainName string, chainId int64, collectionAddress, tokenId string) error {
-	if err := mq.AddSingleItemToRefreshMetadataQueue(svcCtx.KvStore, svcCtx.C.ProjectCfg.Name, chainName, chainId, collectionAddress, tokenId); err != nil {
+	if err := svcCtx.RefreshQueue.AddItem(ctx, chainName, types.RefreshItem{
+		ChainID:        chainId,
+		CollectionAddr: collectionAddress,
+		TokenID:        tokenId,
+	}); err != nil {
 		xzap.WithContext(ctx).Error("failed on add item to refresh queue", zap.Error(err), zap.String("collection address: ", collectionAddress), zap.String("item_id", tokenId))
 		return errcode.ErrUnexpected
 	}
 
+	svcCtx.Search.MarkItemDirty(chainName, collectionAddress, tokenId)
+
 	return nil
 }
 
@@ -716,16 +949,132 @@ func GetItemImage(ctx context.Context, svcCtx *svc.ServerCtx, chain string, coll
 	if err != nil || len(items) == 0 {
 		return nil, errors.Wrap(err, "failed on get item image")
 	}
-	var imageUri string
+	var thumbnails []types.Thumbnail
 	if items[0].IsUploadedOss {
-		imageUri = items[0].OssUri
-	} else {
-		imageUri = items[0].ImageUri
+		thumbnails = itemThumbnails(svcCtx, items[0].OssUri)
 	}
 
 	return &types.ItemImage{
 		CollectionAddress: collectionAddress,
 		TokenID:           tokenId,
-		ImageUri:          imageUri,
+		ImageUri:          itemImageURI(svcCtx, chainIDOf(svcCtx, chain), items[0]),
+		Thumbnails:        thumbnails,
+	}, nil
+}
+
+// itemThumbnails returns the urls of the thumbnails the media pipeline wrote
+// next to the largest one, saved as the oss uri of an uploaded item image.
+func itemThumbnails(svcCtx *svc.ServerCtx, ossURI string) []types.Thumbnail {
+	if svcCtx.Media == nil {
+		return nil
+	}
+
+	var thumbnails []types.Thumbnail
+	for _, size := range svcCtx.Media.Sizes() {
+		uri := media.ThumbnailURL(ossURI, svcCtx.Media.MaxSize(), size, svcCtx.Media.Format())
+		if uri == "" {
+			return nil
+		}
+		thumbnails = append(thumbnails, types.Thumbnail{
+			Size: size,
+			URI:  uri,
+		})
+	}
+	return thumbnails
+}
+
+// GetSweepQuote picks the cheapest valid listings of a collection, one per
+// token, until the count or the budget is reached.
+func GetSweepQuote(ctx context.Context, svcCtx *svc.ServerCtx, chain string, collectionAddr string, params types.SweepParams) (*types.SweepResp, error) {
+	var budget decimal.Decimal
+	if params.Budget != "" {
+		var err error
+		if budget, err = decimal.NewFromString(params.Budget); err != nil {
+			return nil, errors.Wrap(err, "invalid budget")
+		}
+	}
+
+	count := params.Count
+	if count <= 0 || count > MaxSweepCount {
+		count = MaxSweepCount
+	}
+
+	floorPrice, err := svcCtx.Dao.QueryFloorPrice(ctx, chain, collectionAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on query floor price")
+	}
+
+	quote := types.SweepQuote{
+		Items:      []types.SweepItem{},
+		FloorPrice: floorPrice,
+	}
+
+	picked := make(map[string]bool)
+	done := false
+	for offset := 0; !done; offset += SweepBatchSize {
+		listings, err := svcCtx.Dao.QueryCheapestListings(ctx, chain, collectionAddr, params.Traits, params.Markets, SweepBatchSize, offset)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on query listings")
+		}
+
+		for _, listing := range listings {
+			if picked[listing.TokenId] {
+				continue
+			}
+			picked[listing.TokenId] = true
+
+			total := quote.TotalPrice.Add(listing.Price)
+			if len(quote.Items) >= count || (!budget.IsZero() && total.GreaterThan(budget)) {
+				// the first listing left behind becomes the new floor
+				quote.FloorAfter = listing.Price
+				done = true
+				break
+			}
+
+			marketplaceFee := listing.Price.Mul(marketplaceFeeRate(svcCtx.C.Fee, listing.MarketplaceId))
+			quote.Items = append(quote.Items, types.SweepItem{
+				TokenID:        listing.TokenId,
+				OrderID:        listing.OrderID,
+				MarketplaceID:  listing.MarketplaceId,
+				Maker:          listing.Maker,
+				Price:          listing.Price,
+				Salt:           listing.Salt,
+				ExpireTime:     listing.ExpireTime,
+				MarketplaceFee: marketplaceFee,
+				RunningTotal:   total,
+			})
+			quote.TotalPrice = total
+			quote.TotalFee = quote.TotalFee.Add(marketplaceFee)
+		}
+
+		if len(listings) < SweepBatchSize {
+			done = true
+		}
+	}
+
+	// ERC-2981 rates are per token, the rate of the cheapest picked token
+	// stands for the sweep
+	if len(quote.Items) > 0 {
+		royaltyRate, royaltyEstimated, err := quoteRoyaltyRate(ctx, svcCtx, params.ChainID, chain, collectionAddr, quote.Items[0].TokenID)
+		if err != nil {
+			return nil, err
+		}
+		quote.RoyaltyFeeRate, quote.RoyaltyEstimated = royaltyRate, royaltyEstimated
+		for i := range quote.Items {
+			quote.Items[i].RoyaltyFee = quote.Items[i].Price.Mul(royaltyRate)
+			quote.TotalRoyalty = quote.TotalRoyalty.Add(quote.Items[i].RoyaltyFee)
+		}
+	}
+
+	quote.Count = len(quote.Items)
+	if quote.Count > 0 {
+		quote.AveragePrice = quote.TotalPrice.Div(decimal.NewFromInt(int64(quote.Count)))
+		if floorPrice.IsPositive() {
+			quote.PriceImpact = quote.AveragePrice.Sub(floorPrice).Div(floorPrice)
+		}
+	}
+
+	return &types.SweepResp{
+		Result: quote,
 	}, nil
 }
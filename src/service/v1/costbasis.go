@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// itemCost is the FIFO cost basis of a token for a set of user addresses.
+type itemCost struct {
+	// lots are the held units, oldest first
+	lots     []lot
+	proceeds decimal.Decimal
+	realized decimal.Decimal
+	sold     int64
+}
+
+func (c *itemCost) held() int64 {
+	return int64(len(c.lots))
+}
+
+func (c *itemCost) costBasis() decimal.Decimal {
+	cost := decimal.Zero
+	for _, lot := range c.lots {
+		cost = cost.Add(lot.cost)
+	}
+	return cost
+}
+
+// lastCost is the cost of the unit sold next.
+func (c *itemCost) lastCost() decimal.Decimal {
+	if len(c.lots) == 0 {
+		return decimal.Zero
+	}
+	return c.lots[0].cost
+}
+
+func (c *itemCost) unrealized(price decimal.Decimal) decimal.Decimal {
+	if len(c.lots) == 0 || !price.IsPositive() {
+		return decimal.Zero
+	}
+	return price.Mul(decimal.NewFromInt(c.held())).Sub(c.costBasis())
+}
+
+func costKey(chain, collectionAddr, tokenID string) dao.MultiChainItemInfo {
+	return dao.MultiChainItemInfo{
+		ItemInfo:  types.ItemInfo{CollectionAddress: strings.ToLower(collectionAddr), TokenID: tokenID},
+		ChainName: chain,
+	}
+}
+
+// queryCostBasis replays the sales, buys, mints and transfers of userAddrs in
+// FIFO order, for the given items of each chain when items is not nil. Sales
+// are realized net of marketplace fees and royalties. A sale without a known
+// acquisition is left out, as its cost basis is unknown. Hidden collections
+// are left out.
+func queryCostBasis(ctx context.Context, svcCtx *svc.ServerCtx, chainIDs []int, chains []string, userAddrs []string, contractAddrs []string,
+	items map[string][]types.ItemInfo) (map[dao.MultiChainItemInfo]*itemCost, error) {
+	users := make(map[string]bool)
+	var lowerAddrs []string
+	for _, addr := range userAddrs {
+		users[strings.ToLower(addr)] = true
+		lowerAddrs = append(lowerAddrs, strings.ToLower(addr))
+	}
+
+	curated := loadCurations(ctx, svcCtx, chainIDs)
+	costs := make(map[dao.MultiChainItemInfo]*itemCost)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var queryErr error
+	for i, chain := range chains {
+		var chainItems []types.ItemInfo
+		if items != nil {
+			chainItems = items[chain]
+			if len(chainItems) == 0 {
+				continue
+			}
+		}
+
+		wg.Add(1)
+		go func(chainID int, chain string) {
+			defer wg.Done()
+			activities, err := svcCtx.Dao.QueryUserTradeActivities(ctx, chain, lowerAddrs, contractAddrs, chainItems)
+			if err != nil {
+				mu.Lock()
+				queryErr = err
+				mu.Unlock()
+				return
+			}
+
+			var visible []multi.Activity
+			for _, activity := range activities {
+				if !curated.hidden(chainID, activity.CollectionAddress) {
+					visible = append(visible, activity)
+				}
+			}
+
+			royalties := make(map[string]decimal.Decimal)
+			chainCosts := replayCosts(chain, users, visible, func(activity multi.Activity) decimal.Decimal {
+				collectionAddr := strings.ToLower(activity.CollectionAddress)
+				royalty, ok := royalties[collectionAddr]
+				if !ok {
+					royalty, err = svcCtx.Royalty.RoyaltyRate(ctx, int64(chainID), chain, activity.CollectionAddress, activity.TokenId)
+					if err != nil {
+						xzap.WithContext(ctx).Error("failed on get royalty rate", zap.String("collection", activity.CollectionAddress), zap.Error(err))
+						royalty = decimal.Zero
+					}
+					royalties[collectionAddr] = royalty
+				}
+
+				feeRate := marketplaceFeeRate(svcCtx.C.Fee, activity.MarketplaceID)
+				return activity.Price.Mul(decimal.NewFromInt(1).Sub(feeRate).Sub(royalty))
+			})
+
+			mu.Lock()
+			for key, cost := range chainCosts {
+				costs[key] = cost
+			}
+			mu.Unlock()
+		}(chainIDs[i], chain)
+	}
+	wg.Wait()
+
+	if queryErr != nil {
+		return nil, errors.Wrap(queryErr, "failed on query cost basis")
+	}
+	return costs, nil
+}
+
+// replayCosts applies the activities of a chain, oldest first, to the lots of
+// users. A token sent away leaves its oldest lot without proceeds. net
+// returns the proceeds of a sale.
+func replayCosts(chain string, users map[string]bool, activities []multi.Activity,
+	net func(activity multi.Activity) decimal.Decimal) map[dao.MultiChainItemInfo]*itemCost {
+	costs := make(map[dao.MultiChainItemInfo]*itemCost)
+	book := newLotBook(users)
+	// moves never fail here
+	_ = book.replay(chain, activities, func(m lotMove) error {
+		cost, ok := costs[m.key]
+		if !ok {
+			cost = &itemCost{}
+			costs[m.key] = cost
+		}
+		if m.in || !m.held || m.activity.ActivityType == multi.Transfer {
+			return nil
+		}
+
+		proceeds := net(m.activity)
+		cost.proceeds = cost.proceeds.Add(proceeds)
+		cost.realized = cost.realized.Add(proceeds.Sub(m.lot.cost))
+		cost.sold++
+		return nil
+	})
+
+	for key, cost := range costs {
+		cost.lots = book.lots[key]
+	}
+	return costs
+}
+
+// GetMultiChainUserPnL returns the profit and loss of userAddrs per
+// collection, per chain and across chains. Tokens transferred away without a
+// sale are dropped, as they are neither realized nor held.
+func GetMultiChainUserPnL(ctx context.Context, svcCtx *svc.ServerCtx, chainIDs []int, chains []string, userAddrs []string, contractAddrs []string) (*types.PortfolioPnLResp, error) {
+	costs, err := queryCostBasis(ctx, svcCtx, chainIDs, chains, userAddrs, contractAddrs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]bool)
+	for _, addr := range userAddrs {
+		users[strings.ToLower(addr)] = true
+	}
+	var userAddr string
+	if len(userAddrs) > 0 {
+		userAddr = strings.ToLower(userAddrs[0])
+	}
+
+	result := types.PortfolioPnL{
+		Chains:      []types.ChainPnL{},
+		Collections: []types.CollectionPnL{},
+	}
+	for i, chain := range chains {
+		var heldItems []types.ItemInfo
+		collectionPnLs := make(map[string]*types.CollectionPnL)
+		for key, cost := range costs {
+			if key.ChainName != chain {
+				continue
+			}
+			if cost.held() > 0 {
+				heldItems = append(heldItems, key.ItemInfo)
+			}
+			if _, ok := collectionPnLs[key.CollectionAddress]; !ok {
+				collectionPnLs[key.CollectionAddress] = &types.CollectionPnL{ChainID: chainIDs[i], CollectionAddress: key.CollectionAddress}
+			}
+		}
+		if len(collectionPnLs) == 0 {
+			continue
+		}
+
+		items, err := svcCtx.Dao.QueryItemsByInfos(ctx, chain, heldItems)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on query held items")
+		}
+		owned := make(map[dao.MultiChainItemInfo]bool)
+		for _, item := range items {
+			if users[strings.ToLower(item.Owner)] {
+				owned[costKey(chain, item.CollectionAddress, item.TokenId)] = true
+			}
+		}
+
+		var collectionAddrs [][]string
+		var addrs []string
+		for addr := range collectionPnLs {
+			collectionAddrs = append(collectionAddrs, []string{addr, chain})
+			addrs = append(addrs, addr)
+		}
+		collections, err := svcCtx.Dao.QueryMultiChainCollectionsInfo(ctx, collectionAddrs)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on query collections info")
+		}
+		floors := make(map[string]decimal.Decimal)
+		for _, collection := range collections {
+			floors[strings.ToLower(collection.Address)] = collection.FloorPrice
+			if pnl, ok := collectionPnLs[strings.ToLower(collection.Address)]; ok {
+				pnl.CollectionName = collection.Name
+			}
+		}
+
+		collectionBids, err := svcCtx.Dao.QueryCollectionsBestBid(ctx, chain, userAddr, addrs)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on query collections best bids")
+		}
+		bids := make(map[string]decimal.Decimal)
+		for _, bid := range collectionBids {
+			bids[strings.ToLower(bid.CollectionAddress)] = bid.Price
+		}
+
+		itemBids := make(map[dao.MultiChainItemInfo]decimal.Decimal)
+		if len(heldItems) > 0 {
+			orders, err := svcCtx.Dao.QueryItemsBestBids(ctx, chain, userAddr, heldItems)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed on query items best bids")
+			}
+			for _, order := range orders {
+				key := costKey(chain, order.CollectionAddress, order.TokenId)
+				if order.Price.GreaterThan(itemBids[key]) {
+					itemBids[key] = order.Price
+				}
+			}
+		}
+
+		chainPnL := types.ChainPnL{ChainID: chainIDs[i]}
+		for key, cost := range costs {
+			if key.ChainName != chain {
+				continue
+			}
+			if !owned[key] {
+				cost.lots = nil
+			}
+
+			floor := floors[key.CollectionAddress]
+			bid := decimal.Max(bids[key.CollectionAddress], itemBids[key])
+			itemPnL := types.PnL{
+				Held:        cost.held(),
+				Sold:        cost.sold,
+				CostBasis:   cost.costBasis(),
+				Proceeds:    cost.proceeds,
+				RealizedPnL: cost.realized,
+				FloorValue:  floor.Mul(decimal.NewFromInt(cost.held())),
+				BidValue:    bid.Mul(decimal.NewFromInt(cost.held())),
+				FloorPnL:    cost.unrealized(floor),
+				BidPnL:      cost.unrealized(bid),
+			}
+
+			addPnL(&collectionPnLs[key.CollectionAddress].PnL, &itemPnL)
+			addPnL(&chainPnL.PnL, &itemPnL)
+			addPnL(&result.Total, &itemPnL)
+		}
+
+		for _, pnl := range collectionPnLs {
+			if pnl.Held > 0 || pnl.Sold > 0 {
+				result.Collections = append(result.Collections, *pnl)
+			}
+		}
+		result.Chains = append(result.Chains, chainPnL)
+	}
+
+	return &types.PortfolioPnLResp{Result: result}, nil
+}
+
+func addPnL(total *types.PnL, pnl *types.PnL) {
+	total.Held += pnl.Held
+	total.Sold += pnl.Sold
+	total.CostBasis = total.CostBasis.Add(pnl.CostBasis)
+	total.Proceeds = total.Proceeds.Add(pnl.Proceeds)
+	total.RealizedPnL = total.RealizedPnL.Add(pnl.RealizedPnL)
+	total.FloorValue = total.FloorValue.Add(pnl.FloorValue)
+	total.BidValue = total.BidValue.Add(pnl.BidValue)
+	total.FloorPnL = total.FloorPnL.Add(pnl.FloorPnL)
+	total.BidPnL = total.BidPnL.Add(pnl.BidPnL)
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/shopspring/decimal"
+)
+
+func TestReplayCostsTransfers(t *testing.T) {
+	users := map[string]bool{"0xuser": true}
+	activity := func(activityType int, maker, taker, price, txHash, tokenID string) multi.Activity {
+		return multi.Activity{
+			ActivityType:      activityType,
+			Maker:             maker,
+			Taker:             taker,
+			CollectionAddress: "0xcollection",
+			TokenId:           tokenID,
+			Price:             decimal.RequireFromString(price),
+			TxHash:            txHash,
+		}
+	}
+	net := func(activity multi.Activity) decimal.Decimal {
+		return activity.Price
+	}
+
+	costs := replayCosts("eth", users, []multi.Activity{
+		// bought, with the transfer of the sale logged next to it
+		activity(multi.Sale, "0xother", "0xuser", "1", "0x1", "1"),
+		activity(multi.Transfer, "0xother", "0xuser", "0", "0x1", "1"),
+		// given away, then bought again and sold
+		activity(multi.Transfer, "0xuser", "0xfriend", "0", "0x2", "1"),
+		activity(multi.Sale, "0xother", "0xuser", "2", "0x3", "1"),
+		activity(multi.Sale, "0xuser", "0xother", "5", "0x4", "1"),
+		// received for free and sold
+		activity(multi.Transfer, "0xfriend", "0xuser", "0", "0x5", "2"),
+		activity(multi.Sale, "0xuser", "0xother", "3", "0x6", "2"),
+	}, net)
+
+	first := costs[costKey("eth", "0xcollection", "1")]
+	if first.held() != 0 || first.sold != 1 || !first.realized.Equal(decimal.NewFromInt(3)) {
+		t.Fatalf("token 1: held %d, sold %d, realized %s", first.held(), first.sold, first.realized)
+	}
+
+	second := costs[costKey("eth", "0xcollection", "2")]
+	if second.held() != 0 || second.sold != 1 || !second.realized.Equal(decimal.NewFromInt(3)) {
+		t.Fatalf("token 2: held %d, sold %d, realized %s", second.held(), second.sold, second.realized)
+	}
+}
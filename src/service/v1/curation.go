@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/search"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// curations are the curated collections by chain id and address.
+type curations map[string]dao.CollectionCuration
+
+func curationKey(chainID int, collectionAddr string) string {
+	return fmt.Sprintf("%d:%s", chainID, strings.ToLower(collectionAddr))
+}
+
+// loadCurations returns the curations of the given chains, cached by the
+// dao. Responses are served uncurated rather than failed when they can not be
+// loaded.
+func loadCurations(ctx context.Context, svcCtx *svc.ServerCtx, chainIDs []int) curations {
+	rows, err := svcCtx.Dao.QueryCollectionCurations(ctx, chainIDs)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on query collection curations", zap.Error(err))
+		return nil
+	}
+
+	result := make(curations, len(rows))
+	for _, row := range rows {
+		result[curationKey(row.ChainID, row.CollectionAddress)] = row
+	}
+	return result
+}
+
+func (c curations) get(chainID int, collectionAddr string) dao.CollectionCuration {
+	return c[curationKey(chainID, collectionAddr)]
+}
+
+func (c curations) hidden(chainID int, collectionAddr string) bool {
+	return c.get(chainID, collectionAddr).IsHidden
+}
+
+// hiddenDocs returns the search document ids of the hidden collections.
+func (c curations) hiddenDocs() map[string]bool {
+	result := make(map[string]bool)
+	for _, curation := range c {
+		if curation.IsHidden {
+			result[search.CollectionDocID(curation.ChainID, curation.CollectionAddress)] = true
+		}
+	}
+	return result
+}
+
+// curatedChains returns the chain ids by name of the supported chains, the
+// queries given it leave out the hidden collections of these chains.
+func curatedChains(svcCtx *svc.ServerCtx) map[string]int {
+	result := make(map[string]int)
+	for _, supported := range svcCtx.C.ChainSupported {
+		result[supported.Name] = supported.ChainID
+	}
+	return result
+}
+
+// SetCollectionCuration updates the curation flags and note of a collection,
+// the change is recorded in the audit log under operator. Only the given
+// fields change, merged with the stored curation in the same transaction.
+func SetCollectionCuration(ctx context.Context, svcCtx *svc.ServerCtx, operator, chain, collectionAddr string, params types.CurationParams) (*types.CollectionCuration, error) {
+	if _, err := svcCtx.Dao.QueryCollectionInfo(ctx, chain, collectionAddr); err != nil {
+		return nil, errcode.NewCustomErr("collection not found")
+	}
+
+	curation, err := svcCtx.Dao.SaveCollectionCuration(ctx, params.ChainID, collectionAddr, operator,
+		func(curation *dao.CollectionCuration) error {
+			if params.IsVerified != nil {
+				curation.IsVerified = *params.IsVerified
+			}
+			if params.IsFeatured != nil {
+				curation.IsFeatured = *params.IsFeatured
+			}
+			if params.IsHidden != nil {
+				curation.IsHidden = *params.IsHidden
+			}
+			if params.Note != nil {
+				curation.Note = strings.TrimSpace(*params.Note)
+			}
+			if curation.IsHidden && curation.IsFeatured {
+				return dao.ErrCurationRejected
+			}
+			return nil
+		})
+	if errors.Is(err, dao.ErrCurationRejected) {
+		return nil, errcode.NewCustomErr("a hidden collection can not be featured")
+	}
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on save collection curation", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	result := toCollectionCuration(*curation)
+	return &result, nil
+}
+
+// GetCollectionCurations returns the curated collections of a chain.
+func GetCollectionCurations(ctx context.Context, svcCtx *svc.ServerCtx, chainID int) ([]types.CollectionCuration, error) {
+	rows, err := svcCtx.Dao.QueryCollectionCurations(ctx, []int{chainID})
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on query collection curations", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	results := make([]types.CollectionCuration, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, toCollectionCuration(row))
+	}
+	return results, nil
+}
+
+func GetCurationAudits(ctx context.Context, svcCtx *svc.ServerCtx, chainID int, collectionAddr string, page, pageSize int) (*types.CurationAuditResp, error) {
+	audits, count, err := svcCtx.Dao.QueryCurationAudits(ctx, chainID, collectionAddr, page, pageSize)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on query curation audits", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	results := make([]types.CurationAudit, 0, len(audits))
+	for _, audit := range audits {
+		results = append(results, types.CurationAudit{
+			ChainID:           audit.ChainID,
+			CollectionAddress: audit.CollectionAddress,
+			Operator:          audit.Operator,
+			Before:            rawJSON(audit.Before),
+			After:             rawJSON(audit.After),
+			CreateTime:        audit.CreateTime,
+		})
+	}
+
+	return &types.CurationAuditResp{
+		Result: results,
+		Count:  count,
+	}, nil
+}
+
+// GetFeaturedCollections returns the featured collections of a chain for the
+// homepage.
+func GetFeaturedCollections(ctx context.Context, svcCtx *svc.ServerCtx, chainID int, chain string) ([]types.CollectionRankingInfo, error) {
+	curated := loadCurations(ctx, svcCtx, []int{chainID})
+	var addrs []string
+	for _, curation := range curated {
+		if curation.IsFeatured && !curation.IsHidden {
+			addrs = append(addrs, curation.CollectionAddress)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, nil
+	}
+
+	collections, err := svcCtx.Dao.QueryCollectionsInfo(ctx, chain, addrs)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on query featured collections", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	results := make([]types.CollectionRankingInfo, 0, len(collections))
+	for _, collection := range collections {
+		results = append(results, types.CollectionRankingInfo{
+			Name:       collection.Name,
+			Address:    collection.Address,
+			ImageUri:   collection.ImageUri,
+			FloorPrice: collection.FloorPrice.String(),
+			ItemNum:    collection.ItemAmount,
+			ItemOwner:  collection.OwnerAmount,
+			ChainID:    chainID,
+			IsVerified: curated.get(chainID, collection.Address).IsVerified,
+			IsFeatured: true,
+		})
+	}
+	return results, nil
+}
+
+// curationOf returns the curation of a collection, the zero value when it
+// has none.
+func curationOf(ctx context.Context, svcCtx *svc.ServerCtx, chainID int, collectionAddr string) dao.CollectionCuration {
+	curation, err := svcCtx.Dao.QueryCollectionCuration(ctx, chainID, collectionAddr)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on query collection curation", zap.Error(err))
+		return dao.CollectionCuration{}
+	}
+	if curation == nil {
+		return dao.CollectionCuration{}
+	}
+	return *curation
+}
+
+func toCollectionCuration(curation dao.CollectionCuration) types.CollectionCuration {
+	return types.CollectionCuration{
+		ChainID:           curation.ChainID,
+		CollectionAddress: curation.CollectionAddress,
+		IsVerified:        curation.IsVerified,
+		IsFeatured:        curation.IsFeatured,
+		IsHidden:          curation.IsHidden,
+		Note:              curation.Note,
+		UpdatedBy:         curation.UpdatedBy,
+		UpdateTime:        curation.UpdateTime,
+	}
+}
+
+func rawJSON(value string) json.RawMessage {
+	if value == "" {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(value)
+}
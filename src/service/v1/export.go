@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/export"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+)
+
+const ExportFlushSize = 500
+
+// exportStream writes records and flushes them to the client every
+// ExportFlushSize records.
+type exportStream struct {
+	w       *export.Writer
+	pending int
+}
+
+func (s *exportStream) write(record export.Record) error {
+	if err := s.w.Write(record); err != nil {
+		return errors.Wrap(err, "failed on write export record")
+	}
+
+	s.pending++
+	if s.pending < ExportFlushSize {
+		return nil
+	}
+	s.pending = 0
+	return errors.Wrap(s.w.Flush(), "failed on flush export records")
+}
+
+// exportChains returns the chains left to export after the cursor, along with
+// the id to start after on the first of them. Chains are exported in order.
+func exportChains(chainIDs []int, chains []string, cursor types.ExportCursor) ([]int, []string, int64) {
+	if cursor.AfterChainID == 0 {
+		return chainIDs, chains, 0
+	}
+
+	for i, chainID := range chainIDs {
+		if chainID == cursor.AfterChainID {
+			return chainIDs[i:], chains[i:], cursor.AfterID
+		}
+	}
+	return nil, nil, 0
+}
+
+// ExportActivities streams the activities matching the filter of
+// GetMultiChainActivities, chain by chain in id order.
+func ExportActivities(ctx context.Context, svcCtx *svc.ServerCtx, chainIDs []int, chains []string,
+	params types.ActivityExportParams, w *export.Writer) error {
+	stream := &exportStream{w: w}
+	chainIDs, chains, afterID := exportChains(chainIDs, chains, params.ExportCursor)
+	for i, chain := range chains {
+		chainID := chainIDs[i]
+		if i > 0 {
+			afterID = 0
+		}
+
+		if err := svcCtx.Dao.StreamActivities(ctx, chain, params.ActivityMultiChainFilterParams, afterID,
+			func(activity multi.Activity, eventType string) error {
+				return stream.write(types.ExportActivity{
+					ChainID:           chainID,
+					ID:                activity.Id,
+					EventType:         eventType,
+					EventTime:         activity.EventTime,
+					CollectionAddress: activity.CollectionAddress,
+					TokenID:           activity.TokenId,
+					Maker:             activity.Maker,
+					Taker:             activity.Taker,
+					MarketplaceID:     activity.MarketplaceID,
+					CurrencyAddress:   activity.CurrencyAddress,
+					Price:             activity.Price,
+					TxHash:            activity.TxHash,
+				})
+			}); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// ExportActiveOrders streams the active, unexpired orders matching the
+// filter, chain by chain in id order.
+func ExportActiveOrders(ctx context.Context, svcCtx *svc.ServerCtx, chainIDs []int, chains []string,
+	params types.OrderExportParams, w *export.Writer) error {
+	stream := &exportStream{w: w}
+	chainIDs, chains, afterID := exportChains(chainIDs, chains, params.ExportCursor)
+	for i, chain := range chains {
+		chainID := chainIDs[i]
+		if i > 0 {
+			afterID = 0
+		}
+
+		if err := svcCtx.Dao.StreamActiveOrders(ctx, chain, params, afterID, func(order multi.Order) error {
+			return stream.write(types.ExportOrder{
+				ChainID:           chainID,
+				ID:                order.Id,
+				OrderID:           order.OrderID,
+				OrderType:         order.OrderType,
+				MarketplaceID:     order.MarketplaceId,
+				CollectionAddress: order.CollectionAddress,
+				TokenID:           order.TokenId,
+				Maker:             order.Maker,
+				CurrencyAddress:   order.CurrencyAddress,
+				Price:             order.Price,
+				Size:              order.Size,
+				QuantityRemaining: order.QuantityRemaining,
+				EventTime:         order.EventTime,
+				ExpireTime:        order.ExpireTime,
+				Salt:              order.Salt,
+			})
+		}); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// ExportCollectionItems streams the items of a collection matching the filter
+// of GetItems, in id order.
+func ExportCollectionItems(ctx context.Context, svcCtx *svc.ServerCtx, chain string, collectionAddr string,
+	params types.ItemExportParams, w *export.Writer) error {
+	stream := &exportStream{w: w}
+	if err := svcCtx.Dao.StreamCollectionItems(ctx, chain, params.CollectionItemFilterParams, collectionAddr, params.AfterID,
+		func(item dao.CollectionItem) error {
+			return stream.write(types.ExportItem{
+				ChainID:           params.ChainID,
+				ID:                item.Id,
+				CollectionAddress: item.CollectionAddress,
+				TokenID:           item.TokenId,
+				Name:              item.Name,
+				Owner:             item.Owner,
+				Listing:           item.Listing,
+				ListPrice:         item.ListPrice,
+				MarketplaceID:     item.MarketID,
+			})
+		}); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
@@ -9,7 +9,17 @@ import (
 )
 
 func GetItemBidsInfo(ctx context.Context, svcCtx *svc.ServerCtx, chain string, collectionAddr, tokenID string, page, pageSize int) (*types.CollectionBidsResp, error) {
-	bids, count, err := svcCtx.Dao.QueryItemBids(ctx, chain, collectionAddr, tokenID, page, pageSize)
+	traitBids, err := svcCtx.Dao.QueryItemTraitBids(ctx, chain, "", collectionAddr, tokenID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get item trait bids")
+	}
+
+	var traitBidOrderIDs []string
+	for _, bid := range traitBids {
+		traitBidOrderIDs = append(traitBidOrderIDs, bid.OrderID)
+	}
+
+	bids, count, err := svcCtx.Dao.QueryItemBids(ctx, chain, collectionAddr, tokenID, traitBidOrderIDs, page, pageSize)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed on get item info")
 	}
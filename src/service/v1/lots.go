@@ -0,0 +1,103 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/shopspring/decimal"
+)
+
+// lot is a unit of a token held by the users, with its cost.
+type lot struct {
+	cost         decimal.Decimal
+	acquiredTime int64
+}
+
+// lotMove is an activity moving a token in or out of the users' addresses.
+// A token moving in adds lot, a token moving out takes the oldest lot away,
+// when any is held.
+type lotMove struct {
+	key          dao.MultiChainItemInfo
+	activity     multi.Activity
+	in           bool
+	wallet       string
+	counterparty string
+	lot          lot
+	held         bool
+}
+
+// lotBook keeps the FIFO lots of the tokens of a set of user addresses, for
+// the cost basis and the tax export to match disposals with acquisitions
+// the same way.
+type lotBook struct {
+	users map[string]bool
+	lots  map[dao.MultiChainItemInfo][]lot
+}
+
+func newLotBook(users map[string]bool) *lotBook {
+	return &lotBook{
+		users: users,
+		lots:  make(map[dao.MultiChainItemInfo][]lot),
+	}
+}
+
+// replay applies activities of a chain, oldest first, and calls move for
+// each one moving a token in or out. The maker of a trade or transfer is the
+// seller or sender, the taker the buyer or receiver. A token received by
+// transfer is held at no cost, a mint is never a disposal and a move between
+// the users' own addresses keeps the lots. The transfer logged next to a
+// trade or mint of the same token in the same transaction is skipped, it is
+// the same move, so a transaction must not be split across calls.
+func (b *lotBook) replay(chain string, activities []multi.Activity, move func(m lotMove) error) error {
+	trades := make(map[string]bool)
+	for _, activity := range activities {
+		if activity.ActivityType != multi.Transfer {
+			trades[tradeKey(activity)] = true
+		}
+	}
+
+	for _, activity := range activities {
+		if activity.ActivityType == multi.Transfer && trades[tradeKey(activity)] {
+			continue
+		}
+
+		m := lotMove{key: costKey(chain, activity.CollectionAddress, activity.TokenId), activity: activity}
+		seller, buyer := strings.ToLower(activity.Maker), strings.ToLower(activity.Taker)
+		if activity.ActivityType == multi.Mint && buyer == "" {
+			buyer, seller = seller, ""
+		}
+
+		switch {
+		case b.users[seller] && b.users[buyer] && activity.ActivityType != multi.Mint:
+			continue
+		case b.users[buyer]:
+			m.in, m.wallet, m.counterparty = true, buyer, seller
+			m.lot = lot{cost: activity.Price, acquiredTime: activity.EventTime}
+			if activity.ActivityType == multi.Transfer {
+				m.lot.cost = decimal.Zero
+			}
+			m.held = true
+			b.lots[m.key] = append(b.lots[m.key], m.lot)
+		case b.users[seller] && activity.ActivityType != multi.Mint:
+			m.wallet, m.counterparty = seller, buyer
+			if lots := b.lots[m.key]; len(lots) > 0 {
+				m.lot, m.held = lots[0], true
+				b.lots[m.key] = lots[1:]
+			}
+		default:
+			continue
+		}
+
+		if err := move(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func tradeKey(activity multi.Activity) string {
+	return fmt.Sprintf("%s:%s:%s", strings.ToLower(activity.TxHash), strings.ToLower(activity.CollectionAddress), activity.TokenId)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/shopspring/decimal"
+)
+
+func TestLotBookReplay(t *testing.T) {
+	book := newLotBook(map[string]bool{"0xuser": true, "0xvault": true})
+	activity := func(activityType int, maker, taker, price, txHash string, eventTime int64) multi.Activity {
+		return multi.Activity{
+			ActivityType:      activityType,
+			Maker:             maker,
+			Taker:             taker,
+			CollectionAddress: "0xCollection",
+			TokenId:           "1",
+			Price:             decimal.RequireFromString(price),
+			TxHash:            txHash,
+			EventTime:         eventTime,
+		}
+	}
+
+	var moves []lotMove
+	if err := book.replay("eth", []multi.Activity{
+		// the transfer logged before the mint of the same transaction is skipped
+		activity(multi.Transfer, "0x0", "0xUser", "0", "0x1", 1),
+		activity(multi.Mint, "0x0", "0xUser", "1", "0x1", 1),
+		// a move to the user's own vault keeps the lot
+		activity(multi.Transfer, "0xuser", "0xvault", "0", "0x2", 2),
+		activity(multi.Sale, "0xvault", "0xother", "4", "0x3", 3),
+		// sold again without a known acquisition
+		activity(multi.Sale, "0xuser", "0xother", "2", "0x4", 4),
+	}, func(m lotMove) error {
+		moves = append(moves, m)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(moves) != 3 {
+		t.Fatalf("expected 3 moves, got %+v", moves)
+	}
+	if !moves[0].in || moves[0].wallet != "0xuser" || !moves[0].lot.cost.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("mint: %+v", moves[0])
+	}
+	if moves[1].in || !moves[1].held || moves[1].wallet != "0xvault" || moves[1].lot.acquiredTime != 1 {
+		t.Fatalf("sale: %+v", moves[1])
+	}
+	if moves[2].in || moves[2].held {
+		t.Fatalf("sale without a lot: %+v", moves[2])
+	}
+	if lots := book.lots[costKey("eth", "0xcollection", "1")]; len(lots) != 0 {
+		t.Fatalf("lots left: %+v", lots)
+	}
+}
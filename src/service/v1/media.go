@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/media"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+)
+
+const (
+	MediaKindImage     = "image"
+	MediaKindAnimation = "animation"
+
+	// MediaProxyPath is the media proxy route of an item, by chain id,
+	// collection address, token id and media kind.
+	MediaProxyPath = "/api/v1/media/%d/%s/%s/%s"
+	MaxRasterSize  = 1024
+)
+
+// GetItemMedia returns the image or animation of an item through the media
+// proxy.
+func GetItemMedia(ctx context.Context, svcCtx *svc.ServerCtx, chain, collectionAddr, tokenID, kind string, rasterize int) (*media.Object, error) {
+	if svcCtx.MediaProxy == nil {
+		return nil, errcode.NewCustomErr("media proxy is not enabled")
+	}
+
+	items, err := svcCtx.Dao.QueryCollectionItemsImage(ctx, chain, collectionAddr, []string{tokenID})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get item media")
+	}
+	if len(items) == 0 {
+		return nil, errcode.NewCustomErr("item not found")
+	}
+
+	var uri string
+	switch kind {
+	case MediaKindImage:
+		uri = items[0].ImageUri
+	case MediaKindAnimation:
+		uri = items[0].VideoUri
+	default:
+		return nil, errcode.ErrInvalidParams
+	}
+	if uri == "" {
+		return nil, errcode.NewCustomErr("item has no such media")
+	}
+
+	if rasterize > MaxRasterSize {
+		rasterize = MaxRasterSize
+	}
+	return svcCtx.MediaProxy.Get(ctx, uri, rasterize)
+}
+
+func mediaProxyURL(chainID int, collectionAddr, tokenID, kind string) string {
+	return fmt.Sprintf(MediaProxyPath, chainID, strings.ToLower(collectionAddr), tokenID, kind)
+}
+
+// itemImageURI returns the uri clients should load the image of an item
+// from. Images other than our own thumbnails may carry scripts, such as svg
+// or inline data uris, or point at slow gateways, they are served through the
+// media proxy when it is enabled.
+func itemImageURI(svcCtx *svc.ServerCtx, chainID int, item multi.ItemExternal) string {
+	if item.IsUploadedOss {
+		return item.OssUri
+	}
+	if svcCtx.MediaProxy != nil && item.ImageUri != "" {
+		return mediaProxyURL(chainID, item.CollectionAddress, item.TokenId, MediaKindImage)
+	}
+	return item.ImageUri
+}
+
+// itemVideoURI returns the uri of the animation of an item, animations that
+// were not uploaded are served through the media proxy when it is enabled.
+func itemVideoURI(svcCtx *svc.ServerCtx, chainID int, item multi.ItemExternal) string {
+	if item.IsVideoUploaded {
+		return item.VideoOssUri
+	}
+	if svcCtx.MediaProxy != nil && item.VideoUri != "" {
+		return mediaProxyURL(chainID, item.CollectionAddress, item.TokenId, MediaKindAnimation)
+	}
+	return item.VideoUri
+}
+
+func chainIDOf(svcCtx *svc.ServerCtx, chain string) int {
+	for _, supported := range svcCtx.C.ChainSupported {
+		if supported.Name == chain {
+			return supported.ChainID
+		}
+	}
+	return 0
+}
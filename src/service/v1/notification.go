@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/pkg/errors"
+)
+
+func GetNotifications(ctx context.Context, svcCtx *svc.ServerCtx, userAddrs []string, filter types.NotificationFilterParams) (*types.NotificationsResp, error) {
+	notifications, count, err := svcCtx.Dao.QueryUserNotifications(ctx, userAddrs, filter.UnreadOnly, filter.Page, filter.PageSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get notifications")
+	}
+
+	unread, err := svcCtx.Dao.CountUnreadNotifications(ctx, userAddrs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on count unread notifications")
+	}
+
+	results := make([]types.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		results = append(results, types.Notification{
+			ID:                notification.Id,
+			ChainID:           notification.ChainID,
+			Type:              notification.NotificationType,
+			CollectionAddress: notification.CollectionAddress,
+			TokenID:           notification.TokenID,
+			OrderID:           notification.OrderID,
+			Price:             notification.Price,
+			Counterparty:      notification.Counterparty,
+			Detail:            notification.Detail,
+			IsRead:            notification.IsRead,
+			EventTime:         notification.EventTime,
+			CreateTime:        notification.CreateTime,
+		})
+	}
+
+	return &types.NotificationsResp{
+		Result: results,
+		Count:  count,
+		Unread: unread,
+	}, nil
+}
+
+func ReadNotifications(ctx context.Context, svcCtx *svc.ServerCtx, userAddrs []string, ids []int64) error {
+	if err := svcCtx.Dao.MarkNotificationsRead(ctx, userAddrs, ids); err != nil {
+		return errors.Wrap(err, "failed on read notifications")
+	}
+
+	return nil
+}
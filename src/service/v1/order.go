@@ -2,12 +2,25 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"math/big"
 	"sort"
+	"strings"
+	"time"
 
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/orderbook"
 	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
 	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/ordermanager"
 	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
 )
 
 func GetOrderInfos(ctx context.Context, svcCtx *svc.ServerCtx, chainID int, chain string, userAddr string, collectionAddr string, tokenIds []string) ([]types.ItemBid, error) {
@@ -36,6 +49,19 @@ func GetOrderInfos(ctx context.Context, svcCtx *svc.ServerCtx, chainID int, chai
 		}
 	}
 
+	traitBids, err := svcCtx.Dao.QueryTraitBids(ctx, chain, userAddr, collectionAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on query trait bids")
+	}
+
+	if len(traitBids) > 0 {
+		itemsTraits, err := svcCtx.Dao.QueryItemsTraits(ctx, chain, collectionAddr, tokenIds)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on query items traits")
+		}
+		mergeTraitBids(tokenIds, itemsBestBids, traitBids, itemsTraits)
+	}
+
 	collectionBids, err := svcCtx.Dao.QueryCollectionTopNBid(ctx, chain, userAddr, collectionAddr, len(tokenIds))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed on query collection best bids")
@@ -44,6 +70,44 @@ func GetOrderInfos(ctx context.Context, svcCtx *svc.ServerCtx, chainID int, chai
 	return processBids(tokenIds, itemsBestBids, collectionBids, collectionAddr), nil
 }
 
+// mergeTraitBids replaces the best bid of a token with the best matching trait
+// bid when it pays more. A trait bid is assigned to at most as many tokens as
+// its remaining quantity.
+func mergeTraitBids(tokenIds []string, itemsBestBids map[string]multi.Order, traitBids []dao.TraitBid, itemsTraits []multi.ItemTrait) {
+	tokenTraits := make(map[string][]multi.ItemTrait)
+	for _, trait := range itemsTraits {
+		tokenTraits[trait.TokenId] = append(tokenTraits[trait.TokenId], trait)
+	}
+
+	remaining := make(map[string]int64)
+	for _, bid := range traitBids {
+		remaining[bid.OrderID] = bid.QuantityRemaining
+	}
+
+	for _, tokenId := range tokenIds {
+		traits, ok := tokenTraits[tokenId]
+		if !ok {
+			continue
+		}
+
+		// trait bids are sorted by price desc, the first match is the best one
+		for _, bid := range traitBids {
+			if remaining[bid.OrderID] <= 0 || !bid.Match(traits) {
+				continue
+			}
+			if order, ok := itemsBestBids[tokenId]; ok && !bid.Price.GreaterThan(order.Price) {
+				break
+			}
+
+			order := bid.Order
+			order.TokenId = tokenId
+			itemsBestBids[tokenId] = order
+			remaining[bid.OrderID]--
+			break
+		}
+	}
+}
+
 func processBids(tokenIds []string, itemsBestBids map[string]multi.Order, collectionBids []multi.Order, collectionAddr string) []types.ItemBid {
 	var itemsSortedBids []multi.Order
 	for _, bid := range itemsBestBids {
@@ -133,3 +197,376 @@ func processBids(tokenIds []string, itemsBestBids map[string]multi.Order, collec
 
 	return resultBids
 }
+
+func ValidateOrders(ctx context.Context, svcCtx *svc.ServerCtx, params types.OrderValidateParams) (*types.OrderValidityResp, error) {
+	validities, err := svcCtx.Orders.ValidateOrders(ctx, params.ChainID, params.OrderIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on validate orders")
+	}
+
+	return &types.OrderValidityResp{
+		Result: validities,
+		Count:  int64(len(validities)),
+	}, nil
+}
+
+// SubmitOrders verifies signed orders and stores the valid ones as active.
+// Every order is checked on its own, so one bad order does not reject the batch.
+func SubmitOrders(ctx context.Context, svcCtx *svc.ServerCtx, chain string, params types.SubmitOrdersParams) (*types.SubmitOrdersResp, error) {
+	chainCfg := svcCtx.Orders.Chain(params.ChainID)
+	if chainCfg == nil || chainCfg.OrderBookAddress == "" {
+		return nil, errors.New("order book not configured")
+	}
+
+	results := make([]types.SubmitOrderResult, len(params.Orders))
+	orders := make([]*multi.Order, len(params.Orders))
+	makerSalts := make(map[string][]int64)
+	for i, signed := range params.Orders {
+		results[i].Index = i
+		order, err := parseSignedOrder(int64(params.ChainID), chainCfg.OrderBookAddress, signed)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		orders[i] = order
+		results[i].OrderID = order.OrderID
+		makerSalts[order.Maker] = append(makerSalts[order.Maker], order.Salt)
+	}
+
+	usedSalts := make(map[string]bool)
+	for maker, salts := range makerSalts {
+		used, err := svcCtx.Dao.QueryUsedSalts(ctx, chain, maker, salts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on check order salts")
+		}
+		for _, salt := range used {
+			usedSalts[fmt.Sprintf("%s:%d", maker, salt)] = true
+		}
+	}
+
+	for i, order := range orders {
+		if order == nil {
+			continue
+		}
+
+		saltKey := fmt.Sprintf("%s:%d", order.Maker, order.Salt)
+		if usedSalts[saltKey] {
+			results[i].Error = "salt already used"
+			orders[i] = nil
+			continue
+		}
+		usedSalts[saltKey] = true
+
+		validity := svcCtx.Orders.Validate(ctx, chainCfg, []multi.Order{*order})
+		if len(validity) == 1 && !validity[0].Valid {
+			results[i].Error = validity[0].Reason
+			orders[i] = nil
+			continue
+		}
+
+		// the traits were verified against the maker's signature on parsing
+		if err := svcCtx.Dao.CreateOrder(ctx, chain, order, params.Orders[i].Traits); err != nil {
+			orders[i] = nil
+			if errors.Is(err, dao.ErrSaltUsed) {
+				results[i].Error = "salt already used"
+				continue
+			}
+			xzap.WithContext(ctx).Error("failed on save order", zap.String("order_id", order.OrderID), zap.Error(err))
+			results[i].Error = "failed on save order"
+			continue
+		}
+	}
+
+	updatedCollections := make(map[string]bool)
+	for _, order := range orders {
+		if order != nil && order.OrderType == multi.ListingOrder {
+			updatedCollections[order.CollectionAddress] = true
+		}
+	}
+	refreshCollectionsListing(ctx, svcCtx, chain, updatedCollections)
+
+	return &types.SubmitOrdersResp{
+		Result: results,
+		Count:  int64(len(results)),
+	}, nil
+}
+
+func parseSignedOrder(chainID int64, orderBookAddr string, signed types.SignedOrder) (*multi.Order, error) {
+	if signed.MarketplaceID != multi.OrderBookDex {
+		return nil, errors.New("unsupported marketplace")
+	}
+
+	if !common.IsHexAddress(signed.Maker) || !common.IsHexAddress(signed.Nft.Collection) {
+		return nil, errors.New("invalid address")
+	}
+
+	var orderType int64
+	switch {
+	case signed.Side == orderbook.SideList && signed.SaleKind == orderbook.SaleKindForItem:
+		orderType = multi.ListingOrder
+	case signed.Side == orderbook.SideBid && signed.SaleKind == orderbook.SaleKindForItem:
+		orderType = multi.ItemBidOrder
+	case signed.Side == orderbook.SideBid && signed.SaleKind == orderbook.SaleKindForCollection:
+		orderType = multi.CollectionBidOrder
+		if len(signed.Traits) > 0 {
+			orderType = dao.TraitBidOrder
+		}
+	default:
+		return nil, errors.New("unsupported side and sale kind")
+	}
+
+	tokenID, ok := new(big.Int).SetString(signed.Nft.TokenID, 10)
+	if !ok {
+		if signed.SaleKind == orderbook.SaleKindForItem {
+			return nil, errors.New("invalid token id")
+		}
+		tokenID = big.NewInt(0)
+	}
+
+	if signed.Nft.Amount <= 0 {
+		return nil, errors.New("invalid amount")
+	}
+
+	price, ok := new(big.Int).SetString(signed.Price, 10)
+	if !ok || price.Sign() <= 0 {
+		return nil, errors.New("invalid price")
+	}
+
+	if signed.Expiry <= time.Now().Unix() {
+		return nil, errors.New("order expired")
+	}
+
+	if signed.Salt <= 0 {
+		return nil, errors.New("invalid salt")
+	}
+
+	eipOrder := orderbook.Order{
+		Side:     signed.Side,
+		SaleKind: signed.SaleKind,
+		Maker:    common.HexToAddress(signed.Maker),
+		Nft: orderbook.Asset{
+			TokenID:    tokenID,
+			Collection: common.HexToAddress(signed.Nft.Collection),
+			Amount:     big.NewInt(signed.Nft.Amount),
+		},
+		Price:  price,
+		Expiry: uint64(signed.Expiry),
+		Salt:   uint64(signed.Salt),
+	}
+
+	if err := eipOrder.VerifySignature(chainID, common.HexToAddress(orderBookAddr), signed.Signature); err != nil {
+		return nil, err
+	}
+
+	if orderType == dao.TraitBidOrder {
+		criteria := orderbook.TraitCriteria{OrderKey: eipOrder.Hash()}
+		for _, trait := range signed.Traits {
+			if trait.Key == "" || trait.Value == "" {
+				return nil, errors.New("invalid trait")
+			}
+			criteria.Traits = append(criteria.Traits, orderbook.Trait{Key: trait.Key, Value: trait.Value})
+		}
+		if err := criteria.VerifySignature(chainID, common.HexToAddress(orderBookAddr), eipOrder.Maker, signed.TraitsSignature); err != nil {
+			return nil, err
+		}
+	}
+
+	var tokenIDStr string
+	if signed.SaleKind == orderbook.SaleKindForItem {
+		tokenIDStr = tokenID.String()
+	}
+
+	now := time.Now()
+	return &multi.Order{
+		MarketplaceId:     signed.MarketplaceID,
+		CollectionAddress: strings.ToLower(signed.Nft.Collection),
+		TokenId:           tokenIDStr,
+		OrderID:           eipOrder.Hash().Hex(),
+		OrderStatus:       multi.OrderStatusActive,
+		EventTime:         now.Unix(),
+		ExpireTime:        signed.Expiry,
+		CurrencyAddress:   common.Address{}.Hex(),
+		Price:             decimal.NewFromBigInt(price, -18),
+		Maker:             strings.ToLower(signed.Maker),
+		QuantityRemaining: signed.Nft.Amount,
+		Size:              signed.Nft.Amount,
+		OrderType:         orderType,
+		Salt:              signed.Salt,
+		CreateTime:        now.UnixMilli(),
+		UpdateTime:        now.UnixMilli(),
+	}, nil
+}
+
+// refreshCollectionsListing updates the cached listed amount and floor price
+// of collections whose listings changed.
+func refreshCollectionsListing(ctx context.Context, svcCtx *svc.ServerCtx, chain string, collections map[string]bool) {
+	for collectionAddr := range collections {
+		listed, err := svcCtx.Dao.QueryListedAmount(ctx, chain, collectionAddr)
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on get listed count", zap.Error(err))
+		} else if err := svcCtx.Dao.CacheCollectionsListed(ctx, chain, collectionAddr, int(listed)); err != nil {
+			xzap.WithContext(ctx).Error("failed on cache collection listed", zap.Error(err))
+		}
+
+		floorPrice, err := svcCtx.Dao.QueryFloorPrice(ctx, chain, collectionAddr)
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on get floor price", zap.Error(err))
+			continue
+		}
+		if err := ordermanager.AddUpdatePriceEvent(svcCtx.KvStore, &ordermanager.TradeEvent{
+			EventType:      ordermanager.UpdateCollection,
+			CollectionAddr: collectionAddr,
+			Price:          floorPrice,
+		}, chain); err != nil {
+			xzap.WithContext(ctx).Error("failed on update floor price", zap.Error(err))
+		}
+	}
+}
+
+// CancelOrders cancels the orders listed in a cancel message signed by their
+// maker. Orders of other makers or already closed orders are reported back.
+// Errors are errcode errors, internal ones are logged.
+func CancelOrders(ctx context.Context, svcCtx *svc.ServerCtx, chain string, params types.CancelOrdersParams) (*types.CancelOrdersResp, error) {
+	chainCfg := svcCtx.Orders.Chain(params.ChainID)
+	if chainCfg == nil || chainCfg.OrderBookAddress == "" {
+		return nil, errcode.NewCustomErr("order book not configured")
+	}
+
+	if params.Expiry <= time.Now().Unix() {
+		return nil, errcode.NewCustomErr("cancel request expired")
+	}
+
+	request := orderbook.CancelRequest{
+		Maker:  common.HexToAddress(params.Maker),
+		Expiry: uint64(params.Expiry),
+	}
+	for _, orderID := range params.OrderIDs {
+		request.OrderKeys = append(request.OrderKeys, common.HexToHash(orderID))
+	}
+	if err := request.VerifySignature(int64(params.ChainID), common.HexToAddress(chainCfg.OrderBookAddress), params.Signature); err != nil {
+		return nil, errcode.NewCustomErr(err.Error())
+	}
+
+	orders, err := svcCtx.Dao.QueryOrdersByIDs(ctx, chain, params.OrderIDs)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on query orders", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+	orderMap := make(map[string]multi.Order)
+	for _, order := range orders {
+		orderMap[order.OrderID] = order
+	}
+
+	now := time.Now()
+	// the message hash is the proof of a cancel made off chain, there is no
+	// transaction to record on the activity
+	messageHash := hexutil.Encode(request.Digest(int64(params.ChainID), common.HexToAddress(chainCfg.OrderBookAddress)))
+	results := make(map[string]string)
+	var cancellations []dao.OrderCancellation
+	var activities []multi.Activity
+	for _, orderID := range params.OrderIDs {
+		order, ok := orderMap[orderID]
+		if !ok {
+			results[orderID] = "order not found"
+			continue
+		}
+		if !strings.EqualFold(order.Maker, params.Maker) {
+			results[orderID] = "order maker mismatch"
+			continue
+		}
+		if order.OrderStatus != multi.OrderStatusActive && order.OrderStatus != multi.OrderStatusInactive {
+			results[orderID] = "order is not active"
+			continue
+		}
+
+		cancellations = append(cancellations, dao.OrderCancellation{
+			OrderID:     orderID,
+			Maker:       order.Maker,
+			OrderIDs:    strings.Join(params.OrderIDs, ","),
+			Expiry:      params.Expiry,
+			Signature:   params.Signature,
+			MessageHash: messageHash,
+			CreateTime:  now.UnixMilli(),
+			UpdateTime:  now.UnixMilli(),
+		})
+		activities = append(activities, multi.Activity{
+			ActivityType:      cancelActivityType(order.OrderType),
+			Maker:             order.Maker,
+			MarketplaceID:     order.MarketplaceId,
+			CollectionAddress: order.CollectionAddress,
+			TokenId:           order.TokenId,
+			CurrencyAddress:   order.CurrencyAddress,
+			Price:             order.Price,
+			EventTime:         now.Unix(),
+			CreateTime:        now.UnixMilli(),
+			UpdateTime:        now.UnixMilli(),
+		})
+	}
+
+	cancelled, err := svcCtx.Dao.SaveOrderCancellations(ctx, chain, cancellations, activities)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on cancel orders", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	isCancelled := make(map[string]bool)
+	updatedCollections := make(map[string]bool)
+	for _, orderID := range cancelled {
+		isCancelled[orderID] = true
+		if order := orderMap[orderID]; order.OrderType == multi.ListingOrder {
+			updatedCollections[order.CollectionAddress] = true
+		}
+	}
+	refreshCollectionsListing(ctx, svcCtx, chain, updatedCollections)
+
+	var cancelResults []types.CancelOrderResult
+	for _, orderID := range params.OrderIDs {
+		result := types.CancelOrderResult{OrderID: orderID, Error: results[orderID]}
+		if result.Error == "" && !isCancelled[orderID] {
+			result.Error = "order is not active"
+		}
+		cancelResults = append(cancelResults, result)
+	}
+
+	return &types.CancelOrdersResp{
+		Result: cancelResults,
+		Count:  int64(len(cancelResults)),
+	}, nil
+}
+
+func GetOrderCancellation(ctx context.Context, svcCtx *svc.ServerCtx, chainID int, chain string, orderID string) (*types.OrderCancellationResp, error) {
+	cancellation, err := svcCtx.Dao.QueryOrderCancellation(ctx, chain, orderID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get order cancellation")
+	}
+
+	var verifyingContract string
+	if chainCfg := svcCtx.Orders.Chain(chainID); chainCfg != nil {
+		verifyingContract = chainCfg.OrderBookAddress
+	}
+
+	return &types.OrderCancellationResp{
+		Result: types.OrderCancellationProof{
+			ChainID:           chainID,
+			VerifyingContract: verifyingContract,
+			OrderID:           cancellation.OrderID,
+			Maker:             cancellation.Maker,
+			OrderIDs:          strings.Split(cancellation.OrderIDs, ","),
+			Expiry:            cancellation.Expiry,
+			Signature:         cancellation.Signature,
+			MessageHash:       cancellation.MessageHash,
+			CancelTime:        cancellation.CreateTime,
+		},
+	}, nil
+}
+
+func cancelActivityType(orderType int64) int {
+	switch orderType {
+	case multi.ListingOrder:
+		return multi.CancelListing
+	case multi.ItemBidOrder:
+		return multi.CancelItemBid
+	default:
+		return multi.CancelCollectionBid
+	}
+}
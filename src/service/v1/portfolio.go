@@ -26,11 +26,20 @@ func getBidType(origin int64) int64 {
 }
 
 func GetMultiChainUserCollections(ctx context.Context, svcCtx *svc.ServerCtx, chainIDs []int, chainNames []string, userAddrs []string) (*types.UserCollectionsResp, error) {
-	collections, err := svcCtx.Dao.QueryMultiChainUserCollectionInfos(ctx, chainIDs, chainNames, userAddrs)
+	allCollections, err := svcCtx.Dao.QueryMultiChainUserCollectionInfos(ctx, chainIDs, chainNames, userAddrs)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed on get collection info")
 	}
 
+	// hidden collections, mostly spam airdrops, are left out of the value too
+	curated := loadCurations(ctx, svcCtx, chainIDs)
+	var collections []types.UserCollections
+	for _, collection := range allCollections {
+		if !curated.hidden(collection.ChainID, collection.Address) {
+			collections = append(collections, collection)
+		}
+	}
+
 	chainIDToChainName := make(map[int]string)
 	for _, chain := range svcCtx.C.ChainSupported {
 		chainIDToChainName[chain.ChainID] = chain.Name
@@ -83,6 +92,7 @@ func GetMultiChainUserCollections(ctx context.Context, svcCtx *svc.ServerCtx, ch
 			ListAmount: listCount,
 			ItemAmount: collection.ItemCount,
 			FloorPrice: collection.FloorPrice,
+			IsVerified: curated.get(collection.ChainID, collection.Address).IsVerified,
 		})
 
 		chainInfo, ok := chainInfos[collection.ChainID]
@@ -109,7 +119,7 @@ func GetMultiChainUserCollections(ctx context.Context, svcCtx *svc.ServerCtx, ch
 }
 
 func GetMultiChainUserItems(ctx context.Context, svcCtx *svc.ServerCtx, chainID []int, chain []string, userAddrs []string, contractAddrs []string, page, pageSize int) (*types.UserItemsResp, error) {
-	items, count, err := svcCtx.Dao.QueryMultiChainUserItemInfos(ctx, chain, userAddrs, contractAddrs, page, pageSize)
+	items, count, err := svcCtx.Dao.QueryMultiChainUserItemInfos(ctx, chain, userAddrs, contractAddrs, curatedChains(svcCtx), page, pageSize)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed on get user items info")
 	}
@@ -219,6 +229,11 @@ func GetMultiChainUserItems(ctx context.Context, svcCtx *svc.ServerCtx, chainID
 		return nil, errors.Wrap(err, "failed on query collections info")
 	}
 
+	costs, err := queryCostBasis(ctx, svcCtx, chainID, chain, userAddrs, contractAddrs, multichainItems)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on query cost basis")
+	}
+
 	collectionInfos := make(map[string]multi.Collection)
 	for _, collection := range collections {
 		collectionInfos[strings.ToLower(collection.Address)] = collection
@@ -347,11 +362,15 @@ func GetMultiChainUserItems(ctx context.Context, svcCtx *svc.ServerCtx, chainID
 
 		image, ok := itemExternals[strings.ToLower(items[i].CollectionAddress+items[i].TokenID)]
 		if ok {
-			if image.IsUploadedOss {
-				items[i].ImageURI = image.OssUri
-			} else {
-				items[i].ImageURI = image.ImageUri
-			}
+			items[i].ImageURI = itemImageURI(svcCtx, items[i].ChainID, image)
+		}
+
+		if cost, ok := costs[costKey(chainIDToChainName[items[i].ChainID], items[i].CollectionAddress, items[i].TokenID)]; ok {
+			items[i].LastCostPrice = cost.lastCost()
+			items[i].CostBasis = cost.costBasis()
+			items[i].RealizedPnL = cost.realized
+			items[i].FloorPnL = cost.unrealized(items[i].FloorPrice)
+			items[i].BidPnL = cost.unrealized(items[i].BidPrice)
 		}
 	}
 
@@ -363,7 +382,7 @@ func GetMultiChainUserItems(ctx context.Context, svcCtx *svc.ServerCtx, chainID
 
 func GetMultiChainUserListings(ctx context.Context, svcCtx *svc.ServerCtx, chainID []int, chain []string, userAddrs []string, contractAddrs []string, page, pageSize int) (*types.UserListingsResp, error) {
 	var result []types.Listing
-	items, count, err := svcCtx.Dao.QueryMultiChainUserListingItemInfos(ctx, chain, userAddrs, contractAddrs, page, pageSize)
+	items, count, err := svcCtx.Dao.QueryMultiChainUserListingItemInfos(ctx, chain, userAddrs, contractAddrs, curatedChains(svcCtx), page, pageSize)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed on get user items info")
 	}
@@ -407,7 +426,10 @@ func GetMultiChainUserListings(ctx context.Context, svcCtx *svc.ServerCtx, chain
 		})
 	}
 
-	itemLastCost := make(map[dao.MultiChainItemInfo]decimal.Decimal)
+	costs, err := queryCostBasis(ctx, svcCtx, chainID, chain, userAddrs, contractAddrs, multichainItems)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on query cost basis")
+	}
 
 	collectionBestBids := make(map[types.MultichainCollection]multi.Order)
 	var wg sync.WaitGroup
@@ -541,13 +563,9 @@ func GetMultiChainUserListings(ctx context.Context, svcCtx *svc.ServerCtx, chain
 		resultlisting.ChainID = items[i].ChainID
 		resultlisting.CollectionAddress = items[i].CollectionAddress
 		resultlisting.TokenID = items[i].TokenID
-		resultlisting.LastCostPrice = itemLastCost[dao.MultiChainItemInfo{
-			ItemInfo: types.ItemInfo{
-				CollectionAddress: items[i].CollectionAddress,
-				TokenID:           items[i].TokenID,
-			},
-			ChainName: chainIDToChainName[items[i].ChainID],
-		}]
+		if cost, ok := costs[costKey(chainIDToChainName[items[i].ChainID], items[i].CollectionAddress, items[i].TokenID)]; ok {
+			resultlisting.LastCostPrice = cost.lastCost()
+		}
 
 		itemKey := dao.MultiChainItemInfo{ItemInfo: types.ItemInfo{CollectionAddress: strings.ToLower(items[i].CollectionAddress), TokenID: items[i].TokenID}, ChainName: chainIDToChainName[items[i].ChainID]}
 		bidOrder, ok := itemsBestBids[itemKey]
@@ -614,11 +632,7 @@ func GetMultiChainUserListings(ctx context.Context, svcCtx *svc.ServerCtx, chain
 
 		image, ok := itemExternals[strings.ToLower(items[i].CollectionAddress+items[i].TokenID)]
 		if ok {
-			if image.IsUploadedOss {
-				resultlisting.ImageURI = image.OssUri
-			} else {
-				resultlisting.ImageURI = image.ImageUri
-			}
+			resultlisting.ImageURI = itemImageURI(svcCtx, items[i].ChainID, image)
 		}
 		result = append(result, resultlisting)
 	}
@@ -638,7 +652,7 @@ type multiOrder struct {
 func GetMultiChainUserBids(ctx context.Context, svcCtx *svc.ServerCtx, chainID []int, chainNames []string, userAddrs []string, contractAddrs []string) (*types.UserBidsResp, error) {
 	var totalBids []multiOrder
 	for i, chain := range chainNames {
-		orders, err := svcCtx.Dao.QueryUserBids(ctx, chain, userAddrs, contractAddrs)
+		orders, err := svcCtx.Dao.QueryUserBids(ctx, chain, chainID[i], userAddrs, contractAddrs)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed on get user bids info")
 		}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SimonHofman/EasySwapBackend/src/common/utils"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxBioLength     = 280
+	MaxWebsiteLength = 200
+	MaxAvatarSize    = 2 << 20
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,20}$`)
+	twitterPattern  = regexp.MustCompile(`^@?[A-Za-z0-9_]{1,15}$`)
+)
+
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+func GetUserProfile(ctx context.Context, svcCtx *svc.ServerCtx, userAddr, username string) (*types.UserProfileResp, error) {
+	var profile *dao.UserProfile
+	var err error
+	if username != "" {
+		profile, err = svcCtx.Dao.QueryUserProfileByUsername(ctx, username)
+	} else {
+		profile, err = svcCtx.Dao.QueryUserProfile(ctx, userAddr)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get user profile")
+	}
+
+	if profile == nil {
+		if username != "" {
+			return nil, errcode.NewCustomErr("user not found")
+		}
+		// every address has an empty profile
+		return &types.UserProfileResp{Result: types.UserProfile{Address: strings.ToLower(userAddr)}}, nil
+	}
+
+	return &types.UserProfileResp{Result: userProfile(svcCtx, profile)}, nil
+}
+
+func UpdateUserProfile(ctx context.Context, svcCtx *svc.ServerCtx, userAddr string, params types.UpdateProfileParams) (*types.UserProfileResp, error) {
+	profile, err := loadUserProfile(ctx, svcCtx, userAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Username != "" {
+		if !usernamePattern.MatchString(params.Username) {
+			return nil, errcode.NewCustomErr("username must be 3 to 20 letters, digits or underscores")
+		}
+
+		owner, err := svcCtx.Dao.QueryUserProfileByUsername(ctx, params.Username)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on check username")
+		}
+		if owner != nil && owner.Address != profile.Address {
+			return nil, errcode.NewCustomErr("username already taken")
+		}
+
+		usernameKey := strings.ToLower(params.Username)
+		profile.UsernameKey = &usernameKey
+	} else {
+		profile.UsernameKey = nil
+	}
+	profile.Username = params.Username
+
+	if utf8.RuneCountInString(params.Bio) > MaxBioLength {
+		return nil, errcode.NewCustomErr(fmt.Sprintf("bio is limited to %d characters", MaxBioLength))
+	}
+	profile.Bio = params.Bio
+
+	if params.Twitter != "" && !twitterPattern.MatchString(params.Twitter) {
+		return nil, errcode.NewCustomErr("invalid twitter handle")
+	}
+	profile.Twitter = strings.TrimPrefix(params.Twitter, "@")
+
+	if params.Website != "" {
+		u, err := url.Parse(params.Website)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(params.Website) > MaxWebsiteLength {
+			return nil, errcode.NewCustomErr("invalid website")
+		}
+	}
+	profile.Website = params.Website
+
+	profile.UpdateTime = time.Now().UnixMilli()
+	if err := svcCtx.Dao.SaveUserProfile(ctx, profile); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, errcode.NewCustomErr("username already taken")
+		}
+		return nil, errors.Wrap(err, "failed on update user profile")
+	}
+
+	// other instances pick the username up on their next sync
+	if err := svcCtx.Search.IndexProfile(profile.Address, profile.Username); err != nil {
+		xzap.WithContext(ctx).Error("failed on index user profile", zap.String("address", profile.Address), zap.Error(err))
+	}
+
+	return &types.UserProfileResp{Result: userProfile(svcCtx, profile)}, nil
+}
+
+// UploadAvatar stores a new avatar image and replaces the previous one.
+func UploadAvatar(ctx context.Context, svcCtx *svc.ServerCtx, userAddr string, r io.Reader) (*types.UserProfileResp, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxAvatarSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on read avatar")
+	}
+	if len(data) > MaxAvatarSize {
+		return nil, errcode.NewCustomErr("avatar is limited to 2MB")
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := avatarExtensions[contentType]
+	if !ok {
+		return nil, errcode.NewCustomErr("avatar must be a png, jpeg, gif or webp image")
+	}
+
+	profile, err := loadUserProfile(ctx, svcCtx, userAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	previousKey := profile.AvatarKey
+	profile.AvatarKey = "avatars/" + utils.UserOssKey(strconv.FormatInt(time.Now().UnixMilli(), 10), profile.Address) + ext
+	if err := svcCtx.Storage.Put(ctx, profile.AvatarKey, bytes.NewReader(data), contentType); err != nil {
+		return nil, errors.Wrap(err, "failed on store avatar")
+	}
+
+	profile.UpdateTime = time.Now().UnixMilli()
+	if err := svcCtx.Dao.SaveUserProfile(ctx, profile); err != nil {
+		// the profile still points to the previous avatar, the new one is orphaned
+		if err := svcCtx.Storage.Delete(ctx, profile.AvatarKey); err != nil {
+			xzap.WithContext(ctx).Error("failed on delete unsaved avatar", zap.String("key", profile.AvatarKey), zap.Error(err))
+		}
+		return nil, errors.Wrap(err, "failed on update user avatar")
+	}
+
+	if previousKey != "" {
+		if err := svcCtx.Storage.Delete(ctx, previousKey); err != nil {
+			xzap.WithContext(ctx).Error("failed on delete previous avatar", zap.String("key", previousKey), zap.Error(err))
+		}
+	}
+
+	return &types.UserProfileResp{Result: userProfile(svcCtx, profile)}, nil
+}
+
+func loadUserProfile(ctx context.Context, svcCtx *svc.ServerCtx, userAddr string) (*dao.UserProfile, error) {
+	profile, err := svcCtx.Dao.QueryUserProfile(ctx, userAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get user profile")
+	}
+
+	if profile == nil {
+		profile = &dao.UserProfile{
+			Address:    strings.ToLower(userAddr),
+			CreateTime: time.Now().UnixMilli(),
+		}
+	}
+	return profile, nil
+}
+
+func userProfile(svcCtx *svc.ServerCtx, profile *dao.UserProfile) types.UserProfile {
+	result := types.UserProfile{
+		Address:    profile.Address,
+		Username:   profile.Username,
+		Bio:        profile.Bio,
+		Twitter:    profile.Twitter,
+		Website:    profile.Website,
+		UpdateTime: profile.UpdateTime,
+	}
+	if profile.AvatarKey != "" {
+		result.AvatarURL = svcCtx.Storage.URL(profile.AvatarKey)
+	}
+	return result
+}
+
+// displayNames returns the usernames of addrs keyed by lower cased address.
+// Enrichment is best effort, a failed lookup leaves the names empty.
+func displayNames(ctx context.Context, svcCtx *svc.ServerCtx, addrs []string) map[string]string {
+	names := make(map[string]string)
+
+	profiles, err := svcCtx.Dao.QueryUserProfiles(ctx, addrs)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on query display names", zap.Error(err))
+		return names
+	}
+
+	for addr, profile := range profiles {
+		if profile.Username != "" {
+			names[addr] = profile.Username
+		}
+	}
+	return names
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const BelowFloorWarning = "bid price is below the collection floor price"
+
+// GetAcceptBidsQuote computes what the seller receives for every selected bid
+// once royalties and marketplace fees are paid.
+func GetAcceptBidsQuote(ctx context.Context, svcCtx *svc.ServerCtx, chain string, userAddrs []string, params types.AcceptBidsQuoteParams) (*types.AcceptBidsQuoteResp, error) {
+	var orderIDs []string
+	var tokenIds []string
+	for _, item := range params.Items {
+		orderIDs = append(orderIDs, item.OrderID)
+		tokenIds = append(tokenIds, item.TokenID)
+	}
+
+	orders, err := svcCtx.Dao.QueryOrdersByIDs(ctx, chain, orderIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on query bids")
+	}
+	bids := make(map[string]multi.Order)
+	for _, order := range orders {
+		bids[order.OrderID] = order
+	}
+
+	floorPrice, err := svcCtx.Dao.QueryFloorPrice(ctx, chain, params.CollectionAddress)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on query floor price")
+	}
+
+	var tokenID string
+	if len(tokenIds) > 0 {
+		tokenID = tokenIds[0]
+	}
+	royaltyRate, royaltyEstimated, err := quoteRoyaltyRate(ctx, svcCtx, params.ChainID, chain, params.CollectionAddress, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	owned, err := svcCtx.Dao.QueryOwnedAmounts(ctx, chain, params.CollectionAddress, tokenIds, userAddrs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on query owned items")
+	}
+
+	quote := types.AcceptBidsQuote{
+		Items:            []types.AcceptBidQuote{},
+		RoyaltyFeeRate:   royaltyRate,
+		RoyaltyEstimated: royaltyEstimated,
+	}
+	used := make(map[string]int64)
+	sold := make(map[string]int64)
+	for _, item := range params.Items {
+		line := types.AcceptBidQuote{
+			TokenID:        item.TokenID,
+			OrderID:        item.OrderID,
+			FloorPrice:     floorPrice,
+			RoyaltyFeeRate: royaltyRate,
+		}
+
+		// an ERC-1155 holder can sell as many units as it holds
+		if sold[item.TokenID] >= owned[item.TokenID] {
+			line.Error = "item is not owned"
+			quote.Items = append(quote.Items, line)
+			continue
+		}
+
+		bid, ok := bids[item.OrderID]
+		if !ok {
+			line.Error = "bid not found"
+			quote.Items = append(quote.Items, line)
+			continue
+		}
+
+		line.MarketplaceID = bid.MarketplaceId
+		line.BidType = getBidType(bid.OrderType)
+		line.BidPrice = bid.Price
+		if errMsg := checkAcceptableBid(bid, item.TokenID, params.CollectionAddress, userAddrs, used[bid.OrderID]); errMsg != "" {
+			line.Error = errMsg
+			quote.Items = append(quote.Items, line)
+			continue
+		}
+		used[bid.OrderID]++
+		sold[item.TokenID]++
+
+		line.MarketplaceFeeRate = marketplaceFeeRate(svcCtx.C.Fee, bid.MarketplaceId)
+		line.RoyaltyFee = bid.Price.Mul(royaltyRate)
+		line.MarketplaceFee = bid.Price.Mul(line.MarketplaceFeeRate)
+		line.NetProceeds = bid.Price.Sub(line.RoyaltyFee).Sub(line.MarketplaceFee)
+		if floorPrice.IsPositive() && bid.Price.LessThan(floorPrice) {
+			line.Warning = BelowFloorWarning
+		}
+
+		quote.TotalPrice = quote.TotalPrice.Add(bid.Price)
+		quote.TotalRoyalty = quote.TotalRoyalty.Add(line.RoyaltyFee)
+		quote.TotalFee = quote.TotalFee.Add(line.MarketplaceFee)
+		quote.TotalProceeds = quote.TotalProceeds.Add(line.NetProceeds)
+		quote.Items = append(quote.Items, line)
+	}
+
+	return &types.AcceptBidsQuoteResp{
+		Result: quote,
+	}, nil
+}
+
+func checkAcceptableBid(bid multi.Order, tokenID, collectionAddr string, userAddrs []string, used int64) string {
+	if !strings.EqualFold(bid.CollectionAddress, collectionAddr) {
+		return "bid is not for this collection"
+	}
+
+	if bid.OrderStatus != multi.OrderStatusActive || bid.ExpireTime <= time.Now().Unix() {
+		return "bid is not active"
+	}
+
+	if bid.QuantityRemaining-used <= 0 {
+		return "bid is already filled"
+	}
+
+	for _, addr := range userAddrs {
+		if strings.EqualFold(bid.Maker, addr) {
+			return "can not accept own bid"
+		}
+	}
+
+	switch bid.OrderType {
+	case multi.ItemBidOrder:
+		if bid.TokenId != tokenID {
+			return "bid is for another item"
+		}
+	case multi.CollectionBidOrder:
+	case dao.TraitBidOrder:
+		// see dao.TraitBidOrder, the order book would fill it with any item
+		return "trait bids can not be accepted until the order book enforces their traits"
+	default:
+		return "order is not a bid"
+	}
+
+	return ""
+}
+
+func marketplaceFeeRate(feeConf *config.FeeConf, marketplaceID int) decimal.Decimal {
+	if feeConf == nil {
+		return decimal.Zero
+	}
+
+	for _, fee := range feeConf.Marketplaces {
+		if fee.MarketplaceID == marketplaceID {
+			rate, err := decimal.NewFromString(fee.Rate)
+			if err == nil {
+				return rate
+			}
+		}
+	}
+
+	rate, err := decimal.NewFromString(feeConf.DefaultRate)
+	if err != nil {
+		return decimal.Zero
+	}
+	return rate
+}
+
+// quoteRoyaltyRate returns the royalty rate of a collection for a quote, read
+// for one of the quoted tokens. A quote is an estimate, so the last known rate
+// stands in for the chain node while it is unavailable, reported by the
+// returned flag.
+func quoteRoyaltyRate(ctx context.Context, svcCtx *svc.ServerCtx, chainID int, chain, collectionAddr, tokenID string) (decimal.Decimal, bool, error) {
+	rate, err := svcCtx.Royalty.RoyaltyRate(ctx, int64(chainID), chain, collectionAddr, tokenID)
+	if err == nil {
+		return rate, false, nil
+	}
+
+	xzap.WithContext(ctx).Error("failed on get royalty rate", zap.Error(err),
+		zap.String("collection_address", collectionAddr))
+	stored, ok := svcCtx.Royalty.StoredRoyaltyRate(chain, collectionAddr)
+	if !ok {
+		return decimal.Zero, false, errors.Wrap(err, "failed on get royalty rate")
+	}
+	return stored, true, nil
+}
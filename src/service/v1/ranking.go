@@ -79,8 +79,16 @@ func GetTopRanking(ctx context.Context, svcCtx *svc.ServerCtx, chain string, per
 		return nil, queryErr
 	}
 
+	chainID := chainIDOf(svcCtx, chain)
+	curated := loadCurations(ctx, svcCtx, []int{chainID})
+
 	var respInfos []*types.CollectionRankingInfo
 	for _, collection := range allCollections {
+		curation := curated.get(chainID, collection.Address)
+		if curation.IsHidden {
+			continue
+		}
+
 		var priceChange float64
 		var volume decimal.Decimal
 		var sellPrice decimal.Decimal
@@ -119,6 +127,8 @@ func GetTopRanking(ctx context.Context, svcCtx *svc.ServerCtx, chain string, per
 			ItemOwner:   collection.OwnerAmount,
 			ListAmount:  listAmount,
 			ChainID:     collection.ChainId,
+			IsVerified:  curation.IsVerified,
+			IsFeatured:  curation.IsFeatured,
 		})
 	}
 
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/mq"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	RefreshJobBatchSize = 500
+	// RefreshJobBatchInterval spaces the batches of a job so a large
+	// collection does not flood the refresh queue.
+	RefreshJobBatchInterval = time.Second
+	// RefreshCollectionCooldown is the time in seconds before a collection
+	// can be refreshed again.
+	RefreshCollectionCooldown = 600
+	MaxRefreshJobsPerHour     = 10
+)
+
+const (
+	CacheRefreshCollectionLockKey = "cache:es:refresh:collection:%d:%s"
+	CacheRefreshCallerCountKey    = "cache:es:refresh:caller:%s"
+)
+
+// allowCallerRefresh counts a job of the caller in the current hour and
+// reports whether it is within MaxRefreshJobsPerHour.
+func allowCallerRefresh(svcCtx *svc.ServerCtx, caller string) (bool, error) {
+	key := fmt.Sprintf(CacheRefreshCallerCountKey, caller)
+	count, err := svcCtx.KvStore.Incr(key)
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		if err := svcCtx.KvStore.Expire(key, 3600); err != nil {
+			return false, err
+		}
+	}
+	return count <= MaxRefreshJobsPerHour, nil
+}
+
+// RefreshCollectionMetadata starts a job queuing every token of a collection
+// for a metadata refresh. Jobs need the metadata worker: the sync service
+// consuming the legacy refresh set does not report results, so a job would
+// never complete.
+func RefreshCollectionMetadata(ctx context.Context, svcCtx *svc.ServerCtx, chainID int64, chain, collectionAddr, caller string) (*types.RefreshJob, error) {
+	if svcCtx.RefreshQueue.Legacy() {
+		return nil, errcode.NewCustomErr("collection refresh jobs are not available without the metadata worker")
+	}
+
+	collectionAddr = strings.ToLower(collectionAddr)
+	total, err := svcCtx.Dao.QueryCollectionItemCount(ctx, chain, collectionAddr)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on count collection items", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+	if total == 0 {
+		return nil, errcode.NewCustomErr("collection has no items")
+	}
+
+	now := time.Now().Unix()
+	job := &types.RefreshJob{
+		JobID:          uuid.NewString(),
+		ChainID:        chainID,
+		Chain:          chain,
+		CollectionAddr: collectionAddr,
+		Caller:         caller,
+		Status:         mq.RefreshJobQueuing,
+		Total:          total,
+		CreateTime:     now,
+		UpdateTime:     now,
+	}
+
+	lockKey := fmt.Sprintf(CacheRefreshCollectionLockKey, chainID, collectionAddr)
+	locked, err := svcCtx.KvStore.SetnxEx(lockKey, job.JobID, RefreshCollectionCooldown)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on lock collection refresh", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+	if !locked {
+		return nil, errcode.NewCustomErr("collection was refreshed recently, please retry later")
+	}
+
+	// a refused request does not count against the caller, and a caller over
+	// the limit does not hold the collection for its cooldown
+	allowed, err := allowCallerRefresh(svcCtx, caller)
+	if err != nil || !allowed {
+		if _, err := svcCtx.KvStore.Del(lockKey); err != nil {
+			xzap.WithContext(ctx).Error("failed on unlock collection refresh", zap.Error(err))
+		}
+	}
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on check refresh rate", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+	if !allowed {
+		return nil, errcode.NewCustomErr("too many refresh jobs, please retry later")
+	}
+
+	if err := mq.CreateRefreshJob(svcCtx.KvStore, job); err != nil {
+		xzap.WithContext(ctx).Error("failed on create refresh job", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	svcCtx.Go(func(ctx context.Context) {
+		queueRefreshJob(ctx, svcCtx, job)
+	})
+
+	return job, nil
+}
+
+// queueRefreshJob pushes the tokens of a collection to the refresh queue batch
+// by batch, until done or the job is cancelled.
+func queueRefreshJob(ctx context.Context, svcCtx *svc.ServerCtx, job *types.RefreshJob) {
+	var cursor int64
+	for {
+		// the job is cancelled, or failed as orphaned
+		current, err := mq.GetRefreshJob(svcCtx.KvStore, job.JobID)
+		if err != nil || current == nil || current.Status != mq.RefreshJobQueuing {
+			return
+		}
+
+		items, err := svcCtx.Dao.QueryCollectionTokenIDs(ctx, job.Chain, job.CollectionAddr, cursor, RefreshJobBatchSize)
+		if err != nil {
+			xzap.WithContext(ctx).Error("failed on query collection token ids", zap.String("job_id", job.JobID), zap.Error(err))
+			_ = mq.SetRefreshJobStatus(svcCtx.KvStore, job.JobID, mq.RefreshJobFailed, "failed on read collection items")
+			return
+		}
+		if len(items) == 0 {
+			break
+		}
+		cursor = items[len(items)-1].Id
+
+		var tokenIDs []string
+		for _, item := range items {
+			tokenIDs = append(tokenIDs, item.TokenId)
+		}
+		if err := svcCtx.RefreshQueue.AddJobItems(ctx, job.Chain, job, tokenIDs, false); err != nil {
+			xzap.WithContext(ctx).Error("failed on queue refresh job items", zap.String("job_id", job.JobID), zap.Error(err))
+			_ = mq.SetRefreshJobStatus(svcCtx.KvStore, job.JobID, mq.RefreshJobFailed, "failed on queue items")
+			return
+		}
+
+		if len(items) < RefreshJobBatchSize {
+			break
+		}
+		// on shutdown the job stays queuing and is failed as orphaned
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(RefreshJobBatchInterval):
+		}
+	}
+
+	current, err := mq.GetRefreshJob(svcCtx.KvStore, job.JobID)
+	if err != nil || current == nil || current.Status != mq.RefreshJobQueuing {
+		return
+	}
+	status := mq.RefreshJobQueued
+	if current.Done+current.Failed >= current.Queued {
+		status = mq.RefreshJobCompleted
+	}
+	if err := mq.SetRefreshJobStatus(svcCtx.KvStore, job.JobID, status, ""); err != nil {
+		xzap.WithContext(ctx).Error("failed on update refresh job", zap.String("job_id", job.JobID), zap.Error(err))
+	}
+}
+
+// callerRefreshJob returns a job started by one of the callers.
+func callerRefreshJob(ctx context.Context, svcCtx *svc.ServerCtx, jobID string, callers []string) (*types.RefreshJob, error) {
+	job, err := mq.GetRefreshJob(svcCtx.KvStore, jobID)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on get refresh job", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+	if job == nil {
+		return nil, errcode.NewCustomErr("refresh job not found")
+	}
+
+	for _, caller := range callers {
+		if caller == job.Caller {
+			return job, nil
+		}
+	}
+	return nil, errcode.NewCustomErr("refresh job not found")
+}
+
+func GetRefreshJob(ctx context.Context, svcCtx *svc.ServerCtx, jobID string, callers []string) (*types.RefreshJob, error) {
+	return callerRefreshJob(ctx, svcCtx, jobID, callers)
+}
+
+// CancelRefreshJob stops queuing the tokens of a job. Tokens already queued
+// are still refreshed.
+func CancelRefreshJob(ctx context.Context, svcCtx *svc.ServerCtx, jobID string, callers []string) (*types.RefreshJob, error) {
+	job, err := callerRefreshJob(ctx, svcCtx, jobID, callers)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != mq.RefreshJobQueuing && job.Status != mq.RefreshJobQueued {
+		return nil, errcode.NewCustomErr("refresh job is " + job.Status)
+	}
+
+	if err := mq.SetRefreshJobStatus(svcCtx.KvStore, jobID, mq.RefreshJobCancelled, ""); err != nil {
+		xzap.WithContext(ctx).Error("failed on cancel refresh job", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	return callerRefreshJob(ctx, svcCtx, jobID, callers)
+}
+
+// RetryRefreshJob queues the failed tokens of a finished job again.
+func RetryRefreshJob(ctx context.Context, svcCtx *svc.ServerCtx, jobID string, callers []string) (*types.RefreshJob, error) {
+	job, err := callerRefreshJob(ctx, svcCtx, jobID, callers)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != mq.RefreshJobCompleted || job.Failed == 0 {
+		return nil, errcode.NewCustomErr("refresh job has no failures to retry")
+	}
+
+	allowed, err := allowCallerRefresh(svcCtx, job.Caller)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on check refresh rate", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+	if !allowed {
+		return nil, errcode.NewCustomErr("too many refresh jobs, please retry later")
+	}
+
+	tokenIDs, err := mq.TakeRefreshJobFailures(svcCtx.KvStore, jobID)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on get refresh job failures", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+	if err := mq.SetRefreshJobStatus(svcCtx.KvStore, jobID, mq.RefreshJobQueued, ""); err != nil {
+		xzap.WithContext(ctx).Error("failed on update refresh job", zap.Error(err))
+		return nil, errcode.ErrUnexpected
+	}
+
+	for start := 0; start < len(tokenIDs); start += RefreshJobBatchSize {
+		end := start + RefreshJobBatchSize
+		if end > len(tokenIDs) {
+			end = len(tokenIDs)
+		}
+		if err := svcCtx.RefreshQueue.AddJobItems(ctx, job.Chain, job, tokenIDs[start:end], true); err != nil {
+			xzap.WithContext(ctx).Error("failed on queue refresh job items", zap.Error(err))
+			return nil, errcode.ErrUnexpected
+		}
+	}
+
+	return callerRefreshJob(ctx, svcCtx, jobID, callers)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/search"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/pkg/errors"
+)
+
+const MaxSearchLimit = 100
+
+func Search(ctx context.Context, svcCtx *svc.ServerCtx, params types.SearchParams) (*types.SearchResp, error) {
+	curated := loadCurations(ctx, svcCtx, params.ChainIDs)
+	query := buildSearchQuery(params)
+	query.ExcludeCollections = curated.hiddenDocs()
+	hits, err := svcCtx.Search.Index().Search(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on search")
+	}
+
+	results := toSearchResults(hits, curated)
+	return &types.SearchResp{
+		Result: results,
+		Count:  int64(len(results)),
+	}, nil
+}
+
+func Autocomplete(ctx context.Context, svcCtx *svc.ServerCtx, params types.SearchParams) (*types.SearchResp, error) {
+	curated := loadCurations(ctx, svcCtx, params.ChainIDs)
+	query := buildSearchQuery(params)
+	query.ExcludeCollections = curated.hiddenDocs()
+	hits, err := svcCtx.Search.Index().Suggest(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on autocomplete")
+	}
+
+	results := toSearchResults(hits, curated)
+	return &types.SearchResp{
+		Result: results,
+		Count:  int64(len(results)),
+	}, nil
+}
+
+func buildSearchQuery(params types.SearchParams) search.Query {
+	limit := params.Limit
+	if limit <= 0 {
+		limit = search.DefaultLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	return search.Query{
+		Text:     strings.TrimSpace(params.Query),
+		ChainIDs: params.ChainIDs,
+		Kinds:    params.Kinds,
+		Limit:    limit,
+	}
+}
+
+// toSearchResults returns the hits with the verified flag of their
+// collections, hidden collections are left out by the index query.
+func toSearchResults(hits []search.Hit, curated curations) []types.SearchResult {
+	results := make([]types.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		var curation dao.CollectionCuration
+		if hit.Kind == search.KindCollection || hit.Kind == search.KindItem {
+			curation = curated.get(hit.ChainID, hit.Address)
+		}
+
+		results = append(results, types.SearchResult{
+			Kind:       hit.Kind,
+			ChainID:    hit.ChainID,
+			Address:    hit.Address,
+			TokenID:    hit.TokenID,
+			Title:      hit.Title,
+			Subtitle:   hit.Subtitle,
+			ImageURI:   hit.ImageURI,
+			Score:      hit.Score,
+			IsVerified: curation.IsVerified,
+		})
+	}
+
+	return results
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ResolutionDay   = "1d"
+	ResolutionWeek  = "1w"
+	ResolutionMonth = "1M"
+)
+
+func IsHistoryResolution(resolution string) bool {
+	return resolution == ResolutionDay || resolution == ResolutionWeek || resolution == ResolutionMonth
+}
+
+// historyBucket returns the start of the period of a snapshot day.
+func historyBucket(day int64, resolution string) int64 {
+	t := time.Unix(day, 0).UTC()
+	switch resolution {
+	case ResolutionWeek:
+		// weeks start on monday
+		weekday := (int(t.Weekday()) + 6) % 7
+		return t.AddDate(0, 0, -weekday).Unix()
+	case ResolutionMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).Unix()
+	default:
+		return day
+	}
+}
+
+// GetPortfolioHistory returns the portfolio value of the users over time. Each
+// point is the value of the last snapshot day of its period.
+func GetPortfolioHistory(ctx context.Context, svcCtx *svc.ServerCtx, chainIDs []int, userAddrs []string,
+	collectionAddrs []string, from, to int64, resolution string) (*types.PortfolioHistoryResp, error) {
+	for i := range userAddrs {
+		userAddrs[i] = strings.ToLower(userAddrs[i])
+	}
+	for i := range collectionAddrs {
+		collectionAddrs[i] = strings.ToLower(collectionAddrs[i])
+	}
+
+	snapshots, err := svcCtx.Dao.QueryPortfolioSnapshots(ctx, removeRepeatedElement(userAddrs), chainIDs, collectionAddrs, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on query portfolio snapshots")
+	}
+
+	days := make(map[int64]*types.PortfolioValuePoint)
+	dayChains := make(map[int64]map[int]*types.ChainValue)
+	for _, snapshot := range snapshots {
+		point, ok := days[snapshot.Day]
+		if !ok {
+			point = &types.PortfolioValuePoint{Time: snapshot.Day, Value: decimal.Zero}
+			days[snapshot.Day] = point
+			dayChains[snapshot.Day] = make(map[int]*types.ChainValue)
+		}
+		point.ItemCount += snapshot.ItemCount
+		point.Value = point.Value.Add(snapshot.Value)
+		point.IsBackfill = point.IsBackfill || snapshot.IsBackfill
+
+		chain, ok := dayChains[snapshot.Day][snapshot.ChainID]
+		if !ok {
+			chain = &types.ChainValue{ChainID: snapshot.ChainID, Value: decimal.Zero}
+			dayChains[snapshot.Day][snapshot.ChainID] = chain
+		}
+		chain.ItemCount += snapshot.ItemCount
+		chain.Value = chain.Value.Add(snapshot.Value)
+	}
+
+	// keep the last day of every period
+	lastDays := make(map[int64]int64)
+	for day := range days {
+		bucket := historyBucket(day, resolution)
+		if last, ok := lastDays[bucket]; !ok || day > last {
+			lastDays[bucket] = day
+		}
+	}
+
+	points := make([]types.PortfolioValuePoint, 0, len(lastDays))
+	for bucket, day := range lastDays {
+		point := *days[day]
+		point.Time = bucket
+		for _, chain := range dayChains[day] {
+			point.Chains = append(point.Chains, *chain)
+		}
+		sort.Slice(point.Chains, func(i, j int) bool {
+			return point.Chains[i].ChainID < point.Chains[j].ChainID
+		})
+		points = append(points, point)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Time < points[j].Time
+	})
+
+	return &types.PortfolioHistoryResp{
+		Result: points,
+	}, nil
+}
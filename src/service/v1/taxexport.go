@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/export"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const TaxExportBatchSize = 1000
+
+// chainActivities reads the activities of a chain batch by batch.
+type chainActivities struct {
+	chainID   int
+	chain     string
+	buf       []multi.Activity
+	afterTime int64
+	afterID   int64
+	done      bool
+}
+
+func (c *chainActivities) peek(ctx context.Context, svcCtx *svc.ServerCtx, userAddrs []string, to int64) (*multi.Activity, error) {
+	if len(c.buf) == 0 && !c.done {
+		activities, err := svcCtx.Dao.QueryUserActivitiesAfter(ctx, c.chain, userAddrs, dao.TaxActivityTypes,
+			to, c.afterTime, c.afterID, TaxExportBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(activities) < TaxExportBatchSize {
+			c.done = true
+		}
+		if len(activities) > 0 {
+			c.afterTime = activities[len(activities)-1].EventTime
+			c.afterID = activities[len(activities)-1].Id
+		}
+		c.buf = activities
+	}
+
+	if len(c.buf) == 0 {
+		return nil, nil
+	}
+	return &c.buf[0], nil
+}
+
+// taxExporter replays the activities of the exported addresses in time order
+// through the lot book, to match sales with their cost.
+type taxExporter struct {
+	svcCtx    *svc.ServerCtx
+	book      *lotBook
+	from      int64
+	royalties map[string]decimal.Decimal
+	stream    *exportStream
+}
+
+// ExportTaxEvents streams the taxable events of userAddrs between from and to
+// to w, oldest first across chains. Activities before `from` are replayed
+// without being written, so that sales are matched with earlier buys.
+func ExportTaxEvents(ctx context.Context, svcCtx *svc.ServerCtx, chainIDs []int, chains []string, userAddrs []string,
+	from, to int64, w *export.Writer) error {
+	users := make(map[string]bool)
+	var lowerAddrs []string
+	for _, addr := range userAddrs {
+		users[strings.ToLower(addr)] = true
+		lowerAddrs = append(lowerAddrs, strings.ToLower(addr))
+	}
+	e := &taxExporter{
+		svcCtx:    svcCtx,
+		book:      newLotBook(users),
+		from:      from,
+		royalties: make(map[string]decimal.Decimal),
+		stream:    &exportStream{w: w},
+	}
+	lowerAddrs = removeRepeatedElement(lowerAddrs)
+
+	var cursors []*chainActivities
+	for i, chain := range chains {
+		cursors = append(cursors, &chainActivities{chainID: chainIDs[i], chain: chain, afterTime: -1})
+	}
+
+	for {
+		var next *chainActivities
+		var nextActivity *multi.Activity
+		for _, cursor := range cursors {
+			activity, err := cursor.peek(ctx, svcCtx, lowerAddrs, to)
+			if err != nil {
+				return errors.Wrap(err, "failed on query tax activities")
+			}
+			if activity != nil && (nextActivity == nil || activity.EventTime < nextActivity.EventTime) {
+				next, nextActivity = cursor, activity
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		// the activities of a block are handled together, so the transfer
+		// recorded along with a trade is not reported twice
+		eventTime := nextActivity.EventTime
+		var group []multi.Activity
+		for {
+			activity, err := next.peek(ctx, svcCtx, lowerAddrs, to)
+			if err != nil {
+				return errors.Wrap(err, "failed on query tax activities")
+			}
+			if activity == nil || activity.EventTime != eventTime {
+				break
+			}
+			group = append(group, *activity)
+			next.buf = next.buf[1:]
+		}
+
+		if err := e.replay(ctx, next.chainID, next.chain, group); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func (e *taxExporter) replay(ctx context.Context, chainID int, chain string, activities []multi.Activity) error {
+	return e.book.replay(chain, activities, func(m lotMove) error {
+		if m.activity.EventTime < e.from {
+			return nil
+		}
+		return e.stream.write(e.event(ctx, chainID, chain, m))
+	})
+}
+
+// event returns the tax event of a move. A token sold or sent away without a
+// known acquisition has no cost basis.
+func (e *taxExporter) event(ctx context.Context, chainID int, chain string, m lotMove) types.TaxEvent {
+	activity := m.activity
+	event := types.TaxEvent{
+		ChainID:           chainID,
+		EventTime:         activity.EventTime,
+		CollectionAddress: m.key.CollectionAddress,
+		TokenID:           activity.TokenId,
+		MarketplaceID:     activity.MarketplaceID,
+		CurrencyAddress:   activity.CurrencyAddress,
+		TxHash:            activity.TxHash,
+		Wallet:            m.wallet,
+		Counterparty:      m.counterparty,
+		CostBasis:         m.lot.cost,
+		AcquiredTime:      m.lot.acquiredTime,
+	}
+
+	if m.in {
+		switch activity.ActivityType {
+		case multi.Mint:
+			event.EventType = types.TaxEventMint
+		case multi.Transfer:
+			// a received token has no known cost
+			event.EventType = types.TaxEventTransferIn
+		default:
+			event.EventType = types.TaxEventBuy
+		}
+		event.Price = m.lot.cost
+		return event
+	}
+
+	if activity.ActivityType == multi.Transfer {
+		event.EventType = types.TaxEventTransferOut
+		return event
+	}
+
+	event.EventType = types.TaxEventSale
+	event.Price = activity.Price
+	event.MarketplaceFee = activity.Price.Mul(marketplaceFeeRate(e.svcCtx.C.Fee, activity.MarketplaceID))
+	event.Royalty = activity.Price.Mul(e.royaltyRate(ctx, chainID, chain, activity))
+	event.Proceeds = activity.Price.Sub(event.MarketplaceFee).Sub(event.Royalty)
+	event.Gain = event.Proceeds.Sub(event.CostBasis)
+	return event
+}
+
+func (e *taxExporter) royaltyRate(ctx context.Context, chainID int, chain string, activity multi.Activity) decimal.Decimal {
+	key := chain + ":" + strings.ToLower(activity.CollectionAddress)
+	if rate, ok := e.royalties[key]; ok {
+		return rate
+	}
+
+	rate, err := e.svcCtx.Royalty.RoyaltyRate(ctx, int64(chainID), chain, activity.CollectionAddress, activity.TokenId)
+	if err != nil {
+		xzap.WithContext(ctx).Error("failed on get royalty rate", zap.String("collection", activity.CollectionAddress), zap.Error(err))
+		rate = decimal.Zero
+	}
+	e.royalties[key] = rate
+	return rate
+}
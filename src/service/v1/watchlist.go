@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	MaxWatchlistSize   = 100
+	MaxPriceAlertCount = 50
+)
+
+// GetWatchlist returns the watched collections of a user with their live
+// collection detail.
+func GetWatchlist(ctx context.Context, svcCtx *svc.ServerCtx, userAddrs []string) (*types.WatchlistResp, error) {
+	chainNames := make(map[int]string)
+	for _, supported := range svcCtx.C.ChainSupported {
+		chainNames[supported.ChainID] = supported.Name
+	}
+
+	watchlist, err := svcCtx.Dao.QueryWatchlist(ctx, userAddrs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get watchlist")
+	}
+
+	results := make([]types.WatchlistEntry, len(watchlist))
+	var wg sync.WaitGroup
+	for i, entry := range watchlist {
+		results[i] = types.WatchlistEntry{
+			ChainID:           entry.ChainID,
+			CollectionAddress: entry.CollectionAddress,
+			CreateTime:        entry.CreateTime,
+		}
+
+		chain, ok := chainNames[entry.ChainID]
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, chain, collectionAddr string) {
+			defer wg.Done()
+			detail, err := GetCollectionDetail(ctx, svcCtx, chain, collectionAddr)
+			if err != nil {
+				xzap.WithContext(ctx).Error("failed on get watched collection detail", zap.String("collection", collectionAddr), zap.Error(err))
+				return
+			}
+			if collectionDetail, ok := detail.Result.(types.CollectionDetail); ok {
+				results[i].Detail = &collectionDetail
+			}
+		}(i, chain, entry.CollectionAddress)
+	}
+	wg.Wait()
+
+	return &types.WatchlistResp{Result: results}, nil
+}
+
+func AddWatchlist(ctx context.Context, svcCtx *svc.ServerCtx, userAddr string, chain string, params types.WatchlistParams) error {
+	collectionAddr := strings.ToLower(params.CollectionAddress)
+	if _, err := svcCtx.Dao.QueryCollectionInfo(ctx, chain, collectionAddr); err != nil {
+		return errcode.NewCustomErr("collection not found")
+	}
+
+	count, err := svcCtx.Dao.CountWatchlist(ctx, []string{userAddr})
+	if err != nil {
+		return errors.Wrap(err, "failed on count watchlist")
+	}
+	if count >= MaxWatchlistSize {
+		return errcode.NewCustomErr("watchlist is full")
+	}
+
+	now := time.Now().UnixMilli()
+	if err := svcCtx.Dao.AddWatchlist(ctx, &dao.Watchlist{
+		UserAddress:       userAddr,
+		ChainID:           params.ChainID,
+		CollectionAddress: collectionAddr,
+		CreateTime:        now,
+		UpdateTime:        now,
+	}); err != nil {
+		return errors.Wrap(err, "failed on add watchlist")
+	}
+
+	return nil
+}
+
+func RemoveWatchlist(ctx context.Context, svcCtx *svc.ServerCtx, userAddrs []string, params types.WatchlistParams) error {
+	if err := svcCtx.Dao.RemoveWatchlist(ctx, userAddrs, params.ChainID, strings.ToLower(params.CollectionAddress)); err != nil {
+		return errors.Wrap(err, "failed on remove watchlist")
+	}
+
+	return nil
+}
+
+func GetPriceAlerts(ctx context.Context, svcCtx *svc.ServerCtx, userAddrs []string) (*types.PriceAlertsResp, error) {
+	alerts, err := svcCtx.Dao.QueryPriceAlerts(ctx, userAddrs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get price alerts")
+	}
+
+	results := make([]types.PriceAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		results = append(results, types.PriceAlert{
+			ID:                alert.Id,
+			ChainID:           alert.ChainID,
+			CollectionAddress: alert.CollectionAddress,
+			AlertType:         alert.AlertType,
+			Threshold:         alert.Threshold,
+			IsTriggered:       alert.IsTriggered,
+			LastTriggerTime:   alert.LastTriggerTime,
+			CreateTime:        alert.CreateTime,
+		})
+	}
+
+	return &types.PriceAlertsResp{Result: results}, nil
+}
+
+func CreatePriceAlert(ctx context.Context, svcCtx *svc.ServerCtx, userAddr string, chain string, params types.CreatePriceAlertParams) (*types.PriceAlert, error) {
+	switch params.AlertType {
+	case dao.PriceAlertFloorBelow, dao.PriceAlertFloorAbove, dao.PriceAlertVolumeChange:
+	default:
+		return nil, errcode.NewCustomErr("unknown alert type")
+	}
+	if !params.Threshold.IsPositive() {
+		return nil, errcode.NewCustomErr("threshold must be positive")
+	}
+
+	collectionAddr := strings.ToLower(params.CollectionAddress)
+	if _, err := svcCtx.Dao.QueryCollectionInfo(ctx, chain, collectionAddr); err != nil {
+		return nil, errcode.NewCustomErr("collection not found")
+	}
+
+	count, err := svcCtx.Dao.CountPriceAlerts(ctx, []string{userAddr})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on count price alerts")
+	}
+	if count >= MaxPriceAlertCount {
+		return nil, errcode.NewCustomErr("too many price alerts")
+	}
+
+	now := time.Now().UnixMilli()
+	alert := dao.PriceAlert{
+		UserAddress:       userAddr,
+		ChainID:           params.ChainID,
+		CollectionAddress: collectionAddr,
+		AlertType:         params.AlertType,
+		Threshold:         params.Threshold,
+		IsActive:          true,
+		CreateTime:        now,
+		UpdateTime:        now,
+	}
+	if err := svcCtx.Dao.CreatePriceAlert(ctx, &alert); err != nil {
+		return nil, errors.Wrap(err, "failed on create price alert")
+	}
+
+	return &types.PriceAlert{
+		ID:                alert.Id,
+		ChainID:           alert.ChainID,
+		CollectionAddress: alert.CollectionAddress,
+		AlertType:         alert.AlertType,
+		Threshold:         alert.Threshold,
+		CreateTime:        alert.CreateTime,
+	}, nil
+}
+
+func DeletePriceAlert(ctx context.Context, svcCtx *svc.ServerCtx, userAddrs []string, id int64) error {
+	deleted, err := svcCtx.Dao.DeletePriceAlert(ctx, userAddrs, id)
+	if err != nil {
+		return errors.Wrap(err, "failed on delete price alert")
+	}
+	if !deleted {
+		return errcode.NewCustomErr("price alert not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/svc"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/errcode"
+	"github.com/pkg/errors"
+)
+
+const DefaultMaxWebhooksPerOwner = 20
+
+// CreateWebhook registers a webhook for owner. The signing secret is only
+// returned here.
+func CreateWebhook(ctx context.Context, svcCtx *svc.ServerCtx, owner string, params types.CreateWebhookParams) (*types.CreateWebhookResp, error) {
+	if err := svcCtx.Webhooks.ValidateURL(params.URL); err != nil {
+		return nil, errcode.NewCustomErr(err.Error())
+	}
+
+	var eventTypes []string
+	for _, eventType := range params.EventTypes {
+		if !dao.IsActivityEventType(eventType) {
+			return nil, errcode.NewCustomErr("unknown event type " + eventType)
+		}
+		eventTypes = append(eventTypes, eventType)
+	}
+
+	var collections []string
+	for _, collection := range params.Collections {
+		collections = append(collections, strings.ToLower(collection))
+	}
+
+	maxWebhooks := int64(DefaultMaxWebhooksPerOwner)
+	if svcCtx.C.Webhook != nil && svcCtx.C.Webhook.MaxPerOwner > 0 {
+		maxWebhooks = int64(svcCtx.C.Webhook.MaxPerOwner)
+	}
+	count, err := svcCtx.Dao.CountWebhooks(ctx, []string{owner})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on count webhooks")
+	}
+	if count >= maxWebhooks {
+		return nil, errcode.NewCustomErr("too many webhooks")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.Wrap(err, "failed on generate webhook secret")
+	}
+
+	now := time.Now().UnixMilli()
+	webhook := dao.Webhook{
+		Owner:       owner,
+		URL:         params.URL,
+		Secret:      hex.EncodeToString(secret),
+		ChainID:     params.ChainID,
+		EventTypes:  strings.Join(eventTypes, ","),
+		Collections: strings.Join(collections, ","),
+		IsActive:    true,
+		CreateTime:  now,
+		UpdateTime:  now,
+	}
+	if err := svcCtx.Dao.CreateWebhook(ctx, &webhook); err != nil {
+		return nil, errors.Wrap(err, "failed on create webhook")
+	}
+
+	return &types.CreateWebhookResp{
+		Result: webhookInfo(&webhook),
+		Secret: webhook.Secret,
+	}, nil
+}
+
+func GetWebhooks(ctx context.Context, svcCtx *svc.ServerCtx, owners []string) (*types.WebhooksResp, error) {
+	webhooks, err := svcCtx.Dao.QueryWebhooks(ctx, owners)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get webhooks")
+	}
+
+	results := make([]types.Webhook, 0, len(webhooks))
+	for i := range webhooks {
+		results = append(results, webhookInfo(&webhooks[i]))
+	}
+
+	return &types.WebhooksResp{Result: results}, nil
+}
+
+func DeleteWebhook(ctx context.Context, svcCtx *svc.ServerCtx, owners []string, id int64) error {
+	deleted, err := svcCtx.Dao.DeleteWebhook(ctx, owners, id)
+	if err != nil {
+		return errors.Wrap(err, "failed on delete webhook")
+	}
+	if !deleted {
+		return errcode.NewCustomErr("webhook not found")
+	}
+
+	if err := svcCtx.Dao.DeadLetterWebhookDeliveries(ctx, id, "webhook deleted"); err != nil {
+		return errors.Wrap(err, "failed on delete webhook")
+	}
+
+	return nil
+}
+
+func GetWebhookDeliveries(ctx context.Context, svcCtx *svc.ServerCtx, owners []string, id int64, filter types.WebhookDeliveryFilterParams) (*types.WebhookDeliveriesResp, error) {
+	webhook, err := svcCtx.Dao.QueryWebhook(ctx, owners, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get webhook")
+	}
+	if webhook == nil {
+		return nil, errcode.NewCustomErr("webhook not found")
+	}
+
+	deliveries, count, err := svcCtx.Dao.QueryWebhookDeliveries(ctx, id, filter.Status, filter.Page, filter.PageSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get webhook deliveries")
+	}
+
+	results := make([]types.WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		results = append(results, types.WebhookDelivery{
+			ID:              delivery.Id,
+			EventID:         delivery.EventID,
+			EventType:       delivery.EventType,
+			Status:          delivery.Status,
+			Attempts:        delivery.Attempts,
+			NextAttemptTime: delivery.NextAttemptTime,
+			ResponseCode:    delivery.ResponseCode,
+			LastError:       delivery.LastError,
+			CreateTime:      delivery.CreateTime,
+			UpdateTime:      delivery.UpdateTime,
+		})
+	}
+
+	return &types.WebhookDeliveriesResp{
+		Result: results,
+		Count:  count,
+	}, nil
+}
+
+// RetryWebhookDelivery requeues a dead lettered delivery.
+func RetryWebhookDelivery(ctx context.Context, svcCtx *svc.ServerCtx, owners []string, id, deliveryID int64) error {
+	webhook, err := svcCtx.Dao.QueryWebhook(ctx, owners, id)
+	if err != nil {
+		return errors.Wrap(err, "failed on get webhook")
+	}
+	if webhook == nil {
+		return errcode.NewCustomErr("webhook not found")
+	}
+
+	retried, err := svcCtx.Dao.RetryWebhookDelivery(ctx, id, deliveryID)
+	if err != nil {
+		return errors.Wrap(err, "failed on retry webhook delivery")
+	}
+	if !retried {
+		return errcode.NewCustomErr("delivery is not dead lettered")
+	}
+
+	return nil
+}
+
+func webhookInfo(webhook *dao.Webhook) types.Webhook {
+	info := types.Webhook{
+		ID:         webhook.Id,
+		URL:        webhook.URL,
+		ChainID:    webhook.ChainID,
+		CreateTime: webhook.CreateTime,
+	}
+	if webhook.EventTypes != "" {
+		info.EventTypes = strings.Split(webhook.EventTypes, ",")
+	}
+	if webhook.Collections != "" {
+		info.Collections = strings.Split(webhook.Collections, ",")
+	}
+	return info
+}
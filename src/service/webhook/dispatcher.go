@@ -0,0 +1,390 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SimonHofman/EasySwapBackend/src/config"
+	"github.com/SimonHofman/EasySwapBackend/src/dao"
+	"github.com/SimonHofman/EasySwapBackend/src/service/netguard"
+	"github.com/SimonHofman/EasySwapBackend/src/types/v1"
+	"github.com/SimonHofman/EasySwapBase/logger/xzap"
+	"github.com/SimonHofman/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/SimonHofman/EasySwapBase/stores/xkv"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultInterval    = 5 * time.Second
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxAttempts = 8
+	BaseRetryDelay     = 30 * time.Second
+	MaxRetryDelay      = 6 * time.Hour
+	BatchSize          = 200
+	MaxErrorLength     = 512
+	// DeliveryConcurrency bounds the deliveries posted at once.
+	DeliveryConcurrency = 16
+	// DeliverDeadline bounds the delivery of a batch, so a batch is not
+	// left in flight long after the leader lock is lost.
+	DeliverDeadline = 20 * time.Second
+)
+
+const (
+	CacheDispatcherCursorKey = "cache:es:webhook:%s:activity:cursor"
+)
+
+var activityTypes = []int{
+	multi.Sale, multi.Transfer, multi.MakeOffer, multi.CancelOffer, multi.CancelListing, multi.Listing,
+	multi.Mint, multi.Buy, multi.CollectionBid, multi.ItemBid, multi.CancelCollectionBid, multi.CancelItemBid,
+}
+
+// Dispatcher turns the new activities of every chain into deliveries for the
+// matching webhooks and posts the due deliveries. Failed deliveries are
+// retried with exponential backoff until they are dead lettered. It must run
+// on one instance at a time.
+type Dispatcher struct {
+	dao          *dao.Dao
+	store        *xkv.Store
+	chains       []*config.ChainSupported
+	client       *http.Client
+	maxAttempts  int
+	allowPrivate bool
+}
+
+func NewDispatcher(d *dao.Dao, store *xkv.Store, chains []*config.ChainSupported, timeout time.Duration, maxAttempts int, allowPrivate bool) *Dispatcher {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	return &Dispatcher{
+		dao:          d,
+		store:        store,
+		chains:       chains,
+		client:       newClient(timeout, allowPrivate),
+		maxAttempts:  maxAttempts,
+		allowPrivate: allowPrivate,
+	}
+}
+
+// newClient returns a client refusing to connect to private addresses, so
+// webhooks cannot be used to reach internal services. Redirects are not
+// followed.
+func newClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	client := netguard.NewClient(timeout, allowPrivate)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return client
+}
+
+// ValidateURL checks a webhook url before registration.
+func (d *Dispatcher) ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrap(err, "invalid webhook url")
+	}
+
+	if u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return errors.New("webhook url must be an absolute http(s) url")
+	}
+	if d.allowPrivate {
+		return nil
+	}
+
+	if u.Scheme != "https" {
+		return errors.New("webhook url must use https")
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return netguard.ErrPrivateAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && netguard.IsPrivateIP(ip) {
+		return netguard.ErrPrivateAddress
+	}
+	return nil
+}
+
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Dispatch(ctx); err != nil {
+				xzap.WithContext(ctx).Error("failed on dispatch webhooks", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	webhooks, err := d.dao.QueryActiveWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, chain := range d.chains {
+		if err := d.enqueue(ctx, chain, webhooks); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed on enqueue webhook deliveries of %s", chain.Name))
+		}
+	}
+
+	return d.deliver(ctx, webhooks)
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, chain *config.ChainSupported, webhooks []dao.Webhook) error {
+	cursor, err := d.cursor(ctx, chain.Name)
+	if err != nil {
+		return err
+	}
+
+	for {
+		activities, err := d.dao.QueryActivitiesSince(ctx, chain.Name, cursor, activityTypes, BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(activities) == 0 {
+			return nil
+		}
+
+		var chainActivities []dao.ActivityMultiChainInfo
+		for _, activity := range activities {
+			chainActivities = append(chainActivities, dao.ActivityMultiChainInfo{Activity: activity, ChainName: chain.Name})
+		}
+		infos, err := d.dao.QueryMultiChainActivityExternalInfo(ctx, []int{chain.ChainID}, []string{chain.Name}, chainActivities)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UnixMilli()
+		var deliveries []dao.WebhookDelivery
+		for i, info := range infos {
+			eventID := fmt.Sprintf("%s:%d", chain.Name, activities[i].Id)
+			for _, webhook := range webhooks {
+				if !match(&webhook, &info) {
+					continue
+				}
+
+				payload, err := json.Marshal(types.WebhookPayload{
+					ID:         eventID,
+					EventType:  info.EventType,
+					ChainID:    info.ChainID,
+					CreateTime: now,
+					Data:       info,
+				})
+				if err != nil {
+					return errors.Wrap(err, "failed on marshal webhook payload")
+				}
+
+				deliveries = append(deliveries, dao.WebhookDelivery{
+					WebhookID:       webhook.Id,
+					EventID:         eventID,
+					EventType:       info.EventType,
+					Payload:         string(payload),
+					Status:          dao.WebhookDeliveryPending,
+					NextAttemptTime: now,
+					CreateTime:      now,
+					UpdateTime:      now,
+				})
+			}
+		}
+
+		if err := d.dao.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+			return err
+		}
+
+		cursor = activities[len(activities)-1].Id
+		if err := d.setCursor(chain.Name, cursor); err != nil {
+			return err
+		}
+		if len(activities) < BatchSize {
+			return nil
+		}
+	}
+}
+
+func match(webhook *dao.Webhook, info *types.ActivityInfo) bool {
+	if webhook.ChainID != 0 && webhook.ChainID != info.ChainID {
+		return false
+	}
+	if webhook.EventTypes != "" && !containsFold(webhook.EventTypes, info.EventType) {
+		return false
+	}
+	if webhook.Collections != "" && !containsFold(webhook.Collections, info.CollectionAddress) {
+		return false
+	}
+	return true
+}
+
+func containsFold(list string, value string) bool {
+	for _, v := range strings.Split(list, ",") {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, webhooks []dao.Webhook) error {
+	endpoints := make(map[int64]dao.Webhook)
+	for _, webhook := range webhooks {
+		endpoints[webhook.Id] = webhook
+	}
+
+	deliveries, err := d.dao.QueryDueWebhookDeliveries(ctx, time.Now().UnixMilli(), BatchSize)
+	if err != nil {
+		return err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, DeliverDeadline)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var updateErr error
+	sem := make(chan struct{}, DeliveryConcurrency)
+	for i := range deliveries {
+		select {
+		case sem <- struct{}{}:
+		case <-sendCtx.Done():
+		}
+		// deliveries left when the deadline passes wait for the next batch
+		if sendCtx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(delivery *dao.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			webhook, ok := endpoints[delivery.WebhookID]
+			if !ok {
+				delivery.Status = dao.WebhookDeliveryDead
+				delivery.LastError = "webhook deleted"
+			} else {
+				d.send(sendCtx, &webhook, delivery)
+			}
+
+			if err := d.dao.UpdateWebhookDelivery(ctx, delivery); err != nil {
+				mu.Lock()
+				updateErr = err
+				mu.Unlock()
+			}
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	return updateErr
+}
+
+// send posts a delivery and records the outcome on it.
+func (d *Dispatcher) send(ctx context.Context, webhook *dao.Webhook, delivery *dao.WebhookDelivery) {
+	delivery.Attempts++
+
+	code, err := d.post(ctx, webhook, delivery)
+	delivery.ResponseCode = code
+	if err == nil {
+		delivery.Status = dao.WebhookDeliverySucceeded
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if len(delivery.LastError) > MaxErrorLength {
+		delivery.LastError = delivery.LastError[:MaxErrorLength]
+	}
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.Status = dao.WebhookDeliveryDead
+		return
+	}
+
+	delivery.Status = dao.WebhookDeliveryRetrying
+	delivery.NextAttemptTime = time.Now().Add(RetryDelay(delivery.Attempts)).UnixMilli()
+}
+
+func (d *Dispatcher) post(ctx context.Context, webhook *dao.Webhook, delivery *dao.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, time.Now().Unix(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// RetryDelay returns the backoff before the next attempt: 30s doubled after
+// every failed attempt, capped at 6h.
+func RetryDelay(attempts int) time.Duration {
+	delay := BaseRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= MaxRetryDelay {
+			return MaxRetryDelay
+		}
+	}
+	return delay
+}
+
+// cursor returns the last enqueued activity id. A new cursor starts at the
+// current end of the table, webhooks do not receive history.
+func (d *Dispatcher) cursor(ctx context.Context, chain string) (int64, error) {
+	value, err := d.store.Get(fmt.Sprintf(CacheDispatcherCursorKey, chain))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed on get webhook cursor")
+	}
+
+	if value != "" {
+		cursor, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			return cursor, nil
+		}
+	}
+
+	cursor, err := d.dao.QueryMaxID(ctx, multi.ActivityTableName(chain))
+	if err != nil {
+		return 0, err
+	}
+	return cursor, d.setCursor(chain, cursor)
+}
+
+func (d *Dispatcher) setCursor(chain string, cursor int64) error {
+	if err := d.store.Set(fmt.Sprintf(CacheDispatcherCursorKey, chain), strconv.FormatInt(cursor, 10)); err != nil {
+		return errors.Wrap(err, "failed on set webhook cursor")
+	}
+	return nil
+}
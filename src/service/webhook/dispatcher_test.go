@@ -0,0 +1,19 @@
+package webhook
+
+import "testing"
+
+func TestValidateURL(t *testing.T) {
+	d := &Dispatcher{}
+	for rawURL, valid := range map[string]bool{
+		"https://example.com/hook": true,
+		"http://example.com/hook":  false,
+		"https://localhost/hook":   false,
+		"https://10.0.0.1/hook":    false,
+		"https://100.64.0.1/hook":  false,
+		"ftp://example.com/hook":   false,
+	} {
+		if err := d.ValidateURL(rawURL); (err == nil) != valid {
+			t.Fatalf("ValidateURL(%s) = %v, want valid %v", rawURL, err, valid)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderSignature = "X-EasySwap-Signature"
+	HeaderEvent     = "X-EasySwap-Event"
+	HeaderDelivery  = "X-EasySwap-Delivery"
+)
+
+// Sign returns the signature header of a payload: the unix timestamp and the
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac(secret, timestamp, body)))
+}
+
+// Verify checks a signature header produced by Sign, receivers should also
+// reject timestamps too far from their clock.
+func Verify(secret string, header string, body []byte) (int64, bool) {
+	var timestamp int64
+	var signature []byte
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp, _ = strconv.ParseInt(kv[1], 10, 64)
+		case "v1":
+			signature, _ = hex.DecodeString(kv[1])
+		}
+	}
+
+	if timestamp == 0 || len(signature) == 0 {
+		return 0, false
+	}
+	return timestamp, hmac.Equal(signature, mac(secret, timestamp, body))
+}
+
+func mac(secret string, timestamp int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -24,7 +24,9 @@ type ActivityInfo struct {
 	Currency           string          `json:"currency"`
 	Price              decimal.Decimal `json:"price"`
 	Maker              string          `json:"maker"`
+	MakerName          string          `json:"maker_name"`
 	Taker              string          `json:"taker"`
+	TakerName          string          `json:"taker_name"`
 	TxHash             string          `json:"tx_hash"`
 	MarketplaceID      int             `json:"marketplace_id"`
 	ChainID            int             `json:"chain_id"`
@@ -1,6 +1,10 @@
 package types
 
-import "github.com/shopspring/decimal"
+import (
+	"encoding/json"
+
+	"github.com/shopspring/decimal"
+)
 
 type CollectionItemFilterParams struct {
 	Sort        int    `json:"sort"`
@@ -14,9 +18,10 @@ type CollectionItemFilterParams struct {
 }
 
 type CollectionBidFilterParams struct {
-	ChainID  int `json:"chain_id"`
-	Page     int `json:"page"`
-	PageSize int `json:"page_size"`
+	ChainID  int    `json:"chain_id"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+	Trait    string `json:"trait"`
 }
 
 type CollectionBids struct {
@@ -26,6 +31,15 @@ type CollectionBids struct {
 	Bidders int             `json:"bidders"`
 }
 
+type TraitBids struct {
+	Trait      string          `json:"trait"`
+	TraitValue string          `json:"trait_value"`
+	Price      decimal.Decimal `json:"price"`
+	Size       int             `json:"size"`
+	Total      decimal.Decimal `json:"total"`
+	Bidders    int             `json:"bidders"`
+}
+
 type CollectionBidsResp struct {
 	Result interface{} `json:"result"`
 	Count  int64       `json:"count"`
@@ -63,6 +77,9 @@ type NFTListingInfo struct {
 	ListExpireTime int64           `json:"list_expire_time"`
 	ListSalt       int64           `json:"list_salt"`
 	ListMaker      string          `json:"list_maker"`
+	ListMakerName  string          `json:"list_maker_name"`
+	ListSize       int64           `json:"list_size"`
+	ListUnfilled   int64           `json:"list_unfilled"`
 
 	BidOrderID    string          `json:"bid_order_id"`
 	BidTime       int64           `json:"bid_time"`
@@ -70,6 +87,7 @@ type NFTListingInfo struct {
 	BidPrice      decimal.Decimal `json:"bid_price"`
 	BidSalt       int64           `json:"bid_salt"`
 	BidMaker      string          `json:"bid_maker"`
+	BidMakerName  string          `json:"bid_maker_name"`
 	BidType       int64           `json:"bid_type"`
 	BidSize       int64           `json:"bid_size"`
 	BidUnfilled   int64           `json:"bid_unfilled"`
@@ -98,6 +116,8 @@ type CollectionRankingInfo struct {
 	ItemSold    int64           `json:"item_sold"`
 	ListAmount  int             `json:"list_amount"`
 	ChainID     int             `json:"chain_id"`
+	IsVerified  bool            `json:"is_verified"`
+	IsFeatured  bool            `json:"is_featured"`
 }
 
 type CollectionRankingResp struct {
@@ -118,6 +138,8 @@ type CollectionDetail struct {
 	TotalSupply    int64           `json:"total_supply"`
 	OwnerAmount    int64           `json:"owner_amount"`
 	RoyaltyFeeRate string          `json:"royalty_fee_rate"`
+	IsVerified     bool            `json:"is_verified"`
+	IsFeatured     bool            `json:"is_featured"`
 }
 
 type CollectionDetailResp struct {
@@ -132,9 +154,77 @@ type RefreshItem struct {
 	ChainID        int64  `json:"chain_id"`
 	CollectionAddr string `json:"collection_addr"`
 	TokenID        string `json:"token_id"`
+	// JobID is set on the items of a collection refresh job, whose consumers
+	// report the result through mq.ReportRefreshResult.
+	JobID string `json:"job_id,omitempty"`
+}
+
+type RefreshCollectionParams struct {
+	ChainID int `json:"chain_id"`
+}
+
+// RefreshJob is the progress of a collection metadata refresh.
+type RefreshJob struct {
+	JobID          string `json:"job_id"`
+	ChainID        int64  `json:"chain_id"`
+	Chain          string `json:"-"`
+	CollectionAddr string `json:"collection_addr"`
+	Caller         string `json:"-"`
+	Status         string `json:"status"`
+	Total          int64  `json:"total"`
+	Queued         int64  `json:"queued"`
+	Done           int64  `json:"done"`
+	Failed         int64  `json:"failed"`
+	Error          string `json:"error,omitempty"`
+	CreateTime     int64  `json:"create_time"`
+	UpdateTime     int64  `json:"update_time"`
 }
 
 type CollectionListed struct {
 	CollectionAddr string `json:"collection_address"`
 	Count          int    `json:"count"`
 }
+
+type DeadLetterParams struct {
+	ChainID int      `json:"chain_id"`
+	IDs     []string `json:"ids"`
+}
+
+type DeadLetterResult struct {
+	Count int64 `json:"count"`
+}
+
+// CurationParams sets the curation flags of a collection, fields left out
+// keep their value.
+type CurationParams struct {
+	ChainID    int     `json:"chain_id"`
+	IsVerified *bool   `json:"is_verified"`
+	IsFeatured *bool   `json:"is_featured"`
+	IsHidden   *bool   `json:"is_hidden"`
+	Note       *string `json:"note"`
+}
+
+type CollectionCuration struct {
+	ChainID           int    `json:"chain_id"`
+	CollectionAddress string `json:"collection_address"`
+	IsVerified        bool   `json:"is_verified"`
+	IsFeatured        bool   `json:"is_featured"`
+	IsHidden          bool   `json:"is_hidden"`
+	Note              string `json:"note"`
+	UpdatedBy         string `json:"updated_by"`
+	UpdateTime        int64  `json:"update_time"`
+}
+
+type CurationAudit struct {
+	ChainID           int             `json:"chain_id"`
+	CollectionAddress string          `json:"collection_address"`
+	Operator          string          `json:"operator"`
+	Before            json.RawMessage `json:"before"`
+	After             json.RawMessage `json:"after"`
+	CreateTime        int64           `json:"create_time"`
+}
+
+type CurationAuditResp struct {
+	Result interface{} `json:"result"`
+	Count  int64       `json:"count"`
+}
@@ -0,0 +1,225 @@
+package types
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	TaxEventBuy         = "buy"
+	TaxEventSale        = "sale"
+	TaxEventMint        = "mint"
+	TaxEventTransferIn  = "transfer_in"
+	TaxEventTransferOut = "transfer_out"
+)
+
+type TaxExportParams struct {
+	ChainID       []int    `json:"chain_id"`
+	UserAddresses []string `json:"user_addresses"`
+	From          int64    `json:"from"`
+	To            int64    `json:"to"`
+	Format        string   `json:"format"`
+}
+
+// TaxEvent is a buy, sale, mint or transfer of a token by one of the exported
+// addresses. Proceeds are the sale price net of marketplace fees and
+// royalties, gas excluded. CostBasis is the FIFO matched acquisition cost of
+// the token leaving the addresses, zero when unknown.
+type TaxEvent struct {
+	ChainID           int             `json:"chain_id"`
+	EventType         string          `json:"event_type"`
+	EventTime         int64           `json:"event_time"`
+	Wallet            string          `json:"wallet"`
+	Counterparty      string          `json:"counterparty"`
+	CollectionAddress string          `json:"collection_address"`
+	TokenID           string          `json:"token_id"`
+	MarketplaceID     int             `json:"marketplace_id"`
+	CurrencyAddress   string          `json:"currency_address"`
+	Price             decimal.Decimal `json:"price"`
+	MarketplaceFee    decimal.Decimal `json:"marketplace_fee"`
+	Royalty           decimal.Decimal `json:"royalty"`
+	Proceeds          decimal.Decimal `json:"proceeds"`
+	CostBasis         decimal.Decimal `json:"cost_basis"`
+	AcquiredTime      int64           `json:"acquired_time"`
+	Gain              decimal.Decimal `json:"gain"`
+	TxHash            string          `json:"tx_hash"`
+}
+
+func (e TaxEvent) CSVHeader() []string {
+	return []string{
+		"chain_id", "event_type", "date", "event_time", "wallet", "counterparty", "collection_address", "token_id",
+		"marketplace_id", "currency_address", "price", "marketplace_fee", "royalty", "proceeds", "cost_basis",
+		"acquired_time", "gain", "tx_hash",
+	}
+}
+
+func (e TaxEvent) CSVRecord() []string {
+	return []string{
+		strconv.Itoa(e.ChainID),
+		e.EventType,
+		time.Unix(e.EventTime, 0).UTC().Format(time.RFC3339),
+		strconv.FormatInt(e.EventTime, 10),
+		e.Wallet,
+		e.Counterparty,
+		e.CollectionAddress,
+		e.TokenID,
+		strconv.Itoa(e.MarketplaceID),
+		e.CurrencyAddress,
+		e.Price.String(),
+		e.MarketplaceFee.String(),
+		e.Royalty.String(),
+		e.Proceeds.String(),
+		e.CostBasis.String(),
+		strconv.FormatInt(e.AcquiredTime, 10),
+		e.Gain.String(),
+		e.TxHash,
+	}
+}
+
+// ExportCursor is the position to resume an export from: the chain and id of
+// the last row received.
+type ExportCursor struct {
+	AfterChainID int   `json:"after_chain_id"`
+	AfterID      int64 `json:"after_id"`
+}
+
+type ActivityExportParams struct {
+	ActivityMultiChainFilterParams
+	ExportCursor
+	Format string `json:"format"`
+}
+
+type OrderExportParams struct {
+	ChainID           []int  `json:"chain_id"`
+	CollectionAddress string `json:"collection_address"`
+	TokenID           string `json:"token_id"`
+	Maker             string `json:"maker"`
+	OrderTypes        []int  `json:"order_types"`
+	Markets           []int  `json:"markets"`
+	ExportCursor
+	Format string `json:"format"`
+}
+
+type ItemExportParams struct {
+	CollectionItemFilterParams
+	ExportCursor
+	Format string `json:"format"`
+}
+
+type ExportActivity struct {
+	ChainID           int             `json:"chain_id"`
+	ID                int64           `json:"id"`
+	EventType         string          `json:"event_type"`
+	EventTime         int64           `json:"event_time"`
+	CollectionAddress string          `json:"collection_address"`
+	TokenID           string          `json:"token_id"`
+	Maker             string          `json:"maker"`
+	Taker             string          `json:"taker"`
+	MarketplaceID     int             `json:"marketplace_id"`
+	CurrencyAddress   string          `json:"currency_address"`
+	Price             decimal.Decimal `json:"price"`
+	TxHash            string          `json:"tx_hash"`
+}
+
+func (a ExportActivity) CSVHeader() []string {
+	return []string{
+		"chain_id", "id", "event_type", "event_time", "collection_address", "token_id", "maker", "taker",
+		"marketplace_id", "currency_address", "price", "tx_hash",
+	}
+}
+
+func (a ExportActivity) CSVRecord() []string {
+	return []string{
+		strconv.Itoa(a.ChainID),
+		strconv.FormatInt(a.ID, 10),
+		a.EventType,
+		strconv.FormatInt(a.EventTime, 10),
+		a.CollectionAddress,
+		a.TokenID,
+		a.Maker,
+		a.Taker,
+		strconv.Itoa(a.MarketplaceID),
+		a.CurrencyAddress,
+		a.Price.String(),
+		a.TxHash,
+	}
+}
+
+type ExportOrder struct {
+	ChainID           int             `json:"chain_id"`
+	ID                int64           `json:"id"`
+	OrderID           string          `json:"order_id"`
+	OrderType         int64           `json:"order_type"`
+	MarketplaceID     int             `json:"marketplace_id"`
+	CollectionAddress string          `json:"collection_address"`
+	TokenID           string          `json:"token_id"`
+	Maker             string          `json:"maker"`
+	CurrencyAddress   string          `json:"currency_address"`
+	Price             decimal.Decimal `json:"price"`
+	Size              int64           `json:"size"`
+	QuantityRemaining int64           `json:"quantity_remaining"`
+	EventTime         int64           `json:"event_time"`
+	ExpireTime        int64           `json:"expire_time"`
+	Salt              int64           `json:"salt"`
+}
+
+func (o ExportOrder) CSVHeader() []string {
+	return []string{
+		"chain_id", "id", "order_id", "order_type", "marketplace_id", "collection_address", "token_id", "maker",
+		"currency_address", "price", "size", "quantity_remaining", "event_time", "expire_time", "salt",
+	}
+}
+
+func (o ExportOrder) CSVRecord() []string {
+	return []string{
+		strconv.Itoa(o.ChainID),
+		strconv.FormatInt(o.ID, 10),
+		o.OrderID,
+		strconv.FormatInt(o.OrderType, 10),
+		strconv.Itoa(o.MarketplaceID),
+		o.CollectionAddress,
+		o.TokenID,
+		o.Maker,
+		o.CurrencyAddress,
+		o.Price.String(),
+		strconv.FormatInt(o.Size, 10),
+		strconv.FormatInt(o.QuantityRemaining, 10),
+		strconv.FormatInt(o.EventTime, 10),
+		strconv.FormatInt(o.ExpireTime, 10),
+		strconv.FormatInt(o.Salt, 10),
+	}
+}
+
+type ExportItem struct {
+	ChainID           int             `json:"chain_id"`
+	ID                int64           `json:"id"`
+	CollectionAddress string          `json:"collection_address"`
+	TokenID           string          `json:"token_id"`
+	Name              string          `json:"name"`
+	Owner             string          `json:"owner"`
+	Listing           bool            `json:"listing"`
+	ListPrice         decimal.Decimal `json:"list_price"`
+	MarketplaceID     int             `json:"marketplace_id"`
+}
+
+func (i ExportItem) CSVHeader() []string {
+	return []string{
+		"chain_id", "id", "collection_address", "token_id", "name", "owner", "listing", "list_price", "marketplace_id",
+	}
+}
+
+func (i ExportItem) CSVRecord() []string {
+	return []string{
+		strconv.Itoa(i.ChainID),
+		strconv.FormatInt(i.ID, 10),
+		i.CollectionAddress,
+		i.TokenID,
+		i.Name,
+		i.Owner,
+		strconv.FormatBool(i.Listing),
+		i.ListPrice.String(),
+		strconv.Itoa(i.MarketplaceID),
+	}
+}
@@ -18,15 +18,29 @@ type ItemPriceInfo struct {
 }
 
 type ItemOwner struct {
-	CollectionAddress string `json:"collection_address"`
-	TokenID           string `json:"token_id"`
-	Owner             string `json:"owner"`
+	CollectionAddress string       `json:"collection_address"`
+	TokenID           string       `json:"token_id"`
+	Owner             string       `json:"owner"`
+	Holders           []ItemHolder `json:"holders"`
+}
+
+// ItemHolder is an owner of a token with its balance, always 1 for ERC-721.
+type ItemHolder struct {
+	Owner   string `json:"owner"`
+	Balance int64  `json:"balance"`
 }
 
 type ItemImage struct {
-	CollectionAddress string `json:"collection_address"`
-	TokenID           string `json:"token_id"`
-	ImageUri          string `json:"image_uri"`
+	CollectionAddress string      `json:"collection_address"`
+	TokenID           string      `json:"token_id"`
+	ImageUri          string      `json:"image_uri"`
+	Thumbnails        []Thumbnail `json:"thumbnails,omitempty"`
+}
+
+// Thumbnail is a resized copy of an item image in the object storage.
+type Thumbnail struct {
+	Size int    `json:"size"`
+	URI  string `json:"uri"`
 }
 
 type ItemDetailInfo struct {
@@ -37,12 +51,15 @@ type ItemDetailInfo struct {
 	CollectionImageURI string          `json:"collection_image_uri"`
 	TokenID            string          `json:"token_id"`
 	ImageURI           string          `json:"image_uri"`
+	Thumbnails         []Thumbnail     `json:"thumbnails,omitempty"`
 	VideoType          string          `json:"video_type"`
 	VideoURI           string          `json:"video_uri"`
 	LastSellPrice      decimal.Decimal `json:"last_sell_price"`
 	FloorPrice         decimal.Decimal `json:"floor_price"`
 	OwnerAddress       string          `json:"owner_address"`
 	MarketplaceID      int             `json:"marketplace_id"`
+	TokenStandard      int64           `json:"token_standard"`
+	Holders            []ItemHolder    `json:"holders"`
 
 	ListOrderID    string          `json:"list_order_id"`
 	ListTime       int64           `json:"list_time"`
@@ -50,6 +67,9 @@ type ItemDetailInfo struct {
 	ListExpireTime int64           `json:"list_expire_time"`
 	ListSalt       int64           `json:"list_salt"`
 	ListMaker      string          `json:"list_maker"`
+	ListMakerName  string          `json:"list_maker_name"`
+	ListSize       int64           `json:"list_size"`
+	ListUnfilled   int64           `json:"list_unfilled"`
 
 	BidOrderID    string          `json:"bid_order_id"`
 	BidTime       int64           `json:"bid_time"`
@@ -57,6 +77,7 @@ type ItemDetailInfo struct {
 	BidPrice      decimal.Decimal `json:"bid_price"`
 	BidSalt       int64           `json:"bid_salt"`
 	BidMaker      string          `json:"bid_maker"`
+	BidMakerName  string          `json:"bid_maker_name"`
 	BidType       int64           `json:"bid_type"`
 	BidSize       int64           `json:"bid_size"`
 	BidUnfilled   int64           `json:"bid_unfilled"`
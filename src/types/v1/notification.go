@@ -0,0 +1,34 @@
+package types
+
+import "github.com/shopspring/decimal"
+
+type NotificationFilterParams struct {
+	UnreadOnly bool `json:"unread_only"`
+	Page       int  `json:"page"`
+	PageSize   int  `json:"page_size"`
+}
+
+type Notification struct {
+	ID                int64           `json:"id"`
+	ChainID           int             `json:"chain_id"`
+	Type              string          `json:"type"`
+	CollectionAddress string          `json:"collection_address"`
+	TokenID           string          `json:"token_id"`
+	OrderID           string          `json:"order_id"`
+	Price             decimal.Decimal `json:"price"`
+	Counterparty      string          `json:"counterparty"`
+	Detail            string          `json:"detail"`
+	IsRead            bool            `json:"is_read"`
+	EventTime         int64           `json:"event_time"`
+	CreateTime        int64           `json:"create_time"`
+}
+
+type NotificationsResp struct {
+	Result interface{} `json:"result"`
+	Count  int64       `json:"count"`
+	Unread int64       `json:"unread"`
+}
+
+type ReadNotificationsParams struct {
+	IDs []int64 `json:"ids"`
+}
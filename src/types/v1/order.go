@@ -6,3 +6,100 @@ type OrderInfosParam struct {
 	CollectionAddress string   `json:"collection_address"`
 	TokenIds          []string `json:"token_ids"`
 }
+
+type OrderValidateParams struct {
+	ChainID  int      `json:"chain_id"`
+	OrderIDs []string `json:"order_ids"`
+}
+
+// OrderValidity is the result of checking an order on chain. Unknown is set
+// when the chain state could not be read, Valid is false then.
+type OrderValidity struct {
+	OrderID string `json:"order_id"`
+	Valid   bool   `json:"valid"`
+	Unknown bool   `json:"unknown,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type OrderValidityResp struct {
+	Result interface{} `json:"result"`
+	Count  int64       `json:"count"`
+}
+
+type OrderAsset struct {
+	TokenID    string `json:"token_id"`
+	Collection string `json:"collection"`
+	Amount     int64  `json:"amount"`
+}
+
+// SignedOrder is an EasySwap order book order signed as EIP-712 typed data.
+// Price is in wei. The traits of a trait bid are signed apart, as
+// TraitCriteria typed data bound to the order key.
+type SignedOrder struct {
+	Side            uint8       `json:"side"`
+	SaleKind        uint8       `json:"sale_kind"`
+	Maker           string      `json:"maker"`
+	Nft             OrderAsset  `json:"nft"`
+	Price           string      `json:"price"`
+	Expiry          int64       `json:"expiry"`
+	Salt            int64       `json:"salt"`
+	MarketplaceID   int         `json:"marketplace_id"`
+	Traits          []ItemTrait `json:"traits"`
+	Signature       string      `json:"signature"`
+	TraitsSignature string      `json:"traits_signature"`
+}
+
+type SubmitOrderParams struct {
+	ChainID int         `json:"chain_id"`
+	Order   SignedOrder `json:"order"`
+}
+
+type SubmitOrdersParams struct {
+	ChainID int           `json:"chain_id"`
+	Orders  []SignedOrder `json:"orders"`
+}
+
+type SubmitOrderResult struct {
+	Index   int    `json:"index"`
+	OrderID string `json:"order_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type SubmitOrdersResp struct {
+	Result interface{} `json:"result"`
+	Count  int64       `json:"count"`
+}
+
+type CancelOrdersParams struct {
+	ChainID   int      `json:"chain_id"`
+	Maker     string   `json:"maker"`
+	OrderIDs  []string `json:"order_ids"`
+	Expiry    int64    `json:"expiry"`
+	Signature string   `json:"signature"`
+}
+
+type CancelOrderResult struct {
+	OrderID string `json:"order_id"`
+	Error   string `json:"error,omitempty"`
+}
+
+type CancelOrdersResp struct {
+	Result interface{} `json:"result"`
+	Count  int64       `json:"count"`
+}
+
+type OrderCancellationProof struct {
+	ChainID           int      `json:"chain_id"`
+	VerifyingContract string   `json:"verifying_contract"`
+	OrderID           string   `json:"order_id"`
+	Maker             string   `json:"maker"`
+	OrderIDs          []string `json:"order_ids"`
+	Expiry            int64    `json:"expiry"`
+	Signature         string   `json:"signature"`
+	MessageHash       string   `json:"message_hash"`
+	CancelTime        int64    `json:"cancel_time"`
+}
+
+type OrderCancellationResp struct {
+	Result interface{} `json:"result"`
+}
@@ -26,6 +26,7 @@ type CollectionInfo struct {
 	ListAmount int             `json:"list_amount"`
 	ItemAmount int64           `json:"item_amount"`
 	FloorPrice decimal.Decimal `json:"floor_price"`
+	IsVerified bool            `json:"is_verified"`
 }
 
 type ChainInfo struct {
@@ -78,8 +79,13 @@ type PortfolioItemInfo struct {
 	TokenID            string `json:"token_id"`
 	ImageURI           string `json:"image_uri"`
 
-	LastCostPrice float64         `json:"last_cost_price"`
+	LastCostPrice decimal.Decimal `json:"last_cost_price"`
+	CostBasis     decimal.Decimal `json:"cost_basis"`
+	RealizedPnL   decimal.Decimal `json:"realized_pnl"`
+	FloorPnL      decimal.Decimal `json:"unrealized_floor_pnl"`
+	BidPnL        decimal.Decimal `json:"unrealized_bid_pnl"`
 	OwnedTime     int64           `json:"owned_time"`
+	OwnedAmount   int64           `json:"owned_amount"`
 	Owner         string          `json:"owner"`
 	Listing       bool            `json:"listing"`
 	MarketplaceID int             `json:"marketplace_id"`
@@ -172,6 +178,78 @@ type UserBid struct {
 	BidInfos          []BidInfo       `json:"bid_infos"`
 }
 
+type PortfolioPnLFilterParams struct {
+	ChainID             []int    `json:"chain_id"`
+	CollectionAddresses []string `json:"collection_addresses"`
+	UserAddresses       []string `json:"user_addresses"`
+}
+
+// PnL is the profit and loss of held and sold tokens. Unrealized P&L values
+// the held tokens at the collection floor and at the best bid.
+type PnL struct {
+	Held        int64           `json:"held"`
+	Sold        int64           `json:"sold"`
+	CostBasis   decimal.Decimal `json:"cost_basis"`
+	Proceeds    decimal.Decimal `json:"proceeds"`
+	RealizedPnL decimal.Decimal `json:"realized_pnl"`
+	FloorValue  decimal.Decimal `json:"floor_value"`
+	BidValue    decimal.Decimal `json:"bid_value"`
+	FloorPnL    decimal.Decimal `json:"unrealized_floor_pnl"`
+	BidPnL      decimal.Decimal `json:"unrealized_bid_pnl"`
+}
+
+type CollectionPnL struct {
+	ChainID           int    `json:"chain_id"`
+	CollectionAddress string `json:"collection_address"`
+	CollectionName    string `json:"collection_name"`
+	PnL
+}
+
+type ChainPnL struct {
+	ChainID int `json:"chain_id"`
+	PnL
+}
+
+type PortfolioPnL struct {
+	Total       PnL             `json:"total"`
+	Chains      []ChainPnL      `json:"chains"`
+	Collections []CollectionPnL `json:"collections"`
+}
+
+type PortfolioPnLResp struct {
+	Result interface{} `json:"result"`
+}
+
+type PortfolioHistoryFilterParams struct {
+	ChainID             []int    `json:"chain_id"`
+	CollectionAddresses []string `json:"collection_addresses"`
+	UserAddresses       []string `json:"user_addresses"`
+
+	From       int64  `json:"from"`
+	To         int64  `json:"to"`
+	Resolution string `json:"resolution"`
+}
+
+type ChainValue struct {
+	ChainID   int             `json:"chain_id"`
+	ItemCount int64           `json:"item_count"`
+	Value     decimal.Decimal `json:"value"`
+}
+
+// PortfolioValuePoint is the portfolio value at the last snapshot of a period.
+// IsBackfill is set when the value was estimated from the floor price history.
+type PortfolioValuePoint struct {
+	Time       int64           `json:"time"`
+	ItemCount  int64           `json:"item_count"`
+	Value      decimal.Decimal `json:"value"`
+	IsBackfill bool            `json:"is_backfill"`
+	Chains     []ChainValue    `json:"chains"`
+}
+
+type PortfolioHistoryResp struct {
+	Result interface{} `json:"result"`
+}
+
 type MultichainCollection struct {
 	CollectionAddress string `json:"collection_address"`
 	Chain             string `json:"chain"`
@@ -0,0 +1,22 @@
+package types
+
+type UpdateProfileParams struct {
+	Username string `json:"username"`
+	Bio      string `json:"bio"`
+	Twitter  string `json:"twitter"`
+	Website  string `json:"website"`
+}
+
+type UserProfile struct {
+	Address    string `json:"address"`
+	Username   string `json:"username"`
+	AvatarURL  string `json:"avatar_url"`
+	Bio        string `json:"bio"`
+	Twitter    string `json:"twitter"`
+	Website    string `json:"website"`
+	UpdateTime int64  `json:"update_time"`
+}
+
+type UserProfileResp struct {
+	Result interface{} `json:"result"`
+}
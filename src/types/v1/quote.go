@@ -0,0 +1,46 @@
+package types
+
+import "github.com/shopspring/decimal"
+
+type AcceptBidItem struct {
+	TokenID string `json:"token_id"`
+	OrderID string `json:"order_id"`
+}
+
+type AcceptBidsQuoteParams struct {
+	ChainID           int             `json:"chain_id"`
+	CollectionAddress string          `json:"collection_address"`
+	Items             []AcceptBidItem `json:"items"`
+}
+
+type AcceptBidQuote struct {
+	TokenID            string          `json:"token_id"`
+	OrderID            string          `json:"order_id"`
+	MarketplaceID      int             `json:"marketplace_id"`
+	BidType            int64           `json:"bid_type"`
+	BidPrice           decimal.Decimal `json:"bid_price"`
+	RoyaltyFeeRate     decimal.Decimal `json:"royalty_fee_rate"`
+	RoyaltyFee         decimal.Decimal `json:"royalty_fee"`
+	MarketplaceFeeRate decimal.Decimal `json:"marketplace_fee_rate"`
+	MarketplaceFee     decimal.Decimal `json:"marketplace_fee"`
+	NetProceeds        decimal.Decimal `json:"net_proceeds"`
+	FloorPrice         decimal.Decimal `json:"floor_price"`
+	Warning            string          `json:"warning,omitempty"`
+	Error              string          `json:"error,omitempty"`
+}
+
+type AcceptBidsQuote struct {
+	Items          []AcceptBidQuote `json:"items"`
+	TotalPrice     decimal.Decimal  `json:"total_price"`
+	TotalRoyalty   decimal.Decimal  `json:"total_royalty"`
+	TotalFee       decimal.Decimal  `json:"total_fee"`
+	TotalProceeds  decimal.Decimal  `json:"total_proceeds"`
+	RoyaltyFeeRate decimal.Decimal  `json:"royalty_fee_rate"`
+	// RoyaltyEstimated is set when the royalty could not be read from the
+	// chain and the last known rate of the collection was used.
+	RoyaltyEstimated bool `json:"royalty_estimated"`
+}
+
+type AcceptBidsQuoteResp struct {
+	Result interface{} `json:"result"`
+}
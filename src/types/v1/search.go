@@ -0,0 +1,26 @@
+package types
+
+type SearchParams struct {
+	Query    string   `json:"query"`
+	ChainIDs []int    `json:"chain_ids"`
+	Kinds    []string `json:"kinds"`
+	Limit    int      `json:"limit"`
+}
+
+type SearchResult struct {
+	Kind     string  `json:"kind"`
+	ChainID  int     `json:"chain_id"`
+	Address  string  `json:"address"`
+	TokenID  string  `json:"token_id"`
+	Title    string  `json:"title"`
+	Subtitle string  `json:"subtitle"`
+	ImageURI string  `json:"image_uri"`
+	Score    float64 `json:"score"`
+	// IsVerified marks collections, and items of collections, verified by admins.
+	IsVerified bool `json:"is_verified"`
+}
+
+type SearchResp struct {
+	Result interface{} `json:"result"`
+	Count  int64       `json:"count"`
+}
@@ -0,0 +1,44 @@
+package types
+
+import "github.com/shopspring/decimal"
+
+type SweepParams struct {
+	ChainID int         `json:"chain_id"`
+	Count   int         `json:"count"`
+	Budget  string      `json:"budget"`
+	Traits  []ItemTrait `json:"traits"`
+	Markets []int       `json:"markets"`
+}
+
+type SweepItem struct {
+	TokenID        string          `json:"token_id"`
+	OrderID        string          `json:"order_id"`
+	MarketplaceID  int             `json:"marketplace_id"`
+	Maker          string          `json:"maker"`
+	Price          decimal.Decimal `json:"price"`
+	Salt           int64           `json:"salt"`
+	ExpireTime     int64           `json:"expire_time"`
+	RoyaltyFee     decimal.Decimal `json:"royalty_fee"`
+	MarketplaceFee decimal.Decimal `json:"marketplace_fee"`
+	RunningTotal   decimal.Decimal `json:"running_total"`
+}
+
+type SweepQuote struct {
+	Items          []SweepItem     `json:"items"`
+	Count          int             `json:"count"`
+	TotalPrice     decimal.Decimal `json:"total_price"`
+	TotalRoyalty   decimal.Decimal `json:"total_royalty"`
+	TotalFee       decimal.Decimal `json:"total_fee"`
+	RoyaltyFeeRate decimal.Decimal `json:"royalty_fee_rate"`
+	AveragePrice   decimal.Decimal `json:"average_price"`
+	FloorPrice     decimal.Decimal `json:"floor_price"`
+	FloorAfter     decimal.Decimal `json:"floor_after"`
+	PriceImpact    decimal.Decimal `json:"price_impact"`
+	// RoyaltyEstimated is set when the royalty could not be read from the
+	// chain and the last known rate of the collection was used.
+	RoyaltyEstimated bool `json:"royalty_estimated"`
+}
+
+type SweepResp struct {
+	Result interface{} `json:"result"`
+}
@@ -0,0 +1,41 @@
+package types
+
+import "github.com/shopspring/decimal"
+
+type WatchlistParams struct {
+	ChainID           int    `json:"chain_id"`
+	CollectionAddress string `json:"collection_address"`
+}
+
+type WatchlistEntry struct {
+	ChainID           int               `json:"chain_id"`
+	CollectionAddress string            `json:"collection_address"`
+	CreateTime        int64             `json:"create_time"`
+	Detail            *CollectionDetail `json:"detail"`
+}
+
+type WatchlistResp struct {
+	Result interface{} `json:"result"`
+}
+
+type CreatePriceAlertParams struct {
+	ChainID           int             `json:"chain_id"`
+	CollectionAddress string          `json:"collection_address"`
+	AlertType         string          `json:"alert_type"`
+	Threshold         decimal.Decimal `json:"threshold"`
+}
+
+type PriceAlert struct {
+	ID                int64           `json:"id"`
+	ChainID           int             `json:"chain_id"`
+	CollectionAddress string          `json:"collection_address"`
+	AlertType         string          `json:"alert_type"`
+	Threshold         decimal.Decimal `json:"threshold"`
+	IsTriggered       bool            `json:"is_triggered"`
+	LastTriggerTime   int64           `json:"last_trigger_time"`
+	CreateTime        int64           `json:"create_time"`
+}
+
+type PriceAlertsResp struct {
+	Result interface{} `json:"result"`
+}
@@ -0,0 +1,60 @@
+package types
+
+type CreateWebhookParams struct {
+	URL         string   `json:"url"`
+	ChainID     int      `json:"chain_id"`
+	EventTypes  []string `json:"event_types"`
+	Collections []string `json:"collections"`
+}
+
+type Webhook struct {
+	ID          int64    `json:"id"`
+	URL         string   `json:"url"`
+	ChainID     int      `json:"chain_id"`
+	EventTypes  []string `json:"event_types"`
+	Collections []string `json:"collections"`
+	CreateTime  int64    `json:"create_time"`
+}
+
+type CreateWebhookResp struct {
+	Result Webhook `json:"result"`
+	// Secret signs the payloads, it is only returned on creation.
+	Secret string `json:"secret"`
+}
+
+type WebhooksResp struct {
+	Result interface{} `json:"result"`
+}
+
+type WebhookDeliveryFilterParams struct {
+	Status   string `json:"status"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+}
+
+type WebhookDelivery struct {
+	ID              int64  `json:"id"`
+	EventID         string `json:"event_id"`
+	EventType       string `json:"event_type"`
+	Status          string `json:"status"`
+	Attempts        int    `json:"attempts"`
+	NextAttemptTime int64  `json:"next_attempt_time"`
+	ResponseCode    int    `json:"response_code"`
+	LastError       string `json:"last_error"`
+	CreateTime      int64  `json:"create_time"`
+	UpdateTime      int64  `json:"update_time"`
+}
+
+type WebhookDeliveriesResp struct {
+	Result interface{} `json:"result"`
+	Count  int64       `json:"count"`
+}
+
+// WebhookPayload is the body posted to webhook endpoints.
+type WebhookPayload struct {
+	ID         string       `json:"id"`
+	EventType  string       `json:"event_type"`
+	ChainID    int          `json:"chain_id"`
+	CreateTime int64        `json:"create_time"`
+	Data       ActivityInfo `json:"data"`
+}